@@ -12,6 +12,8 @@ import (
 	"runtime"
 	"time"
 
+	driver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
@@ -63,10 +65,41 @@ type ThreatMixtape struct {
 	C2OverDNSScore           float32 `ch:"c2_over_dns_score"`
 	C2OverDNSDirectConnScore float32 `ch:"c2_over_dns_direct_conn_score"`
 
+	// DNS Tunneling
+	DNSTunnelingScore float32 `ch:"dns_tunneling_score"`
+
 	// Threat Intel
 	ThreatIntel      bool    `ch:"threat_intel"`
 	ThreatIntelScore float32 `ch:"threat_intel_score"`
 
+	// DGA
+	DGAScore         float32  `ch:"dga_score"`
+	NXDomainCount    uint64   `ch:"nxdomain_count"`     // NXDOMAIN answers that the source got
+	DGADomains       uint64   `ch:"dga_domains"`        // random looking domains that the source got NXDOMAIN answers for
+	DGASampleDomains []string `ch:"dga_sample_domains"` // the most random looking of those domains
+
+	// Exfiltration
+	ExfiltrationScore float32 `ch:"exfiltration_score"`
+
+	// Port Scan
+	PortScanScore float32 `ch:"port_scan_score"`
+	ScanType      string  `ch:"scan_type"`     // horizontal (many hosts on one port) or vertical (many ports on one host)
+	ScannedHosts  uint64  `ch:"scanned_hosts"` // hosts probed on the same port in the busiest hour
+	ScannedPorts  uint64  `ch:"scanned_ports"` // ports probed on the same host in the busiest hour
+
+	// SSH
+	SSHBruteForceScore         float32 `ch:"ssh_brute_force_score"`
+	SSHAuthAttempts            uint64  `ch:"ssh_auth_attempts"` // most authentication attempts made to the destination in an hour
+	SSHLoginAfterFailuresScore float32 `ch:"ssh_login_after_failures_score"`
+	SSHFailedLogins            uint64  `ch:"ssh_failed_logins"` // failed authentication attempts before the first successful login
+
+	// Lateral Movement
+	LateralMovementScore float32 `ch:"lateral_movement_score"`
+	LateralHosts         uint64  `ch:"lateral_hosts"`         // internal hosts reached through admin shares or service control
+	AdminShareHosts      uint64  `ch:"admin_share_hosts"`     // internal hosts whose admin shares were newly mapped
+	ServiceControlHosts  uint64  `ch:"service_control_hosts"` // internal hosts whose service control manager was newly contacted
+	NewServiceTickets    uint64  `ch:"new_service_tickets"`   // kerberos services that tickets were newly requested for
+
 	// **** MODIFIERS ****
 	// for modifiers detected during the modifiers phase
 	ModifierName  string  `ch:"modifier_name"`
@@ -80,6 +113,19 @@ type ThreatMixtape struct {
 	ThreatIntelDataSizeScore float32 `ch:"threat_intel_data_size_score"`
 	MissingHostHeaderScore   float32 `ch:"missing_host_header_score"`
 
+	// certificate details, only set by the certificate anomaly modifier
+	CertSubject        string    `ch:"cert_subject"`
+	CertIssuer         string    `ch:"cert_issuer"`
+	CertNotValidBefore time.Time `ch:"cert_not_valid_before"`
+	CertNotValidAfter  time.Time `ch:"cert_not_valid_after"`
+
+	// resolved ip details, only set by the fast flux modifier
+	ResolvedIPCount    uint64   `ch:"resolved_ip_count"`
+	ResolvedIPNetworks uint64   `ch:"resolved_ip_networks"` // /16 networks (/32 for IPv6) of the resolved ips
+	ResolvedIPRate     float32  `ch:"resolved_ip_rate"`     // new ips per hour over the time span that they were first seen in
+	ResolvedIPTTL      uint32   `ch:"resolved_ip_ttl"`      // median ttl of the resolved ips
+	ResolvedIPs        []net.IP `ch:"resolved_ips"`         // most recently first resolved first
+
 	// FirstSeenMod           float32 `ch:"first_seen_mod"`
 	// PrevalenceMod          float32 `ch:"prevalence_mod"`
 	// MissingHostHeaderMod   float32 `ch:"missing_host_header_mod"`
@@ -155,6 +201,27 @@ func (analyzer *Analyzer) Analyze() error {
 		return fmt.Errorf("could not perform spagoop analysis: %w", err)
 	}
 
+	// score lateral movement, which is written straight to the mixtape since it isn't tracked by connection pair
+	if err := analyzer.ScoopLateralMovement(ctx); err != nil {
+		return fmt.Errorf("could not perform lateral movement analysis: %w", err)
+	}
+
+	// score hosts with bursts of NXDOMAIN answers for generated domains, which are also written straight to the mixtape
+	if err := analyzer.ScoopDGA(ctx); err != nil {
+		return fmt.Errorf("could not perform dga analysis: %w", err)
+	}
+
+	// score horizontal and vertical port scans, which are written straight to the mixtape as one entry per scan
+	if err := analyzer.ScoopPortScans(ctx); err != nil {
+		return fmt.Errorf("could not perform port scan analysis: %w", err)
+	}
+
+	// score outbound SSH brute force and logins after failed attempts, which are written straight to the mixtape
+	// so that they are reported for connection pairs without any other threat indicators
+	if err := analyzer.ScoopSSH(ctx); err != nil {
+		return fmt.Errorf("could not perform ssh analysis: %w", err)
+	}
+
 	// wait for all analysis threads to finish
 	if err := analysisErrGroup.Wait(); err != nil {
 		logger.Fatal().Err(err).Msg("could not perform beacon analysis")
@@ -224,6 +291,16 @@ func (analyzer *Analyzer) runAnalysis() error {
 				}
 			}
 
+			// DNS TUNNELING
+			// score the lexical features of the subdomains if there are enough of them to tell encoded data apart from normal names
+			if entry.SubdomainCount >= uint64(analyzer.Config.Scoring.DNSTunneling.MinimumSubdomains) {
+				dnsTunnelingScore := calculateBucketedScore(calculateDNSTunnelingScore(&entry, analyzer.Config.Scoring.DNSTunneling), analyzer.Config.Scoring.DNSTunneling.ScoreThresholds)
+				if dnsTunnelingScore > 0 {
+					hasThreatIndicator = true
+					mixtape.DNSTunnelingScore = dnsTunnelingScore
+				}
+			}
+
 		} else {
 
 			// ALL OTHER THREAT INDICATORS
@@ -250,6 +327,12 @@ func (analyzer *Analyzer) runAnalysis() error {
 				mixtape.LongConnScore = longConnScore
 			}
 
+			// run exfiltration analysis on entry if its source sent far more data than it received
+			if exfiltrationScore := calculateExfiltrationScore(&entry, analyzer.Config.Scoring.Exfiltration); exfiltrationScore > 0 {
+				hasThreatIndicator = true
+				mixtape.ExfiltrationScore = exfiltrationScore
+			}
+
 			// record entry as a strobe if the overall connection count meets the strobe threshold (1 connection per second)
 			if entry.Count >= 86400 {
 				hasThreatIndicator = true
@@ -309,6 +392,68 @@ func (analyzer *Analyzer) runAnalysis() error {
 	return nil
 }
 
+// scoopMixtape scans each row of an indicator that is scored outside of the analysis workers into a result, formats
+// it into a mixtape entry and writes it to the mixtape. Results that format returns a nil entry for aren't scored.
+func scoopMixtape[T any](ctx context.Context, analyzer *Analyzer, indicator string, rows driver.Rows, format func(T) (*ThreatMixtape, error)) error {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	for rows.Next() {
+		select {
+		// abort this function if the context was cancelled
+		case <-ctx.Done():
+			logger.Warn().Str("indicator", indicator).Msg("cancelling query for analysis")
+			return ctx.Err()
+		default:
+			var res T
+			if err := rows.ScanStruct(&res); err != nil {
+				return fmt.Errorf("could not read %s during analysis: %w", indicator, err)
+			}
+
+			mixtape, err := format(res)
+			if err != nil {
+				logger.Debug().Err(err).Str("indicator", indicator).Msg("could not format analysis result")
+				continue
+			}
+
+			// the result didn't reach the base threshold to be scored
+			if mixtape == nil {
+				continue
+			}
+
+			mixtape.AnalyzedAt = analyzer.Database.ImportStartedAt.Truncate(time.Microsecond)
+			analyzer.writer.WriteChannel <- mixtape
+		}
+	}
+
+	return rows.Err()
+}
+
+// newSourceMixtape returns the mixtape entry of an indicator that is scored per source host instead of per connection
+// pair, so the destination is left unspecified and the hash is built from the source and the keys of the indicator
+func (analyzer *Analyzer) newSourceMixtape(src net.IP, srcNUID uuid.UUID, count uint64, firstSeen time.Time, lastSeen time.Time, keys ...string) (*ThreatMixtape, error) {
+	hash, err := util.NewFixedStringHash(append([]string{src.To16().String(), srcNUID.String()}, keys...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSeen, _ = util.ValidateTimestamp(firstSeen)
+	lastSeen, _ = util.ValidateTimestamp(lastSeen)
+
+	return &ThreatMixtape{
+		ImportID: analyzer.ImportID,
+		AnalysisResult: AnalysisResult{
+			Hash:                hash,
+			Src:                 src,
+			SrcNUID:             srcNUID,
+			Dst:                 net.IPv6unspecified,
+			Count:               count,
+			FirstSeenHistorical: firstSeen,
+			LastSeen:            lastSeen,
+		},
+	}, nil
+}
+
 func calculateBucketedScore(value float64, thresholds config.ScoreThresholds) float32 {
 	base := float64(thresholds.Base)
 	low := float64(thresholds.Low)
@@ -342,6 +487,53 @@ func calculateBucketedScore(value float64, thresholds config.ScoreThresholds) fl
 	return score / 100
 }
 
+// calculateExfiltrationScore scores a connection pair by the megabytes that its source sent, with the bytes sent during off hours
+// weighted more heavily, scaled by how far the ratio of the bytes it sent to the bytes it received is above the minimum ratio.
+// Pairs whose source didn't send at least the minimum ratio of the bytes that it received aren't scored.
+func calculateExfiltrationScore(entry *AnalysisResult, cfg config.Exfiltration) float32 {
+	if entry.BytesSent <= 0 || float64(entry.BytesSent) < cfg.MinimumRatio*float64(entry.BytesReceived) {
+		return 0
+	}
+
+	weightedBytes := float64(entry.BytesSent) + float64(entry.OffHoursBytesSent)*(cfg.OffHoursWeight-1)
+	volumeScore := calculateBucketedScore(weightedBytes/(1024*1024), cfg.ScoreThresholds)
+
+	// the ratio is scored on a log scale from the minimum ratio to 100 times the minimum ratio, since uploads that
+	// receive nothing but acknowledgements back are thousands of times larger than what they receive
+	ratioScore := 1.0
+	if entry.BytesReceived > 0 {
+		ratio := float64(entry.BytesSent) / float64(entry.BytesReceived)
+		ratioScore = normalizeFeature(math.Log10(ratio/cfg.MinimumRatio), 0, 2)
+	}
+
+	return volumeScore * float32(1-cfg.RatioWeight+cfg.RatioWeight*ratioScore)
+}
+
+// calculateDNSTunnelingScore combines the lexical features of the subdomains queried under a domain into a score from 0 to 100.
+// Each feature is normalized to a subscore from 0 to 1 between the values seen for typical hostnames and for encoded data.
+func calculateDNSTunnelingScore(entry *AnalysisResult, cfg config.DNSTunneling) float64 {
+	// hostnames rarely have more than 2.5 bits of entropy per character, while encoded data approaches 4.5 or more
+	entropyScore := normalizeFeature(float64(entry.LabelEntropy), 2.5, 4.5)
+
+	// hostname labels are usually short words, while tunnels fill labels up to their 63 character limit
+	labelLengthScore := normalizeFeature(float64(entry.AvgLabelLength), 8, 40)
+
+	// use the encoding that the most labels look like
+	encodingScore := float64(max(entry.HexLabelRatio, entry.Base32LabelRatio, entry.Base64LabelRatio))
+
+	// TXT and NULL records carry the largest responses back from the tunnel server
+	queryTypeScore := float64(entry.TXTNullQueryRatio)
+
+	score := cfg.EntropyWeight*entropyScore + cfg.LabelLengthWeight*labelLengthScore + cfg.EncodingWeight*encodingScore + cfg.QueryTypeWeight*queryTypeScore
+
+	return math.Min(score, 1) * 100
+}
+
+// normalizeFeature scales the value linearly from 0 at low to 1 at high, clamping values outside of that range
+func normalizeFeature(value, low, high float64) float64 {
+	return math.Max(0, math.Min(1, (value-low)/(high-low)))
+}
+
 // shouldHaveC2OverDNSDirectConnModifier returns true if no ips other than the ones in queriedby made connections to this domain
 func shouldHaveC2OverDNSDirectConnModifier(directConns, queriedBy []net.IP) bool {
 	if len(queriedBy) > 0 {
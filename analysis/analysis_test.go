@@ -3,6 +3,7 @@ package analysis
 import (
 	"activecm/rita/config"
 	"log"
+	"net"
 	"testing"
 
 	"github.com/joho/godotenv"
@@ -53,6 +54,9 @@ func TestCalculateBucketedScore(t *testing.T) {
 		{Name: "C2 Over DNS", Thresholds: cfg.Scoring.C2ScoreThresholds},
 		{Name: "Long Connections", Thresholds: cfg.Scoring.LongConnectionScoreThresholds},
 		{Name: "Beacons", Thresholds: cfg.Scoring.Beacon.ScoreThresholds},
+		{Name: "Lateral Movement", Thresholds: cfg.Scoring.LateralMovementScoreThresholds},
+		{Name: "DNS Tunneling", Thresholds: cfg.Scoring.DNSTunneling.ScoreThresholds},
+		{Name: "DGA", Thresholds: cfg.Scoring.DGA.ScoreThresholds},
 	}
 
 	for _, test := range testCases {
@@ -100,3 +104,189 @@ func TestCalculateBucketedScore(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculateDNSTunnelingScore(t *testing.T) {
+	cfg, err := config.ReadFileConfig(afero.NewOsFs(), "../config.hjson")
+	require.NoError(t, err)
+	tunneling := cfg.Scoring.DNSTunneling
+
+	type testCase struct {
+		name     string
+		entry    AnalysisResult
+		expected float64
+	}
+
+	testCases := []testCase{
+		{
+			name:     "No Features",
+			entry:    AnalysisResult{},
+			expected: 0,
+		},
+		{
+			name:     "Typical Hostnames",
+			entry:    AnalysisResult{LabelEntropy: 2.1, AvgLabelLength: 5, HexLabelRatio: 0.01},
+			expected: 100 * tunneling.EncodingWeight * 0.01,
+		},
+		{
+			name: "Encoded Labels",
+			entry: AnalysisResult{
+				LabelEntropy: 3.5, AvgLabelLength: 24,
+				HexLabelRatio: 0.2, Base32LabelRatio: 0.9, Base64LabelRatio: 0.5, TXTNullQueryRatio: 0.5,
+			},
+			expected: 100 * (tunneling.EntropyWeight*0.5 + tunneling.LabelLengthWeight*0.5 + tunneling.EncodingWeight*0.9 + tunneling.QueryTypeWeight*0.5),
+		},
+		{
+			name: "Features Past Their Limits",
+			entry: AnalysisResult{
+				LabelEntropy: 5.5, AvgLabelLength: 63,
+				Base64LabelRatio: 1, TXTNullQueryRatio: 1,
+			},
+			expected: 100,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			score := calculateDNSTunnelingScore(&test.entry, tunneling)
+			require.InDelta(t, test.expected, score, 0.001, "dns tunneling score should match expected value")
+		})
+	}
+
+	// verify that the weights are applied to their features
+	entropyOnly := tunneling
+	entropyOnly.EntropyWeight, entropyOnly.LabelLengthWeight, entropyOnly.EncodingWeight, entropyOnly.QueryTypeWeight = 1, 0, 0, 0
+	score := calculateDNSTunnelingScore(&AnalysisResult{LabelEntropy: 4.5, AvgLabelLength: 63, Base32LabelRatio: 1, TXTNullQueryRatio: 1}, entropyOnly)
+	require.InDelta(t, 100, score, 0.001, "score should only include the entropy subscore")
+	score = calculateDNSTunnelingScore(&AnalysisResult{AvgLabelLength: 63, Base32LabelRatio: 1, TXTNullQueryRatio: 1}, entropyOnly)
+	require.InDelta(t, 0, score, 0.001, "score should ignore the features that have no weight")
+}
+
+func TestCalculateExfiltrationScore(t *testing.T) {
+	cfg := config.Exfiltration{
+		MinimumRatio:    10,
+		OffHoursStart:   19,
+		OffHoursEnd:     7,
+		OffHoursWeight:  2,
+		RatioWeight:     0.5,
+		ScoreThresholds: config.ScoreThresholds{Base: 100, Low: 500, Med: 1000, High: 5000},
+	}
+	megabyte := int64(1024 * 1024)
+
+	type testCase struct {
+		name     string
+		entry    AnalysisResult
+		expected float32
+	}
+
+	testCases := []testCase{
+		{
+			name:     "No Bytes Sent",
+			entry:    AnalysisResult{BytesReceived: 900 * megabyte},
+			expected: 0,
+		},
+		{
+			name:     "Below Minimum Ratio",
+			entry:    AnalysisResult{BytesSent: 900 * megabyte, BytesReceived: 100 * megabyte},
+			expected: 0,
+		},
+		{
+			name:     "Below Base Threshold",
+			entry:    AnalysisResult{BytesSent: 99 * megabyte},
+			expected: 0,
+		},
+		{
+			name:     "Nothing Received",
+			entry:    AnalysisResult{BytesSent: 750 * megabyte},
+			expected: calculateBucketedScore(750, cfg.ScoreThresholds),
+		},
+		{
+			name:     "At Minimum Ratio",
+			entry:    AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 75 * megabyte},
+			expected: calculateBucketedScore(750, cfg.ScoreThresholds) * 0.5,
+		},
+		{
+			name:     "Ten Times Minimum Ratio",
+			entry:    AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 75 * megabyte / 10},
+			expected: calculateBucketedScore(750, cfg.ScoreThresholds) * 0.75,
+		},
+		{
+			name:     "Past 100 Times Minimum Ratio",
+			entry:    AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 1},
+			expected: calculateBucketedScore(750, cfg.ScoreThresholds),
+		},
+		{
+			name:     "Off Hours Bytes Weighted",
+			entry:    AnalysisResult{BytesSent: 750 * megabyte, OffHoursBytesSent: 500 * megabyte},
+			expected: calculateBucketedScore(1250, cfg.ScoreThresholds),
+		},
+		{
+			name:     "Off Hours Bytes Push Past Base Threshold",
+			entry:    AnalysisResult{BytesSent: 60 * megabyte, OffHoursBytesSent: 60 * megabyte},
+			expected: calculateBucketedScore(120, cfg.ScoreThresholds),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			score := calculateExfiltrationScore(&test.entry, cfg)
+			require.InDelta(t, test.expected, score, 0.0001, "exfiltration score should match expected value")
+		})
+	}
+
+	// verify that off hours bytes aren't weighted more heavily when the weight is 1
+	unweighted := cfg
+	unweighted.OffHoursWeight = 1
+	score := calculateExfiltrationScore(&AnalysisResult{BytesSent: 750 * megabyte, OffHoursBytesSent: 750 * megabyte}, unweighted)
+	require.InDelta(t, calculateBucketedScore(750, cfg.ScoreThresholds), score, 0.0001, "off hours bytes should count once with a weight of 1")
+
+	// verify that a more lopsided ratio scores higher than a ratio that barely clears the minimum at the same volume
+	barely := calculateExfiltrationScore(&AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 60 * megabyte}, cfg)
+	lopsided := calculateExfiltrationScore(&AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 2 * megabyte}, cfg)
+	require.Greater(t, barely, float32(0), "ratio above the minimum ratio should be scored")
+	require.Greater(t, lopsided, barely, "more lopsided ratio should score higher at the same volume")
+
+	// verify that the ratio doesn't affect the score when its weight is 0
+	volumeOnly := cfg
+	volumeOnly.RatioWeight = 0
+	require.InDelta(t, calculateExfiltrationScore(&AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 60 * megabyte}, volumeOnly),
+		calculateExfiltrationScore(&AnalysisResult{BytesSent: 750 * megabyte, BytesReceived: 2 * megabyte}, volumeOnly), 0.0001,
+		"ratio should not affect the score with a weight of 0")
+}
+
+func TestFormatLateralMovementMixtape(t *testing.T) {
+	cfg, err := config.ReadFileConfig(afero.NewOsFs(), "../config.hjson")
+	require.NoError(t, err)
+	cfg.Scoring.LateralMovementScoreThresholds = config.ScoreThresholds{Base: 5, Low: 10, Med: 20, High: 50}
+
+	analyzer := &Analyzer{Config: &cfg}
+
+	// verify that a source that touched too few new hosts or services isn't scored
+	mixtape, err := analyzer.formatLateralMovementMixtape(lateralMovementResult{
+		Src:             net.ParseIP("10.55.100.105"),
+		AdminShareHosts: 2,
+	})
+	require.NoError(t, err)
+	require.Nil(t, mixtape, "source below the base threshold should not be scored")
+
+	// verify that admin shares, service control pipes and service tickets are counted together
+	res := lateralMovementResult{
+		Src:                 net.ParseIP("10.55.100.105"),
+		Count:               42,
+		LateralHosts:        8,
+		AdminShareHosts:     6,
+		ServiceControlHosts: 4,
+		NewServiceTickets:   10,
+	}
+	mixtape, err = analyzer.formatLateralMovementMixtape(res)
+	require.NoError(t, err)
+	require.NotNil(t, mixtape, "source above the base threshold should be scored")
+	require.InDelta(t, calculateBucketedScore(20, cfg.Scoring.LateralMovementScoreThresholds), mixtape.LateralMovementScore, 0.0001, "score should be bucketed from the total count")
+	require.True(t, mixtape.Dst.IsUnspecified(), "destination should be left unspecified")
+	require.EqualValues(t, 8, mixtape.LateralHosts, "lateral hosts should match expected value")
+	require.EqualValues(t, 42, mixtape.Count, "count should match expected value")
+
+	// verify that the same source always produces the same hash
+	other, err := analyzer.formatLateralMovementMixtape(res)
+	require.NoError(t, err)
+	require.Equal(t, mixtape.Hash, other.Hash, "hash should be consistent for the same source")
+}
@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// dgaSampleSize is the number of domains that are kept as samples of a host's random looking NXDOMAIN answers
+	dgaSampleSize = 5
+	// minDGANameLength is the shortest registered name that is scored for randomness, since short names have too
+	// few character pairs to tell generated names apart from abbreviations
+	minDGANameLength = 6
+)
+
+// commonBigrams holds the most common character pairs in English text, which make up most of the character pairs
+// of domains that are made of words, but only a small share of those of algorithmically generated domains
+var commonBigrams = func() map[string]struct{} {
+	bigrams := make(map[string]struct{})
+	for _, bigram := range strings.Fields(`
+		th he in er an re on at en nd ti es or te of ed is it al ar st to nt ng se ha as ou io le ve co me de hi
+		ri ro ic ne ea ra ce li ch ll be ma si om ur ca el ta la ns di fo ho pe ec pr no ct us ac ot il tr ly nc
+		et ut ss so rs un lo wa ge ie wh ee wi em ad ol rt po we na ul ni ts mo ow pa im mi ai sh ir su id os iv
+		ia am fi ci vi pl ig tu ev ld ry mp fe bl ab gh ty op wo sa ay ex ke fr oo av ag if ap gr od bo sp rd do
+		uc bu ei ov by rm ep tt oc fa ef cu rn sc gi da yo cr cl du ga qu ue ff ba ey ls va um pp ua up lu go ht
+		ru ug ds lt pi rc rr eg au ck ew mu br bi pt ak pu ui rg ib tl ny ki rk ys ob mm fu ph og ms ye ud mb ip
+		ub oi rl gu dr hr cc tw ft wn nu af hu nn eo vo rv nf xp gn sm fl iz ok nl my gl aw ju oa eq sy sl ps
+	`) {
+		bigrams[bigram] = struct{}{}
+	}
+	return bigrams
+}()
+
+type dgaResult struct {
+	Src           net.IP     `ch:"src"`
+	SrcNUID       uuid.UUID  `ch:"src_nuid"`
+	NXDomainCount uint64     `ch:"nxdomain_count"`
+	HourlyDomains [][]string `ch:"hourly_domains"`
+	FirstSeen     time.Time  `ch:"first_seen"`
+	LastSeen      time.Time  `ch:"last_seen"`
+}
+
+// ScoopDGA scores internal hosts that got bursts of NXDOMAIN answers for domains that look algorithmically generated,
+// which is how malware that cycles through generated domains finds the one that its operator registered
+func (analyzer *Analyzer) ScoopDGA(ctx context.Context) error {
+	chCtx := clickhouse.Context(analyzer.Database.GetContext(), clickhouse.WithParameters(clickhouse.Parameters{
+		// use minTS (not minTSBeacon) because DNS logs don't get correlated with conn logs
+		"min_ts":         fmt.Sprintf("%d", analyzer.minTS.UTC().Unix()),
+		"base_threshold": fmt.Sprint(analyzer.Config.Scoring.DGA.ScoreThresholds.Base),
+	}))
+
+	rows, err := analyzer.Database.Conn.Query(chCtx, `--sql
+		SELECT src, src_nuid,
+			sum(nxdomain_count) AS nxdomain_count,
+			groupArray(domains) AS hourly_domains,
+			min(first_seen) AS first_seen,
+			max(last_seen) AS last_seen
+		FROM (
+			-- get the unique domains that each source got NXDOMAIN answers for in each hour
+			SELECT src, src_nuid, hour,
+				countMerge(count) AS nxdomain_count,
+				groupUniqArray(1000)(tld) AS domains,
+				minMerge(first_seen) AS first_seen,
+				maxMerge(last_seen) AS last_seen
+			FROM nxdomain
+			WHERE hour >= toStartOfHour(fromUnixTimestamp({min_ts:Int64}))
+			GROUP BY src, src_nuid, hour
+		)
+		GROUP BY src, src_nuid
+		-- only sources with enough NXDOMAIN answers in an hour to reach the base threshold can be scored
+		HAVING max(length(domains)) >= {base_threshold:Int32}
+	`)
+	if err != nil {
+		return fmt.Errorf("could not retrieve nxdomain answers for analysis: %w", err)
+	}
+
+	return scoopMixtape(ctx, analyzer, "nxdomain answers", rows, analyzer.formatDGAMixtape)
+}
+
+// formatDGAMixtape scores a source host's NXDOMAIN answers for random looking domains and formats it into a mixtape entry
+func (analyzer *Analyzer) formatDGAMixtape(res dgaResult) (*ThreatMixtape, error) {
+	threshold := analyzer.Config.Scoring.DGA.RandomnessThreshold
+
+	// find the most random looking domains that the source queried in a single hour
+	randomness := make(map[string]float64)
+	burst := 0
+	for _, domains := range res.HourlyDomains {
+		count := 0
+		for _, domain := range domains {
+			r := domainRandomness(domain)
+			if r < threshold {
+				continue
+			}
+			randomness[domain] = r
+			count++
+		}
+		burst = max(burst, count)
+	}
+
+	score := calculateBucketedScore(float64(burst), analyzer.Config.Scoring.DGA.ScoreThresholds)
+	if score <= 0 {
+		return nil, nil
+	}
+
+	// keep the most random looking domains as samples
+	samples := make([]string, 0, len(randomness))
+	for domain := range randomness {
+		samples = append(samples, domain)
+	}
+	slices.SortFunc(samples, func(a, b string) int {
+		if randomness[a] != randomness[b] {
+			if randomness[a] > randomness[b] {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	if len(samples) > dgaSampleSize {
+		samples = samples[:dgaSampleSize]
+	}
+
+	mixtape, err := analyzer.newSourceMixtape(res.Src, res.SrcNUID, res.NXDomainCount, res.FirstSeen, res.LastSeen, "dga")
+	if err != nil {
+		return nil, err
+	}
+
+	mixtape.DGAScore = score
+	mixtape.NXDomainCount = res.NXDomainCount
+	mixtape.DGADomains = uint64(len(randomness))
+	mixtape.DGASampleDomains = samples
+
+	return mixtape, nil
+}
+
+// domainRandomness returns the share of the character pairs in the registered name of a domain that are uncommon in
+// English, from 0 for names that are made of words to 1 for names that look randomly generated
+func domainRandomness(domain string) float64 {
+	name, _, _ := strings.Cut(strings.ToLower(domain), ".")
+
+	// internationalized names are punycode encoded, which looks random regardless of the name
+	if len(name) < minDGANameLength || strings.HasPrefix(name, "xn--") {
+		return 0
+	}
+
+	uncommon := 0
+	for i := 0; i < len(name)-1; i++ {
+		if _, ok := commonBigrams[name[i:i+2]]; !ok {
+			uncommon++
+		}
+	}
+
+	return float64(uncommon) / float64(len(name)-1)
+}
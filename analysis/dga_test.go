@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"activecm/rita/config"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainRandomness(t *testing.T) {
+	cfg, err := config.ReadFileConfig(afero.NewOsFs(), "../config.hjson")
+	require.NoError(t, err)
+	threshold := cfg.Scoring.DGA.RandomnessThreshold
+
+	// verify that domains made of words are below the randomness threshold
+	for _, domain := range []string{"google.com", "microsoft.com", "wikipedia.org", "activecountermeasures.com", "windowsupdate.com", "amazon.co.uk"} {
+		require.Less(t, domainRandomness(domain), threshold, "%s should not look random", domain)
+	}
+
+	// verify that generated domains are above the randomness threshold
+	for _, domain := range []string{"xjq7vk2zpw.com", "qxzvbkwprt.net", "a8f3k2m9q1.biz", "ydqmbklnvt.info", "pmqtcvkhwusl.ru"} {
+		require.GreaterOrEqual(t, domainRandomness(domain), threshold, "%s should look random", domain)
+	}
+
+	// verify that short and internationalized names aren't scored
+	require.InDelta(t, 0, domainRandomness("xq7z.com"), 0.0001, "short names should not be scored")
+	require.InDelta(t, 0, domainRandomness("xn--bcher-kva.com"), 0.0001, "punycode names should not be scored")
+
+	// verify that case is ignored
+	require.InDelta(t, domainRandomness("qxzvbkwprt.net"), domainRandomness("QXZVBKWPRT.NET"), 0.0001, "randomness should not depend on case")
+}
+
+func TestFormatDGAMixtape(t *testing.T) {
+	cfg, err := config.ReadFileConfig(afero.NewOsFs(), "../config.hjson")
+	require.NoError(t, err)
+	cfg.Scoring.DGA.ScoreThresholds = config.ScoreThresholds{Base: 5, Low: 10, Med: 20, High: 50}
+
+	analyzer := &Analyzer{Config: &cfg}
+
+	generated := func(n int) []string {
+		domains := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			domains = append(domains, fmt.Sprintf("qxzvbkw%03dz.com", i))
+		}
+		return domains
+	}
+
+	// verify that a source with too few random looking NXDOMAIN answers in an hour isn't scored
+	mixtape, err := analyzer.formatDGAMixtape(dgaResult{
+		Src:           net.ParseIP("10.55.100.105"),
+		NXDomainCount: 30,
+		HourlyDomains: [][]string{generated(3), {"google.com", "microsoft.com", "wikipedia.org", "github.com", "youtube.com"}},
+	})
+	require.NoError(t, err)
+	require.Nil(t, mixtape, "source below the base threshold should not be scored")
+
+	// verify that the score is bucketed from the busiest hour and that domains made of words aren't counted
+	res := dgaResult{
+		Src:           net.ParseIP("10.55.100.105"),
+		NXDomainCount: 120,
+		HourlyDomains: [][]string{
+			append(generated(20), "google.com", "microsoft.com"),
+			generated(8),
+			append(generated(4), "xjq7vk2zpw.com"),
+		},
+	}
+	mixtape, err = analyzer.formatDGAMixtape(res)
+	require.NoError(t, err)
+	require.NotNil(t, mixtape, "source above the base threshold should be scored")
+	require.InDelta(t, calculateBucketedScore(20, cfg.Scoring.DGA.ScoreThresholds), mixtape.DGAScore, 0.0001, "score should be bucketed from the busiest hour")
+	require.True(t, mixtape.Dst.IsUnspecified(), "destination should be left unspecified")
+	require.EqualValues(t, 120, mixtape.NXDomainCount, "nxdomain count should match expected value")
+	require.EqualValues(t, 21, mixtape.DGADomains, "dga domains should count the unique random looking domains")
+	require.Len(t, mixtape.DGASampleDomains, dgaSampleSize, "sample domains should be limited to the sample size")
+	for i := 1; i < len(mixtape.DGASampleDomains); i++ {
+		require.GreaterOrEqual(t, domainRandomness(mixtape.DGASampleDomains[i-1]), domainRandomness(mixtape.DGASampleDomains[i]), "sample domains should be sorted from most to least random looking")
+	}
+	require.NotContains(t, mixtape.DGASampleDomains, "google.com", "sample domains should not include domains made of words")
+
+	// verify that the same source always produces the same hash and a different hash than its lateral movement
+	other, err := analyzer.formatDGAMixtape(res)
+	require.NoError(t, err)
+	require.Equal(t, mixtape.Hash, other.Hash, "hash should be consistent for the same source")
+	require.Equal(t, mixtape.DGASampleDomains, other.DGASampleDomains, "sample domains should be consistent for the same source")
+
+	lateral, err := analyzer.formatLateralMovementMixtape(lateralMovementResult{Src: res.Src, AdminShareHosts: 50})
+	require.NoError(t, err)
+	require.NotEqual(t, mixtape.Hash, lateral.Hash, "dga and lateral movement hashes should differ for the same source")
+}
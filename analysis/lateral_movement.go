@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+)
+
+type lateralMovementResult struct {
+	Src                 net.IP    `ch:"src"`
+	SrcNUID             uuid.UUID `ch:"src_nuid"`
+	Count               uint64    `ch:"count"`
+	LateralHosts        uint64    `ch:"lateral_hosts"`
+	AdminShareHosts     uint64    `ch:"admin_share_hosts"`
+	ServiceControlHosts uint64    `ch:"service_control_hosts"`
+	NewServiceTickets   uint64    `ch:"new_service_tickets"`
+	FirstSeen           time.Time `ch:"first_seen"`
+	LastSeen            time.Time `ch:"last_seen"`
+}
+
+// ScoopLateralMovement scores internal hosts that started touching many admin shares, remote service control
+// pipes or kerberos services during the analysis window
+func (analyzer *Analyzer) ScoopLateralMovement(ctx context.Context) error {
+	chCtx := clickhouse.Context(analyzer.Database.GetContext(), clickhouse.WithParameters(clickhouse.Parameters{
+		// use minTS (not minTSBeacon) because lateral movement logs don't get correlated with conn logs
+		"min_ts":         fmt.Sprintf("%d", analyzer.minTS.UTC().Unix()),
+		"base_threshold": fmt.Sprint(analyzer.Config.Scoring.LateralMovementScoreThresholds.Base),
+	}))
+
+	rows, err := analyzer.Database.Conn.Query(chCtx, `--sql
+		WITH first_touches AS (
+			-- get the first time that each source touched each admin share, service control manager and kerberos service
+			-- across all of the retained history so that only the ones that are new to this analysis window get counted
+			SELECT src, src_nuid,
+				multiIf(admin_share, 'admin_share', service_control, 'service_control', 'service_ticket') AS kind,
+				if(kind = 'service_ticket', service, toString(dst)) AS target,
+				count() AS count,
+				min(ts) AS first_seen,
+				max(ts) AS last_seen
+			FROM lateral_movement
+			WHERE admin_share OR service_control OR (log_type = 'kerberos' AND request_type = 'TGS' AND success AND service != '')
+			GROUP BY src, src_nuid, kind, target
+		)
+		SELECT src, src_nuid,
+			sumIf(count, first_seen >= fromUnixTimestamp({min_ts:Int64})) AS count,
+			uniqExactIf(target, kind != 'service_ticket' AND first_seen >= fromUnixTimestamp({min_ts:Int64})) AS lateral_hosts,
+			uniqExactIf(target, kind = 'admin_share' AND first_seen >= fromUnixTimestamp({min_ts:Int64})) AS admin_share_hosts,
+			uniqExactIf(target, kind = 'service_control' AND first_seen >= fromUnixTimestamp({min_ts:Int64})) AS service_control_hosts,
+			uniqExactIf(target, kind = 'service_ticket' AND first_seen >= fromUnixTimestamp({min_ts:Int64})) AS new_service_tickets,
+			minIf(first_seen, first_seen >= fromUnixTimestamp({min_ts:Int64})) AS first_seen,
+			max(last_seen) AS last_seen
+		FROM first_touches
+		GROUP BY src, src_nuid
+		HAVING admin_share_hosts + service_control_hosts + new_service_tickets >= {base_threshold:Int32}
+	`)
+	if err != nil {
+		return fmt.Errorf("could not retrieve lateral movement for analysis: %w", err)
+	}
+
+	return scoopMixtape(ctx, analyzer, "lateral movement", rows, analyzer.formatLateralMovementMixtape)
+}
+
+// formatLateralMovementMixtape scores a source host's lateral movement and formats it into a mixtape entry
+func (analyzer *Analyzer) formatLateralMovementMixtape(res lateralMovementResult) (*ThreatMixtape, error) {
+	total := res.AdminShareHosts + res.ServiceControlHosts + res.NewServiceTickets
+	score := calculateBucketedScore(float64(total), analyzer.Config.Scoring.LateralMovementScoreThresholds)
+	if score <= 0 {
+		return nil, nil
+	}
+
+	mixtape, err := analyzer.newSourceMixtape(res.Src, res.SrcNUID, res.Count, res.FirstSeen, res.LastSeen, "lateral_movement")
+	if err != nil {
+		return nil, err
+	}
+
+	mixtape.LateralMovementScore = score
+	mixtape.LateralHosts = res.LateralHosts
+	mixtape.AdminShareHosts = res.AdminShareHosts
+	mixtape.ServiceControlHosts = res.ServiceControlHosts
+	mixtape.NewServiceTickets = res.NewServiceTickets
+
+	return mixtape, nil
+}
@@ -0,0 +1,171 @@
+package analysis
+
+import (
+	"activecm/rita/util"
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// horizontalScan is a scan of many hosts on the same port
+	horizontalScan = "horizontal"
+	// verticalScan is a scan of many ports on the same host
+	verticalScan = "vertical"
+)
+
+type portScanResult struct {
+	ScanType         string    `ch:"scan_type"`
+	Src              net.IP    `ch:"src"`
+	SrcNUID          uuid.UUID `ch:"src_nuid"`
+	Dst              net.IP    `ch:"dst"`
+	DstNUID          uuid.UUID `ch:"dst_nuid"`
+	ScannedHosts     uint64    `ch:"scanned_hosts"`
+	ScannedPorts     uint64    `ch:"scanned_ports"`
+	PortProtoService []string  `ch:"port_proto_service"`
+	Count            uint64    `ch:"count"`
+	FirstSeen        time.Time `ch:"first_seen"`
+	LastSeen         time.Time `ch:"last_seen"`
+}
+
+// ScoopPortScans scores internal hosts that probed many hosts on the same port (horizontal scans) or many ports on
+// the same host (vertical scans) within an hour. Only connection attempts that were never established are counted,
+// since a host that talks to many servers on the same port is usually just browsing the web. Each scan is its own
+// mixtape entry.
+func (analyzer *Analyzer) ScoopPortScans(ctx context.Context) error {
+	chCtx := clickhouse.Context(analyzer.Database.GetContext(), clickhouse.WithParameters(clickhouse.Parameters{
+		"min_ts":               fmt.Sprintf("%d", analyzer.minTS.UTC().Unix()),
+		"horizontal_threshold": fmt.Sprint(analyzer.Config.Scoring.PortScan.HorizontalScoreThresholds.Base),
+		"vertical_threshold":   fmt.Sprint(analyzer.Config.Scoring.PortScan.VerticalScoreThresholds.Base),
+	}))
+
+	rows, err := analyzer.Database.Conn.Query(chCtx, `--sql
+		WITH probes AS (
+			-- connection attempts from internal hosts that were rejected or went unanswered
+			SELECT src, src_nuid, dst, dst_nuid, dst_port, proto, toStartOfHour(ts) AS hour, ts
+			FROM conn
+			WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND src_local AND proto IN ('tcp', 'udp')
+				AND conn_state IN ('S0', 'REJ', 'RSTOS0', 'RSTRH', 'SH', 'SHR', 'OTH')
+		)
+		SELECT 'horizontal' AS scan_type, src, src_nuid,
+			toIPv6('::') AS dst,
+			toUUID('00000000-0000-0000-0000-000000000000') AS dst_nuid,
+			max(hosts) AS scanned_hosts,
+			toUInt64(1) AS scanned_ports,
+			[concat(toString(dst_port), ':', proto, ':')] AS port_proto_service,
+			sum(attempts) AS count,
+			min(first_seen) AS first_seen,
+			max(last_seen) AS last_seen
+		FROM (
+			-- get the number of hosts that each source probed on each port in each hour
+			SELECT src, src_nuid, dst_port, proto, hour,
+				uniqExact(dst, dst_nuid) AS hosts,
+				count() AS attempts,
+				min(ts) AS first_seen,
+				max(ts) AS last_seen
+			FROM probes
+			GROUP BY src, src_nuid, dst_port, proto, hour
+		)
+		GROUP BY src, src_nuid, dst_port, proto
+		-- only scans that probed enough hosts in an hour to reach the base threshold can be scored
+		HAVING scanned_hosts >= {horizontal_threshold:UInt64}
+
+		UNION ALL
+
+		SELECT 'vertical' AS scan_type, src, src_nuid, dst, dst_nuid,
+			toUInt64(1) AS scanned_hosts,
+			max(ports) AS scanned_ports,
+			arraySlice(arrayDistinct(arrayFlatten(groupArray(port_proto_services))), 1, 20) AS port_proto_service,
+			sum(attempts) AS count,
+			min(first_seen) AS first_seen,
+			max(last_seen) AS last_seen
+		FROM (
+			-- get the number of ports that each source probed on each host in each hour
+			SELECT src, src_nuid, dst, dst_nuid, hour,
+				uniqExact(dst_port, proto) AS ports,
+				groupUniqArray(20)(concat(toString(dst_port), ':', proto, ':')) AS port_proto_services,
+				count() AS attempts,
+				min(ts) AS first_seen,
+				max(ts) AS last_seen
+			FROM probes
+			GROUP BY src, src_nuid, dst, dst_nuid, hour
+		)
+		GROUP BY src, src_nuid, dst, dst_nuid
+		-- only scans that probed enough ports in an hour to reach the base threshold can be scored
+		HAVING scanned_ports >= {vertical_threshold:UInt64}
+	`)
+	if err != nil {
+		return fmt.Errorf("could not retrieve port scans for analysis: %w", err)
+	}
+
+	return scoopMixtape(ctx, analyzer, "port scans", rows, analyzer.formatPortScanMixtape)
+}
+
+// formatPortScanMixtape scores a horizontal or vertical port scan and formats it into a mixtape entry
+func (analyzer *Analyzer) formatPortScanMixtape(res portScanResult) (*ThreatMixtape, error) {
+	var score float32
+	var mixtape *ThreatMixtape
+
+	switch res.ScanType {
+	case horizontalScan:
+		score = calculateBucketedScore(float64(res.ScannedHosts), analyzer.Config.Scoring.PortScan.HorizontalScoreThresholds)
+		if score <= 0 {
+			return nil, nil
+		}
+
+		// a horizontal scan is tracked per source host and port
+		if len(res.PortProtoService) != 1 {
+			return nil, fmt.Errorf("horizontal scan should have exactly one port, got %d", len(res.PortProtoService))
+		}
+		var err error
+		mixtape, err = analyzer.newSourceMixtape(res.Src, res.SrcNUID, res.Count, res.FirstSeen, res.LastSeen, res.PortProtoService[0], "port_scan")
+		if err != nil {
+			return nil, err
+		}
+		mixtape.PortProtoService = res.PortProtoService
+
+	case verticalScan:
+		score = calculateBucketedScore(float64(res.ScannedPorts), analyzer.Config.Scoring.PortScan.VerticalScoreThresholds)
+		if score <= 0 {
+			return nil, nil
+		}
+
+		// keep vertical scans apart from the connection pair's other indicators, since they're scored separately
+		hash, err := util.NewFixedStringHash(res.Src.To16().String(), res.SrcNUID.String(), res.Dst.To16().String(), res.DstNUID.String(), "port_scan")
+		if err != nil {
+			return nil, err
+		}
+
+		firstSeen, _ := util.ValidateTimestamp(res.FirstSeen)
+		lastSeen, _ := util.ValidateTimestamp(res.LastSeen)
+
+		mixtape = &ThreatMixtape{
+			ImportID: analyzer.ImportID,
+			AnalysisResult: AnalysisResult{
+				Hash:                hash,
+				Src:                 res.Src,
+				SrcNUID:             res.SrcNUID,
+				Dst:                 res.Dst,
+				DstNUID:             res.DstNUID,
+				Count:               res.Count,
+				PortProtoService:    res.PortProtoService,
+				FirstSeenHistorical: firstSeen,
+				LastSeen:            lastSeen,
+			},
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown port scan type: %s", res.ScanType)
+	}
+
+	mixtape.PortScanScore = score
+	mixtape.ScanType = res.ScanType
+	mixtape.ScannedHosts = res.ScannedHosts
+	mixtape.ScannedPorts = res.ScannedPorts
+
+	return mixtape, nil
+}
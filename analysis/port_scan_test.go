@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"activecm/rita/config"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPortScanMixtape(t *testing.T) {
+	cfg, err := config.ReadFileConfig(afero.NewOsFs(), "../config.hjson")
+	require.NoError(t, err)
+	cfg.Scoring.PortScan.HorizontalScoreThresholds = config.ScoreThresholds{Base: 10, Low: 20, Med: 30, High: 40}
+	cfg.Scoring.PortScan.VerticalScoreThresholds = config.ScoreThresholds{Base: 20, Low: 40, Med: 60, High: 80}
+
+	analyzer := &Analyzer{Config: &cfg}
+
+	src := net.ParseIP("10.55.100.105")
+	dst := net.ParseIP("24.220.6.168")
+
+	// verify that scans below the base thresholds aren't scored
+	mixtape, err := analyzer.formatPortScanMixtape(portScanResult{ScanType: horizontalScan, Src: src, ScannedHosts: 9, ScannedPorts: 1, PortProtoService: []string{"445:tcp:"}})
+	require.NoError(t, err)
+	require.Nil(t, mixtape, "horizontal scan below the base threshold should not be scored")
+
+	mixtape, err = analyzer.formatPortScanMixtape(portScanResult{ScanType: verticalScan, Src: src, Dst: dst, ScannedHosts: 1, ScannedPorts: 19})
+	require.NoError(t, err)
+	require.Nil(t, mixtape, "vertical scan below the base threshold should not be scored")
+
+	// verify that a horizontal scan is scored by the hosts probed and its destination is left unspecified
+	horizontal := portScanResult{ScanType: horizontalScan, Src: src, SrcNUID: uuid.New(), ScannedHosts: 35, ScannedPorts: 1, PortProtoService: []string{"445:tcp:"}, Count: 70}
+	mixtape, err = analyzer.formatPortScanMixtape(horizontal)
+	require.NoError(t, err)
+	require.NotNil(t, mixtape, "horizontal scan above the base threshold should be scored")
+	require.InDelta(t, calculateBucketedScore(35, cfg.Scoring.PortScan.HorizontalScoreThresholds), mixtape.PortScanScore, 0.0001, "score should be bucketed from the hosts probed")
+	require.True(t, mixtape.Dst.IsUnspecified(), "destination should be left unspecified")
+	require.Equal(t, horizontalScan, mixtape.ScanType, "scan type should match expected value")
+	require.EqualValues(t, 35, mixtape.ScannedHosts, "scanned hosts should match expected value")
+	require.Equal(t, []string{"445:tcp:"}, mixtape.PortProtoService, "scanned port should match expected value")
+
+	// verify that scans of different ports from the same source are separate entries
+	other := horizontal
+	other.PortProtoService = []string{"3389:tcp:"}
+	otherMixtape, err := analyzer.formatPortScanMixtape(other)
+	require.NoError(t, err)
+	require.NotEqual(t, mixtape.Hash, otherMixtape.Hash, "scans of different ports should have different hashes")
+
+	// verify that a vertical scan is scored by the ports probed and keeps its destination
+	vertical := portScanResult{ScanType: verticalScan, Src: src, SrcNUID: horizontal.SrcNUID, Dst: dst, DstNUID: uuid.New(), ScannedHosts: 1, ScannedPorts: 100, PortProtoService: []string{"22:tcp:", "80:tcp:"}}
+	mixtape, err = analyzer.formatPortScanMixtape(vertical)
+	require.NoError(t, err)
+	require.NotNil(t, mixtape, "vertical scan above the base threshold should be scored")
+	require.InDelta(t, calculateBucketedScore(100, cfg.Scoring.PortScan.VerticalScoreThresholds), mixtape.PortScanScore, 0.0001, "score should be bucketed from the ports probed")
+	require.True(t, mixtape.Dst.Equal(dst), "destination should match expected value")
+	require.EqualValues(t, 100, mixtape.ScannedPorts, "scanned ports should match expected value")
+
+	// verify that malformed results produce an error
+	_, err = analyzer.formatPortScanMixtape(portScanResult{ScanType: "diagonal", Src: src, ScannedHosts: 100, ScannedPorts: 100})
+	require.Error(t, err, "unknown scan type should produce an error")
+
+	_, err = analyzer.formatPortScanMixtape(portScanResult{ScanType: horizontalScan, Src: src, ScannedHosts: 100})
+	require.Error(t, err, "horizontal scan without a port should produce an error")
+}
@@ -43,6 +43,11 @@ type AnalysisResult struct {
 	ProxyIPs            []net.IP         `ch:"proxy_ips"`  // array of unique proxy (destination IPs) for SNI conns
 	MissingHostCount    uint64           `ch:"missing_host_count"`
 
+	// Exfiltration
+	BytesSent         int64 `ch:"bytes_sent"`           // bytes sent by the source
+	BytesReceived     int64 `ch:"bytes_received"`       // bytes received by the source
+	OffHoursBytesSent int64 `ch:"off_hours_bytes_sent"` // bytes sent by the source during the configured off hours
+
 	// C2 OVER DNS Connection Info
 	DirectConns []net.IP `ch:"direct_conns"`
 	QueriedBy   []net.IP `ch:"queried_by"`
@@ -55,6 +60,14 @@ type AnalysisResult struct {
 	TLD            string `ch:"tld"`
 	SubdomainCount uint64 `ch:"subdomain_count"`
 
+	// DNS Tunneling
+	LabelEntropy      float32 `ch:"label_entropy"`
+	AvgLabelLength    float32 `ch:"avg_label_length"`
+	HexLabelRatio     float32 `ch:"hex_label_ratio"`
+	Base32LabelRatio  float32 `ch:"base32_label_ratio"`
+	Base64LabelRatio  float32 `ch:"base64_label_ratio"`
+	TXTNullQueryRatio float32 `ch:"txt_null_query_ratio"`
+
 	// Threat Intel
 	OnThreatIntel bool `ch:"on_threat_intel"`
 }
@@ -138,6 +151,20 @@ func (analyzer *Analyzer) Spagoop(ctx context.Context) error {
 	return nil
 }
 
+// directionalBytesCTE totals the bytes sent and received by the source of each connection pair from the hourly_bytes CTE
+// of the query that it's used in, along with the bytes sent during the off hours in the configured timezone
+const directionalBytesCTE = `directional_bytes AS (
+		SELECT hash,
+			sum(bytes_sent) AS bytes_sent,
+			sum(bytes_received) AS bytes_received,
+			sumIf(bytes_sent, if({off_hours_start:UInt8} <= {off_hours_end:UInt8},
+				toHour(hour, {off_hours_timezone:String}) >= {off_hours_start:UInt8} AND toHour(hour, {off_hours_timezone:String}) < {off_hours_end:UInt8},
+				toHour(hour, {off_hours_timezone:String}) >= {off_hours_start:UInt8} OR toHour(hour, {off_hours_timezone:String}) < {off_hours_end:UInt8}
+			)) AS off_hours_bytes_sent
+		FROM hourly_bytes
+		GROUP BY hash
+	)`
+
 func (analyzer *Analyzer) ScoopSNIConns(ctx context.Context, progress *tea.Program) error {
 	logger := logger.GetLogger()
 
@@ -162,9 +189,12 @@ func (analyzer *Analyzer) ScoopSNIConns(ctx context.Context, progress *tea.Progr
 		"unique_connection_threshold": fmt.Sprint(analyzer.Config.Scoring.Beacon.UniqueConnectionThreshold),
 		"network_size":                fmt.Sprint(analyzer.networkSize),
 		"rolling":                     strconv.FormatBool(analyzer.Database.Rolling),
+		"off_hours_start":             fmt.Sprint(analyzer.Config.Scoring.Exfiltration.OffHoursStart),
+		"off_hours_end":               fmt.Sprint(analyzer.Config.Scoring.Exfiltration.OffHoursEnd),
+		"off_hours_timezone":          analyzer.Config.Scoring.Exfiltration.OffHoursTimezone,
 	}))
 	// panic(strconv.FormatBool(analyzer.Database.Rolling))
-	rows, err := analyzer.Database.Conn.Query(chCtx, `--sql
+	query := `--sql
 	WITH unique_sni AS (
 		SELECT DISTINCT hash FROM sniconn_tmp
 	),
@@ -203,7 +233,7 @@ func (analyzer *Analyzer) ScoopSNIConns(ctx context.Context, progress *tea.Progr
 	    GROUP BY fqdn
 	),  
 	sniconns AS (
-		-- Get SNI connections (HTTP + SSL for a source IP -> destination FQDN pair)
+		-- Get SNI connections (HTTP + SSL + QUIC for a source IP -> destination FQDN pair)
 		SELECT hash, src, src_nuid, fqdn, 
 			countMerge(count) AS conn_count, 
 			countMerge(proxy_count) AS proxy_count,
@@ -284,6 +314,22 @@ func (analyzer *Analyzer) ScoopSNIConns(ctx context.Context, progress *tea.Progr
 		)
 		GROUP BY hash
 	),
+	hourly_bytes AS (
+		-- get the bytes sent and received by the source of each connection pair in each hour
+		SELECT hash, hour, sumMerge(bytes_sent) AS bytes_sent, sumMerge(bytes_received) AS bytes_received
+		FROM port_info
+		WHERE hour >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND hash IN (SELECT hash FROM unique_sni)
+		GROUP BY hash, hour
+		UNION ALL
+		SELECT hash, toStartOfHour(ts) AS hour, sum(src_ip_bytes) AS bytes_sent, sum(dst_ip_bytes) AS bytes_received
+		FROM openhttp
+		GROUP BY hash, hour
+		UNION ALL
+		SELECT hash, toStartOfHour(ts) AS hour, sum(src_ip_bytes) AS bytes_sent, sum(dst_ip_bytes) AS bytes_received
+		FROM openssl
+		GROUP BY hash, hour
+	),
+	` + directionalBytesCTE + `,
 	-- Aggregate data between all union groups into final structure
 	totaled_sniconns AS (
 		SELECT s.hash AS hash, s.src AS src, s.src_nuid AS src_nuid, s.fqdn AS fqdn, 
@@ -321,13 +367,19 @@ func (analyzer *Analyzer) ScoopSNIConns(ctx context.Context, progress *tea.Progr
 			server_ips,
 			proxy_ips,
 			last_seen,
-			po.port_proto_service as port_proto_service
+			po.port_proto_service as port_proto_service,
+			b.bytes_sent AS bytes_sent,
+			b.bytes_received AS bytes_received,
+			b.off_hours_bytes_sent AS off_hours_bytes_sent
 	FROM totaled_sniconns s
 	LEFT JOIN prevalence_counts USING fqdn
 	LEFT JOIN metadatabase.threat_intel t ON s.fqdn = t.fqdn 
 	LEFT JOIN historical h ON h.fqdn = s.fqdn
 	LEFT JOIN port_proto po ON s.hash = po.hash
-`)
+	LEFT JOIN directional_bytes b ON s.hash = b.hash
+`
+
+	rows, err := analyzer.Database.Conn.Query(chCtx, query)
 	if err != nil {
 		// return error and cancel all uconn analysis
 		return fmt.Errorf("could not retrieve unique SNI connections for analysis: %w", err)
@@ -388,6 +440,9 @@ func (analyzer *Analyzer) ScoopIPConns(ctx context.Context, progress *tea.Progra
 		"unique_connection_threshold": fmt.Sprint(analyzer.Config.Scoring.Beacon.UniqueConnectionThreshold),
 		"network_size":                fmt.Sprint(analyzer.networkSize),
 		"rolling":                     strconv.FormatBool(analyzer.Database.Rolling),
+		"off_hours_start":             fmt.Sprint(analyzer.Config.Scoring.Exfiltration.OffHoursStart),
+		"off_hours_end":               fmt.Sprint(analyzer.Config.Scoring.Exfiltration.OffHoursEnd),
+		"off_hours_timezone":          analyzer.Config.Scoring.Exfiltration.OffHoursTimezone,
 	}))
 
 	query := `--sql
@@ -517,7 +572,20 @@ func (analyzer *Analyzer) ScoopIPConns(ctx context.Context, progress *tea.Progra
 				WHERE missing_host_header = false
 			)
 			GROUP BY hash
-		)
+		),
+		hourly_bytes AS (
+			-- get the bytes sent and received by the source of each connection pair in each hour
+			SELECT hash, hour, sumMerge(bytes_sent) AS bytes_sent, sumMerge(bytes_received) AS bytes_received
+			FROM port_info
+			WHERE hour >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND hash IN (SELECT hash FROM filtered_hashes)
+			GROUP BY hash, hour
+			UNION ALL
+			SELECT hash, toStartOfHour(ts) AS hour, sum(src_ip_bytes) AS bytes_sent, sum(dst_ip_bytes) AS bytes_received
+			FROM openconn
+			WHERE missing_host_header = false
+			GROUP BY hash, hour
+		),
+		` + directionalBytesCTE + `
 		SELECT  i.hash AS hash, i.src as src, i.src_nuid as src_nuid, i.dst as dst, i.dst_nuid as dst_nuid, 
 				'ip' AS beacon_type,
 				missing_host_count,
@@ -535,12 +603,16 @@ func (analyzer *Analyzer) ScoopIPConns(ctx context.Context, progress *tea.Progra
 				prevalence_total, 
 				toFloat32(prevalence_total / {network_size:UInt64}) AS prevalence,
 				if({rolling:Bool}, h.first_seen, i.first_seen) AS first_seen_historical,
-				po.port_proto_service as port_proto_service
+				po.port_proto_service as port_proto_service,
+				b.bytes_sent AS bytes_sent,
+				b.bytes_received AS bytes_received,
+				b.off_hours_bytes_sent AS off_hours_bytes_sent
 		FROM totaled_ipconns i 
 		LEFT JOIN prevalence_counts p ON if(src_local = true, i.dst, i.src) = p.ip
 		LEFT JOIN metadatabase.threat_intel t ON multiIf(src_local = true, i.dst, dst_local = true, i.src, i.dst) = t.ip
 		LEFT JOIN port_proto po ON i.hash = po.hash
 		LEFT JOIN historical h ON multiIf(src_local = true, i.dst, dst_local = true, i.src, i.dst) = h.ip
+		LEFT JOIN directional_bytes b ON i.hash = b.hash
 
 	`
 
@@ -598,10 +670,11 @@ func (analyzer *Analyzer) ScoopDNS(ctx context.Context, progress *tea.Program) e
 
 	}), clickhouse.WithParameters(clickhouse.Parameters{
 		// use minTS (not minTSBeacon) because DNS logs don't get correlated with conn logs
-		"min_ts":              fmt.Sprintf("%d", analyzer.minTS.UTC().Unix()),
-		"subdomain_threshold": fmt.Sprint(analyzer.Config.Scoring.C2SubdomainThreshold),
-		"rolling":             strconv.FormatBool(analyzer.Database.Rolling),
-		"network_size":        fmt.Sprint(analyzer.networkSize),
+		"min_ts":                        fmt.Sprintf("%d", analyzer.minTS.UTC().Unix()),
+		"subdomain_threshold":           fmt.Sprint(analyzer.Config.Scoring.C2SubdomainThreshold),
+		"tunneling_subdomain_threshold": fmt.Sprint(analyzer.Config.Scoring.DNSTunneling.MinimumSubdomains),
+		"rolling":                       strconv.FormatBool(analyzer.Database.Rolling),
+		"network_size":                  fmt.Sprint(analyzer.networkSize),
 	}))
 
 	rows, err := analyzer.Database.Conn.Query(chCtx, `--sql
@@ -687,7 +760,21 @@ func (analyzer *Analyzer) ScoopDNS(ctx context.Context, progress *tea.Program) e
 			FROM exploded_dns
 			WHERE hour >= toStartOfHour(fromUnixTimestamp({min_ts:Int64}))
 			GROUP BY tld
-			HAVING subdomain_count >= {subdomain_threshold:Int32}
+			-- keep the tlds with enough subdomains to be scored for either c2 over dns or dns tunneling
+			HAVING subdomain_count >= least({subdomain_threshold:Int32}, {tunneling_subdomain_threshold:Int32})
+		),
+		-- get the lexical features of the subdomains that were queried under each tld
+		lexical_features AS (
+			SELECT tld,
+				sumMerge(entropy) / greatest(sumMerge(subdomain_queries), 1) AS label_entropy,
+				sumMerge(label_chars) / greatest(sumMerge(labels), 1) AS avg_label_length,
+				sumMerge(hex_labels) / greatest(sumMerge(labels), 1) AS hex_label_ratio,
+				sumMerge(base32_labels) / greatest(sumMerge(labels), 1) AS base32_label_ratio,
+				sumMerge(base64_labels) / greatest(sumMerge(labels), 1) AS base64_label_ratio,
+				sumMerge(txt_null_queries) / greatest(countMerge(queries), 1) AS txt_null_query_ratio
+			FROM dns_lexical
+			WHERE hour >= toStartOfHour(fromUnixTimestamp({min_ts:Int64}))
+			GROUP BY tld
 		)
 		-- get the subdomain counts and the last seen count for each tld
 		SELECT e.tld AS tld, e.subdomain_count as subdomain_count, 
@@ -697,13 +784,17 @@ func (analyzer *Analyzer) ScoopDNS(ctx context.Context, progress *tea.Program) e
 			toFloat32(prevalence_total / {network_size:UInt64}) AS prevalence,
 			-- use the historical first seen value if this dataset is rolling
 			if({rolling:Bool}, h.first_seen, u.first_seen) AS first_seen_historical,
-			if(cutToFirstSignificantSubdomain(t.fqdn) != '', true, false) AS on_threat_intel
+			if(cutToFirstSignificantSubdomain(t.fqdn) != '', true, false) AS on_threat_intel,
+			toFloat32(l.label_entropy) AS label_entropy, toFloat32(l.avg_label_length) AS avg_label_length,
+			toFloat32(l.hex_label_ratio) AS hex_label_ratio, toFloat32(l.base32_label_ratio) AS base32_label_ratio,
+			toFloat32(l.base64_label_ratio) AS base64_label_ratio, toFloat32(l.txt_null_query_ratio) AS txt_null_query_ratio
 		FROM totaled_exploded e
 		INNER JOIN unique_dns u ON e.tld = u.tld
 		LEFT JOIN prevalence_counts p ON e.tld = p.tld
 		LEFT JOIN historical h ON e.tld = h.tld
 		LEFT JOIN direct_connections d ON e.tld = d.tld
 		LEFT JOIN queried_by q ON e.tld = q.tld
+		LEFT JOIN lexical_features l ON e.tld = l.tld
 		LEFT JOIN metadatabase.threat_intel t ON e.tld = cutToFirstSignificantSubdomain(t.fqdn)	
 	`)
 	if err != nil {
@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"activecm/rita/util"
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+)
+
+type sshResult struct {
+	Src              net.IP    `ch:"src"`
+	SrcNUID          uuid.UUID `ch:"src_nuid"`
+	Dst              net.IP    `ch:"dst"`
+	DstNUID          uuid.UUID `ch:"dst_nuid"`
+	PortProtoService []string  `ch:"port_proto_service"`
+	Count            uint64    `ch:"count"`
+	AuthAttempts     uint64    `ch:"max_auth_attempts"`
+	FailedLogins     uint64    `ch:"failed_logins"`
+	FirstSeen        time.Time `ch:"first_seen"`
+	LastSeen         time.Time `ch:"last_seen"`
+}
+
+// ScoopSSH scores connection pairs where an internal host made a burst of SSH authentication attempts to an external
+// host within an hour (brute force), or where a login succeeded after many failed attempts. The results are written
+// directly to the mixtape, so they are reported whether or not the pair has any other threat indicators.
+func (analyzer *Analyzer) ScoopSSH(ctx context.Context) error {
+	chCtx := clickhouse.Context(analyzer.Database.GetContext(), clickhouse.WithParameters(clickhouse.Parameters{
+		"min_ts":                  fmt.Sprintf("%d", analyzer.minTS.UTC().Unix()),
+		"brute_force_threshold":   fmt.Sprint(analyzer.Config.Scoring.SSH.BruteForceScoreThresholds.Base),
+		"login_failure_threshold": fmt.Sprint(analyzer.Config.Scoring.SSH.LoginAfterFailuresScoreThresholds.Base),
+	}))
+
+	rows, err := analyzer.Database.Conn.Query(chCtx, `--sql
+		WITH brute_force AS (
+			-- get the most authentication attempts made by an internal host to an external host within a single hour
+			SELECT hash, max(attempts) AS max_attempts FROM (
+				SELECT hash, toStartOfHour(ts) AS hour, sum(auth_attempts) AS attempts
+				FROM ssh
+				WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND src_local AND NOT dst_local
+				GROUP BY hash, hour
+			)
+			GROUP BY hash
+			HAVING max_attempts >= {brute_force_threshold:UInt64}
+		), first_logins AS (
+			SELECT hash, min(ts) AS first_login
+			FROM ssh
+			WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND auth_success
+			GROUP BY hash
+		), login_after_failures AS (
+			-- count the failed authentication attempts made up until the first successful login,
+			-- the last attempt of a successful connection is the one that succeeded
+			SELECT s.hash AS hash, toUInt64(sum(greatest(toInt64(s.auth_attempts) - if(s.auth_success, 1, 0), 0))) AS failed_logins
+			FROM ssh s
+			INNER JOIN first_logins l USING hash
+			WHERE s.ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND s.ts <= l.first_login
+			GROUP BY hash
+			HAVING failed_logins >= {login_failure_threshold:UInt64}
+		), flagged AS (
+			SELECT hash, max(max_attempts) AS max_attempts, max(failed_logins) AS failures FROM (
+				SELECT hash, max_attempts, toUInt64(0) AS failed_logins FROM brute_force
+				UNION ALL
+				SELECT hash, toUInt64(0) AS max_attempts, failed_logins FROM login_after_failures
+			)
+			GROUP BY hash
+		)
+		SELECT any(s.src) AS src, any(s.src_nuid) AS src_nuid, any(s.dst) AS dst, any(s.dst_nuid) AS dst_nuid,
+			groupUniqArray(20)(concat(toString(s.dst_port), ':', s.proto, ':', s.service)) AS port_proto_service,
+			count() AS count,
+			any(f.max_attempts) AS max_auth_attempts,
+			any(f.failures) AS failed_logins,
+			min(s.ts) AS first_seen,
+			max(s.ts) AS last_seen
+		FROM ssh s
+		INNER JOIN flagged f USING hash
+		WHERE s.ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64}))
+		GROUP BY hash
+	`)
+	if err != nil {
+		return fmt.Errorf("could not retrieve ssh connections for analysis: %w", err)
+	}
+
+	return scoopMixtape(ctx, analyzer, "ssh connections", rows, analyzer.formatSSHMixtape)
+}
+
+// formatSSHMixtape scores a connection pair's SSH brute force and login after failures and formats them into a mixtape entry
+func (analyzer *Analyzer) formatSSHMixtape(res sshResult) (*ThreatMixtape, error) {
+	bruteForceScore := calculateBucketedScore(float64(res.AuthAttempts), analyzer.Config.Scoring.SSH.BruteForceScoreThresholds)
+	loginScore := calculateBucketedScore(float64(res.FailedLogins), analyzer.Config.Scoring.SSH.LoginAfterFailuresScoreThresholds)
+	if bruteForceScore <= 0 && loginScore <= 0 {
+		return nil, nil
+	}
+
+	// keep the SSH results apart from the connection pair's other indicators, since they're scored separately
+	hash, err := util.NewFixedStringHash(res.Src.To16().String(), res.SrcNUID.String(), res.Dst.To16().String(), res.DstNUID.String(), "ssh")
+	if err != nil {
+		return nil, err
+	}
+
+	firstSeen, _ := util.ValidateTimestamp(res.FirstSeen)
+	lastSeen, _ := util.ValidateTimestamp(res.LastSeen)
+
+	mixtape := &ThreatMixtape{
+		ImportID: analyzer.ImportID,
+		AnalysisResult: AnalysisResult{
+			Hash:                hash,
+			Src:                 res.Src,
+			SrcNUID:             res.SrcNUID,
+			Dst:                 res.Dst,
+			DstNUID:             res.DstNUID,
+			Count:               res.Count,
+			PortProtoService:    res.PortProtoService,
+			FirstSeenHistorical: firstSeen,
+			LastSeen:            lastSeen,
+		},
+		SSHBruteForceScore:         bruteForceScore,
+		SSHAuthAttempts:            res.AuthAttempts,
+		SSHLoginAfterFailuresScore: loginScore,
+		SSHFailedLogins:            res.FailedLogins,
+	}
+
+	return mixtape, nil
+}
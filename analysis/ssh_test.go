@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"activecm/rita/config"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSSHMixtape(t *testing.T) {
+	cfg, err := config.ReadFileConfig(afero.NewOsFs(), "../config.hjson")
+	require.NoError(t, err)
+	cfg.Scoring.SSH.BruteForceScoreThresholds = config.ScoreThresholds{Base: 30, Low: 100, Med: 250, High: 500}
+	cfg.Scoring.SSH.LoginAfterFailuresScoreThresholds = config.ScoreThresholds{Base: 10, Low: 25, Med: 50, High: 100}
+
+	analyzer := &Analyzer{Config: &cfg}
+
+	res := sshResult{
+		Src:              net.ParseIP("10.55.100.105"),
+		SrcNUID:          uuid.New(),
+		Dst:              net.ParseIP("24.220.6.168"),
+		DstNUID:          uuid.New(),
+		PortProtoService: []string{"22:tcp:ssh"},
+		Count:            40,
+	}
+
+	// verify that connection pairs below both base thresholds aren't scored
+	below := res
+	below.AuthAttempts = 29
+	below.FailedLogins = 9
+	mixtape, err := analyzer.formatSSHMixtape(below)
+	require.NoError(t, err)
+	require.Nil(t, mixtape, "connection pair below the base thresholds should not be scored")
+
+	// verify that brute force is scored on its own, without a successful login
+	bruteForce := res
+	bruteForce.AuthAttempts = 150
+	mixtape, err = analyzer.formatSSHMixtape(bruteForce)
+	require.NoError(t, err)
+	require.NotNil(t, mixtape, "brute force above the base threshold should be scored")
+	require.InDelta(t, calculateBucketedScore(150, cfg.Scoring.SSH.BruteForceScoreThresholds), mixtape.SSHBruteForceScore, 0.0001, "score should be bucketed from the auth attempts")
+	require.Zero(t, mixtape.SSHLoginAfterFailuresScore, "login after failures should not be scored without failed logins")
+	require.EqualValues(t, 150, mixtape.SSHAuthAttempts, "auth attempts should match expected value")
+	require.True(t, mixtape.Dst.Equal(res.Dst), "destination should match expected value")
+	require.Equal(t, []string{"22:tcp:ssh"}, mixtape.PortProtoService, "port proto service should match expected value")
+
+	// verify that a login after failures is scored on its own
+	login := res
+	login.FailedLogins = 30
+	mixtape, err = analyzer.formatSSHMixtape(login)
+	require.NoError(t, err)
+	require.NotNil(t, mixtape, "login after failures above the base threshold should be scored")
+	require.Zero(t, mixtape.SSHBruteForceScore, "brute force should not be scored without a burst of attempts")
+	require.InDelta(t, calculateBucketedScore(30, cfg.Scoring.SSH.LoginAfterFailuresScoreThresholds), mixtape.SSHLoginAfterFailuresScore, 0.0001, "score should be bucketed from the failed logins")
+	require.EqualValues(t, 30, mixtape.SSHFailedLogins, "failed logins should match expected value")
+
+	// verify that both indicators of a connection pair are scored in the same entry
+	both := res
+	both.AuthAttempts = 150
+	both.FailedLogins = 30
+	bothMixtape, err := analyzer.formatSSHMixtape(both)
+	require.NoError(t, err)
+	require.Equal(t, mixtape.Hash, bothMixtape.Hash, "hash should only depend on the connection pair")
+	require.Positive(t, bothMixtape.SSHBruteForceScore, "brute force should be scored")
+	require.Positive(t, bothMixtape.SSHLoginAfterFailuresScore, "login after failures should be scored")
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/afero"
+)
+
+var ErrDryRunWithFollow = errors.New("--dry-run only reports what would be imported from --logs and can't be used with --follow or --kafka")
+
+// dryRunSampleSize is the number of records read from the start of each log to validate it and estimate how many
+// records the filters would leave out of the import
+const dryRunSampleSize = 1000
+
+// dryRunInternalSubnetsWarningRatio is the share of sampled records filtered by internal_subnets that most likely
+// means that the internal subnets in the config file don't match the network the logs were recorded on
+const dryRunInternalSubnetsWarningRatio = 0.9
+
+// DryRunHour is the plan for importing one hour of logs
+type DryRunHour struct {
+	Day  int
+	Hour int
+	// Files is the number of files of each log type that would be imported
+	Files map[string]int
+	// PreviouslyImported is the number of files that would be skipped because they were already imported
+	PreviouslyImported int
+}
+
+// DryRunReport describes what an import would do without importing anything
+type DryRunReport struct {
+	Hours []DryRunHour
+	// WalkErrors holds the files that were left out of the import while walking the log directory
+	WalkErrors []WalkError
+	// InvalidLogs holds the files whose header or records failed validation while sampling
+	InvalidLogs []WalkError
+	// Records is the number of records that were sampled across all logs
+	Records int
+	// Filtered is the number of sampled records that would be left out of the import, by the filter rule that applies to them
+	Filtered map[string]int
+}
+
+// RunDryRunCmd walks the logs in logDir with walkFiles and reports which files would be imported into each hour of the
+// dataset, sampling each log to validate it and to estimate how many of its records the filters would drop. No databases
+// are created, so a misconfigured import can be caught before it runs.
+func RunDryRunCmd(cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool, walkFiles func(afero.Fs, string) ([]HourlyZeekLogs, []WalkError, error)) (DryRunReport, error) {
+	var report DryRunReport
+
+	logFs, logDir, err := openLogFs(cfg, afs, logDir)
+	if err != nil {
+		return report, err
+	}
+
+	// walk and sample the logs inside of tar and zip archives as if the archives were directories
+	archiveFs := importer.NewArchiveFs(logFs)
+	defer archiveFs.Close()
+
+	logMap, walkErrors, err := walkFiles(archiveFs, logDir)
+	if err != nil {
+		return report, err
+	}
+
+	// files that were already imported are only checked if the dataset could have them
+	server, db, err := database.ConnectForDryRun(cfg, dbName, rolling, rebuild)
+	if err != nil {
+		return report, err
+	}
+	defer server.Conn.Close()
+
+	var checkImported func(map[string][]string) (int, error)
+	if db != nil {
+		checkImported = db.CheckIfFilesWereAlreadyImported
+	}
+
+	return PlanDryRun(cfg, archiveFs, logMap, walkErrors, checkImported)
+}
+
+// PlanDryRun builds the dry run report for the hourly logs in logMap. checkImported filters the files of an hour down
+// to the ones that haven't been imported yet, the same way as the import does, and is nil if none could have been imported.
+func PlanDryRun(cfg *config.Config, afs afero.Fs, logMap []HourlyZeekLogs, walkErrors []WalkError, checkImported func(map[string][]string) (int, error)) (DryRunReport, error) {
+	report := DryRunReport{WalkErrors: walkErrors, Filtered: make(map[string]int)}
+
+	for day, hourlyLogs := range logMap {
+		for hour, files := range hourlyLogs {
+
+			// files are checked against the metadatabase by the identity that their filesystem gives them
+			fileMap := make(map[string][]string, len(files))
+			paths := make(map[string]string)
+			totalFileCount := 0
+			for logType, logList := range files {
+				for _, path := range logList {
+					id, err := importer.GetFileID(afs, path)
+					if err != nil {
+						return report, err
+					}
+					fileMap[logType] = append(fileMap[logType], id)
+					paths[id] = path
+					totalFileCount++
+				}
+			}
+
+			if totalFileCount < 1 {
+				continue
+			}
+
+			remainingFileCount := totalFileCount
+			if checkImported != nil {
+				var err error
+				remainingFileCount, err = checkImported(fileMap)
+				if err != nil {
+					return report, err
+				}
+			}
+
+			plan := DryRunHour{Day: day, Hour: hour, Files: make(map[string]int), PreviouslyImported: totalFileCount - remainingFileCount}
+
+			for logType, ids := range fileMap {
+				for _, id := range ids {
+					plan.Files[logType]++
+
+					// packet captures, EVE logs, ECS exports and flow exports aren't zeek logs, so they can't be sampled
+					if logType == importer.PCAPPrefix || logType == importer.EVEPrefix || logType == importer.ECSPrefix || logType == importer.FlowPrefix {
+						continue
+					}
+
+					sample, err := importer.SampleLogFile(afs, paths[id], logType, &cfg.Filter, dryRunSampleSize)
+					if err != nil {
+						report.InvalidLogs = append(report.InvalidLogs, WalkError{Path: paths[id], Error: err})
+						continue
+					}
+
+					report.Records += sample.Records
+					for rule, count := range sample.Filtered {
+						report.Filtered[rule] += count
+					}
+				}
+			}
+
+			report.Hours = append(report.Hours, plan)
+		}
+	}
+
+	return report, nil
+}
+
+// printDryRunReport prints the plan, the files that would be left out and the filter estimates of a dry run
+func printDryRunReport(report DryRunReport) {
+	if len(report.Hours) == 0 {
+		fmt.Println("No hours of logs would be imported.")
+	} else {
+		fmt.Println(FormatDryRunPlanTable(report.Hours))
+	}
+
+	skipped := slices.Concat(report.WalkErrors, report.InvalidLogs)
+	if len(skipped) > 0 {
+		fmt.Println("Files left out of the import:")
+		fmt.Println(FormatDryRunSkippedTable(skipped))
+	}
+
+	fmt.Printf("Sampled %d records (up to %d from the start of each log), records that would be filtered:\n", report.Records, dryRunSampleSize)
+	fmt.Println(FormatDryRunFilterTable(report.Records, report.Filtered))
+
+	if report.Records > 0 && float64(report.Filtered[config.FilterRuleInternalSubnets])/float64(report.Records) >= dryRunInternalSubnetsWarningRatio {
+		fmt.Println("WARNING: nearly all sampled records would be filtered by internal_subnets, make sure that 'filter.internal_subnets' in the config file includes the internal networks in these logs.")
+	}
+}
+
+// FormatDryRunPlanTable formats the hours that a dry run would import as a table
+func FormatDryRunPlanTable(hours []DryRunHour) *table.Table {
+	var data [][]string
+
+	for _, h := range hours {
+		var logTypes []string
+		for logType := range h.Files {
+			logTypes = append(logTypes, logType)
+		}
+		slices.Sort(logTypes)
+
+		var files []string
+		for _, logType := range logTypes {
+			files = append(files, fmt.Sprintf("%s: %d", logType, h.Files[logType]))
+		}
+
+		data = append(data, []string{strconv.Itoa(h.Day + 1), fmt.Sprintf("%02d", h.Hour), strings.Join(files, ", "), strconv.Itoa(h.PreviouslyImported)})
+	}
+
+	return formatDryRunTable([]string{"Day", "Hour", "Files", "Previously Imported"}, data)
+}
+
+// FormatDryRunSkippedTable formats the files that a dry run would leave out of the import as a table
+func FormatDryRunSkippedTable(skipped []WalkError) *table.Table {
+	var data [][]string
+
+	for _, s := range skipped {
+		data = append(data, []string{s.Path, s.Error.Error()})
+	}
+
+	return formatDryRunTable([]string{"Path", "Reason"}, data)
+}
+
+// FormatDryRunFilterTable formats the number of sampled records that each filter rule would drop as a table
+func FormatDryRunFilterTable(records int, filtered map[string]int) *table.Table {
+	var data [][]string
+
+	rules := []string{config.FilterRuleInternalSubnets, config.FilterRuleNeverIncludedSubnets, config.FilterRuleNeverIncludedDomains, config.FilterRuleExternalToInternal}
+	for _, rule := range rules {
+		percent := 0.0
+		if records > 0 {
+			percent = float64(filtered[rule]) / float64(records) * 100
+		}
+		data = append(data, []string{rule, strconv.Itoa(filtered[rule]), fmt.Sprintf("%.1f%%", percent)})
+	}
+
+	return formatDryRunTable([]string{"Filter Rule", "Records", "Percent"}, data)
+}
+
+// formatDryRunTable formats rows of a dry run report in the same style as the list command
+func formatDryRunTable(headers []string, data [][]string) *table.Table {
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == 0 {
+				return headerStyle
+			}
+
+			even := row%2 == 0
+
+			if even {
+				return baseStyle.Foreground(lipgloss.Color("245"))
+			}
+			return baseStyle.Foreground(lipgloss.Color("252"))
+		})
+	return t
+}
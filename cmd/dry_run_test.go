@@ -0,0 +1,72 @@
+package cmd_test
+
+import (
+	"activecm/rita/cmd"
+	"activecm/rita/config"
+	"activecm/rita/importer"
+	"activecm/rita/util"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanDryRun(t *testing.T) {
+	data, err := afero.ReadFile(afero.NewOsFs(), "../test_data/quic/quic.log")
+	require.NoError(t, err)
+
+	// the conn log is really a quic log, so it should fail validation
+	afs := afero.NewMemMapFs()
+	connPath := "/logs/2024-04-19/conn.16:00:00-17:00:00.log"
+	quicPath := "/logs/2024-04-19/quic.16:00:00-17:00:00.log"
+	require.NoError(t, afero.WriteFile(afs, connPath, data, 0o644))
+	require.NoError(t, afero.WriteFile(afs, quicPath, data, 0o644))
+
+	logMap, walkErrors, err := cmd.WalkFiles(afs, "/logs")
+	require.NoError(t, err)
+	require.Empty(t, walkErrors)
+
+	internalSubnets, err := util.ParseSubnets([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	otherSubnets, err := util.ParseSubnets([]string{"192.168.0.0/16"})
+	require.NoError(t, err)
+
+	t.Run("Nothing Previously Imported", func(t *testing.T) {
+		cfg := &config.Config{Filter: config.Filter{InternalSubnets: internalSubnets}}
+		report, err := cmd.PlanDryRun(cfg, afs, logMap, walkErrors, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, []cmd.DryRunHour{{Day: 0, Hour: 16, Files: map[string]int{importer.ConnPrefix: 1, importer.QUICPrefix: 1}}}, report.Hours, "plan should match expected value")
+		require.Len(t, report.InvalidLogs, 1, "the mismatched conn log should be invalid")
+		require.Equal(t, connPath, report.InvalidLogs[0].Path, "the mismatched conn log should be invalid")
+		require.Equal(t, 3, report.Records, "only the quic log should be sampled")
+		require.Empty(t, report.Filtered, "no records should be filtered")
+	})
+
+	t.Run("Misconfigured Internal Subnets", func(t *testing.T) {
+		cfg := &config.Config{Filter: config.Filter{InternalSubnets: otherSubnets}}
+		report, err := cmd.PlanDryRun(cfg, afs, logMap, walkErrors, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, 3, report.Records, "only the quic log should be sampled")
+		require.Equal(t, map[string]int{config.FilterRuleInternalSubnets: 3}, report.Filtered, "all records should be filtered by internal_subnets")
+	})
+
+	t.Run("Conn Log Previously Imported", func(t *testing.T) {
+		cfg := &config.Config{Filter: config.Filter{InternalSubnets: internalSubnets}}
+
+		// mark the conn log as imported the same way as the metadatabase check does
+		checkImported := func(fileMap map[string][]string) (int, error) {
+			require.Equal(t, []string{connPath}, fileMap[importer.ConnPrefix], "files should be checked by their id")
+			fileMap[importer.ConnPrefix] = nil
+			return len(fileMap[importer.QUICPrefix]), nil
+		}
+
+		report, err := cmd.PlanDryRun(cfg, afs, logMap, walkErrors, checkImported)
+		require.NoError(t, err)
+
+		require.Equal(t, []cmd.DryRunHour{{Day: 0, Hour: 16, Files: map[string]int{importer.QUICPrefix: 1}, PreviouslyImported: 1}}, report.Hours, "plan should match expected value")
+		require.Empty(t, report.InvalidLogs, "previously imported logs should not be sampled")
+		require.Equal(t, 3, report.Records, "only the quic log should be sampled")
+	})
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer"
+	"activecm/rita/importer/s3fs"
+	"activecm/rita/logger"
+	"activecm/rita/util"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var ErrCannotFollowS3 = errors.New("logs in S3 can't be followed, schedule rolling imports of the bucket instead")
+
+// zeekCurrentLogDir is the directory Zeek writes its live logs to before rotating them into a dated directory
+const zeekCurrentLogDir = "current"
+
+// ImportFunc imports the logs in logDir into a dataset, such as RunImportCmd or RunPCAPImportCmd
+type ImportFunc func(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool) (ImportResults, error)
+
+// logFollower detects log files that Zeek finished writing in a log directory
+type logFollower struct {
+	afs    afero.Fs
+	logDir string
+	// settleTime is how long a file must go unmodified before it is considered complete
+	settleTime time.Duration
+	// seen holds the modification time of every file that was already imported
+	seen map[string]time.Time
+	// pending holds the files that are ready to be imported, grouped by directory
+	pending map[string][]followedFile
+}
+
+type followedFile struct {
+	path    string
+	modTime time.Time
+}
+
+// RunFollowImportCmd watches logDir and imports each completed log rotation into a rolling dataset as it appears,
+// until the context is cancelled. Files that were already imported are skipped by the metadatabase, so restarting
+// the follower or pointing it at a directory that was imported by cron is safe.
+func RunFollowImportCmd(ctx context.Context, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rebuild bool, interval time.Duration, runImportCmd ImportFunc) error {
+	logger := logger.GetLogger()
+
+	// object storage doesn't have modification times to tell when zeek finished writing a log
+	if s3fs.IsURL(logDir) {
+		return ErrCannotFollowS3
+	}
+
+	logDir, err := util.ParseRelativePath(logDir)
+	if err != nil {
+		return err
+	}
+
+	follower := newLogFollower(afs, logDir, interval)
+
+	logger.Info().Str("directory", logDir).Str("dataset", dbName).Str("interval", interval.String()).Msg("Following log directory for new logs...")
+
+	for {
+		dirs, err := follower.poll(time.Now())
+		if err != nil {
+			return err
+		}
+
+		for _, dir := range dirs {
+			if ctx.Err() != nil {
+				break
+			}
+
+			_, err := runImportCmd(time.Now(), cfg, afs, dir, dbName, true, rebuild)
+			switch {
+			// another import is running against the dataset, so try again on the next poll
+			case errors.Is(err, database.ErrImportLocked):
+				logger.Warn().Str("directory", dir).Str("dataset", dbName).Msg("dataset is locked by another import, retrying later")
+				continue
+			case errors.Is(err, importer.ErrAllFilesPreviouslyImported), errors.Is(err, ErrNoValidFilesFound):
+				logger.Debug().Str("directory", dir).Err(err).Msg("no new logs to import")
+			case err != nil:
+				return err
+			}
+
+			// the dataset should only be rebuilt before the first import
+			rebuild = false
+			follower.markImported(dir)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info().Str("directory", logDir).Msg("Stopped following log directory")
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func newLogFollower(afs afero.Fs, logDir string, settleTime time.Duration) *logFollower {
+	return &logFollower{
+		afs:        afs,
+		logDir:     logDir,
+		settleTime: settleTime,
+		seen:       make(map[string]time.Time),
+		pending:    make(map[string][]followedFile),
+	}
+}
+
+// poll returns the directories that contain new or modified files, once every new file in the directory
+// has stopped changing. Directories are returned in lexical order, so dated directories are imported oldest first.
+func (f *logFollower) poll(now time.Time) ([]string, error) {
+	changed := make(map[string][]followedFile)
+	unsettled := make(map[string]bool)
+
+	err := afero.Walk(f.afs, f.logDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files can be rotated or removed while walking
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			// the logs Zeek is currently writing are imported once they are rotated
+			if info.Name() == zeekCurrentLogDir && path != f.logDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if modTime, ok := f.seen[path]; ok && modTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		changed[dir] = append(changed[dir], followedFile{path: path, modTime: info.ModTime()})
+
+		// files that are still being written or copied are picked up on a later poll
+		if now.Sub(info.ModTime()) < f.settleTime {
+			unsettled[dir] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	f.pending = make(map[string][]followedFile)
+	for dir, files := range changed {
+		if !unsettled[dir] {
+			f.pending[dir] = files
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.Sort(dirs)
+
+	return dirs, nil
+}
+
+// markImported records that the new files of a directory returned by the last poll were imported
+func (f *logFollower) markImported(dir string) {
+	for _, file := range f.pending[dir] {
+		f.seen[file.path] = file.modTime
+	}
+	delete(f.pending, dir)
+}
@@ -0,0 +1,94 @@
+package cmd_test
+
+import (
+	"activecm/rita/cmd"
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+type followedImport struct {
+	logDir  string
+	rolling bool
+	rebuild bool
+}
+
+func TestRunFollowImportCmd(t *testing.T) {
+	afs := afero.NewMemMapFs()
+	interval := 10 * time.Millisecond
+	old := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	createFile := func(path string, modTime time.Time) {
+		t.Helper()
+		require.NoError(t, afero.WriteFile(afs, path, []byte("data"), 0o644))
+		require.NoError(t, afs.Chtimes(path, modTime, modTime))
+	}
+
+	// a completed day, a day whose latest rotation is still being written, and zeek's live logs
+	createFile("/logs/2024-05-01/conn.00:00:00-01:00:00.log.gz", old)
+	createFile("/logs/2024-05-01/dns.00:00:00-01:00:00.log.gz", old)
+	createFile("/logs/2024-05-02/conn.00:00:00-01:00:00.log.gz", future)
+	createFile("/logs/current/conn.log", old)
+
+	// the fake import reports each call and returns the error it is given back
+	calls := make(chan followedImport)
+	results := make(chan error)
+	runImportCmd := func(_ time.Time, _ *config.Config, _ afero.Fs, logDir string, _ string, rolling bool, rebuild bool) (cmd.ImportResults, error) {
+		calls <- followedImport{logDir: logDir, rolling: rolling, rebuild: rebuild}
+		return cmd.ImportResults{}, <-results
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- cmd.RunFollowImportCmd(ctx, &config.Config{}, afs, "/logs", "follow_test", true, interval, runImportCmd)
+	}()
+
+	nextCall := func() followedImport {
+		t.Helper()
+		select {
+		case call := <-calls:
+			return call
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "follower should have imported a directory")
+		}
+		return followedImport{}
+	}
+
+	// only the completed day is imported, and the dataset is only rebuilt before the first import
+	require.Equal(t, followedImport{logDir: "/logs/2024-05-01", rolling: true, rebuild: true}, nextCall())
+	results <- nil
+
+	// the second day is imported once its files stop changing, and is retried if another import holds the lock
+	require.NoError(t, afs.Chtimes("/logs/2024-05-02/conn.00:00:00-01:00:00.log.gz", old, old))
+	require.Equal(t, followedImport{logDir: "/logs/2024-05-02", rolling: true, rebuild: false}, nextCall())
+	results <- database.ErrImportLocked
+	require.Equal(t, followedImport{logDir: "/logs/2024-05-02", rolling: true, rebuild: false}, nextCall())
+	results <- nil
+
+	// a new rotation of an imported day is imported once it is complete
+	createFile("/logs/2024-05-01/conn.01:00:00-02:00:00.log.gz", old)
+	require.Equal(t, followedImport{logDir: "/logs/2024-05-01", rolling: true, rebuild: false}, nextCall())
+	results <- nil
+
+	// nothing is imported again once every file was imported
+	select {
+	case call := <-calls:
+		require.FailNow(t, "follower should not import a directory without new files", call.logDir)
+	case <-time.After(10 * interval):
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err, "follower should stop without error when cancelled")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "follower should stop when cancelled")
+	}
+}
@@ -5,19 +5,23 @@ import (
 	"activecm/rita/config"
 	"activecm/rita/database"
 	"activecm/rita/importer"
+	"activecm/rita/importer/s3fs"
 	"activecm/rita/logger"
 	"activecm/rita/modifier"
 	"activecm/rita/util"
+	"context"
 	"errors"
 	"fmt"
 	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 	"unicode"
 
@@ -38,6 +42,8 @@ var ErrInvalidLogHourFormat = errors.New("could not parse hour from log file nam
 var ErrInvalidLogHourRange = errors.New("could not parse hour from log file name - hour out of range")
 var ErrInvalidLogType = errors.New("incompatible log type")
 var ErrIncompatibleFileExtension = errors.New("incompatible file extension")
+var ErrInvalidFollowInterval = errors.New("follow interval must be greater than zero")
+var ErrResumeWithFollow = errors.New("--resume can't be used with --follow or --kafka, which skip the files that were already imported on their own")
 var ErrSkippedDuplicateLog = errors.New("encountered file with same name but different extension, skipping file due to older last modified time")
 
 type WalkError struct {
@@ -49,7 +55,7 @@ type HourlyZeekLogs []map[string][]string
 var ImportCommand = &cli.Command{
 	Name:      "import",
 	Usage:     "import zeek logs into a target database",
-	UsageText: "rita import [--database NAME] [-logs DIRECTORY] [--rolling] [--rebuild]",
+	UsageText: "rita import [--database NAME] [-logs DIRECTORY] [--rolling] [--rebuild] [--pcap] [--bucket-by-ts] [--follow] [--kafka] [--resume] [--dry-run]",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:     "database",
@@ -63,7 +69,7 @@ var ImportCommand = &cli.Command{
 		&cli.StringFlag{
 			Name:     "logs",
 			Aliases:  []string{"l"},
-			Usage:    "path to log directory",
+			Usage:    "path to log directory or file",
 			Required: false,
 			Action: func(_ *cli.Context, path string) error {
 				return ValidateLogDirectory(afero.NewOsFs(), path)
@@ -83,6 +89,55 @@ var ImportCommand = &cli.Command{
 			Value:    false,
 			Required: false,
 		},
+		&cli.BoolFlag{
+			Name:     "pcap",
+			Usage:    "import pcap or pcapng packet captures instead of zeek logs, reassembling their packets into conn, dns, http and ssl records",
+			Value:    false,
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "bucket-by-ts",
+			Usage:    "split zeek logs into hourly chunks by the ts field of each record instead of the hour in the file name, for logs that weren't rotated hourly",
+			Value:    false,
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "follow",
+			Aliases:  []string{"f"},
+			Usage:    "keep running and import each hour of logs into a rolling database once zeek finishes rotating it",
+			Value:    false,
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:     "follow-interval",
+			Usage:    "how often to check for new logs with --follow; files must also be unchanged for this long before they are imported",
+			Value:    time.Minute,
+			Required: false,
+			Action: func(_ *cli.Context, interval time.Duration) error {
+				if interval <= 0 {
+					return ErrInvalidFollowInterval
+				}
+				return nil
+			},
+		},
+		&cli.BoolFlag{
+			Name:     "kafka",
+			Usage:    "keep running and import zeek JSON logs from the kafka topics in the config file into a rolling database, one hour at a time",
+			Value:    false,
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "resume",
+			Usage:    "finish the imports into the database that were interrupted, picking up each hour at the first stage it didn't finish, before importing new logs",
+			Value:    false,
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "dry-run",
+			Usage:    "report which files would be imported into each hour and estimate how many records the filters would drop, without creating the database",
+			Value:    false,
+			Required: false,
+		},
 		ConfigFlag(false),
 	},
 	Action: func(cCtx *cli.Context) error {
@@ -94,6 +149,32 @@ var ImportCommand = &cli.Command{
 			return err
 		}
 
+		// run import command
+		walkFiles := WalkFiles
+		runImportCmd := RunImportCmd
+		switch {
+		// packet captures are always split into hours by packet timestamp
+		case cCtx.Bool("pcap"):
+			walkFiles = WalkPCAPFiles
+			runImportCmd = RunPCAPImportCmd
+		case cCtx.Bool("bucket-by-ts"):
+			walkFiles = WalkFilesByTimestamp
+			runImportCmd = RunImportByTimestampCmd
+		}
+
+		if cCtx.Bool("dry-run") {
+			if cCtx.Bool("follow") || cCtx.Bool("kafka") {
+				return ErrDryRunWithFollow
+			}
+
+			report, err := RunDryRunCmd(cfg, afs, cCtx.String("logs"), cCtx.String("database"), cCtx.Bool("rolling"), cCtx.Bool("rebuild"), walkFiles)
+			if err != nil {
+				return err
+			}
+			printDryRunReport(report)
+			return nil
+		}
+
 		// set the number of workers based on the number of CPUs
 		numParsers = int(math.Floor(math.Max(4, float64(runtime.NumCPU())/2)))
 		numDigesters = int(math.Floor(math.Max(4, float64(runtime.NumCPU())/2)))
@@ -102,8 +183,29 @@ var ImportCommand = &cli.Command{
 		// set the import start time in microseconds
 		startTime := time.Now()
 
-		// run import command
-		_, err = RunImportCmd(startTime, cfg, afs, cCtx.String("logs"), cCtx.String("database"), cCtx.Bool("rolling"), cCtx.Bool("rebuild"))
+		if cCtx.Bool("resume") && (cCtx.Bool("follow") || cCtx.Bool("kafka")) {
+			return ErrResumeWithFollow
+		}
+
+		switch {
+		case cCtx.Bool("kafka"):
+			if cCtx.String("logs") != "" {
+				return ErrKafkaWithLogDirectory
+			}
+			// stop consuming once the import of the current hour finishes when interrupted
+			ctx, stop := signal.NotifyContext(cCtx.Context, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			err = RunKafkaImportCmd(ctx, cfg, afs, cCtx.String("database"), cCtx.Bool("rebuild"))
+		case cCtx.Bool("follow"):
+			// stop following once the import of the current directory finishes when interrupted
+			ctx, stop := signal.NotifyContext(cCtx.Context, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			err = RunFollowImportCmd(ctx, cfg, afs, cCtx.String("logs"), cCtx.String("database"), cCtx.Bool("rebuild"), cCtx.Duration("follow-interval"), FollowImportCmd(walkFiles))
+		case cCtx.Bool("resume"):
+			_, err = RunResumeImportCmd(startTime, cfg, afs, cCtx.String("logs"), cCtx.String("database"), cCtx.Bool("rolling"), walkFiles)
+		default:
+			_, err = runImportCmd(startTime, cfg, afs, cCtx.String("logs"), cCtx.String("database"), cCtx.Bool("rolling"), cCtx.Bool("rebuild"))
+		}
 		if err != nil {
 			return err
 		}
@@ -130,6 +232,37 @@ type ImportResults struct {
 }
 
 func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool) (ImportResults, error) {
+	return runImport(startTime, cfg, afs, logDir, dbName, rolling, rebuild, false, false, WalkFiles)
+}
+
+// RunImportByTimestampCmd imports the logs in logDir, bucketing their records into hours by the ts field of each record
+func RunImportByTimestampCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool) (ImportResults, error) {
+	return runImport(startTime, cfg, afs, logDir, dbName, rolling, rebuild, false, false, WalkFilesByTimestamp)
+}
+
+// RunPCAPImportCmd imports the packet captures in logDir, bucketing their traffic into hours by packet timestamp
+func RunPCAPImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool) (ImportResults, error) {
+	return runImport(startTime, cfg, afs, logDir, dbName, rolling, rebuild, false, false, WalkPCAPFiles)
+}
+
+// FollowImportCmd returns the ImportFunc that --follow uses to import the hourly files found by walkFiles. Unlike other
+// imports, it skips the hours that were already imported instead of failing, since the follower imports a directory
+// again every time Zeek rotates new logs into it.
+func FollowImportCmd(walkFiles func(afero.Fs, string) ([]HourlyZeekLogs, []WalkError, error)) ImportFunc {
+	return func(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool) (ImportResults, error) {
+		return runImport(startTime, cfg, afs, logDir, dbName, rolling, rebuild, false, true, walkFiles)
+	}
+}
+
+// RunResumeImportCmd resumes the imports into the database that were interrupted, then imports the hourly files found
+// by walkFiles in logDir that haven't been imported yet
+func RunResumeImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, walkFiles func(afero.Fs, string) ([]HourlyZeekLogs, []WalkError, error)) (ImportResults, error) {
+	return runImport(startTime, cfg, afs, logDir, dbName, rolling, false, true, true, walkFiles)
+}
+
+// runImport imports the hourly files found by walkFiles in logDir into the database, resuming interrupted imports first if resume is set.
+// Hours whose files were all imported before are skipped if skipImportedHours is set, otherwise they fail the import.
+func runImport(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool, resume bool, skipImportedHours bool, walkFiles func(afero.Fs, string) ([]HourlyZeekLogs, []WalkError, error)) (ImportResults, error) {
 
 	var importResults ImportResults
 	logger := logger.GetLogger()
@@ -139,21 +272,103 @@ func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir
 
 	logger.Info().Str("directory", logDir).Bool("rolling", rolling).Bool("rebuild", rebuild).Str("dataset", dbName).Str("started_at", importStartedAt.String()).Msg("Initiating new import...")
 
+	// logs are read from logFs, while the files used to set up the import are read from afs
+	logFs, logDir, err := openLogFs(cfg, afs, logDir)
+	if err != nil {
+		return importResults, err
+	}
+
+	return importLogs(startTime, cfg, afs, logFs, logDir, dbName, rolling, rebuild, resume, skipImportedHours, walkFiles)
+}
+
+// openLogFs returns the filesystem that the logs in logDir are read from and the path of logDir on that filesystem
+func openLogFs(cfg *config.Config, afs afero.Fs, logDir string) (afero.Fs, string, error) {
+	if s3fs.IsURL(logDir) {
+		// walk and import the objects under the prefix of the bucket, ie, s3://bucket/prefix
+		bucket, prefix, err := s3fs.ParseURL(logDir)
+		if err != nil {
+			return nil, "", err
+		}
+
+		logFs, err := s3fs.New(context.Background(), cfg.S3, bucket)
+		if err != nil {
+			return nil, "", err
+		}
+		return logFs, "/" + prefix, nil
+	}
+
 	// load dataset relative to the current working directory
 	// this is done here instead of in the flag parsing so that anyone calling RunImportCmd will have the relative path
 	logDir, err := util.ParseRelativePath(logDir)
+	if err != nil {
+		return nil, "", err
+	}
+	return afs, logDir, nil
+}
+
+// importLogs imports the hourly files found by walkFiles in logDir of logFs into the database, while the files used
+// to set up the import, such as the threat intel feeds, are read from afs
+func importLogs(startTime time.Time, cfg *config.Config, afs afero.Fs, logFs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool, resume bool, skipImportedHours bool, walkFiles func(afero.Fs, string) ([]HourlyZeekLogs, []WalkError, error)) (ImportResults, error) {
+
+	var importResults ImportResults
+	logger := logger.GetLogger()
+
+	// keep track of the cumulative elapsed time
+	importStartedAt := startTime
+
+	// make sure no other import is running against this dataset, since both would import the same files
+	lock, err := database.AcquireImportLock(context.Background(), cfg, dbName)
 	if err != nil {
 		return importResults, err
 	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			logger.Warn().Err(err).Str("dataset", dbName).Msg("failed to release import lock")
+		}
+	}()
+
+	// walk and import the logs inside of tar and zip archives as if the archives were directories
+	archiveFs := importer.NewArchiveFs(logFs)
+	defer archiveFs.Close()
 
 	// create import database if it doesn't already exist and connect to it
-	db, err := database.SetUpNewImport(afs, cfg, dbName, rolling, rebuild)
+	setUpImport := database.SetUpNewImport
+	if resume {
+		setUpImport = database.SetUpResumedImport
+	}
+	db, err := setUpImport(afs, cfg, dbName, rolling, rebuild)
 	if err != nil {
 		return importResults, err
 	}
 
+	// stop writing to the dataset if another import takes over the lock
+	lock.Guard(db)
+
+	// keep track of whether any hour had files that weren't imported yet
+	importedHours := 0
+
+	// interrupted imports are resumed before any new hours are imported, since importing an hour resets the temporary
+	// tables that an interrupted analysis needs
+	if resume {
+		resumed, err := resumeImports(db, cfg)
+		if err != nil {
+			return importResults, err
+		}
+
+		for _, checkpoint := range resumed {
+			importResults.ImportID = append(importResults.ImportID, checkpoint.ImportID)
+			importResults.ImportTimestamps = append(importResults.ImportTimestamps, ImportTimestamps{
+				MinTS:       checkpoint.MinTS,
+				MaxTS:       checkpoint.MaxTS,
+				MinTSBeacon: checkpoint.MinTSBeacon,
+				maxTSBeacon: checkpoint.MaxTSBeacon,
+			})
+		}
+		importedHours += len(resumed)
+	}
+
 	// get list of hourly log maps of all days of log files in directory
-	logMap, walkErrors, err := WalkFiles(afs, logDir)
+	logMap, walkErrors, err := walkFiles(archiveFs, logDir)
 	if err != nil {
 		return importResults, err
 	}
@@ -163,6 +378,9 @@ func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir
 		logger.Debug().Str("path", walkErr.Path).Err(walkErr.Error).Msg("file was left out of import due to error or incompatibility")
 	}
 
+	// count the hours that each archive has files in, so that each archive can be closed once all of its hours are imported
+	archiveHours := countArchiveHours(archiveFs, logMap)
+
 	var elapsedTime int64
 	// var dayStartedAt time.Time
 
@@ -176,6 +394,10 @@ func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir
 
 		// loop through each hour's log files
 		for hour, files := range hourlyLogs {
+			// stop importing if another import took over the lock while this one was stalled
+			if err := lock.Err(); err != nil {
+				return importResults, err
+			}
 
 			logger.Debug().Msg(fmt.Sprintf("------------- STARTING HOUR %v!! -------------", hour))
 			hourStart := time.Now()
@@ -207,29 +429,54 @@ func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir
 			// 	// add the duration of all imports up to now to the original importStartedAt date
 			// 	importStart = importStartedAt.Add(time.Duration(elapsedTime) * time.Nanosecond)
 			// }
-			importer, err := importer.NewImporter(db, cfg, importStartedAt, numDigesters, numParsers, numWriters)
+			hourImporter, err := importer.NewImporter(db, cfg, importStartedAt, numDigesters, numParsers, numWriters)
 			if err != nil {
 				return importResults, err
 			}
 
-			err = importer.Import(afs, files)
+			err = hourImporter.Import(archiveFs, files)
+			closeImportedArchives(archiveFs, archiveHours, files)
+			removeImportedHourLogs(archiveFs, files)
+
+			// hours that were already imported are skipped when the same directory is imported repeatedly
+			if skipImportedHours && errors.Is(err, importer.ErrAllFilesPreviouslyImported) {
+				logger.Debug().Int("day", day).Int("hour", hour).Msg("all files for this hour were previously imported")
+				continue
+			}
 			if err != nil {
 				return importResults, err
 			}
+			importedHours++
 
 			// update result counts (used for testing)
-			importResults.Conn += importer.ResultCounts.Conn
-			importResults.OpenConn += importer.ResultCounts.OpenConn
-			importResults.HTTP += importer.ResultCounts.HTTP
-			importResults.OpenHTTP += importer.ResultCounts.OpenHTTP
-			importResults.DNS += importer.ResultCounts.DNS
-			importResults.UDNS += importer.ResultCounts.UDNS
-			importResults.PDNSRaw += importer.ResultCounts.PDNSRaw
-			importResults.SSL += importer.ResultCounts.SSL
-			importResults.OpenSSL += importer.ResultCounts.OpenSSL
-			importResults.ImportID = append(importResults.ImportID, importer.ImportID)
-			logger.Debug().Msg("------------- RUNNING ANALYSIS!! -------------")
-
+			importResults.Conn += hourImporter.ResultCounts.Conn
+			importResults.OpenConn += hourImporter.ResultCounts.OpenConn
+			importResults.HTTP += hourImporter.ResultCounts.HTTP
+			importResults.OpenHTTP += hourImporter.ResultCounts.OpenHTTP
+			importResults.DNS += hourImporter.ResultCounts.DNS
+			importResults.UDNS += hourImporter.ResultCounts.UDNS
+			importResults.PDNSRaw += hourImporter.ResultCounts.PDNSRaw
+			importResults.SSL += hourImporter.ResultCounts.SSL
+			importResults.OpenSSL += hourImporter.ResultCounts.OpenSSL
+			importResults.X509 += hourImporter.ResultCounts.X509
+			importResults.SSH += hourImporter.ResultCounts.SSH
+			importResults.QUIC += hourImporter.ResultCounts.QUIC
+			importResults.DHCP += hourImporter.ResultCounts.DHCP
+			importResults.SMBFiles += hourImporter.ResultCounts.SMBFiles
+			importResults.SMBMapping += hourImporter.ResultCounts.SMBMapping
+			importResults.DCERPC += hourImporter.ResultCounts.DCERPC
+			importResults.NTLM += hourImporter.ResultCounts.NTLM
+			importResults.Kerberos += hourImporter.ResultCounts.Kerberos
+			for prefix, count := range hourImporter.ResultCounts.Custom {
+				if importResults.Custom == nil {
+					importResults.Custom = make(map[string]uint64)
+				}
+				importResults.Custom[prefix] += count
+			}
+			importResults.RejectedLines += hourImporter.ResultCounts.RejectedLines
+			importResults.InvalidFields += hourImporter.ResultCounts.InvalidFields
+			importResults.AbandonedFiles += hourImporter.ResultCounts.AbandonedFiles
+			importResults.ImportID = append(importResults.ImportID, hourImporter.ImportID)
 			// TODO pull useCurrentTime out of beacon?
 			minTSBeacon, maxTSBeacon, _, err := db.GetBeaconMinMaxTimestamps()
 			missingBeaconTS := errors.Is(err, database.ErrInvalidMinMaxTimestamp)
@@ -251,32 +498,26 @@ func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir
 
 			logger.Debug().Time("min_ts", minTS).Time("max_ts", maxTS).Time("min_beacon_ts", minTSBeacon).Time("max_beacon_ts", maxTSBeacon).Bool("skip_beaconing", missingBeaconTS).Msg("timestamps used in analysis")
 
-			// set up new analyzer
-			analyzer, err := analysis.NewAnalyzer(db, cfg, importer.ImportID, minTS, maxTS, minTSBeacon, maxTSBeacon, useCurrentTime, missingBeaconTS)
-			if err != nil {
-				return importResults, err
+			// record that the hour chunk was imported along with the timestamps used to analyze it, so that
+			// the import can be resumed with the same timestamps if it is interrupted from here on
+			checkpoint := database.Checkpoint{
+				ImportID:       hourImporter.ImportID,
+				Stage:          database.CheckpointImported,
+				StartedAt:      db.ImportStartedAt,
+				MinTS:          minTS,
+				MaxTS:          maxTS,
+				MinTSBeacon:    minTSBeacon,
+				MaxTSBeacon:    maxTSBeacon,
+				UseCurrentTime: useCurrentTime,
+				SkipBeaconing:  missingBeaconTS,
 			}
-
-			// analyze the data
-			err = analyzer.Analyze()
+			err = db.AddCheckpointToMetaDB(checkpoint)
 			if err != nil {
 				return importResults, err
 			}
 
-			// set up new modifier
-			modifier, err := modifier.NewModifier(db, cfg, importer.ImportID, minTS, maxTS)
-			if err != nil {
-				return importResults, err
-			}
-
-			// modify the data
-			err = modifier.Modify()
-			if err != nil {
-				return importResults, err
-			}
-
-			// add import finished record to metadatabase
-			err = db.AddImportFinishedRecordToMetaDB(importer.ImportID, minTS, maxTS)
+			// analyze, modify and finish the hour chunk
+			err = runImportStages(db, cfg, checkpoint, false)
 			if err != nil {
 				return importResults, err
 			}
@@ -297,27 +538,233 @@ func RunImportCmd(startTime time.Time, cfg *config.Config, afs afero.Fs, logDir
 		}
 	}
 
+	if skipImportedHours && importedHours == 0 {
+		return importResults, importer.ErrAllFilesPreviouslyImported
+	}
+
+	// summarize the data that was left out of the import across all hours
+	if importResults.RejectedLines > 0 || importResults.InvalidFields > 0 {
+		logger.Warn().Uint64("skipped_lines", importResults.RejectedLines).Uint64("invalid_fields", importResults.InvalidFields).Uint64("abandoned_files", importResults.AbandonedFiles).Str("dataset", dbName).
+			Msg("Some log lines failed to parse and were left out of the import, see the metadatabase.rejected_lines table for details")
+	}
+
 	logger.Info().Str("elapsed_time", fmt.Sprintf("%1.1fs", time.Since(startTime).Seconds())).Msg("🎊✨ Finished Import! ✨🎊")
 
 	return importResults, nil
 }
 
+// countArchiveHours returns the number of hours that each archive in the log map has files in
+func countArchiveHours(archiveFs *importer.ArchiveFs, logMap []HourlyZeekLogs) map[string]int {
+	archiveHours := make(map[string]int)
+	for _, hourlyLogs := range logMap {
+		for _, files := range hourlyLogs {
+			for archive := range hourArchives(archiveFs, files) {
+				archiveHours[archive]++
+			}
+		}
+	}
+	return archiveHours
+}
+
+// closeImportedArchives closes the archives that have files in an hour that was just imported if none of their
+// other hours are left to import, since unpacked archives can take up a lot of space
+func closeImportedArchives(archiveFs *importer.ArchiveFs, archiveHours map[string]int, files map[string][]string) {
+	logger := logger.GetLogger()
+
+	for archive := range hourArchives(archiveFs, files) {
+		archiveHours[archive]--
+		if archiveHours[archive] > 0 {
+			continue
+		}
+
+		if err := archiveFs.CloseArchive(archive); err != nil {
+			logger.Warn().Err(err).Str("path", archive).Msg("failed to close archive")
+		}
+	}
+}
+
+// removeImportedHourLogs removes the files that the hours of split logs were written to once the hour is imported
+func removeImportedHourLogs(archiveFs *importer.ArchiveFs, files map[string][]string) {
+	logger := logger.GetLogger()
+
+	for _, paths := range files {
+		for _, path := range paths {
+			if err := archiveFs.RemoveHourLog(path); err != nil {
+				logger.Warn().Err(err).Str("path", path).Msg("failed to remove split hour of log")
+			}
+		}
+	}
+}
+
+// hourArchives returns the set of archives that the files of an hour are in
+func hourArchives(archiveFs *importer.ArchiveFs, files map[string][]string) map[string]struct{} {
+	archives := make(map[string]struct{})
+	for _, paths := range files {
+		for _, path := range paths {
+			if archive, ok := archiveFs.ArchivePath(path); ok {
+				archives[archive] = struct{}{}
+			}
+		}
+	}
+	return archives
+}
+
+// resumeImports picks up the imports into the database that were interrupted. Imports that were interrupted after their
+// hour chunk was imported are resumed at the first stage that they didn't finish, while the records of imports that were
+// interrupted before then are cleared so that their files are imported again. Imports that can't be resumed are marked as
+// abandoned so that they aren't picked up again. The checkpoints of the resumed imports are returned.
+func resumeImports(db *database.DB, cfg *config.Config) ([]database.Checkpoint, error) {
+	logger := logger.GetLogger()
+
+	incomplete, err := db.GetIncompleteImports()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(incomplete) == 0 {
+		logger.Info().Str("dataset", db.GetSelectedDB()).Msg("No interrupted imports to resume")
+		return nil, nil
+	}
+
+	// only the import that was interrupted last still has the temporary tables that its analysis needs
+	latestImportID, err := db.GetLatestImportID()
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed []database.Checkpoint
+	for _, checkpoint := range incomplete {
+		switch {
+		// the hour chunk wasn't fully imported, so its files have to be imported again
+		case checkpoint.Stage == "":
+			logger.Warn().Str("import_id", checkpoint.ImportID.Hex()).
+				Msg("Import was interrupted before its hour chunk was imported, importing its files again")
+			if err := db.ClearInterruptedImport(checkpoint.ImportID, checkpoint.StartedAt); err != nil {
+				return resumed, err
+			}
+			if err := abandonImport(db, checkpoint); err != nil {
+				return resumed, err
+			}
+
+		case checkpoint.ImportID.Data != latestImportID.Data:
+			logger.Warn().Str("import_id", checkpoint.ImportID.Hex()).Str("stage", checkpoint.Stage).
+				Msg("Cannot resume import because a later import reset the temporary tables that it needs, rebuild the dataset to analyze its hour chunk")
+			if err := abandonImport(db, checkpoint); err != nil {
+				return resumed, err
+			}
+
+		default:
+			logger.Info().Str("import_id", checkpoint.ImportID.Hex()).Str("stage", checkpoint.Stage).Msg("Resuming interrupted import...")
+			if err := runImportStages(db, cfg, checkpoint, true); err != nil {
+				return resumed, err
+			}
+			resumed = append(resumed, checkpoint)
+		}
+	}
+
+	return resumed, nil
+}
+
+// abandonImport records that an interrupted import can't be resumed
+func abandonImport(db *database.DB, checkpoint database.Checkpoint) error {
+	checkpoint.Stage = database.CheckpointAbandoned
+	return db.AddCheckpointToMetaDB(checkpoint)
+}
+
+// runImportStages runs the stages of an imported hour chunk that come after the last stage in checkpoint, recording a
+// checkpoint as each stage finishes. If resumed is set, the results that an interrupted stage left behind are cleared first.
+func runImportStages(db *database.DB, cfg *config.Config, checkpoint database.Checkpoint, resumed bool) error {
+	logger := logger.GetLogger()
+
+	// analysis results are stamped with the start time of the import that they belong to
+	db.ImportStartedAt = checkpoint.StartedAt
+
+	stages := database.CheckpointStages[slices.Index(database.CheckpointStages, checkpoint.Stage)+1:]
+	for _, stage := range stages {
+		switch stage {
+		case database.CheckpointAnalyzed:
+			logger.Debug().Msg("------------- RUNNING ANALYSIS!! -------------")
+
+			if resumed {
+				if err := db.ClearAnalysisResults(checkpoint.ImportID, false); err != nil {
+					return err
+				}
+			}
+
+			// set up new analyzer
+			analyzer, err := analysis.NewAnalyzer(db, cfg, checkpoint.ImportID, checkpoint.MinTS, checkpoint.MaxTS, checkpoint.MinTSBeacon, checkpoint.MaxTSBeacon, checkpoint.UseCurrentTime, checkpoint.SkipBeaconing)
+			if err != nil {
+				return err
+			}
+
+			// analyze the data
+			err = analyzer.Analyze()
+			if err != nil {
+				return err
+			}
+
+		case database.CheckpointModified:
+			if resumed {
+				if err := db.ClearAnalysisResults(checkpoint.ImportID, true); err != nil {
+					return err
+				}
+			}
+
+			// set up new modifier
+			modifier, err := modifier.NewModifier(db, cfg, checkpoint.ImportID, checkpoint.MinTS, checkpoint.MaxTS)
+			if err != nil {
+				return err
+			}
+
+			// modify the data
+			err = modifier.Modify()
+			if err != nil {
+				return err
+			}
+
+		case database.CheckpointFinished:
+			// add import finished record to metadatabase
+			err := db.AddImportFinishedRecordToMetaDB(checkpoint.ImportID, checkpoint.MinTS, checkpoint.MaxTS)
+			if err != nil {
+				return err
+			}
+		}
+
+		// only the first stage that is run can have been interrupted
+		resumed = false
+
+		checkpoint.Stage = stage
+		if err := db.AddCheckpointToMetaDB(checkpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func ValidateLogDirectory(afs afero.Fs, logDir string) error {
 	if logDir == "" {
 		return fmt.Errorf("log directory flag is required")
 	}
 
+	// the bucket is checked when connecting to the object storage server during the import
+	if s3fs.IsURL(logDir) {
+		_, _, err := s3fs.ParseURL(logDir)
+		return err
+	}
+
 	dir, err := util.ParseRelativePath(logDir)
 	if err != nil {
 		return err
 	}
 
-	// check if directory exists
-	if err := util.ValidateDirectory(afs, dir); err != nil {
-		return err
+	// check if directory exists, a single file can be imported as well
+	err = util.ValidateDirectory(afs, dir)
+	if errors.Is(err, util.ErrPathIsNotDir) {
+		return util.ValidateFile(afs, dir)
 	}
 
-	return nil
+	return err
 }
 
 func ValidateDatabaseName(name string) error {
@@ -361,6 +808,18 @@ func parseFolderDate(folder string) (time.Time, error) {
 // path of each regular file on the string channel.  It sends the result of the
 // walk on the error channel.  If done is closed, WalkFiles abandons its work.
 func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error) {
+	return walkLogFiles(afs, root, false)
+}
+
+// WalkFilesByTimestamp walks the directory tree at root like WalkFiles, but adds each zeek log to every hour that it
+// contains records for, using the ts field of the records instead of the hour in the file name. This allows logs that
+// weren't rotated hourly, such as a single day long conn.log or concatenated exports, to be imported an hour at a time.
+func WalkFilesByTimestamp(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error) {
+	return walkLogFiles(afs, root, true)
+}
+
+// walkLogFiles walks the directory tree at root and groups the log files found into days and hours
+func walkLogFiles(afs afero.Fs, root string, byTimestamp bool) ([]HourlyZeekLogs, []WalkError, error) {
 	logger := logger.GetLogger()
 
 	// check if root is a valid directory or file
@@ -400,7 +859,7 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 		}
 
 		// skip if file is not a compatible log file
-		if !(strings.HasSuffix(path, ".log") || strings.HasSuffix(path, ".gz")) {
+		if !(strings.HasSuffix(path, ".log") || importer.IsCompressedFile(path) || isEVEFile(path) || importer.IsECSFile(path) || importer.IsFlowFile(path)) {
 			walkErrors = append(walkErrors, WalkError{Path: path, Error: ErrIncompatibleFileExtension})
 			return nil // log the issue and continue walking
 		}
@@ -412,8 +871,9 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 			return nil //nolint:nilerr // log the issue and continue walking
 		}
 
-		// trim the path name to remove the file extensions, only to leave .log
-		trimmedFileName := strings.TrimSuffix(path, ".gz")
+		// trim the path name to remove the compression extension, only to leave .log, so that every
+		// compressed variant of a log (ie, conn.log.gz and conn.log.zst) is treated as the same log
+		trimmedFileName := importer.TrimCompressionExtension(path)
 
 		// check if path doesn't have .log suffix anymore and add it if not
 		if !strings.HasSuffix(trimmedFileName, ".log") {
@@ -456,6 +916,21 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 		return nil, nil, fmt.Errorf("file walk failed: %w", err)
 	}
 
+	// addLog adds a file to the log map, creating the entries for its day and hour if they don't exist yet
+	addLog := func(day time.Time, hour int, prefix string, path string) {
+		// Check if the entry for the day exists, if not, initialize it
+		if _, ok := logMap[day]; !ok {
+			logMap[day] = make(HourlyZeekLogs, 24)
+		}
+
+		// Check if the entry for the hour exists, if not, initialize it
+		if logMap[day][hour] == nil {
+			logMap[day][hour] = make(map[string][]string)
+		}
+
+		logMap[day][hour][prefix] = append(logMap[day][hour][prefix], path)
+	}
+
 	// group files into arrays by their log type
 	for _, file := range fTracker {
 		path := file.path
@@ -463,6 +938,12 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 		// check if the file is one of the accepted log types
 		var prefix string
 		switch {
+		case importer.IsFlowFile(path):
+			prefix = importer.FlowPrefix
+		case isEVEFile(path):
+			prefix = importer.EVEPrefix
+		case importer.IsECSFile(path):
+			prefix = importer.ECSPrefix
 		case strings.HasPrefix(filepath.Base(path), importer.ConnPrefix) && !strings.HasPrefix(filepath.Base(path), importer.ConnSummaryPrefixUnderscore) && !strings.HasPrefix(filepath.Base(path), importer.ConnSummaryPrefixHyphen):
 			prefix = importer.ConnPrefix
 		case strings.HasPrefix(filepath.Base(path), importer.OpenConnPrefix):
@@ -477,13 +958,59 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 			prefix = importer.SSLPrefix
 		case strings.HasPrefix(filepath.Base(path), importer.OpenSSLPrefix):
 			prefix = importer.OpenSSLPrefix
-		default: // skip file if it doesn't match any of the accepted prefixes
-			walkErrors = append(walkErrors, WalkError{Path: path, Error: ErrInvalidLogType})
+		case strings.HasPrefix(filepath.Base(path), importer.X509Prefix):
+			prefix = importer.X509Prefix
+		case strings.HasPrefix(filepath.Base(path), importer.SSHPrefix):
+			prefix = importer.SSHPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.QUICPrefix):
+			prefix = importer.QUICPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.DHCPPrefix):
+			prefix = importer.DHCPPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.SMBFilesPrefix):
+			prefix = importer.SMBFilesPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.SMBMappingPrefix):
+			prefix = importer.SMBMappingPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.DCERPCPrefix):
+			prefix = importer.DCERPCPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.NTLMPrefix):
+			prefix = importer.NTLMPrefix
+		case strings.HasPrefix(filepath.Base(path), importer.KerberosPrefix):
+			prefix = importer.KerberosPrefix
+		default:
+			// skip file if it doesn't match any of the accepted prefixes or the prefix of a registered log type
+			registeredPrefix, ok := importer.RegisteredRecordTypePrefix(path)
+			if !ok {
+				walkErrors = append(walkErrors, WalkError{Path: path, Error: ErrInvalidLogType})
+				continue
+			}
+			prefix = registeredPrefix
+		}
+
+		// ECS exports and EVE logs usually span many hours, so they are always added to each hour that they have records
+		// for, as are the other logs when bucketing by record timestamp. Flow exports are still bucketed by file.
+		if prefix == importer.ECSPrefix || prefix == importer.EVEPrefix || (byTimestamp && prefix != importer.FlowPrefix) {
+			// logs that span many hours are split into a file per hour while their hours are read
+			hours, err := importer.SplitLogHours(afs, path, prefix)
+			if err != nil {
+				walkErrors = append(walkErrors, WalkError{Path: path, Error: err})
+				continue
+			}
+
+			for _, hour := range hours {
+				addLog(hour.Truncate(24*time.Hour), hour.Hour(), prefix, importer.HourPath(path, hour))
+			}
 			continue
 		}
 
 		// parse the hour from the filename
 		hour, err := ParseHourFromFilename(file.path)
+
+		// flow exports aren't always named by hour, so fall back to the timestamp of the first record in the file
+		if errors.Is(err, ErrInvalidLogHourFormat) && prefix == importer.FlowPrefix {
+			if ts, tsErr := importer.GetFlowFirstTimestamp(afs, file.path); tsErr == nil {
+				hour, err = ts.Hour(), nil
+			}
+		}
 		if err != nil {
 			walkErrors = append(walkErrors, WalkError{Path: path, Error: err})
 			continue
@@ -495,18 +1022,8 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 			walkErrors = append(walkErrors, WalkError{Path: path, Error: err})
 		}
 
-		// Check if the entry for the day exists, if not, initialize it
-		if _, ok := logMap[folderDate]; !ok {
-			logMap[folderDate] = make(HourlyZeekLogs, 24)
-		}
-
-		// Check if the entry for the hour exists, if not, initialize it
-		if logMap[folderDate][hour] == nil {
-			logMap[folderDate][hour] = make(map[string][]string)
-		}
-
 		// add the file to the hour map
-		logMap[folderDate][hour][prefix] = append(logMap[folderDate][hour][prefix], path)
+		addLog(folderDate, hour, prefix, path)
 
 	}
 
@@ -525,6 +1042,18 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 				delete(logMap[day][hour], importer.HTTPPrefix)
 			}
 
+			// ssh logs are linked to conn logs the same way as SSL logs, so they have to be skipped as well
+			if len(logMap[day][hour][importer.ConnPrefix]) == 0 && len(logMap[day][hour][importer.SSHPrefix]) > 0 {
+				logger.Warn().Msg("SSH logs are present, but no conn logs exist, skipping SSH logs...")
+				delete(logMap[day][hour], importer.SSHPrefix)
+			}
+
+			// quic logs are linked to conn logs the same way as SSL logs, so they have to be skipped as well
+			if len(logMap[day][hour][importer.ConnPrefix]) == 0 && len(logMap[day][hour][importer.QUICPrefix]) > 0 {
+				logger.Warn().Msg("QUIC logs are present, but no conn logs exist, skipping QUIC logs...")
+				delete(logMap[day][hour], importer.QUICPrefix)
+			}
+
 			// 	// if there are no open conn logs in the hour, we have to skip any open SSL and open HTTP logs for that hour
 			if len(logMap[day][hour][importer.OpenConnPrefix]) == 0 && (len(logMap[day][hour][importer.OpenSSLPrefix]) > 0 || len(logMap[day][hour][importer.OpenHTTPPrefix]) > 0) {
 				logger.Warn().Msg("Open SSL / open HTTP logs are present, but no conn logs exist, skipping open SSL / open HTTP logs...")
@@ -564,10 +1093,142 @@ func WalkFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error)
 	return importLogs, walkErrors, err
 }
 
+// WalkPCAPFiles walks the directory tree at root to find packet capture files. Captures aren't rotated hourly like
+// zeek logs, so each capture is added to every hour that it contains packets for, using the packet timestamps.
+func WalkPCAPFiles(afs afero.Fs, root string) ([]HourlyZeekLogs, []WalkError, error) {
+	// check if root is a valid directory or file
+	err := util.ValidateDirectory(afs, root)
+	if err != nil && !errors.Is(err, util.ErrPathIsNotDir) {
+		return nil, nil, err
+	}
+	if err != nil && errors.Is(err, util.ErrPathIsNotDir) {
+		if err := util.ValidateFile(afs, root); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	logMap := make(map[time.Time]HourlyZeekLogs)
+
+	type fileTrack struct {
+		lastModified time.Time
+		path         string
+	}
+	fTracker := make(map[string]fileTrack)
+
+	var walkErrors []WalkError
+
+	err = afero.Walk(afs, root, func(path string, info os.FileInfo, afErr error) error {
+
+		// check if afero failed to access or find a file or directory
+		if afErr != nil {
+			walkErrors = append(walkErrors, WalkError{Path: path, Error: afErr})
+			return nil //nolint:nilerr // log the issue and continue walking
+		}
+
+		// skip if path is a directory
+		if info.IsDir() {
+			return nil
+		}
+
+		// skip if file is not a packet capture
+		if !importer.IsPCAPFile(path) {
+			walkErrors = append(walkErrors, WalkError{Path: path, Error: ErrIncompatibleFileExtension})
+			return nil // log the issue and continue walking
+		}
+
+		// check if the file is readable
+		_, err := afs.Open(path)
+		if err != nil || !(info.Mode().Perm()&0444 == 0444) {
+			walkErrors = append(walkErrors, WalkError{Path: path, Error: ErrInsufficientReadPermissions})
+			return nil //nolint:nilerr // log the issue and continue walking
+		}
+
+		// keep the most recently modified of a compressed and uncompressed copy of the same capture
+		trimmedFileName := importer.TrimCompressionExtension(path)
+		fileData, exists := fTracker[trimmedFileName]
+		switch {
+		case !exists:
+			fTracker[trimmedFileName] = fileTrack{lastModified: info.ModTime(), path: path}
+		case fileData.lastModified.Before(info.ModTime()):
+			walkErrors = append(walkErrors, WalkError{Path: fileData.path, Error: ErrSkippedDuplicateLog})
+			fTracker[trimmedFileName] = fileTrack{lastModified: info.ModTime(), path: path}
+		default:
+			walkErrors = append(walkErrors, WalkError{Path: path, Error: ErrSkippedDuplicateLog})
+		}
+
+		return nil
+	})
+
+	// return an error if the file walk failed completely
+	if err != nil {
+		return nil, nil, fmt.Errorf("file walk failed: %w", err)
+	}
+
+	totalFilesFound := 0
+	for _, file := range fTracker {
+		hours, err := importer.GetPCAPHours(afs, file.path)
+		if err != nil {
+			walkErrors = append(walkErrors, WalkError{Path: file.path, Error: err})
+			continue
+		}
+
+		for _, hour := range hours {
+			day := time.Date(hour.Year(), hour.Month(), hour.Day(), 0, 0, 0, 0, time.UTC)
+
+			// Check if the entry for the day exists, if not, initialize it
+			if _, ok := logMap[day]; !ok {
+				logMap[day] = make(HourlyZeekLogs, 24)
+			}
+
+			// Check if the entry for the hour exists, if not, initialize it
+			if logMap[day][hour.Hour()] == nil {
+				logMap[day][hour.Hour()] = make(map[string][]string)
+			}
+
+			logMap[day][hour.Hour()][importer.PCAPPrefix] = append(logMap[day][hour.Hour()][importer.PCAPPrefix], importer.HourPath(file.path, hour))
+			totalFilesFound++
+		}
+	}
+
+	// return an error if no files were found
+	if totalFilesFound == 0 {
+		return nil, walkErrors, ErrNoValidFilesFound
+	}
+
+	var importLogs []HourlyZeekLogs
+
+	var days []time.Time
+	for day := range logMap {
+		days = append(days, day)
+		// sort the captures of each hour, necessary for tests
+		for hour := range logMap[day] {
+			slices.Sort(logMap[day][hour][importer.PCAPPrefix])
+		}
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+
+	for _, day := range days {
+		importLogs = append(importLogs, logMap[day])
+	}
+
+	return importLogs, walkErrors, nil
+}
+
 // ParseHourFromFilename extracts the hour from a given filename
 func ParseHourFromFilename(filename string) (int, error) {
+	// rotated flow captures are named using the start of the capture interval (ie, flows.202404191600.ipfix)
+	if importer.IsFlowFile(filename) {
+		return parseHourFromFlowFilename(filepath.Base(filename))
+	}
+
+	// EVE logs rotated by Suricata are named using a date (ie, eve-2024-04-19-16:00.json) or a unix timestamp (ie, eve-1713542400.json)
+	if isEVEFile(filename) {
+		return parseHourFromEVEFilename(filepath.Base(filename))
+	}
+
 	// define regex patterns to extract the hour from the filename
-	timePattern := `[A-Za-z]+\.(\d{2})[:/_]\d{2}`
+	// the log type may contain digits after its first letter (ie, x509)
+	timePattern := `[A-Za-z][A-Za-z0-9]*\.(\d{2})[:/_]\d{2}`
 
 	// compile the timeRegex
 	timeRegex := regexp.MustCompile(timePattern)
@@ -578,11 +1239,11 @@ func ParseHourFromFilename(filename string) (int, error) {
 	// if hour pattern didn't match, check if the filename is a simple log file
 	if matches == nil {
 		// regex to identify simple log files (ie, conn.log, open_conn.log, /logs/conn.log.gz, etc) without hour
-		simpleLogPattern := `^\w+\.log(\.gz)?$`
+		simpleLogPattern := `^\w+\.log$`
 		simpleLogRegex := regexp.MustCompile(simpleLogPattern)
 
 		// if the filename matches the simple log pattern, consider file as 0 hour and return
-		if simpleLogRegex.MatchString(filepath.Base(filename)) {
+		if simpleLogRegex.MatchString(importer.TrimCompressionExtension(filepath.Base(filename))) {
 			return 0, nil
 		}
 
@@ -604,3 +1265,61 @@ func ParseHourFromFilename(filename string) (int, error) {
 
 	return hour, nil
 }
+
+// isEVEFile returns whether the file is a Suricata EVE JSON log (ie, eve.json, eve-2024-04-19-16:00.json.gz)
+func isEVEFile(path string) bool {
+	base := importer.TrimCompressionExtension(filepath.Base(path))
+	return strings.HasPrefix(base, importer.EVEPrefix) && strings.HasSuffix(base, ".json")
+}
+
+// parseHourFromEVEFilename extracts the hour from the name of a Suricata EVE JSON log
+func parseHourFromEVEFilename(filename string) (int, error) {
+	dateRegex := regexp.MustCompile(`^eve[-_.]\d{4}-\d{2}-\d{2}[-_T](\d{2})`)
+	unixRegex := regexp.MustCompile(`^eve[-_.](\d{9,10})\b`)
+
+	if matches := dateRegex.FindStringSubmatch(filename); matches != nil {
+		hour, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return 0, ErrInvalidLogHourFormat
+		}
+
+		// ensure the hour is in the 0-23 range
+		if hour < 0 || hour > 23 {
+			return 0, ErrInvalidLogHourRange
+		}
+		return hour, nil
+	}
+
+	if matches := unixRegex.FindStringSubmatch(filename); matches != nil {
+		ts, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return 0, ErrInvalidLogHourFormat
+		}
+		return time.Unix(ts, 0).UTC().Hour(), nil
+	}
+
+	// the hour must be determined from the records in the log
+	return 0, ErrInvalidLogHourFormat
+}
+
+// parseHourFromFlowFilename extracts the hour from the name of a NetFlow v9 / IPFIX capture file
+func parseHourFromFlowFilename(filename string) (int, error) {
+	timestampRegex := regexp.MustCompile(`(?:^|[._-])\d{8}(\d{2})\d{2}(?:\d{2})?(?:[._-]|$)`)
+
+	matches := timestampRegex.FindStringSubmatch(filename)
+	if matches == nil {
+		// the hour must be determined from the export time of the messages in the file
+		return 0, ErrInvalidLogHourFormat
+	}
+
+	hour, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, ErrInvalidLogHourFormat
+	}
+
+	// ensure the hour is in the 0-23 range
+	if hour < 0 || hour > 23 {
+		return 0, ErrInvalidLogHourRange
+	}
+	return hour, nil
+}
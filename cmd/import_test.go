@@ -2,12 +2,18 @@ package cmd_test
 
 import (
 	"activecm/rita/cmd"
+	"activecm/rita/config"
 	"activecm/rita/database"
 	"activecm/rita/util"
 	"context"
 	"fmt"
 
 	"activecm/rita/importer"
+	"activecm/rita/importer/pcap"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	iofs "io/fs"
 	"os"
 	"path/filepath"
@@ -16,8 +22,12 @@ import (
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 /*
@@ -195,6 +205,112 @@ func (c *CmdTestSuite) TestRunImportCmd() {
 
 }
 
+func (c *CmdTestSuite) TestFollowImportCmdSkipsImportedHours() {
+	t := c.T()
+	afs := afero.NewMemMapFs()
+	followImportCmd := cmd.FollowImportCmd(cmd.WalkFiles)
+
+	firstHour := []string{"2024-04-29/conn.00:00:00-01:00:00.log", "2024-04-29/dns.00:00:00-01:00:00.log", "2024-04-29/http.00:00:00-01:00:00.log", "2024-04-29/ssl.00:00:00-01:00:00.log"}
+	secondHour := []string{"2024-04-29/conn.01:00:00-02:00:00.log", "2024-04-29/dns.01:00:00-02:00:00.log", "2024-04-29/http.01:00:00-02:00:00.log", "2024-04-29/ssl.01:00:00-02:00:00.log"}
+
+	createMockZeekLogs(t, afs, "/logs", firstHour, true)
+	importResults, err := followImportCmd(time.Now(), c.cfg, afs, "/logs", "bingbong", true, true)
+	require.NoError(t, err, "following a new directory should not produce an error")
+	require.Len(t, importResults.ImportID, 1, "the first hour should be imported")
+
+	// zeek rotates the next hour into the same directory, so only the new hour should be imported
+	createMockZeekLogs(t, afs, "/logs", secondHour, true)
+	importResults, err = followImportCmd(time.Now(), c.cfg, afs, "/logs", "bingbong", true, false)
+	require.NoError(t, err, "following a directory with a new hour should not produce an error")
+	require.Len(t, importResults.ImportID, 1, "only the new hour should be imported")
+
+	// nothing is left to import once every hour was imported
+	_, err = followImportCmd(time.Now(), c.cfg, afs, "/logs", "bingbong", true, false)
+	require.ErrorIs(t, err, importer.ErrAllFilesPreviouslyImported, "following a directory without new hours should skip all of them")
+
+	// other imports still fail on the first hour that was already imported
+	_, err = cmd.RunImportCmd(time.Now(), c.cfg, afs, "/logs", "bingbong", true, false)
+	require.ErrorIs(t, err, importer.ErrAllFilesPreviouslyImported, "importing hours that were already imported should fail")
+
+	require.NoError(t, c.server.DeleteSensorDB("bingbong"), "dropping database should not produce an error")
+}
+
+// minioImage is the MinIO release used as the object storage server in tests
+const minioImage = "minio/minio:RELEASE.2024-05-10T01-41-38Z"
+
+func (c *CmdTestSuite) TestRunImportCmdFromS3() {
+	t := c.T()
+	ctx := context.Background()
+
+	// start a MinIO container to act as the object storage server
+	minioContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        minioImage,
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{"9000/tcp"},
+			Env:          map[string]string{"MINIO_ROOT_USER": "rita", "MINIO_ROOT_PASSWORD": "supersecret"},
+			WaitingFor:   wait.ForHTTP("/minio/health/live").WithPort("9000/tcp"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err, "failed to start minio container")
+	defer func() {
+		require.NoError(t, minioContainer.Terminate(ctx), "terminating minio container should not produce an error")
+	}()
+
+	endpoint, err := minioContainer.PortEndpoint(ctx, "9000/tcp", "")
+	require.NoError(t, err, "failed to get minio endpoint")
+
+	cfg := *c.cfg
+	cfg.S3 = config.S3{Endpoint: endpoint, AccessKeyID: "rita", SecretAccessKey: "supersecret"}
+
+	// upload the logs to the bucket
+	client, err := minio.New(endpoint, &minio.Options{Creds: credentials.NewStaticV4(cfg.S3.AccessKeyID, cfg.S3.SecretAccessKey, "")})
+	require.NoError(t, err, "creating minio client should not produce an error")
+	require.NoError(t, client.MakeBucket(ctx, "zeek", minio.MakeBucketOptions{}), "creating bucket should not produce an error")
+
+	files, err := afero.ReadDir(afero.NewOsFs(), filepath.Join(TestDataPath, "valid_tsv"))
+	require.NoError(t, err, "reading test data directory should not produce an error")
+	var expectedPaths []string
+	for _, file := range files {
+		key := "sensor1/" + file.Name()
+		_, err := client.FPutObject(ctx, "zeek", key, filepath.Join(TestDataPath, "valid_tsv", file.Name()), minio.PutObjectOptions{})
+		require.NoError(t, err, "uploading log should not produce an error")
+
+		info, err := client.StatObject(ctx, "zeek", key, minio.StatObjectOptions{})
+		require.NoError(t, err, "getting uploaded log should not produce an error")
+		expectedPaths = append(expectedPaths, fmt.Sprintf("s3://zeek/%s?etag=%s", key, info.ETag))
+	}
+
+	// import the logs from the bucket
+	importResults, err := cmd.RunImportCmd(time.Now(), &cfg, afero.NewOsFs(), "s3://zeek/sensor1", "bingbong", true, false)
+	require.NoError(t, err, "running import command should not produce an error")
+	require.Len(t, importResults.ImportID, 1, "import results should have expected number of import IDs")
+
+	// verify that the objects were marked as imported by their key and ETag
+	var result struct {
+		Paths []string `ch:"paths"`
+	}
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithParameters(clickhouse.Parameters{
+		"import_id": importResults.ImportID[0].Hex(),
+		"database":  "bingbong",
+	}))
+	err = c.server.Conn.QueryRow(queryCtx, `
+		SELECT groupArray(path) AS paths
+		FROM metadatabase.files
+		WHERE import_id = unhex({import_id:String}) AND database = {database:String}
+	`).ScanStruct(&result)
+	require.NoError(t, err, "querying for imported files should not produce an error")
+	require.ElementsMatch(t, expectedPaths, result.Paths, "paths should match expected value")
+
+	// importing the same objects again should skip all of them
+	_, err = cmd.RunImportCmd(time.Now(), &cfg, afero.NewOsFs(), "s3://zeek/sensor1", "bingbong", true, false)
+	require.ErrorIs(t, err, importer.ErrAllFilesPreviouslyImported, "importing the same objects again should skip them")
+
+	// clean up the database
+	require.NoError(t, c.server.DeleteSensorDB("bingbong"), "dropping database should not produce an error")
+}
+
 // createMockZeekLogs creates a directory with files that contain mock Zeek logs, filling them with valid
 // log values if necessary for the test
 func createMockZeekLogs(t *testing.T, afs afero.Fs, directory string, files []string, valid bool) {
@@ -280,19 +396,30 @@ func TestWalkFiles(t *testing.T) {
 				"conn.log", "dns.log", "http.log", "ssl.log", "open_conn.log", "open_http.log", "open_ssl.log",
 				"conn_red.log", "dns_red.log", "http_red.log", "ssl_red.log",
 				"conn_blue.log.gz", "dns_blue.log.gz", "http_blue.log.gz", "ssl_blue.log.gz",
-				".DS_STORE", "capture_loss.16:00:00-17:00:00.log.gz", "stats.16:00:00-17:00:00.log.gz", "x509.16:00:00-17:00:00.log.gz",
+				"x509.log", "x509_red.log", "ssh.log", "ssh_red.log", "quic.log", "dhcp.log",
+				"smb_files.log", "smb_mapping.log", "dce_rpc.log", "ntlm.log", "kerberos.log",
+				".DS_STORE", "capture_loss.16:00:00-17:00:00.log.gz", "stats.16:00:00-17:00:00.log.gz",
 				"known_certs.16:00:00-17:00:00.log.gz",
 			},
 			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
 				0: {
 					0: {
-						importer.ConnPrefix:     []string{"/logs/conn.log", "/logs/conn_blue.log.gz", "/logs/conn_red.log"},
-						importer.OpenConnPrefix: []string{"/logs/open_conn.log"},
-						importer.DNSPrefix:      []string{"/logs/dns.log", "/logs/dns_blue.log.gz", "/logs/dns_red.log"},
-						importer.HTTPPrefix:     []string{"/logs/http.log", "/logs/http_blue.log.gz", "/logs/http_red.log"},
-						importer.OpenHTTPPrefix: []string{"/logs/open_http.log"},
-						importer.SSLPrefix:      []string{"/logs/ssl.log", "/logs/ssl_blue.log.gz", "/logs/ssl_red.log"},
-						importer.OpenSSLPrefix:  []string{"/logs/open_ssl.log"},
+						importer.ConnPrefix:       []string{"/logs/conn.log", "/logs/conn_blue.log.gz", "/logs/conn_red.log"},
+						importer.OpenConnPrefix:   []string{"/logs/open_conn.log"},
+						importer.DNSPrefix:        []string{"/logs/dns.log", "/logs/dns_blue.log.gz", "/logs/dns_red.log"},
+						importer.HTTPPrefix:       []string{"/logs/http.log", "/logs/http_blue.log.gz", "/logs/http_red.log"},
+						importer.OpenHTTPPrefix:   []string{"/logs/open_http.log"},
+						importer.SSLPrefix:        []string{"/logs/ssl.log", "/logs/ssl_blue.log.gz", "/logs/ssl_red.log"},
+						importer.OpenSSLPrefix:    []string{"/logs/open_ssl.log"},
+						importer.X509Prefix:       []string{"/logs/x509.log", "/logs/x509_red.log"},
+						importer.SSHPrefix:        []string{"/logs/ssh.log", "/logs/ssh_red.log"},
+						importer.QUICPrefix:       []string{"/logs/quic.log"},
+						importer.DHCPPrefix:       []string{"/logs/dhcp.log"},
+						importer.SMBFilesPrefix:   []string{"/logs/smb_files.log"},
+						importer.SMBMappingPrefix: []string{"/logs/smb_mapping.log"},
+						importer.DCERPCPrefix:     []string{"/logs/dce_rpc.log"},
+						importer.NTLMPrefix:       []string{"/logs/ntlm.log"},
+						importer.KerberosPrefix:   []string{"/logs/kerberos.log"},
 					},
 				},
 			}),
@@ -300,7 +427,6 @@ func TestWalkFiles(t *testing.T) {
 				{Path: "/logs/.DS_STORE", Error: cmd.ErrIncompatibleFileExtension},
 				{Path: "/logs/capture_loss.16:00:00-17:00:00.log.gz", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/stats.16:00:00-17:00:00.log.gz", Error: cmd.ErrInvalidLogType},
-				{Path: "/logs/x509.16:00:00-17:00:00.log.gz", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/known_certs.16:00:00-17:00:00.log.gz", Error: cmd.ErrInvalidLogType},
 			},
 			expectedError: nil,
@@ -463,6 +589,110 @@ func TestWalkFiles(t *testing.T) {
 			expectedWalkErrors: nil,
 			expectedError:      nil,
 		},
+		{
+			name:                 "Hour Logs, Missing ssl & open_ssl, has x509",
+			directory:            "/logs",
+			directoryPermissions: os.FileMode(0o775),
+			filePermissions:      os.FileMode(0o775),
+			files: []string{
+				// missing ssl and open ssl, has x509, which is kept since certificates are linked to ssl logs across hours
+				"conn.05:00:00-06:00:00.log", "dns.05:00:00-06:00:00.log", "x509.05:00:00-06:00:00.log",
+				// has ssl and x509
+				"conn.06:00:00-07:00:00.log", "ssl.06:00:00-07:00:00.log", "x509.06:00:00-07:00:00.log",
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					5: {
+						importer.ConnPrefix: []string{"/logs/conn.05:00:00-06:00:00.log"},
+						importer.DNSPrefix:  []string{"/logs/dns.05:00:00-06:00:00.log"},
+						importer.X509Prefix: []string{"/logs/x509.05:00:00-06:00:00.log"},
+					},
+					6: {
+						importer.ConnPrefix: []string{"/logs/conn.06:00:00-07:00:00.log"},
+						importer.SSLPrefix:  []string{"/logs/ssl.06:00:00-07:00:00.log"},
+						importer.X509Prefix: []string{"/logs/x509.06:00:00-07:00:00.log"},
+					},
+				},
+			}),
+			expectedWalkErrors: nil,
+			expectedError:      nil,
+		},
+		{
+			name:                 "Hour Logs, Missing conn, has ssh",
+			directory:            "/logs",
+			directoryPermissions: os.FileMode(0o775),
+			filePermissions:      os.FileMode(0o775),
+			files: []string{
+				// missing conn, has ssh
+				"dns.07:00:00-08:00:00.log", "ssh.07:00:00-08:00:00.log",
+				// has conn and ssh
+				"conn.08:00:00-09:00:00.log", "ssh.08:00:00-09:00:00.log",
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					7: {
+						importer.DNSPrefix: []string{"/logs/dns.07:00:00-08:00:00.log"},
+					},
+					8: {
+						importer.ConnPrefix: []string{"/logs/conn.08:00:00-09:00:00.log"},
+						importer.SSHPrefix:  []string{"/logs/ssh.08:00:00-09:00:00.log"},
+					},
+				},
+			}),
+			expectedWalkErrors: nil,
+			expectedError:      nil,
+		},
+		{
+			name:                 "Hour Logs, Missing conn, has quic",
+			directory:            "/logs",
+			directoryPermissions: os.FileMode(0o775),
+			filePermissions:      os.FileMode(0o775),
+			files: []string{
+				// missing conn, has quic
+				"dns.09:00:00-10:00:00.log", "quic.09:00:00-10:00:00.log",
+				// has conn and quic
+				"conn.10:00:00-11:00:00.log", "quic.10:00:00-11:00:00.log",
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					9: {
+						importer.DNSPrefix: []string{"/logs/dns.09:00:00-10:00:00.log"},
+					},
+					10: {
+						importer.ConnPrefix: []string{"/logs/conn.10:00:00-11:00:00.log"},
+						importer.QUICPrefix: []string{"/logs/quic.10:00:00-11:00:00.log"},
+					},
+				},
+			}),
+			expectedWalkErrors: nil,
+			expectedError:      nil,
+		},
+		{
+			name:                 "NetFlow v9 / IPFIX Captures",
+			directory:            "/logs",
+			directoryPermissions: os.FileMode(0o775),
+			filePermissions:      os.FileMode(0o775),
+			files: []string{
+				// hour from timestamp in filename
+				"flows.202404191600.ipfix", "flows.202404191705.netflow.gz",
+				// hour can't be determined from filename or export time
+				"flows.ipfix",
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					16: {
+						importer.FlowPrefix: []string{"/logs/flows.202404191600.ipfix"},
+					},
+					17: {
+						importer.FlowPrefix: []string{"/logs/flows.202404191705.netflow.gz"},
+					},
+				},
+			}),
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/logs/flows.ipfix", Error: cmd.ErrInvalidLogHourFormat},
+			},
+			expectedError: nil,
+		},
 		{
 			name:                 "Hour Logs, Missing open_conn, conn, and dns",
 			directory:            "/logs",
@@ -697,6 +927,29 @@ func TestWalkFiles(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			// every compressed variant of the same log is treated as one file
+			name:                 "Duplicate Logs - Same Name, One Newer - Different Compression Formats",
+			directory:            "/logs_dupe",
+			directoryPermissions: iofs.FileMode(0o775),
+			filePermissions:      iofs.FileMode(0o775),
+			files: []string{
+				"conn.log.bz2", "conn.log.xz", "conn.log.gz", "conn.log.zst",
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					0: {
+						importer.ConnPrefix: []string{"/logs_dupe/conn.log.zst"},
+					},
+				},
+			}),
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/logs_dupe/conn.log.bz2", Error: cmd.ErrSkippedDuplicateLog},
+				{Path: "/logs_dupe/conn.log.xz", Error: cmd.ErrSkippedDuplicateLog},
+				{Path: "/logs_dupe/conn.log.gz", Error: cmd.ErrSkippedDuplicateLog},
+			},
+			expectedError: nil,
+		},
 		{
 			name:                 "No Prefix on Files",
 			directory:            "/logs",
@@ -751,7 +1004,7 @@ func TestWalkFiles(t *testing.T) {
 			directoryPermissions: iofs.FileMode(0o775),
 			filePermissions:      iofs.FileMode(0o775),
 			files: []string{
-				"files.log", "ntp.log", "radius.log", "sip.log", "x509.log.gz", "dhcp.log", "weird.log",
+				"files.log", "ntp.log", "radius.log", "sip.log", "known_certs.log.gz", "snmp.log", "weird.log",
 				"conn_summary.log", "conn-summary.log", "foo.log",
 			},
 			expectedWalkErrors: []cmd.WalkError{
@@ -759,8 +1012,8 @@ func TestWalkFiles(t *testing.T) {
 				{Path: "/logs/ntp.log", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/radius.log", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/sip.log", Error: cmd.ErrInvalidLogType},
-				{Path: "/logs/x509.log.gz", Error: cmd.ErrInvalidLogType},
-				{Path: "/logs/dhcp.log", Error: cmd.ErrInvalidLogType},
+				{Path: "/logs/known_certs.log.gz", Error: cmd.ErrInvalidLogType},
+				{Path: "/logs/snmp.log", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/weird.log", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/conn_summary.log", Error: cmd.ErrInvalidLogType},
 				{Path: "/logs/conn-summary.log", Error: cmd.ErrInvalidLogType},
@@ -872,6 +1125,111 @@ func TestWalkFiles(t *testing.T) {
 	}
 }
 
+func TestWalkPCAPFiles(t *testing.T) {
+	afs := afero.NewMemMapFs()
+
+	capture, err := os.ReadFile("../test_data/pcap/sample.pcap")
+	require.NoError(t, err)
+	captureNG, err := os.ReadFile("../test_data/pcap/sample.pcapng")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		root               string
+		files              map[string][]byte
+		expectedFiles      []cmd.HourlyZeekLogs
+		expectedWalkErrors []cmd.WalkError
+		expectedError      error
+	}{
+		{
+			name: "Capture Spanning Two Hours",
+			root: "/captures",
+			files: map[string][]byte{
+				"/captures/sample.pcap": capture,
+				"/captures/notes.txt":   []byte("testytesttestboop"),
+				"/captures/bogus.pcap":  []byte("testytesttestboop"),
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					16: {importer.PCAPPrefix: []string{"/captures/sample.pcap#2024-04-19T16"}},
+					17: {importer.PCAPPrefix: []string{"/captures/sample.pcap#2024-04-19T17"}},
+				},
+			}),
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/captures/notes.txt", Error: cmd.ErrIncompatibleFileExtension},
+				{Path: "/captures/bogus.pcap", Error: pcap.ErrUnknownCaptureFormat},
+			},
+		},
+		{
+			name: "Single Capture File",
+			root: "/captures/2024-01-01/sample.pcapng.gz",
+			files: map[string][]byte{
+				"/captures/2024-01-01/sample.pcapng.gz": gzipBytes(t, captureNG),
+			},
+			// captures are bucketed by the date of their packets instead of their parent directory
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					16: {importer.PCAPPrefix: []string{"/captures/2024-01-01/sample.pcapng.gz#2024-04-19T16"}},
+					17: {importer.PCAPPrefix: []string{"/captures/2024-01-01/sample.pcapng.gz#2024-04-19T17"}},
+				},
+			}),
+		},
+		{
+			name: "No Valid Captures",
+			root: "/captures",
+			files: map[string][]byte{
+				"/captures/conn.log": []byte("testytesttestboop"),
+			},
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/captures/conn.log", Error: cmd.ErrIncompatibleFileExtension},
+			},
+			expectedError: cmd.ErrNoValidFilesFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// create the files
+			for path, data := range test.files {
+				err := afero.WriteFile(afs, path, data, os.FileMode(0o775))
+				require.NoError(t, err, "creating mock file should not produce an error")
+			}
+
+			logMap, walkErrors, err := cmd.WalkPCAPFiles(afs, test.root)
+
+			// check if the error is expected
+			if test.expectedError == nil {
+				require.NoError(t, err, "running WalkPCAPFiles should not produce an error")
+			} else {
+				require.ErrorIs(t, err, test.expectedError, "error should match expected value")
+			}
+
+			// verify that the returned log map matches the expected values
+			require.Equal(t, test.expectedFiles, logMap, "log map should match expected value")
+
+			// verify that the returned walk errors match the expected values
+			require.ElementsMatch(t, test.expectedWalkErrors, walkErrors, "walk errors should match expected value")
+
+			// clean up the directory
+			err = afs.RemoveAll("/captures")
+			require.NoError(t, err, "removing mock directory should not produce an error")
+		})
+	}
+}
+
+// gzipBytes compresses data using gzip
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
 func TestParseHourFromFilename(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -904,6 +1262,54 @@ func TestParseHourFromFilename(t *testing.T) {
 			wantHour: 23,
 			wantErr:  nil,
 		},
+		{
+			name:     "Valid hour with digits in log type",
+			filename: "x509.16:00:00-17:00:00.log.gz",
+			wantHour: 16,
+			wantErr:  nil,
+		},
+		{
+			name:     "Valid EVE hour from date",
+			filename: "/logs/eve-2024-04-19-08:00:00.json",
+			wantHour: 8,
+			wantErr:  nil,
+		},
+		{
+			name:     "Valid EVE hour from unix timestamp",
+			filename: "eve-1713546000.json.gz",
+			wantHour: 17,
+			wantErr:  nil,
+		},
+		{
+			name:     "Invalid EVE hour range",
+			filename: "eve-2024-04-19-25:00.json",
+			wantHour: 0,
+			wantErr:  cmd.ErrInvalidLogHourRange,
+		},
+		{
+			name:     "EVE without hour",
+			filename: "eve.json",
+			wantHour: 0,
+			wantErr:  cmd.ErrInvalidLogHourFormat,
+		},
+		{
+			name:     "Valid flow capture hour from timestamp",
+			filename: "/flows/flows.202404190930.ipfix",
+			wantHour: 9,
+			wantErr:  nil,
+		},
+		{
+			name:     "Invalid flow capture hour range",
+			filename: "flows.202404192700.netflow",
+			wantHour: 0,
+			wantErr:  cmd.ErrInvalidLogHourRange,
+		},
+		{
+			name:     "Flow capture without hour",
+			filename: "export.ipfix.gz",
+			wantHour: 0,
+			wantErr:  cmd.ErrInvalidLogHourFormat,
+		},
 		{
 			name:     "Invalid Hour Range",
 			filename: "log.24:00",
@@ -971,3 +1377,267 @@ func TestValidateDatabaseName(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkFilesByTimestamp(t *testing.T) {
+	afs := afero.NewMemMapFs()
+
+	// zeek log with records at the given unix timestamps
+	zeekLog := func(path string, timestamps ...int64) []byte {
+		log := "#separator \\x09\n#path\t" + path + "\n#fields\tts\tuid\n#types\ttime\tstring\n"
+		for i, ts := range timestamps {
+			log += fmt.Sprintf("%d.000000\tC%d\n", ts, i)
+		}
+		return []byte(log)
+	}
+	day := time.Date(2024, 4, 19, 0, 0, 0, 0, time.UTC).Unix()
+	hour := int64(time.Hour.Seconds())
+
+	tests := []struct {
+		name               string
+		root               string
+		files              map[string][]byte
+		expectedFiles      []cmd.HourlyZeekLogs
+		expectedWalkErrors []cmd.WalkError
+		expectedError      error
+	}{
+		{
+			name: "Day Long Logs",
+			root: "/logs",
+			files: map[string][]byte{
+				// the hour in the file name is ignored in favor of the record timestamps
+				"/logs/conn.log":                     zeekLog("conn", day+30, day+2*hour+5, day+2*hour+10),
+				"/logs/dns.00:00:00-01:00:00.log.gz": gzipBytes(t, zeekLog("dns", day+23*hour, day+24*hour+1)),
+				"/logs/notes.txt":                    []byte("testytesttestboop"),
+				"/logs/http.log":                     zeekLog("http"),
+			},
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					0:  {importer.ConnPrefix: []string{"/logs/conn.log#2024-04-19T00"}},
+					2:  {importer.ConnPrefix: []string{"/logs/conn.log#2024-04-19T02"}},
+					23: {importer.DNSPrefix: []string{"/logs/dns.00:00:00-01:00:00.log.gz#2024-04-19T23"}},
+				},
+				// records after midnight are imported with the next day
+				1: {
+					0: {importer.DNSPrefix: []string{"/logs/dns.00:00:00-01:00:00.log.gz#2024-04-20T00"}},
+				},
+			}),
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/logs/notes.txt", Error: cmd.ErrIncompatibleFileExtension},
+				{Path: "/logs/http.log", Error: importer.ErrLogHasNoRecords},
+			},
+		},
+		{
+			name: "No Records",
+			root: "/logs",
+			files: map[string][]byte{
+				"/logs/conn.log": zeekLog("conn"),
+			},
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/logs/conn.log", Error: importer.ErrLogHasNoRecords},
+			},
+			expectedError: cmd.ErrNoValidFilesFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// create the files
+			for path, data := range test.files {
+				err := afero.WriteFile(afs, path, data, os.FileMode(0o775))
+				require.NoError(t, err, "creating mock file should not produce an error")
+			}
+
+			logMap, walkErrors, err := cmd.WalkFilesByTimestamp(afs, test.root)
+
+			// check if the error is expected
+			if test.expectedError == nil {
+				require.NoError(t, err, "running WalkFilesByTimestamp should not produce an error")
+			} else {
+				require.ErrorIs(t, err, test.expectedError, "error should match expected value")
+			}
+
+			// verify that the returned log map matches the expected values
+			require.Equal(t, test.expectedFiles, logMap, "log map should match expected value")
+
+			// verify that the returned walk errors match the expected values
+			require.ElementsMatch(t, test.expectedWalkErrors, walkErrors, "walk errors should match expected value")
+
+			// clean up the directory
+			err = afs.RemoveAll("/logs")
+			require.NoError(t, err, "removing mock directory should not produce an error")
+		})
+	}
+}
+
+func TestWalkEVEFiles(t *testing.T) {
+	afs := afero.NewMemMapFs()
+
+	files := map[string][]byte{
+		// logs are split into hours by the timestamp of their events, whether or not the hour is in their name
+		"/logs/eve.json": []byte(`{"timestamp":"2024-04-19T16:00:01.512345+0000","event_type":"flow"}` + "\n" +
+			`{"timestamp":"2024-04-19T17:05:00.000000+0000","event_type":"dns"}` + "\n"),
+		"/logs/eve-2024-04-19-16:00.json.gz": gzipBytes(t, []byte(`{"timestamp":"2024-04-19T18:00:00.000000+0000","event_type":"http"}`+"\n")),
+		// log without any valid timestamps
+		"/logs/eve-1713546000.json": []byte("test"),
+		// not an EVE log
+		"/logs/alerts.json": []byte(`{"timestamp":"2024-04-19T16:00:01.512345+0000"}` + "\n"),
+	}
+	for path, data := range files {
+		err := afero.WriteFile(afs, path, data, os.FileMode(0o775))
+		require.NoError(t, err, "creating mock file should not produce an error")
+	}
+
+	logMap, walkErrors, err := cmd.WalkFiles(afs, "/logs")
+	require.NoError(t, err, "running WalkFiles should not produce an error")
+
+	require.Equal(t, createExpectedResults([]cmd.HourlyZeekLogs{
+		0: {
+			16: {importer.EVEPrefix: []string{"/logs/eve.json#2024-04-19T16"}},
+			17: {importer.EVEPrefix: []string{"/logs/eve.json#2024-04-19T17"}},
+			18: {importer.EVEPrefix: []string{"/logs/eve-2024-04-19-16:00.json.gz#2024-04-19T18"}},
+		},
+	}), logMap, "log map should match expected value")
+
+	require.ElementsMatch(t, []cmd.WalkError{
+		{Path: "/logs/eve-1713546000.json", Error: importer.ErrEVELogHasNoRecords},
+		{Path: "/logs/alerts.json", Error: cmd.ErrIncompatibleFileExtension},
+	}, walkErrors, "walk errors should match expected value")
+}
+
+func TestWalkECSFiles(t *testing.T) {
+	afs := afero.NewMemMapFs()
+
+	files := map[string][]byte{
+		// exports are split into hours by the @timestamp of their records, whether or not the hour is in their name
+		"/logs/ecs-zeek.ndjson": []byte(`{"@timestamp":"2024-04-19T16:00:01.512Z","event":{"dataset":"zeek.connection"}}` + "\n" +
+			`{"@timestamp":"2024-04-19T17:05:00Z","event":{"dataset":"zeek.dns"}}` + "\n"),
+		"/logs/ecs-2024-04-19-16:00.json.gz": gzipBytes(t, []byte(`{"@timestamp":"2024-04-19T18:00:00Z","event":{"dataset":"zeek.http"}}`+"\n")),
+		// export without any valid timestamps
+		"/logs/ecs-empty.ndjson": []byte(`{"event":{"dataset":"zeek.connection"}}` + "\n"),
+		// not an ECS export
+		"/logs/export.ndjson": []byte(`{"@timestamp":"2024-04-19T16:00:01.512Z"}` + "\n"),
+	}
+	for path, data := range files {
+		err := afero.WriteFile(afs, path, data, os.FileMode(0o775))
+		require.NoError(t, err, "creating mock file should not produce an error")
+	}
+
+	logMap, walkErrors, err := cmd.WalkFiles(afs, "/logs")
+	require.NoError(t, err, "running WalkFiles should not produce an error")
+
+	require.Equal(t, createExpectedResults([]cmd.HourlyZeekLogs{
+		0: {
+			16: {importer.ECSPrefix: []string{"/logs/ecs-zeek.ndjson#2024-04-19T16"}},
+			17: {importer.ECSPrefix: []string{"/logs/ecs-zeek.ndjson#2024-04-19T17"}},
+			18: {importer.ECSPrefix: []string{"/logs/ecs-2024-04-19-16:00.json.gz#2024-04-19T18"}},
+		},
+	}), logMap, "log map should match expected value")
+
+	require.ElementsMatch(t, []cmd.WalkError{
+		{Path: "/logs/ecs-empty.ndjson", Error: importer.ErrECSExportHasNoRecords},
+		{Path: "/logs/export.ndjson", Error: cmd.ErrIncompatibleFileExtension},
+	}, walkErrors, "walk errors should match expected value")
+}
+
+func TestWalkFilesInArchives(t *testing.T) {
+	afs := afero.NewMemMapFs()
+
+	// a gzipped tar bundle with a directory entry for only one of its day folders
+	var tarBuf bytes.Buffer
+	tarWriter := tar.NewWriter(&tarBuf)
+	for _, member := range []string{
+		"2024-04-19/", "2024-04-19/conn.00:00:00-01:00:00.log.gz", "2024-04-19/dns.00:00:00-01:00:00.log.gz",
+		"2024-04-20/conn.01:00:00-02:00:00.log",
+	} {
+		header := &tar.Header{Name: member, Typeflag: tar.TypeReg, Mode: 0o600, Size: int64(len("testytesttestboop"))}
+		if strings.HasSuffix(member, "/") {
+			header.Typeflag, header.Mode, header.Size = tar.TypeDir, 0o700, 0
+		}
+		require.NoError(t, tarWriter.WriteHeader(header))
+		if header.Typeflag == tar.TypeReg {
+			_, err := tarWriter.Write([]byte("testytesttestboop"))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, afero.WriteFile(afs, "/bundles/logs.tar.gz", gzipBytes(t, tarBuf.Bytes()), os.FileMode(0o775)))
+
+	// a zip bundle without any directory entries
+	var zipBuf bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuf)
+	for _, member := range []string{"2024-04-21/conn.log", "2024-04-21/notes.txt"} {
+		writer, err := zipWriter.Create(member)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte("testytesttestboop"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+	require.NoError(t, afero.WriteFile(afs, "/bundles/logs.zip", zipBuf.Bytes(), os.FileMode(0o775)))
+
+	tests := []struct {
+		name               string
+		root               string
+		expectedFiles      []cmd.HourlyZeekLogs
+		expectedWalkErrors []cmd.WalkError
+	}{
+		{
+			name: "Directory of Archives",
+			root: "/bundles",
+			// day folders inside of archives are used just like day folders on disk
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					0: {
+						importer.ConnPrefix: []string{"/bundles/logs.tar.gz/2024-04-19/conn.00:00:00-01:00:00.log.gz"},
+						importer.DNSPrefix:  []string{"/bundles/logs.tar.gz/2024-04-19/dns.00:00:00-01:00:00.log.gz"},
+					},
+				},
+				1: {
+					1: {importer.ConnPrefix: []string{"/bundles/logs.tar.gz/2024-04-20/conn.01:00:00-02:00:00.log"}},
+				},
+				2: {
+					0: {importer.ConnPrefix: []string{"/bundles/logs.zip/2024-04-21/conn.log"}},
+				},
+			}),
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/bundles/logs.zip/2024-04-21/notes.txt", Error: cmd.ErrIncompatibleFileExtension},
+			},
+		},
+		{
+			name: "Archive Passed In as Root Directory",
+			root: "/bundles/logs.zip",
+			expectedFiles: createExpectedResults([]cmd.HourlyZeekLogs{
+				0: {
+					0: {importer.ConnPrefix: []string{"/bundles/logs.zip/2024-04-21/conn.log"}},
+				},
+			}),
+			expectedWalkErrors: []cmd.WalkError{
+				{Path: "/bundles/logs.zip/2024-04-21/notes.txt", Error: cmd.ErrIncompatibleFileExtension},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			archiveFs := importer.NewArchiveFs(afs)
+			defer archiveFs.Close()
+
+			logMap, walkErrors, err := cmd.WalkFiles(archiveFs, test.root)
+			require.NoError(t, err, "running WalkFiles should not produce an error")
+
+			// verify that the returned log map matches the expected values
+			require.Equal(t, test.expectedFiles, logMap, "log map should match expected value")
+
+			// verify that the returned walk errors match the expected values
+			require.ElementsMatch(t, test.expectedWalkErrors, walkErrors, "walk errors should match expected value")
+
+			// verify that the logs can be read from the archive
+			data, err := afero.ReadFile(archiveFs, test.expectedFiles[0][0][importer.ConnPrefix][0])
+			require.NoError(t, err, "reading a log inside of an archive should not produce an error")
+			require.Equal(t, "testytesttestboop", string(data), "log contents should match")
+		})
+	}
+
+	// archives are only walked through the archive filesystem
+	_, _, err := cmd.WalkFiles(afs, "/bundles/logs.zip")
+	require.ErrorIs(t, err, cmd.ErrNoValidFilesFound, "archive should not be walked without the archive filesystem")
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer"
+	"activecm/rita/importer/zeektypes"
+	"activecm/rita/logger"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/afero"
+)
+
+var ErrNoKafkaBrokers = errors.New("no kafka brokers are configured, set kafka.brokers in the config file")
+var ErrNoKafkaTopics = errors.New("no kafka topics are configured, set at least one topic in kafka.topics in the config file")
+var ErrMissingKafkaConnTopic = errors.New("http and ssl logs can't be imported without conn logs, set kafka.topics.conn in the config file")
+var ErrKafkaWithLogDirectory = errors.New("--kafka imports logs from the kafka topics in the config file and can't be used with --logs")
+
+// kafkaLogDir is the directory that the records of each hour are written to before they are imported
+const kafkaLogDir = "/kafka"
+
+// kafkaLockRetryInterval is how long to wait before importing an hour again when another import holds the dataset lock
+const kafkaLockRetryInterval = time.Minute
+
+// KafkaReader reads messages from the topics of a consumer group and commits their offsets, such as a *kafka.Reader
+type KafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type kafkaPartition struct {
+	topic     string
+	partition int
+}
+
+// kafkaWindow holds the records of one hour that were consumed from kafka
+type kafkaWindow struct {
+	hour time.Time
+	// records holds the records of each topic, in the order they were consumed
+	records map[string][][]byte
+	// offsets holds the offsets of the messages in the window for each partition
+	offsets map[kafkaPartition][]int64
+}
+
+// kafkaConsumer groups the zeek records consumed from kafka into hourly windows by their ts field and tracks the offsets
+// that can be committed as the windows are imported
+type kafkaConsumer struct {
+	// logTypes maps each topic to the zeek log type that is streamed to it
+	logTypes    map[string]string
+	gracePeriod time.Duration
+	windows     map[time.Time]*kafkaWindow
+	// watermark is the latest record timestamp that was consumed
+	watermark time.Time
+	// fetched holds the offset of the latest message that was consumed from each partition
+	fetched map[kafkaPartition]int64
+	// committed holds the latest offset that was committed for each partition
+	committed map[kafkaPartition]int64
+}
+
+// kafkaWindowFs holds the records of a window as log files, which are tracked in the metadatabase by the kafka
+// messages they were written from, so that a window which is consumed again after it was imported is skipped
+type kafkaWindowFs struct {
+	afero.Fs
+	ids map[string]string
+}
+
+// RunKafkaImportCmd consumes zeek JSON logs from the kafka topics in the config and imports each hour into a rolling
+// dataset once records from later than the end of the hour plus the grace period have been consumed, until the
+// context is cancelled. Offsets are only committed after the hour they belong to has been imported, so records that
+// were consumed but not imported yet are consumed again when the importer is restarted.
+func RunKafkaImportCmd(ctx context.Context, cfg *config.Config, afs afero.Fs, dbName string, rebuild bool) error {
+	if len(cfg.Kafka.Brokers) == 0 {
+		return ErrNoKafkaBrokers
+	}
+
+	consumer, err := newKafkaConsumer(cfg.Kafka)
+	if err != nil {
+		return err
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Kafka.Brokers,
+		GroupID:     cfg.Kafka.GroupID,
+		GroupTopics: consumer.topics(),
+		// read each topic from the beginning the first time the consumer group reads it
+		StartOffset: kafka.FirstOffset,
+	})
+	defer reader.Close()
+
+	// the records of each hour are imported from the filesystem they were written to, while the files used to set up
+	// the import, such as the threat intel feeds, are still read from afs
+	importWindow := func(startTime time.Time, cfg *config.Config, windowFs afero.Fs, logDir string, dbName string, rolling bool, rebuild bool) (ImportResults, error) {
+		return importLogs(startTime, cfg, afs, windowFs, logDir, dbName, rolling, rebuild, false, false, WalkFiles)
+	}
+
+	return consumeKafka(ctx, cfg, dbName, rebuild, consumer, reader, importWindow)
+}
+
+// ConsumeKafka reads zeek records from the reader into hourly windows and imports each window with importWindow
+// as it closes, committing the offsets of the records that were imported
+func ConsumeKafka(ctx context.Context, cfg *config.Config, dbName string, rebuild bool, reader KafkaReader, importWindow ImportFunc) error {
+	consumer, err := newKafkaConsumer(cfg.Kafka)
+	if err != nil {
+		return err
+	}
+
+	return consumeKafka(ctx, cfg, dbName, rebuild, consumer, reader, importWindow)
+}
+
+// consumeKafka reads the records of the consumer's topics from the reader, see ConsumeKafka
+func consumeKafka(ctx context.Context, cfg *config.Config, dbName string, rebuild bool, consumer *kafkaConsumer, reader KafkaReader, importWindow ImportFunc) error {
+	logger := logger.GetLogger()
+
+	logger.Info().Strs("brokers", cfg.Kafka.Brokers).Strs("topics", consumer.topics()).Str("dataset", dbName).Msg("Consuming logs from kafka...")
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			// records in windows that weren't imported yet are consumed again on the next run
+			if ctx.Err() != nil {
+				logger.Info().Int("open_windows", len(consumer.windows)).Msg("Stopped consuming logs from kafka")
+				return nil
+			}
+			return fmt.Errorf("could not read from kafka: %w", err)
+		}
+
+		consumer.add(msg)
+
+		windows := consumer.closedWindows()
+		if len(windows) == 0 {
+			continue
+		}
+
+		for _, window := range windows {
+			err := importKafkaWindow(cfg, dbName, rebuild, window, consumer.logTypes, importWindow)
+			// another import is running against the dataset, so wait for it to finish
+			for errors.Is(err, database.ErrImportLocked) {
+				logger.Warn().Time("hour", window.hour).Str("dataset", dbName).Msg("dataset is locked by another import, retrying later")
+				select {
+				case <-ctx.Done():
+					logger.Info().Int("open_windows", len(consumer.windows)).Msg("Stopped consuming logs from kafka")
+					return nil
+				case <-time.After(kafkaLockRetryInterval):
+				}
+				err = importKafkaWindow(cfg, dbName, rebuild, window, consumer.logTypes, importWindow)
+			}
+
+			switch {
+			case errors.Is(err, importer.ErrAllFilesPreviouslyImported), errors.Is(err, ErrNoValidFilesFound):
+				logger.Debug().Time("hour", window.hour).Err(err).Msg("no new logs to import")
+			case err != nil:
+				return err
+			}
+
+			// the dataset should only be rebuilt before the first import
+			rebuild = false
+			consumer.remove(window)
+		}
+
+		// commit even if the context was cancelled, since the windows were already imported
+		offsets := consumer.commitOffsets()
+		if len(offsets) > 0 {
+			if err := reader.CommitMessages(context.WithoutCancel(ctx), offsets...); err != nil {
+				return fmt.Errorf("could not commit kafka offsets: %w", err)
+			}
+			consumer.markCommitted(offsets)
+		}
+	}
+}
+
+// importKafkaWindow writes the records of a window to log files and imports them into the dataset
+func importKafkaWindow(cfg *config.Config, dbName string, rebuild bool, window *kafkaWindow, logTypes map[string]string, importWindow ImportFunc) error {
+	logger := logger.GetLogger()
+
+	windowFs := &kafkaWindowFs{Fs: afero.NewMemMapFs(), ids: make(map[string]string)}
+	logDir, err := window.write(windowFs, logTypes)
+	if err != nil {
+		return err
+	}
+
+	logger.Info().Time("hour", window.hour).Bool("rebuild", rebuild).Str("dataset", dbName).Msg("Importing hour of logs from kafka...")
+
+	_, err = importWindow(time.Now(), cfg, windowFs, logDir, dbName, true, rebuild)
+	return err
+}
+
+func newKafkaConsumer(cfg config.Kafka) (*kafkaConsumer, error) {
+	logTypes := make(map[string]string)
+	for logType, topic := range map[string]string{
+		importer.ConnPrefix: cfg.Topics.Conn,
+		importer.DNSPrefix:  cfg.Topics.DNS,
+		importer.HTTPPrefix: cfg.Topics.HTTP,
+		importer.SSLPrefix:  cfg.Topics.SSL,
+	} {
+		if topic != "" {
+			logTypes[topic] = logType
+		}
+	}
+
+	if len(logTypes) == 0 {
+		return nil, ErrNoKafkaTopics
+	}
+
+	// http and ssl records are linked to conn records when they are imported
+	if cfg.Topics.Conn == "" && (cfg.Topics.HTTP != "" || cfg.Topics.SSL != "") {
+		return nil, ErrMissingKafkaConnTopic
+	}
+
+	return &kafkaConsumer{
+		logTypes:    logTypes,
+		gracePeriod: time.Duration(cfg.WindowGracePeriod) * time.Second,
+		windows:     make(map[time.Time]*kafkaWindow),
+		fetched:     make(map[kafkaPartition]int64),
+		committed:   make(map[kafkaPartition]int64),
+	}, nil
+}
+
+// topics returns the topics that are consumed, in lexical order
+func (c *kafkaConsumer) topics() []string {
+	topics := make([]string, 0, len(c.logTypes))
+	for topic := range c.logTypes {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+	return topics
+}
+
+// add adds the record in the message to the window of the hour of its timestamp. Records without a valid timestamp
+// are added to the hour of the message, so that the lines which fail to parse are still recorded by the import.
+func (c *kafkaConsumer) add(msg kafka.Message) {
+	partition := kafkaPartition{topic: msg.Topic, partition: msg.Partition}
+	c.fetched[partition] = max(c.fetched[partition], msg.Offset)
+
+	if _, ok := c.logTypes[msg.Topic]; !ok {
+		return
+	}
+
+	ts := msg.Time.UTC()
+	var record struct {
+		TimeStamp zeektypes.Timestamp `json:"ts"`
+	}
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(msg.Value, &record); err == nil && record.TimeStamp > 0 {
+		ts = time.Unix(int64(record.TimeStamp), 0).UTC()
+	}
+
+	hour := ts.Truncate(time.Hour)
+	window, ok := c.windows[hour]
+	if !ok {
+		window = &kafkaWindow{
+			hour:    hour,
+			records: make(map[string][][]byte),
+			offsets: make(map[kafkaPartition][]int64),
+		}
+		c.windows[hour] = window
+	}
+
+	// each line of the log is a record, so records can't contain line breaks
+	window.records[msg.Topic] = append(window.records[msg.Topic], bytes.ReplaceAll(bytes.TrimSpace(msg.Value), []byte("\n"), []byte(" ")))
+	window.offsets[partition] = append(window.offsets[partition], msg.Offset)
+
+	if ts.After(c.watermark) {
+		c.watermark = ts
+	}
+}
+
+// closedWindows returns the windows that ended more than the grace period before the latest record that was consumed,
+// oldest first
+func (c *kafkaConsumer) closedWindows() []*kafkaWindow {
+	var windows []*kafkaWindow
+	for hour, window := range c.windows {
+		if !c.watermark.Before(hour.Add(time.Hour + c.gracePeriod)) {
+			windows = append(windows, window)
+		}
+	}
+	slices.SortFunc(windows, func(a, b *kafkaWindow) int { return a.hour.Compare(b.hour) })
+	return windows
+}
+
+// remove removes a window that was imported, so that its offsets can be committed
+func (c *kafkaConsumer) remove(window *kafkaWindow) {
+	delete(c.windows, window.hour)
+}
+
+// commitOffsets returns the latest message of each partition whose offset can be committed, which is the message before
+// the oldest message that is still in a window. Committing an offset commits every message before it in the partition,
+// so messages of later hours that were consumed before the messages of an imported hour hold back the commit.
+func (c *kafkaConsumer) commitOffsets() []kafka.Message {
+	offsets := make(map[kafkaPartition]int64, len(c.fetched))
+	for partition, offset := range c.fetched {
+		offsets[partition] = offset
+	}
+
+	for _, window := range c.windows {
+		for partition, windowOffsets := range window.offsets {
+			offsets[partition] = min(offsets[partition], slices.Min(windowOffsets)-1)
+		}
+	}
+
+	var msgs []kafka.Message
+	for partition, offset := range offsets {
+		committed, ok := c.committed[partition]
+		if offset < 0 || (ok && offset <= committed) {
+			continue
+		}
+		msgs = append(msgs, kafka.Message{Topic: partition.topic, Partition: partition.partition, Offset: offset})
+	}
+
+	slices.SortFunc(msgs, func(a, b kafka.Message) int {
+		if a.Topic != b.Topic {
+			return strings.Compare(a.Topic, b.Topic)
+		}
+		return a.Partition - b.Partition
+	})
+	return msgs
+}
+
+// markCommitted records that the offsets of the messages were committed
+func (c *kafkaConsumer) markCommitted(msgs []kafka.Message) {
+	for _, msg := range msgs {
+		c.committed[kafkaPartition{topic: msg.Topic, partition: msg.Partition}] = msg.Offset
+	}
+}
+
+// write writes the records of each topic to a log file named after the hour of the window, ie,
+// /kafka/2024-04-19/conn.16:00:00-17:00:00.log, and returns the directory of the files
+func (w *kafkaWindow) write(afs *kafkaWindowFs, logTypes map[string]string) (string, error) {
+	logDir := filepath.Join(kafkaLogDir, w.hour.Format("2006-01-02"))
+	if err := afs.MkdirAll(logDir, 0o755); err != nil {
+		return "", err
+	}
+
+	hours := fmt.Sprintf("%s-%s", w.hour.Format("15:04:05"), w.hour.Add(time.Hour).Format("15:04:05"))
+	for topic, records := range w.records {
+		path := filepath.Join(logDir, fmt.Sprintf("%s.%s.log", logTypes[topic], hours))
+		if err := afero.WriteFile(afs, path, append(bytes.Join(records, []byte("\n")), '\n'), 0o644); err != nil {
+			return "", err
+		}
+		afs.ids[path] = w.id(topic)
+	}
+
+	return logDir, nil
+}
+
+// id identifies the records of a topic in the window by the range of offsets they were consumed from in each
+// partition, ie, kafka://zeek_conn/2024-04-19T16?partitions=0:120-455,1:80-300
+func (w *kafkaWindow) id(topic string) string {
+	var partitions []string
+	for partition, offsets := range w.offsets {
+		if partition.topic == topic {
+			partitions = append(partitions, fmt.Sprintf("%d:%d-%d", partition.partition, slices.Min(offsets), slices.Max(offsets)))
+		}
+	}
+	slices.Sort(partitions)
+	return fmt.Sprintf("kafka://%s/%s?partitions=%s", topic, w.hour.Format("2006-01-02T15"), strings.Join(partitions, ","))
+}
+
+// FileID identifies the log files of a window by the kafka messages they were written from
+func (afs *kafkaWindowFs) FileID(path string) (string, error) {
+	if id, ok := afs.ids[path]; ok {
+		return id, nil
+	}
+	return path, nil
+}
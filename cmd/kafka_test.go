@@ -0,0 +1,302 @@
+package cmd_test
+
+import (
+	"activecm/rita/cmd"
+	"activecm/rita/config"
+	"activecm/rita/importer"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// fakeKafkaReader returns the messages sent on its messages channel and reports the offsets it is asked to commit
+type fakeKafkaReader struct {
+	messages chan kafka.Message
+	commits  chan []kafka.Message
+}
+
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.commits <- msgs
+	return nil
+}
+
+type kafkaImport struct {
+	logDir  string
+	rolling bool
+	rebuild bool
+	// files holds the contents of each log file that was imported
+	files map[string]string
+	// ids holds the identity of each log file that was imported
+	ids map[string]string
+}
+
+func TestConsumeKafka(t *testing.T) {
+	hour := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)
+	cfg := &config.Config{Kafka: config.Kafka{
+		Brokers:           []string{"localhost:9092"},
+		GroupID:           "rita",
+		Topics:            config.KafkaTopics{Conn: "zeek_conn", DNS: "zeek_dns"},
+		WindowGracePeriod: 300,
+	}}
+
+	reader := &fakeKafkaReader{messages: make(chan kafka.Message), commits: make(chan []kafka.Message)}
+	send := func(topic string, offset int64, ts time.Time) {
+		t.Helper()
+		value := fmt.Sprintf(`{"ts":%d.123456,"uid":"C%d"}`, ts.Unix(), offset)
+		select {
+		case reader.messages <- kafka.Message{Topic: topic, Partition: 0, Offset: offset, Value: []byte(value), Time: ts}:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "consumer should have read the message")
+		}
+	}
+
+	// the fake import reports each call along with the files it was given, and returns the error it is given back
+	calls := make(chan kafkaImport)
+	results := make(chan error)
+	importWindow := func(_ time.Time, _ *config.Config, afs afero.Fs, logDir string, _ string, rolling bool, rebuild bool) (cmd.ImportResults, error) {
+		call := kafkaImport{logDir: logDir, rolling: rolling, rebuild: rebuild, files: make(map[string]string), ids: make(map[string]string)}
+		files, err := afero.ReadDir(afs, logDir)
+		require.NoError(t, err, "reading window directory should not produce an error")
+		for _, file := range files {
+			path := filepath.Join(logDir, file.Name())
+			contents, err := afero.ReadFile(afs, path)
+			require.NoError(t, err, "reading window file should not produce an error")
+			call.files[file.Name()] = string(contents)
+
+			identifier, ok := afs.(importer.FileIdentifier)
+			require.True(t, ok, "window filesystem should identify its files")
+			call.ids[file.Name()], err = identifier.FileID(path)
+			require.NoError(t, err, "identifying window file should not produce an error")
+		}
+		calls <- call
+		return cmd.ImportResults{}, <-results
+	}
+
+	nextCall := func() kafkaImport {
+		t.Helper()
+		select {
+		case call := <-calls:
+			return call
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "consumer should have imported a window")
+		}
+		return kafkaImport{}
+	}
+
+	nextCommit := func() []kafka.Message {
+		t.Helper()
+		select {
+		case commit := <-reader.commits:
+			return commit
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "consumer should have committed offsets")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- cmd.ConsumeKafka(ctx, cfg, "kafka_test", true, reader, importWindow)
+	}()
+
+	// records from the next hour and late records within the grace period don't close the hour
+	send("zeek_conn", 0, hour.Add(10*time.Second))
+	send("zeek_conn", 1, hour.Add(time.Hour+10*time.Second))
+	send("zeek_conn", 2, hour.Add(20*time.Second))
+	send("zeek_dns", 0, hour.Add(30*time.Second))
+	send("zeek_conn", 3, hour.Add(time.Hour+4*time.Minute))
+
+	// a record from past the grace period closes the hour
+	send("zeek_conn", 4, hour.Add(time.Hour+6*time.Minute))
+	call := nextCall()
+	require.Equal(t, "/kafka/2024-04-19", call.logDir, "window should be written to a directory named after its day")
+	require.True(t, call.rolling, "windows should be imported into a rolling dataset")
+	require.True(t, call.rebuild, "first import should rebuild the dataset")
+	require.Equal(t, map[string]string{
+		"conn.16:00:00-17:00:00.log": "{\"ts\":1713542410.123456,\"uid\":\"C0\"}\n{\"ts\":1713542420.123456,\"uid\":\"C2\"}\n",
+		"dns.16:00:00-17:00:00.log":  "{\"ts\":1713542430.123456,\"uid\":\"C0\"}\n",
+	}, call.files, "window should hold the records of its hour for each log type")
+	require.Equal(t, map[string]string{
+		"conn.16:00:00-17:00:00.log": "kafka://zeek_conn/2024-04-19T16?partitions=0:0-2",
+		"dns.16:00:00-17:00:00.log":  "kafka://zeek_dns/2024-04-19T16?partitions=0:0-0",
+	}, call.ids, "window files should be identified by the messages they were written from")
+
+	// offsets must not be committed until the import finishes
+	select {
+	case <-reader.commits:
+		require.FailNow(t, "offsets should not be committed before the window is imported")
+	case <-time.After(50 * time.Millisecond):
+	}
+	results <- nil
+
+	// the conn offsets are held back by the record of the next hour that was consumed before the late record
+	require.Equal(t, []kafka.Message{
+		{Topic: "zeek_conn", Partition: 0, Offset: 0},
+		{Topic: "zeek_dns", Partition: 0, Offset: 0},
+	}, nextCommit(), "only the offsets before the oldest record that wasn't imported should be committed")
+
+	// windows that were already imported are still committed
+	send("zeek_conn", 5, hour.Add(2*time.Hour+6*time.Minute))
+	call = nextCall()
+	require.False(t, call.rebuild, "only the first import should rebuild the dataset")
+	require.Equal(t, []string{"conn.17:00:00-18:00:00.log"}, keys(call.files), "window should only hold the log types it has records for")
+	require.Equal(t, 3, strings.Count(call.files["conn.17:00:00-18:00:00.log"], "\n"), "window should hold every record of its hour")
+	results <- importer.ErrAllFilesPreviouslyImported
+
+	require.Equal(t, []kafka.Message{{Topic: "zeek_conn", Partition: 0, Offset: 4}}, nextCommit(), "offsets of a window that was already imported should be committed")
+
+	// stopping the consumer leaves the records of the open windows uncommitted
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err, "consumer should stop without error")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "consumer should stop when the context is cancelled")
+	}
+}
+
+func TestConsumeKafkaInvalidTopics(t *testing.T) {
+	tests := []struct {
+		name        string
+		topics      config.KafkaTopics
+		expectedErr error
+	}{
+		{name: "No Topics", topics: config.KafkaTopics{}, expectedErr: cmd.ErrNoKafkaTopics},
+		{name: "HTTP Without Conn", topics: config.KafkaTopics{DNS: "zeek_dns", HTTP: "zeek_http"}, expectedErr: cmd.ErrMissingKafkaConnTopic},
+		{name: "SSL Without Conn", topics: config.KafkaTopics{SSL: "zeek_ssl"}, expectedErr: cmd.ErrMissingKafkaConnTopic},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.Config{Kafka: config.Kafka{Brokers: []string{"localhost:9092"}, Topics: test.topics}}
+			err := cmd.ConsumeKafka(context.Background(), cfg, "kafka_test", false, &fakeKafkaReader{}, nil)
+			require.ErrorIs(t, err, test.expectedErr, "error should match expected value")
+		})
+	}
+
+	err := cmd.RunKafkaImportCmd(context.Background(), &config.Config{}, afero.NewMemMapFs(), "kafka_test", false)
+	require.ErrorIs(t, err, cmd.ErrNoKafkaBrokers, "consuming without brokers should fail")
+}
+
+func (c *CmdTestSuite) TestRunKafkaImportCmd() {
+	t := c.T()
+	ctx := context.Background()
+
+	// kafka advertises the address that clients must connect to, so it has to be known before the container starts
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err, "finding a free port should not produce an error")
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close(), "releasing the free port should not produce an error")
+	broker := fmt.Sprintf("localhost:%d", port)
+
+	// start a single node kafka container
+	kafkaContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "apache/kafka:3.7.0",
+			ExposedPorts: []string{fmt.Sprintf("%d:9092/tcp", port)},
+			Env: map[string]string{
+				"KAFKA_NODE_ID":                                  "1",
+				"KAFKA_PROCESS_ROLES":                            "broker,controller",
+				"KAFKA_LISTENERS":                                "PLAINTEXT://:9092,CONTROLLER://:9093",
+				"KAFKA_ADVERTISED_LISTENERS":                     "PLAINTEXT://" + broker,
+				"KAFKA_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+				"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+				"KAFKA_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+				"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+				"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+				"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+				"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS":         "0",
+			},
+			WaitingFor: wait.ForLog("Kafka Server started").WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	})
+	require.NoError(t, err, "failed to start kafka container")
+	defer func() {
+		require.NoError(t, kafkaContainer.Terminate(ctx), "terminating kafka container should not produce an error")
+	}()
+
+	cfg := *c.cfg
+	cfg.Kafka = config.Kafka{
+		Brokers:           []string{broker},
+		GroupID:           "rita_test",
+		Topics:            config.KafkaTopics{Conn: "zeek_conn"},
+		WindowGracePeriod: 300,
+	}
+
+	// stream an hour of conn records, followed by a record from the next hour that closes it
+	hour := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)
+	var messages []kafka.Message
+	for i, ts := range []time.Time{hour.Add(time.Minute), hour.Add(2 * time.Minute), hour.Add(3 * time.Minute), hour.Add(time.Hour + 10*time.Minute)} {
+		messages = append(messages, kafka.Message{Value: []byte(fmt.Sprintf(
+			`{"ts":%d.0,"uid":"CKafka%d","id.orig_h":"10.0.0.%d","id.orig_p":52000,"id.resp_h":"52.12.0.%d","id.resp_p":443,"proto":"tcp","duration":1.5,"orig_bytes":100,"resp_bytes":200,"conn_state":"SF","missed_bytes":0,"history":"ShADadFf","orig_pkts":5,"orig_ip_bytes":360,"resp_pkts":5,"resp_ip_bytes":460}`,
+			ts.Unix(), i, i+1, i+1,
+		))})
+	}
+	writer := &kafka.Writer{Addr: kafka.TCP(broker), Topic: "zeek_conn", AllowAutoTopicCreation: true}
+	require.Eventually(t, func() bool {
+		return writer.WriteMessages(ctx, messages...) == nil
+	}, time.Minute, time.Second, "writing records to kafka should succeed once the topic is created")
+	require.NoError(t, writer.Close(), "closing kafka writer should not produce an error")
+
+	consumerCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- cmd.RunKafkaImportCmd(consumerCtx, &cfg, afero.NewOsFs(), "kafka_test", true)
+	}()
+
+	// the offsets of the imported hour are committed once it has been imported, the record of the next hour is still open
+	client := &kafka.Client{Addr: kafka.TCP(broker)}
+	require.Eventually(t, func() bool {
+		resp, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: "rita_test", Topics: map[string][]int{"zeek_conn": {0}}})
+		return err == nil && len(resp.Topics["zeek_conn"]) == 1 && resp.Topics["zeek_conn"][0].CommittedOffset == 3
+	}, 3*time.Minute, time.Second, "offsets of the imported hour should be committed")
+
+	cancel()
+	require.NoError(t, <-done, "consumer should stop without error")
+
+	// verify that the hour was marked as imported by the kafka messages it was written from
+	var result struct {
+		Paths []string `ch:"paths"`
+	}
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithParameters(clickhouse.Parameters{"database": "kafka_test"}))
+	err = c.server.Conn.QueryRow(queryCtx, `
+		SELECT groupArray(path) AS paths
+		FROM metadatabase.files
+		WHERE database = {database:String}
+	`).ScanStruct(&result)
+	require.NoError(t, err, "querying for imported files should not produce an error")
+	require.ElementsMatch(t, []string{"kafka://zeek_conn/2024-04-19T16?partitions=0:0-2"}, result.Paths, "paths should match expected value")
+
+	// clean up the database
+	require.NoError(t, c.server.DeleteSensorDB("kafka_test"), "dropping database should not produce an error")
+}
+
+func keys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
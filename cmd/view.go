@@ -114,6 +114,11 @@ func runViewCmd(afs afero.Fs, configPath string, dbName string, stdout bool, sea
 		return err
 	}
 
+	// datasets created by older versions are missing the tables and columns that the viewer reads
+	if err := db.MigrateSensorDB(); err != nil {
+		return err
+	}
+
 	// if stdout was requested, get CSV output
 	if stdout {
 
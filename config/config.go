@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
+
+	// embed the timezone database, since the container image doesn't have one to validate timezone names against
+	_ "time/tzdata"
 
 	"github.com/hjson/hjson-go/v4"
 	"github.com/spf13/afero"
@@ -40,6 +44,32 @@ type (
 		CustomFeedsDirectory string   `json:"custom_feeds_directory"`
 	}
 
+	// S3 holds the connection settings for importing logs from S3 compatible object storage (ie, s3://bucket/prefix)
+	S3 struct {
+		Endpoint        string `json:"endpoint"`
+		Region          string `json:"region"`
+		AccessKeyID     string `json:"access_key_id"`
+		SecretAccessKey string `json:"secret_access_key"`
+		UseSSL          bool   `json:"use_ssl"`
+	}
+
+	// Kafka holds the settings for consuming Zeek JSON logs from Kafka topics (ie, rita import --kafka)
+	Kafka struct {
+		Brokers []string    `json:"brokers"`
+		GroupID string      `json:"group_id"`
+		Topics  KafkaTopics `json:"topics"`
+		// WindowGracePeriod is how many seconds past the end of an hour to wait for late records before the hour is imported
+		WindowGracePeriod int `json:"window_grace_period"`
+	}
+
+	// KafkaTopics holds the topic that each Zeek log type is streamed to, topics that are left empty aren't consumed
+	KafkaTopics struct {
+		Conn string `json:"conn"`
+		DNS  string `json:"dns"`
+		HTTP string `json:"http"`
+		SSL  string `json:"ssl"`
+	}
+
 	// ScoreThresholds is used for indicators that have prorated (graduated) values rather than
 	// binary outcomes. This allows for the definition of the severity of an indicator by categorizing
 	// it into one of several buckets (Base, Low, Med, High), each representing a range of values
@@ -68,6 +98,18 @@ type (
 		C2SubdomainThreshold int             `json:"c2_subdomain_threshold"`
 		C2ScoreThresholds    ScoreThresholds `json:"c2_score_thresholds"`
 
+		DNSTunneling DNSTunneling `json:"dns_tunneling"`
+
+		DGA DGA `json:"dga"`
+
+		PortScan PortScan `json:"port_scan"`
+
+		SSH SSH `json:"ssh"`
+
+		Exfiltration Exfiltration `json:"exfiltration"`
+
+		LateralMovementScoreThresholds ScoreThresholds `json:"lateral_movement_score_thresholds"`
+
 		StrobeImpact ScoreImpact `json:"strobe_impact"`
 
 		ThreatIntelImpact ScoreImpact `json:"threat_intel_impact"`
@@ -94,6 +136,64 @@ type (
 		C2OverDNSDirectConnScoreIncrease float32 `json:"c2_over_dns_direct_conn_score_increase"`
 
 		MIMETypeMismatchScoreIncrease float32 `json:"mime_type_mismatch_score_increase"`
+
+		CertificateAnomalyScoreIncrease float32 `json:"certificate_anomaly_score_increase"`
+		ShortLivedCertificateDays       int     `json:"short_lived_certificate_days"`
+		NewCertificateDays              int     `json:"new_certificate_days"`
+
+		RareSSHVersionScoreIncrease       float32 `json:"rare_ssh_version_score_increase"`
+		RareSSHVersionPrevalenceThreshold float32 `json:"rare_ssh_version_prevalence_threshold"`
+
+		FastFluxScoreIncrease       float32 `json:"fast_flux_score_increase"`
+		FastFluxResolvedIPThreshold int     `json:"fast_flux_resolved_ip_threshold"`
+		FastFluxNetworkThreshold    int     `json:"fast_flux_network_threshold"`
+		FastFluxNewIPsPerHour       float32 `json:"fast_flux_new_ips_per_hour"`
+		FastFluxMaxTTL              int     `json:"fast_flux_max_ttl"`
+	}
+
+	// DNSTunneling configures the lexical analysis of the subdomains that were queried under each domain, which
+	// combines the weighted subscores into a score from 0 to 100 that is bucketed by ScoreThresholds
+	DNSTunneling struct {
+		MinimumSubdomains int             `json:"minimum_subdomains"`
+		EntropyWeight     float64         `json:"entropy_score_weight"`
+		LabelLengthWeight float64         `json:"label_length_score_weight"`
+		EncodingWeight    float64         `json:"encoding_score_weight"`
+		QueryTypeWeight   float64         `json:"query_type_score_weight"`
+		ScoreThresholds   ScoreThresholds `json:"score_thresholds"`
+	}
+
+	// DGA configures the detection of internal hosts that get bursts of NXDOMAIN answers for domains that look algorithmically
+	// generated, which are scored by the number of these domains that a host queried in its busiest hour
+	DGA struct {
+		RandomnessThreshold float64         `json:"randomness_threshold"`
+		ScoreThresholds     ScoreThresholds `json:"score_thresholds"`
+	}
+
+	// PortScan configures the detection of internal hosts that probe many hosts on the same port (horizontal scans) or
+	// many ports on the same host (vertical scans) in an hour, which are scored by the number of hosts or ports probed
+	PortScan struct {
+		HorizontalScoreThresholds ScoreThresholds `json:"horizontal_score_thresholds"`
+		VerticalScoreThresholds   ScoreThresholds `json:"vertical_score_thresholds"`
+	}
+
+	// SSH configures the detection of outbound SSH brute force, which is scored by the most authentication attempts made
+	// to the same host in an hour, and of logins to any host that succeeded after many failed attempts
+	SSH struct {
+		BruteForceScoreThresholds         ScoreThresholds `json:"brute_force_score_thresholds"`
+		LoginAfterFailuresScoreThresholds ScoreThresholds `json:"login_after_failures_score_thresholds"`
+	}
+
+	// Exfiltration configures the detection of connection pairs whose source sent far more data than it received, which
+	// are scored by the megabytes that the source sent, with the bytes sent during off hours weighted more heavily, and
+	// by how lopsided the ratio of bytes sent to bytes received is
+	Exfiltration struct {
+		MinimumRatio     float64         `json:"minimum_ratio"`
+		RatioWeight      float64         `json:"ratio_weight"`
+		OffHoursStart    int             `json:"off_hours_start"`
+		OffHoursEnd      int             `json:"off_hours_end"`
+		OffHoursTimezone string          `json:"off_hours_timezone"`
+		OffHoursWeight   float64         `json:"off_hours_weight"`
+		ScoreThresholds  ScoreThresholds `json:"score_thresholds"`
 	}
 
 	Beacon struct {
@@ -130,6 +230,14 @@ type (
 
 		ThreatIntel ThreatIntel `json:"threat_intel"`
 
+		S3 S3 `json:"s3"`
+
+		Kafka Kafka `json:"kafka"`
+
+		// FieldMapping maps the names that fields are recorded under in the logs of each log type (ie, "conn") to the
+		// names of the zeek fields that RITA parses them as, for logs written by zeek packages that rename fields
+		FieldMapping map[string]map[string]string `json:"field_mapping"`
+
 		LogLevel       int  `json:"log_level"`
 		LoggingEnabled bool `json:"logging_enabled"`
 	}
@@ -388,6 +496,100 @@ func (cfg *Config) verifyConfig() error {
 		return err
 	}
 
+	// validate the configured DNS tunneling minimum subdomains
+	if cfg.Scoring.DNSTunneling.MinimumSubdomains < 1 {
+		return fmt.Errorf("the DNS tunneling minimum subdomains must be at least 1, got %v", cfg.Scoring.DNSTunneling.MinimumSubdomains)
+	}
+
+	// validate the configured DNS tunneling score weights
+	totalTunnelingWeight := 0.0
+	tunnelingWeights := []float64{
+		cfg.Scoring.DNSTunneling.EntropyWeight,
+		cfg.Scoring.DNSTunneling.LabelLengthWeight,
+		cfg.Scoring.DNSTunneling.EncodingWeight,
+		cfg.Scoring.DNSTunneling.QueryTypeWeight,
+	}
+	for _, weight := range tunnelingWeights {
+		if weight < 0 || weight > 1 {
+			return fmt.Errorf("the DNS tunneling weight must be between 0 and 1, got %v", weight)
+		}
+		totalTunnelingWeight += weight
+	}
+
+	// sum of weights must equal 1
+	if totalTunnelingWeight != 1 {
+		return fmt.Errorf("the sum of the DNS tunneling weights must equal 1, got %v", totalTunnelingWeight)
+	}
+
+	// validate the configured DNS tunneling score thresholds ( scores are between 0 and 100 )
+	if err := validateScoreThresholds(cfg.Scoring.DNSTunneling.ScoreThresholds, 0, 100); err != nil {
+		return err
+	}
+
+	// validate the configured DGA randomness threshold
+	if cfg.Scoring.DGA.RandomnessThreshold < 0 || cfg.Scoring.DGA.RandomnessThreshold > 1 {
+		return fmt.Errorf("the DGA randomness threshold must be between 0 and 1, got %v", cfg.Scoring.DGA.RandomnessThreshold)
+	}
+
+	// validate the configured DGA score thresholds ( at least 1, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.DGA.ScoreThresholds, 1, -1); err != nil {
+		return err
+	}
+
+	// validate the configured horizontal port scan score thresholds ( at least 2 hosts, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.PortScan.HorizontalScoreThresholds, 2, -1); err != nil {
+		return err
+	}
+
+	// validate the configured vertical port scan score thresholds ( at least 2 ports, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.PortScan.VerticalScoreThresholds, 2, -1); err != nil {
+		return err
+	}
+
+	// validate the configured SSH brute force score thresholds ( at least 1 attempt, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.SSH.BruteForceScoreThresholds, 1, -1); err != nil {
+		return err
+	}
+
+	// validate the configured SSH login after failures score thresholds ( at least 1 failure, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.SSH.LoginAfterFailuresScoreThresholds, 1, -1); err != nil {
+		return err
+	}
+
+	// validate the configured exfiltration upload/download ratio
+	if cfg.Scoring.Exfiltration.MinimumRatio < 1 {
+		return fmt.Errorf("the exfiltration minimum ratio must be at least 1, got %v", cfg.Scoring.Exfiltration.MinimumRatio)
+	}
+
+	if cfg.Scoring.Exfiltration.RatioWeight < 0 || cfg.Scoring.Exfiltration.RatioWeight > 1 {
+		return fmt.Errorf("the exfiltration ratio weight must be between 0 and 1, got %v", cfg.Scoring.Exfiltration.RatioWeight)
+	}
+
+	// validate the configured exfiltration off hours
+	if cfg.Scoring.Exfiltration.OffHoursStart < 0 || cfg.Scoring.Exfiltration.OffHoursStart > 23 {
+		return fmt.Errorf("the exfiltration off hours start must be between 0 and 23, got %v", cfg.Scoring.Exfiltration.OffHoursStart)
+	}
+	if cfg.Scoring.Exfiltration.OffHoursEnd < 0 || cfg.Scoring.Exfiltration.OffHoursEnd > 23 {
+		return fmt.Errorf("the exfiltration off hours end must be between 0 and 23, got %v", cfg.Scoring.Exfiltration.OffHoursEnd)
+	}
+	// the timezone is passed to ClickHouse, which doesn't know the local timezone of this host
+	if _, err := time.LoadLocation(cfg.Scoring.Exfiltration.OffHoursTimezone); err != nil || cfg.Scoring.Exfiltration.OffHoursTimezone == "" || cfg.Scoring.Exfiltration.OffHoursTimezone == "Local" {
+		return fmt.Errorf("the exfiltration off hours timezone must be a valid IANA timezone name, got %q", cfg.Scoring.Exfiltration.OffHoursTimezone)
+	}
+	if cfg.Scoring.Exfiltration.OffHoursWeight < 1 {
+		return fmt.Errorf("the exfiltration off hours weight must be at least 1, got %v", cfg.Scoring.Exfiltration.OffHoursWeight)
+	}
+
+	// validate the configured exfiltration score thresholds ( at least 1 megabyte, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.Exfiltration.ScoreThresholds, 1, -1); err != nil {
+		return err
+	}
+
+	// validate the configured lateral movement score thresholds ( at least 1, no max limit )
+	if err := validateScoreThresholds(cfg.Scoring.LateralMovementScoreThresholds, 1, -1); err != nil {
+		return err
+	}
+
 	// validate the configured strobe impact category
 	if err := ValidateImpactCategory(cfg.Scoring.StrobeImpact.Category); err != nil {
 		return err
@@ -472,6 +674,74 @@ func (cfg *Config) verifyConfig() error {
 		return fmt.Errorf("the MIME type/URI mismatch score increase must be between 0 and 1, got %v", cfg.Modifiers.MIMETypeMismatchScoreIncrease)
 	}
 
+	// validate the configured certificate anomaly score increase
+	if cfg.Modifiers.CertificateAnomalyScoreIncrease < 0 || cfg.Modifiers.CertificateAnomalyScoreIncrease > 1 {
+		return fmt.Errorf("the certificate anomaly score increase must be between 0 and 1, got %v", cfg.Modifiers.CertificateAnomalyScoreIncrease)
+	}
+
+	// validate the configured short-lived certificate threshold
+	if cfg.Modifiers.ShortLivedCertificateDays < 1 {
+		return fmt.Errorf("the short-lived certificate threshold must be at least 1 day, got %v", cfg.Modifiers.ShortLivedCertificateDays)
+	}
+
+	// validate the configured newly issued certificate threshold
+	if cfg.Modifiers.NewCertificateDays < 1 {
+		return fmt.Errorf("the newly issued certificate threshold must be at least 1 day, got %v", cfg.Modifiers.NewCertificateDays)
+	}
+
+	// validate the configured rare SSH version score increase
+	if cfg.Modifiers.RareSSHVersionScoreIncrease < 0 || cfg.Modifiers.RareSSHVersionScoreIncrease > 1 {
+		return fmt.Errorf("the rare SSH version score increase must be between 0 and 1, got %v", cfg.Modifiers.RareSSHVersionScoreIncrease)
+	}
+	// validate rare SSH version prevalence threshold
+	if cfg.Modifiers.RareSSHVersionPrevalenceThreshold < 0 || cfg.Modifiers.RareSSHVersionPrevalenceThreshold > 1 {
+		return fmt.Errorf("the rare SSH version prevalence threshold must be between 0 and 1, got %v", cfg.Modifiers.RareSSHVersionPrevalenceThreshold)
+	}
+
+	// validate the configured fast flux score increase
+	if cfg.Modifiers.FastFluxScoreIncrease < 0 || cfg.Modifiers.FastFluxScoreIncrease > 1 {
+		return fmt.Errorf("the fast flux score increase must be between 0 and 1, got %v", cfg.Modifiers.FastFluxScoreIncrease)
+	}
+
+	// validate the configured fast flux resolved IP threshold
+	if cfg.Modifiers.FastFluxResolvedIPThreshold < 2 {
+		return fmt.Errorf("the fast flux resolved IP threshold must be at least 2 IPs, got %v", cfg.Modifiers.FastFluxResolvedIPThreshold)
+	}
+
+	// validate the configured fast flux network threshold
+	if cfg.Modifiers.FastFluxNetworkThreshold < 1 || cfg.Modifiers.FastFluxNetworkThreshold > cfg.Modifiers.FastFluxResolvedIPThreshold {
+		return fmt.Errorf("the fast flux network threshold must be between 1 and the resolved IP threshold, got %v", cfg.Modifiers.FastFluxNetworkThreshold)
+	}
+
+	// validate the configured fast flux churn rate
+	if cfg.Modifiers.FastFluxNewIPsPerHour <= 0 {
+		return fmt.Errorf("the fast flux new IPs per hour must be greater than 0, got %v", cfg.Modifiers.FastFluxNewIPsPerHour)
+	}
+
+	// validate the configured fast flux TTL
+	if cfg.Modifiers.FastFluxMaxTTL < 0 {
+		return fmt.Errorf("the fast flux max TTL must be at least 0 seconds, got %v", cfg.Modifiers.FastFluxMaxTTL)
+	}
+
+	// validate the configured kafka window grace period
+	if cfg.Kafka.WindowGracePeriod < 0 {
+		return fmt.Errorf("the kafka window grace period must be at least 0 seconds, got %v", cfg.Kafka.WindowGracePeriod)
+	}
+
+	// validate the configured field mappings, each zeek field can only be parsed from one field of a log
+	for logType, mapping := range cfg.FieldMapping {
+		mappedFrom := make(map[string]string, len(mapping))
+		for from, to := range mapping {
+			if from == "" || to == "" {
+				return fmt.Errorf("the field mapping for %s logs cannot contain empty field names", logType)
+			}
+			if other, ok := mappedFrom[to]; ok {
+				return fmt.Errorf("the field mapping for %s logs maps both %s and %s to %s", logType, other, from, to)
+			}
+			mappedFrom[to] = from
+		}
+	}
+
 	// validate log level
 	if cfg.LogLevel < -1 || cfg.LogLevel > 5 {
 		return fmt.Errorf("the LogLevel must be between -1 and 5 (inclusive)")
@@ -619,6 +889,82 @@ func defaultConfig() Config {
 				High: 1000,
 			},
 
+			DNSTunneling: DNSTunneling{
+				MinimumSubdomains: 10,
+				EntropyWeight:     0.25,
+				LabelLengthWeight: 0.25,
+				EncodingWeight:    0.25,
+				QueryTypeWeight:   0.25,
+				ScoreThresholds: ScoreThresholds{
+					Base: 40,
+					Low:  55,
+					Med:  70,
+					High: 85,
+				},
+			},
+
+			DGA: DGA{
+				RandomnessThreshold: 0.5,
+				ScoreThresholds: ScoreThresholds{
+					Base: 10,
+					Low:  25,
+					Med:  50,
+					High: 100,
+				},
+			},
+
+			PortScan: PortScan{
+				HorizontalScoreThresholds: ScoreThresholds{
+					Base: 50,
+					Low:  100,
+					Med:  250,
+					High: 500,
+				},
+				VerticalScoreThresholds: ScoreThresholds{
+					Base: 100,
+					Low:  250,
+					Med:  500,
+					High: 1000,
+				},
+			},
+
+			SSH: SSH{
+				BruteForceScoreThresholds: ScoreThresholds{
+					Base: 30,
+					Low:  100,
+					Med:  250,
+					High: 500,
+				},
+				LoginAfterFailuresScoreThresholds: ScoreThresholds{
+					Base: 10,
+					Low:  25,
+					Med:  50,
+					High: 100,
+				},
+			},
+
+			Exfiltration: Exfiltration{
+				MinimumRatio:     10,
+				RatioWeight:      0.5,
+				OffHoursStart:    19,
+				OffHoursEnd:      7,
+				OffHoursTimezone: "UTC",
+				OffHoursWeight:   2,
+				ScoreThresholds: ScoreThresholds{
+					Base: 100,
+					Low:  500,
+					Med:  1000,
+					High: 5000,
+				},
+			},
+
+			LateralMovementScoreThresholds: ScoreThresholds{
+				Base: 5,
+				Low:  10,
+				Med:  20,
+				High: 50,
+			},
+
 			StrobeImpact: ScoreImpact{Category: HighThreat, Score: HIGH_CATEGORY_SCORE},
 
 			ThreatIntelImpact: ScoreImpact{Category: HighThreat, Score: HIGH_CATEGORY_SCORE},
@@ -645,11 +991,39 @@ func defaultConfig() Config {
 			C2OverDNSDirectConnScoreIncrease: 0.15, // +15% score for domains that were queried but had no direct connections
 
 			MIMETypeMismatchScoreIncrease: 0.15, // +15% score for connections with mismatched MIME type/URI
+
+			CertificateAnomalyScoreIncrease: 0.15, // +15% score for beaconing SNI connections with a self-signed, expired, short-lived or newly issued certificate
+			ShortLivedCertificateDays:       14,   // certificates valid for fewer than 14 days are considered short-lived
+			NewCertificateDays:              3,    // certificates issued fewer than 3 days before they were seen are considered newly issued
+
+			RareSSHVersionScoreIncrease:       0.1,  // +10% score for SSH connections with a client or server version that is rare on the network
+			RareSSHVersionPrevalenceThreshold: 0.02, // versions used by 2% or fewer of the hosts that use SSH are rare
+
+			FastFluxScoreIncrease:       0.2, // +20% score for connections to domains whose resolved IPs churn across many networks
+			FastFluxResolvedIPThreshold: 10,  // domains that resolved to 10 or more IPs
+			FastFluxNetworkThreshold:    5,   // in 5 or more /16 networks
+			FastFluxNewIPsPerHour:       1,   // that were first seen at a rate of 1 or more new IPs per hour
+			FastFluxMaxTTL:              300, // with a median TTL of 5 minutes or less
 		},
 		ThreatIntel: ThreatIntel{
 			OnlineFeeds:          []string{},
 			CustomFeedsDirectory: "/etc/rita/threat_intel_feeds",
 		},
+		S3: S3{
+			Endpoint: "s3.amazonaws.com",
+			UseSSL:   true,
+		},
+		Kafka: Kafka{
+			Brokers: []string{"localhost:9092"},
+			GroupID: "rita",
+			Topics: KafkaTopics{
+				Conn: "zeek_conn",
+				DNS:  "zeek_dns",
+				HTTP: "zeek_http",
+				SSL:  "zeek_ssl",
+			},
+			WindowGracePeriod: 300, // wait 5 minutes for late records before importing an hour
+		},
 		LogLevel:       1,    // INFO level is default
 		LoggingEnabled: true, // enable logging by default
 	}
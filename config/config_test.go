@@ -79,6 +79,30 @@ func TestParseJSON(t *testing.T) {
 					online_feeds: ["https://example.com/feed1", "https://example.com/feed2"],
 					custom_feeds_directory: "/path/to/custom/feeds",
 				},
+				s3: {
+					endpoint: "minio.local:9000",
+					region: "us-east-2",
+					access_key_id: "rita",
+					secret_access_key: "supersecret",
+					use_ssl: false,
+				},
+				kafka: {
+					brokers: ["kafka1:9092", "kafka2:9092"],
+					group_id: "rita_sensor1",
+					topics: {
+						conn: "sensor1_conn",
+						dns: "sensor1_dns",
+						http: "",
+						ssl: "sensor1_ssl",
+					},
+					window_grace_period: 60,
+				},
+				field_mapping: {
+					conn: {
+						"source.ip": "id.orig_h",
+						"sensor_name": "agent_hostname",
+					},
+				},
 				scoring: {
 					beacon: {
 						unique_connection_threshold: 10,
@@ -112,6 +136,76 @@ func TestParseJSON(t *testing.T) {
 						medium: 2,
 						high: 3
 					},
+					dns_tunneling: {
+						minimum_subdomains: 5,
+						entropy_score_weight: 0.5,
+						label_length_score_weight: 0.125,
+						encoding_score_weight: 0.25,
+						query_type_score_weight: 0.125,
+						score_thresholds: {
+							base: 10,
+							low: 20,
+							medium: 30,
+							high: 40
+						}
+					},
+					dga: {
+						randomness_threshold: 0.75,
+						score_thresholds: {
+							base: 5,
+							low: 10,
+							medium: 15,
+							high: 20
+						}
+					},
+					port_scan: {
+						horizontal_score_thresholds: {
+							base: 10,
+							low: 20,
+							medium: 30,
+							high: 40
+						},
+						vertical_score_thresholds: {
+							base: 20,
+							low: 40,
+							medium: 60,
+							high: 80
+						}
+					},
+					ssh: {
+						brute_force_score_thresholds: {
+							base: 5,
+							low: 10,
+							medium: 20,
+							high: 40
+						},
+						login_after_failures_score_thresholds: {
+							base: 3,
+							low: 6,
+							medium: 9,
+							high: 12
+						}
+					},
+					exfiltration: {
+						minimum_ratio: 4,
+						ratio_weight: 0.25,
+						off_hours_start: 22,
+						off_hours_end: 6,
+						off_hours_timezone: "America/Denver",
+						off_hours_weight: 1.5,
+						score_thresholds: {
+							base: 50,
+							low: 100,
+							medium: 200,
+							high: 400
+						}
+					},
+					lateral_movement_score_thresholds: {
+						base: 1,
+						low: 2,
+						medium: 3,
+						high: 4
+					},
 					strobe_impact: {
 						category: "low",
 					},
@@ -133,7 +227,17 @@ func TestParseJSON(t *testing.T) {
 					missing_host_count_score_increase: 0.4,
 					rare_signature_score_increase: 0.4,
 					c2_over_dns_direct_conn_score_increase: 0.9,
-					mime_type_mismatch_score_increase: 0.6
+					mime_type_mismatch_score_increase: 0.6,
+					certificate_anomaly_score_increase: 0.3,
+					short_lived_certificate_days: 7,
+					new_certificate_days: 2,
+					rare_ssh_version_score_increase: 0.05,
+					rare_ssh_version_prevalence_threshold: 0.1,
+					fast_flux_score_increase: 0.3,
+					fast_flux_resolved_ip_threshold: 20,
+					fast_flux_network_threshold: 8,
+					fast_flux_new_ips_per_hour: 2.5,
+					fast_flux_max_ttl: 60
 				},
 				log_level: 3,
 				logging_enabled: false,
@@ -210,6 +314,76 @@ func TestParseJSON(t *testing.T) {
 						Med:  2,
 						High: 3,
 					},
+					DNSTunneling: DNSTunneling{
+						MinimumSubdomains: 5,
+						EntropyWeight:     0.5,
+						LabelLengthWeight: 0.125,
+						EncodingWeight:    0.25,
+						QueryTypeWeight:   0.125,
+						ScoreThresholds: ScoreThresholds{
+							Base: 10,
+							Low:  20,
+							Med:  30,
+							High: 40,
+						},
+					},
+					DGA: DGA{
+						RandomnessThreshold: 0.75,
+						ScoreThresholds: ScoreThresholds{
+							Base: 5,
+							Low:  10,
+							Med:  15,
+							High: 20,
+						},
+					},
+					PortScan: PortScan{
+						HorizontalScoreThresholds: ScoreThresholds{
+							Base: 10,
+							Low:  20,
+							Med:  30,
+							High: 40,
+						},
+						VerticalScoreThresholds: ScoreThresholds{
+							Base: 20,
+							Low:  40,
+							Med:  60,
+							High: 80,
+						},
+					},
+					SSH: SSH{
+						BruteForceScoreThresholds: ScoreThresholds{
+							Base: 5,
+							Low:  10,
+							Med:  20,
+							High: 40,
+						},
+						LoginAfterFailuresScoreThresholds: ScoreThresholds{
+							Base: 3,
+							Low:  6,
+							Med:  9,
+							High: 12,
+						},
+					},
+					Exfiltration: Exfiltration{
+						MinimumRatio:     4,
+						RatioWeight:      0.25,
+						OffHoursStart:    22,
+						OffHoursEnd:      6,
+						OffHoursTimezone: "America/Denver",
+						OffHoursWeight:   1.5,
+						ScoreThresholds: ScoreThresholds{
+							Base: 50,
+							Low:  100,
+							Med:  200,
+							High: 400,
+						},
+					},
+					LateralMovementScoreThresholds: ScoreThresholds{
+						Base: 1,
+						Low:  2,
+						Med:  3,
+						High: 4,
+					},
 					StrobeImpact: ScoreImpact{
 						Category: LowThreat,
 						Score:    LOW_CATEGORY_SCORE,
@@ -220,25 +394,56 @@ func TestParseJSON(t *testing.T) {
 					},
 				},
 				Modifiers: Modifiers{
-					ThreatIntelScoreIncrease:         0.1,
-					ThreatIntelDataSizeThreshold:     100,
-					PrevalenceScoreIncrease:          0.6,
-					PrevalenceIncreaseThreshold:      0.1,
-					PrevalenceScoreDecrease:          0.1,
-					PrevalenceDecreaseThreshold:      0.2,
-					FirstSeenScoreIncrease:           0.8,
-					FirstSeenIncreaseThreshold:       10,
-					FirstSeenScoreDecrease:           0.2,
-					FirstSeenDecreaseThreshold:       50,
-					MissingHostCountScoreIncrease:    0.4,
-					RareSignatureScoreIncrease:       0.4,
-					C2OverDNSDirectConnScoreIncrease: 0.9,
-					MIMETypeMismatchScoreIncrease:    0.6,
+					ThreatIntelScoreIncrease:          0.1,
+					ThreatIntelDataSizeThreshold:      100,
+					PrevalenceScoreIncrease:           0.6,
+					PrevalenceIncreaseThreshold:       0.1,
+					PrevalenceScoreDecrease:           0.1,
+					PrevalenceDecreaseThreshold:       0.2,
+					FirstSeenScoreIncrease:            0.8,
+					FirstSeenIncreaseThreshold:        10,
+					FirstSeenScoreDecrease:            0.2,
+					FirstSeenDecreaseThreshold:        50,
+					MissingHostCountScoreIncrease:     0.4,
+					RareSignatureScoreIncrease:        0.4,
+					C2OverDNSDirectConnScoreIncrease:  0.9,
+					MIMETypeMismatchScoreIncrease:     0.6,
+					CertificateAnomalyScoreIncrease:   0.3,
+					ShortLivedCertificateDays:         7,
+					NewCertificateDays:                2,
+					RareSSHVersionScoreIncrease:       0.05,
+					RareSSHVersionPrevalenceThreshold: 0.1,
+					FastFluxScoreIncrease:             0.3,
+					FastFluxResolvedIPThreshold:       20,
+					FastFluxNetworkThreshold:          8,
+					FastFluxNewIPsPerHour:             2.5,
+					FastFluxMaxTTL:                    60,
 				},
 				ThreatIntel: ThreatIntel{
 					OnlineFeeds:          []string{"https://example.com/feed1", "https://example.com/feed2"},
 					CustomFeedsDirectory: "/path/to/custom/feeds",
 				},
+				S3: S3{
+					Endpoint:        "minio.local:9000",
+					Region:          "us-east-2",
+					AccessKeyID:     "rita",
+					SecretAccessKey: "supersecret",
+					UseSSL:          false,
+				},
+				Kafka: Kafka{
+					Brokers: []string{"kafka1:9092", "kafka2:9092"},
+					GroupID: "rita_sensor1",
+					Topics: KafkaTopics{
+						Conn: "sensor1_conn",
+						DNS:  "sensor1_dns",
+						HTTP: "",
+						SSL:  "sensor1_ssl",
+					},
+					WindowGracePeriod: 60,
+				},
+				FieldMapping: map[string]map[string]string{
+					"conn": {"source.ip": "id.orig_h", "sensor_name": "agent_hostname"},
+				},
 				LogLevel:       3,
 				LoggingEnabled: false,
 			},
@@ -331,6 +536,10 @@ func TestParseJSON(t *testing.T) {
 			require.Equal(test.expectedConfig.ThreatIntel.OnlineFeeds, cfg.ThreatIntel.OnlineFeeds, "OnlineFeeds should match expected value")
 			require.Equal(test.expectedConfig.ThreatIntel.CustomFeedsDirectory, cfg.ThreatIntel.CustomFeedsDirectory, "CustomFeedsDirectory should match expected value")
 
+			require.Equal(test.expectedConfig.S3, cfg.S3, "S3 should match expected value")
+			require.Equal(test.expectedConfig.Kafka, cfg.Kafka, "Kafka should match expected value")
+			require.Equal(test.expectedConfig.FieldMapping, cfg.FieldMapping, "FieldMapping should match expected value")
+
 			require.Equal(test.expectedConfig.Scoring.Beacon.UniqueConnectionThreshold, cfg.Scoring.Beacon.UniqueConnectionThreshold, "BeaconUniqueConnectionThreshold should match expected value")
 			require.InDelta(test.expectedConfig.Scoring.Beacon.TsWeight, cfg.Scoring.Beacon.TsWeight, 0.00001, "BeaconTsWeight should match expected value")
 			require.InDelta(test.expectedConfig.Scoring.Beacon.DsWeight, cfg.Scoring.Beacon.DsWeight, 0.00001, "BeaconDsWeight should match expected value")
@@ -358,6 +567,18 @@ func TestParseJSON(t *testing.T) {
 			require.Equal(test.expectedConfig.Scoring.C2ScoreThresholds.Med, cfg.Scoring.C2ScoreThresholds.Med, "C2ScoreThresholds.Med should match expected value")
 			require.Equal(test.expectedConfig.Scoring.C2ScoreThresholds.High, cfg.Scoring.C2ScoreThresholds.High, "C2ScoreThresholds.High should match expected value")
 
+			require.Equal(test.expectedConfig.Scoring.DNSTunneling, cfg.Scoring.DNSTunneling, "DNSTunneling should match expected value")
+
+			require.Equal(test.expectedConfig.Scoring.DGA, cfg.Scoring.DGA, "DGA should match expected value")
+
+			require.Equal(test.expectedConfig.Scoring.PortScan, cfg.Scoring.PortScan, "PortScan should match expected value")
+
+			require.Equal(test.expectedConfig.Scoring.SSH, cfg.Scoring.SSH, "SSH should match expected value")
+
+			require.Equal(test.expectedConfig.Scoring.Exfiltration, cfg.Scoring.Exfiltration, "Exfiltration should match expected value")
+
+			require.Equal(test.expectedConfig.Scoring.LateralMovementScoreThresholds, cfg.Scoring.LateralMovementScoreThresholds, "LateralMovementScoreThresholds should match expected value")
+
 			require.Equal(test.expectedConfig.Scoring.StrobeImpact.Category, cfg.Scoring.StrobeImpact.Category, "StrobeImpact.Category should match expected value")
 			require.InDelta(test.expectedConfig.Scoring.StrobeImpact.Score, cfg.Scoring.StrobeImpact.Score, 0.00001, "StrobeImpact.Score should match expected value")
 
@@ -378,6 +599,16 @@ func TestParseJSON(t *testing.T) {
 			require.InDelta(test.expectedConfig.Modifiers.RareSignatureScoreIncrease, cfg.Modifiers.RareSignatureScoreIncrease, 0.00001, "RareSignatureScoreIncrease should match expected value")
 			require.InDelta(test.expectedConfig.Modifiers.C2OverDNSDirectConnScoreIncrease, cfg.Modifiers.C2OverDNSDirectConnScoreIncrease, 0.00001, "C2OverDNSDirectConnScoreIncrease should match expected value")
 			require.InDelta(test.expectedConfig.Modifiers.MIMETypeMismatchScoreIncrease, cfg.Modifiers.MIMETypeMismatchScoreIncrease, 0.00001, "MIMETypeMismatchScoreIncrease should match expected value")
+			require.InDelta(test.expectedConfig.Modifiers.CertificateAnomalyScoreIncrease, cfg.Modifiers.CertificateAnomalyScoreIncrease, 0.00001, "CertificateAnomalyScoreIncrease should match expected value")
+			require.Equal(test.expectedConfig.Modifiers.ShortLivedCertificateDays, cfg.Modifiers.ShortLivedCertificateDays, "ShortLivedCertificateDays should match expected value")
+			require.Equal(test.expectedConfig.Modifiers.NewCertificateDays, cfg.Modifiers.NewCertificateDays, "NewCertificateDays should match expected value")
+			require.InDelta(test.expectedConfig.Modifiers.RareSSHVersionScoreIncrease, cfg.Modifiers.RareSSHVersionScoreIncrease, 0.00001, "RareSSHVersionScoreIncrease should match expected value")
+			require.InDelta(test.expectedConfig.Modifiers.RareSSHVersionPrevalenceThreshold, cfg.Modifiers.RareSSHVersionPrevalenceThreshold, 0.00001, "RareSSHVersionPrevalenceThreshold should match expected value")
+			require.InDelta(test.expectedConfig.Modifiers.FastFluxScoreIncrease, cfg.Modifiers.FastFluxScoreIncrease, 0.00001, "FastFluxScoreIncrease should match expected value")
+			require.Equal(test.expectedConfig.Modifiers.FastFluxResolvedIPThreshold, cfg.Modifiers.FastFluxResolvedIPThreshold, "FastFluxResolvedIPThreshold should match expected value")
+			require.Equal(test.expectedConfig.Modifiers.FastFluxNetworkThreshold, cfg.Modifiers.FastFluxNetworkThreshold, "FastFluxNetworkThreshold should match expected value")
+			require.InDelta(test.expectedConfig.Modifiers.FastFluxNewIPsPerHour, cfg.Modifiers.FastFluxNewIPsPerHour, 0.00001, "FastFluxNewIPsPerHour should match expected value")
+			require.Equal(test.expectedConfig.Modifiers.FastFluxMaxTTL, cfg.Modifiers.FastFluxMaxTTL, "FastFluxMaxTTL should match expected value")
 
 			require.Equal(test.expectedConfig.LogLevel, cfg.LogLevel, "LogLevel should match expected value")
 			require.Equal(test.expectedConfig.LoggingEnabled, cfg.LoggingEnabled, "LoggingEnabled should match expected value")
@@ -568,6 +799,169 @@ func TestVerifyBeaconConfig(t *testing.T) {
 	require.Equal(11, cfg.Scoring.Beacon.HistBimodalMinHours, "BeaconHistBimodalMinHoursSeen should match expected value")
 }
 
+func TestVerifyFieldMapping(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+
+	cfg.FieldMapping = map[string]map[string]string{"conn": {"source.ip": "id.orig_h", "destination.ip": "id.resp_h"}}
+	require.NoError(cfg.verifyConfig(), "valid field mapping should not produce an error")
+
+	cfg.FieldMapping = map[string]map[string]string{"conn": {"source.ip": ""}}
+	require.Error(cfg.verifyConfig(), "field mapping with an empty field name should produce an error")
+
+	cfg.FieldMapping = map[string]map[string]string{"conn": {"source.ip": "id.orig_h", "client.ip": "id.orig_h"}}
+	require.Error(cfg.verifyConfig(), "field mapping that maps two fields to the same field should produce an error")
+}
+
+func TestVerifyDNSTunneling(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+	require.NoError(cfg.verifyConfig(), "default DNS tunneling config should not produce an error")
+
+	invalid := cfg
+	invalid.Scoring.DNSTunneling.MinimumSubdomains = 0
+	require.Error(invalid.verifyConfig(), "minimum subdomains less than 1 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.DNSTunneling.EntropyWeight = 0.5
+	require.Error(invalid.verifyConfig(), "weights that don't sum to 1 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.DNSTunneling.EntropyWeight = -0.25
+	invalid.Scoring.DNSTunneling.EncodingWeight = 0.75
+	require.Error(invalid.verifyConfig(), "negative weight should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.DNSTunneling.ScoreThresholds = ScoreThresholds{Base: 40, Low: 60, Med: 80, High: 120}
+	require.Error(invalid.verifyConfig(), "score thresholds over 100 should produce an error")
+}
+
+func TestVerifyDGA(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+	require.NoError(cfg.verifyConfig(), "default DGA config should not produce an error")
+
+	invalid := cfg
+	invalid.Scoring.DGA.RandomnessThreshold = -0.1
+	require.Error(invalid.verifyConfig(), "negative randomness threshold should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.DGA.RandomnessThreshold = 1.5
+	require.Error(invalid.verifyConfig(), "randomness threshold greater than 1 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.DGA.ScoreThresholds = ScoreThresholds{Base: 0, Low: 25, Med: 50, High: 100}
+	require.Error(invalid.verifyConfig(), "base threshold less than 1 should produce an error")
+}
+
+func TestVerifyPortScan(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+	require.NoError(cfg.verifyConfig(), "default port scan config should not produce an error")
+
+	invalid := cfg
+	invalid.Scoring.PortScan.HorizontalScoreThresholds = ScoreThresholds{Base: 1, Low: 100, Med: 250, High: 500}
+	require.Error(invalid.verifyConfig(), "horizontal base threshold less than 2 hosts should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.PortScan.VerticalScoreThresholds = ScoreThresholds{Base: 100, Low: 500, Med: 250, High: 1000}
+	require.Error(invalid.verifyConfig(), "vertical thresholds out of order should produce an error")
+}
+
+func TestVerifySSH(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+	require.NoError(cfg.verifyConfig(), "default SSH config should not produce an error")
+
+	invalid := cfg
+	invalid.Scoring.SSH.BruteForceScoreThresholds = ScoreThresholds{Base: 0, Low: 100, Med: 250, High: 500}
+	require.Error(invalid.verifyConfig(), "brute force base threshold less than 1 attempt should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.SSH.LoginAfterFailuresScoreThresholds = ScoreThresholds{Base: 10, Low: 50, Med: 25, High: 100}
+	require.Error(invalid.verifyConfig(), "login after failures thresholds out of order should produce an error")
+
+	invalid = cfg
+	invalid.Modifiers.RareSSHVersionPrevalenceThreshold = 1.5
+	require.Error(invalid.verifyConfig(), "rare SSH version prevalence threshold greater than 1 should produce an error")
+}
+
+func TestVerifyExfiltration(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+	require.NoError(cfg.verifyConfig(), "default exfiltration config should not produce an error")
+
+	valid := cfg
+	valid.Scoring.Exfiltration.OffHoursStart = 0
+	valid.Scoring.Exfiltration.OffHoursEnd = 0
+	require.NoError(valid.verifyConfig(), "matching off hours start and end should not produce an error")
+
+	invalid := cfg
+	invalid.Scoring.Exfiltration.MinimumRatio = 0.5
+	require.Error(invalid.verifyConfig(), "minimum ratio less than 1 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.RatioWeight = 1.5
+	require.Error(invalid.verifyConfig(), "ratio weight greater than 1 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.OffHoursStart = 24
+	require.Error(invalid.verifyConfig(), "off hours start greater than 23 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.OffHoursEnd = -1
+	require.Error(invalid.verifyConfig(), "negative off hours end should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.OffHoursTimezone = "Mars/Olympus_Mons"
+	require.Error(invalid.verifyConfig(), "unknown off hours timezone should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.OffHoursTimezone = ""
+	require.Error(invalid.verifyConfig(), "empty off hours timezone should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.OffHoursWeight = 0.5
+	require.Error(invalid.verifyConfig(), "off hours weight less than 1 should produce an error")
+
+	invalid = cfg
+	invalid.Scoring.Exfiltration.ScoreThresholds = ScoreThresholds{Base: 0, Low: 500, Med: 1000, High: 5000}
+	require.Error(invalid.verifyConfig(), "base threshold less than 1 megabyte should produce an error")
+}
+
+func TestVerifyFastFlux(t *testing.T) {
+	require := require.New(t)
+	cfg, err := getDefaultConfig()
+	require.NoError(err, "getDefaultConfig should not produce an error")
+	require.NoError(cfg.verifyConfig(), "default fast flux config should not produce an error")
+
+	invalid := cfg
+	invalid.Modifiers.FastFluxScoreIncrease = 1.5
+	require.Error(invalid.verifyConfig(), "score increase greater than 1 should produce an error")
+
+	invalid = cfg
+	invalid.Modifiers.FastFluxResolvedIPThreshold = 1
+	require.Error(invalid.verifyConfig(), "resolved IP threshold less than 2 should produce an error")
+
+	invalid = cfg
+	invalid.Modifiers.FastFluxNetworkThreshold = invalid.Modifiers.FastFluxResolvedIPThreshold + 1
+	require.Error(invalid.verifyConfig(), "network threshold greater than the resolved IP threshold should produce an error")
+
+	invalid = cfg
+	invalid.Modifiers.FastFluxNewIPsPerHour = 0
+	require.Error(invalid.verifyConfig(), "new IPs per hour of 0 should produce an error")
+
+	invalid = cfg
+	invalid.Modifiers.FastFluxMaxTTL = -1
+	require.Error(invalid.verifyConfig(), "negative max TTL should produce an error")
+}
+
 func TestResetConfig(t *testing.T) {
 	require := require.New(t)
 
@@ -654,6 +1048,8 @@ func TestGetDefaultConfig(t *testing.T) {
 	require.Equal(origConfigVar.Scoring, cfg.Scoring, "config scoring should match expected value")
 	require.Equal(origConfigVar.Modifiers, cfg.Modifiers, "config modifiers should match expected value")
 	require.Equal(origConfigVar.ThreatIntel, cfg.ThreatIntel, "config threat intel should match expected value")
+	require.Equal(origConfigVar.S3, cfg.S3, "config s3 should match expected value")
+	require.Equal(origConfigVar.Kafka, cfg.Kafka, "config kafka should match expected value")
 	require.Equal(origConfigVar.LogLevel, cfg.LogLevel, "config log level should match expected value")
 	require.Equal(origConfigVar.LoggingEnabled, cfg.LoggingEnabled, "config logging enabled should match expected value")
 
@@ -24,6 +24,14 @@ type Filter struct {
 	FilterExternalToInternal bool `json:"filter_external_to_internal"`
 }
 
+// Filter rules that can exclude a record, named after the config file settings that they come from
+const (
+	FilterRuleNeverIncludedSubnets = "never_included_subnets"
+	FilterRuleNeverIncludedDomains = "never_included_domains"
+	FilterRuleInternalSubnets      = "internal_subnets"
+	FilterRuleExternalToInternal   = "filter_external_to_internal"
+)
+
 func getMandatoryNeverIncludeSubnets() []string {
 	// s2 := make([]string, len(mandatoryNeverIncludeSubnets))
 
@@ -123,6 +131,12 @@ func (fs *Filter) FilterConnPairForHTTP(srcIP net.IP, dstIP net.IP) bool {
 //  5. Filtered if the source IP is external and the destination IP is internal and FilterExternalToInternal has been set in the configuration file
 //  6. Not filtered in all other cases
 func (fs *Filter) FilterConnPair(srcIP net.IP, dstIP net.IP) bool {
+	return fs.ConnPairFilterRule(srcIP, dstIP) != ""
+}
+
+// ConnPairFilterRule returns the rule that filters/excludes a connection pair, or an empty string if the pair is not filtered.
+// The rules are checked in the same order as FilterConnPair.
+func (fs *Filter) ConnPairFilterRule(srcIP net.IP, dstIP net.IP) string {
 
 	// check if on always included list
 	isSrcIncluded := util.ContainsIP(fs.AlwaysIncludedSubnets, srcIP)
@@ -134,19 +148,19 @@ func (fs *Filter) FilterConnPair(srcIP net.IP, dstIP net.IP) bool {
 
 	// if either IP is on the AlwaysInclude list, filter does not apply
 	if isSrcIncluded || isDstIncluded {
-		return false
+		return ""
 	}
 
 	// if either IP is on the NeverInclude list, filter applies
 	if isSrcExcluded || isDstExcluded {
-		return true
+		return FilterRuleNeverIncludedSubnets
 	}
 
 	// if no internal subnets are defined, return false
 	// note: this should not happen since we validate the config to ensure
 	// that internal subnets is not empty
 	if len(fs.InternalSubnets) == 0 {
-		return false
+		return ""
 	}
 
 	// check if src and dst are internal
@@ -155,21 +169,21 @@ func (fs *Filter) FilterConnPair(srcIP net.IP, dstIP net.IP) bool {
 
 	// if both addresses are internal, filter applies
 	if isSrcInternal && isDstInternal {
-		return true
+		return FilterRuleInternalSubnets
 	}
 
 	// if both addresses are external, filter applies
 	if (!isSrcInternal) && (!isDstInternal) {
-		return true
+		return FilterRuleInternalSubnets
 	}
 
 	// filter external to internal traffic if the user has specified to do so
 	if fs.FilterExternalToInternal && (!isSrcInternal) && isDstInternal {
-		return true
+		return FilterRuleExternalToInternal
 	}
 
 	// default to not filter the connection pair
-	return false
+	return ""
 }
 
 // filterDNSPair returns true if a DNS connection pair is filtered/excluded.
@@ -183,6 +197,12 @@ func (fs *Filter) FilterConnPair(srcIP net.IP, dstIP net.IP) bool {
 //  5. Filtered if the source IP is external and the destination IP is internal and FilterExternalToInternal has been set in the configuration file
 //  6. Not filtered in all other cases
 func (fs *Filter) FilterDNSPair(srcIP net.IP, dstIP net.IP) bool {
+	return fs.DNSPairFilterRule(srcIP, dstIP) != ""
+}
+
+// DNSPairFilterRule returns the rule that filters/excludes a DNS connection pair, or an empty string if the pair is not filtered.
+// The rules are checked in the same order as FilterDNSPair.
+func (fs *Filter) DNSPairFilterRule(srcIP net.IP, dstIP net.IP) string {
 	// check if on always included list
 	isSrcIncluded := util.ContainsIP(fs.AlwaysIncludedSubnets, srcIP)
 	isDstIncluded := util.ContainsIP(fs.AlwaysIncludedSubnets, dstIP)
@@ -193,18 +213,18 @@ func (fs *Filter) FilterDNSPair(srcIP net.IP, dstIP net.IP) bool {
 
 	// if either IP is on the AlwaysInclude list, filter does not apply
 	if isSrcIncluded || isDstIncluded {
-		return false
+		return ""
 	}
 
 	// if either IP is on the NeverInclude list, filter applies
 	if isSrcExcluded || isDstExcluded {
-		return true
+		return FilterRuleNeverIncludedSubnets
 	}
 
 	// if no internal subnets are defined, filter does not apply
 	// this is was the default behavior before InternalSubnets was added
 	if len(fs.InternalSubnets) == 0 {
-		return false
+		return ""
 	}
 
 	// check if src and dst are internal
@@ -213,16 +233,57 @@ func (fs *Filter) FilterDNSPair(srcIP net.IP, dstIP net.IP) bool {
 
 	// if both addresses are external, filter applies
 	if (!isSrcInternal) && (!isDstInternal) {
-		return true
+		return FilterRuleInternalSubnets
 	}
 
 	// filter external to internal traffic if the user has specified to do so
 	if fs.FilterExternalToInternal && (!isSrcInternal) && isDstInternal {
-		return true
+		return FilterRuleExternalToInternal
 	}
 
 	// default to not filter the connection pair
-	return false
+	return ""
+}
+
+// FilterLateralPair returns true if a lateral movement connection pair is filtered/excluded.
+// Lateral movement is treated specially since it only happens between internal hosts, which is
+// the traffic that filterConnPair excludes.
+// This is determined by the following rules, in order:
+//  1. Not filtered if either IP is on the AlwaysInclude list
+//  2. Filtered if either IP is on the NeverInclude list
+//  3. Filtered if InternalSubnets is empty
+//  4. Filtered if either IP is external
+//  5. Not filtered in all other cases
+func (fs *Filter) FilterLateralPair(srcIP net.IP, dstIP net.IP) bool {
+	// check if on always included list
+	isSrcIncluded := util.ContainsIP(fs.AlwaysIncludedSubnets, srcIP)
+	isDstIncluded := util.ContainsIP(fs.AlwaysIncludedSubnets, dstIP)
+
+	// check if on never included list
+	isSrcExcluded := util.ContainsIP(fs.NeverIncludedSubnets, srcIP)
+	isDstExcluded := util.ContainsIP(fs.NeverIncludedSubnets, dstIP)
+
+	// if either IP is on the AlwaysInclude list, filter does not apply
+	if isSrcIncluded || isDstIncluded {
+		return false
+	}
+
+	// if either IP is on the NeverInclude list, filter applies
+	if isSrcExcluded || isDstExcluded {
+		return true
+	}
+
+	// without internal subnets, there is no way to tell which hosts are internal
+	if len(fs.InternalSubnets) == 0 {
+		return true
+	}
+
+	// check if src and dst are internal
+	isSrcInternal := util.ContainsIP(fs.InternalSubnets, srcIP)
+	isDstInternal := util.ContainsIP(fs.InternalSubnets, dstIP)
+
+	// filter applies unless both addresses are internal
+	return !isSrcInternal || !isDstInternal
 }
 
 // filterSingleIP returns true if an IP is filtered/excluded.
@@ -148,6 +148,116 @@ func TestFilterDNSPair(t *testing.T) {
 	})
 }
 
+func TestFilterRules(t *testing.T) {
+	// load config
+	cfg, err := getDefaultConfig()
+	require.NoError(t, err)
+
+	cfg.Filter.InternalSubnets = []*net.IPNet{
+		{IP: net.IP{10, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+	}
+	cfg.Filter.AlwaysIncludedSubnets = []*net.IPNet{
+		{IP: net.IP{35, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+	}
+	cfg.Filter.NeverIncludedSubnets = []*net.IPNet{
+		{IP: net.IP{12, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+	}
+	cfg.Filter.FilterExternalToInternal = true
+
+	tests := []struct {
+		name     string
+		src      net.IP
+		dst      net.IP
+		connRule string
+		dnsRule  string
+	}{
+		{name: "Internal to External", src: net.IP{10, 0, 0, 1}, dst: net.IP{80, 0, 0, 1}, connRule: "", dnsRule: ""},
+		{name: "AlwaysInclude", src: net.IP{12, 0, 0, 1}, dst: net.IP{35, 0, 0, 1}, connRule: "", dnsRule: ""},
+		{name: "NeverInclude", src: net.IP{10, 0, 0, 1}, dst: net.IP{12, 0, 0, 1}, connRule: FilterRuleNeverIncludedSubnets, dnsRule: FilterRuleNeverIncludedSubnets},
+		{name: "Internal to Internal", src: net.IP{10, 0, 0, 1}, dst: net.IP{10, 0, 0, 2}, connRule: FilterRuleInternalSubnets, dnsRule: ""},
+		{name: "External to External", src: net.IP{80, 0, 0, 1}, dst: net.IP{90, 0, 0, 1}, connRule: FilterRuleInternalSubnets, dnsRule: FilterRuleInternalSubnets},
+		{name: "External to Internal", src: net.IP{80, 0, 0, 1}, dst: net.IP{10, 0, 0, 1}, connRule: FilterRuleExternalToInternal, dnsRule: FilterRuleExternalToInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.connRule, cfg.Filter.ConnPairFilterRule(test.src, test.dst), "conn filter rule should match expected value")
+			require.Equal(t, test.dnsRule, cfg.Filter.DNSPairFilterRule(test.src, test.dst), "dns filter rule should match expected value")
+
+			// the rule functions must agree with the boolean filters
+			require.Equal(t, test.connRule != "", cfg.Filter.FilterConnPair(test.src, test.dst), "conn filter state should match expected value")
+			require.Equal(t, test.dnsRule != "", cfg.Filter.FilterDNSPair(test.src, test.dst), "dns filter state should match expected value")
+		})
+	}
+}
+
+func TestFilterLateralPair(t *testing.T) {
+	internalSubnetListEmpty := []*net.IPNet{}
+
+	internalSubnetList := []*net.IPNet{
+		{IP: net.IP{11, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+		{IP: net.IP{120, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+	}
+
+	alwaysIncludedSubnetList := []*net.IPNet{
+		{IP: net.IP{35, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+		{IP: net.IP{170, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+	}
+
+	neverIncludedSubnetList := []*net.IPNet{
+		{IP: net.IP{12, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+		{IP: net.IP{150, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
+	}
+
+	// load config
+	cfg, err := getDefaultConfig()
+	require.NoError(t, err)
+
+	// AlwaysInclude list tests
+	t.Run("AlwaysInclude list tests", func(t *testing.T) {
+		cfg.Filter.AlwaysIncludedSubnets = alwaysIncludedSubnetList
+		checkCases := cfg.Filter.FilterLateralPair(net.IP{35, 0, 0, 0}, net.IP{190, 0, 0, 0})
+		require.False(t, checkCases, "filter state should match expected value")
+		checkCases = cfg.Filter.FilterLateralPair(net.IP{190, 0, 0, 0}, net.IP{35, 0, 0, 0})
+		require.False(t, checkCases, "filter state should match expected value")
+	})
+
+	// NeverInclude list tests
+	t.Run("NeverInclude list tests", func(t *testing.T) {
+		cfg.Filter.NeverIncludedSubnets = neverIncludedSubnetList
+		checkCases := cfg.Filter.FilterLateralPair(net.IP{12, 0, 0, 0}, net.IP{11, 0, 0, 0})
+		require.True(t, checkCases, "filter state should match expected value")
+		checkCases = cfg.Filter.FilterLateralPair(net.IP{11, 0, 0, 0}, net.IP{12, 0, 0, 0})
+		require.True(t, checkCases, "filter state should match expected value")
+	})
+
+	// InternalSubnets tests
+	t.Run("InternalSubnets tests", func(t *testing.T) {
+		cfg.Filter.InternalSubnets = internalSubnetList
+
+		// Both are internal
+		checkCases := cfg.Filter.FilterLateralPair(net.IP{11, 0, 0, 0}, net.IP{120, 0, 0, 0})
+		require.False(t, checkCases, "filter state should match expected value")
+
+		// Source is internal, destination is external
+		checkCases = cfg.Filter.FilterLateralPair(net.IP{11, 0, 0, 0}, net.IP{80, 0, 0, 0})
+		require.True(t, checkCases, "filter state should match expected value")
+
+		// Source is external, destination is internal
+		checkCases = cfg.Filter.FilterLateralPair(net.IP{180, 0, 0, 0}, net.IP{120, 0, 0, 0})
+		require.True(t, checkCases, "filter state should match expected value")
+
+		// Both are external
+		checkCases = cfg.Filter.FilterLateralPair(net.IP{185, 0, 0, 0}, net.IP{16, 0, 0, 0})
+		require.True(t, checkCases, "filter state should match expected value")
+
+		// Empty list
+		cfg.Filter.InternalSubnets = internalSubnetListEmpty
+		checkCases = cfg.Filter.FilterLateralPair(net.IP{11, 0, 0, 0}, net.IP{120, 0, 0, 0})
+		require.True(t, checkCases, "filter state should match expected value")
+	})
+}
+
 func TestFilterSingleIP(t *testing.T) {
 	alwaysIncludedSubnetList := []*net.IPNet{
 		{IP: net.IP{35, 0, 0, 0}, Mask: net.IPMask{255, 0, 0, 0}},
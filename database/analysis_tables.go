@@ -59,10 +59,50 @@ func (db *DB) createThreatMixtapeTable(ctx context.Context) error {
 			c2_over_dns_score Float32,
 			c2_over_dns_direct_conn_score Float32,
 
+			-- DNS TUNNELING
+			label_entropy Float32,
+			avg_label_length Float32,
+			hex_label_ratio Float32,
+			base32_label_ratio Float32,
+			base64_label_ratio Float32,
+			txt_null_query_ratio Float32,
+			dns_tunneling_score Float32,
+
 			-- THREAT INTEL
 			threat_intel Bool,
 			threat_intel_score Float32,
 
+			-- DGA
+			dga_score Float32,
+			nxdomain_count UInt64,
+			dga_domains UInt64,
+			dga_sample_domains Array(String),
+
+			-- EXFILTRATION
+			bytes_sent Int64,
+			bytes_received Int64,
+			off_hours_bytes_sent Int64,
+			exfiltration_score Float32,
+
+			-- PORT SCAN
+			port_scan_score Float32,
+			scan_type LowCardinality(String),
+			scanned_hosts UInt64,
+			scanned_ports UInt64,
+
+			-- SSH
+			ssh_brute_force_score Float32,
+			ssh_auth_attempts UInt64,
+			ssh_login_after_failures_score Float32,
+			ssh_failed_logins UInt64,
+
+			-- LATERAL MOVEMENT
+			lateral_movement_score Float32,
+			lateral_hosts UInt64,
+			admin_share_hosts UInt64,
+			service_control_hosts UInt64,
+			new_service_tickets UInt64,
+
 			-- **** MODIFIERS ****
 			modifier_name LowCardinality(String),
 			modifier_score Float32,
@@ -82,7 +122,20 @@ func (db *DB) createThreatMixtapeTable(ctx context.Context) error {
 
 			-- MISSING HOST HEADER
 			missing_host_count UInt64,
-			missing_host_header_score Float32
+			missing_host_header_score Float32,
+
+			-- CERTIFICATE ANOMALIES
+			cert_subject String,
+			cert_issuer String,
+			cert_not_valid_before DateTime(),
+			cert_not_valid_after DateTime(),
+
+			-- FAST FLUX
+			resolved_ip_count UInt64,
+			resolved_ip_networks UInt64,
+			resolved_ip_rate Float32,
+			resolved_ip_ttl UInt32,
+			resolved_ips Array(IPv6)
 
 		) ENGINE = MergeTree()
 		PRIMARY KEY (analyzed_at, dst_nuid, src_nuid, src, fqdn, dst, hash)
@@ -148,6 +201,20 @@ func (db *DB) createHistoricalFirstSeenMaterializedViews(ctx context.Context) er
 		return err
 	}
 
+	if err := db.Conn.Exec(ctx, `--sql
+		CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.historical_first_seen_quic_mv
+		TO metadatabase.historical_first_seen AS
+			SELECT
+				'::' as ip,
+				server_name as fqdn,
+				minSimpleState(ts) as first_seen,
+				maxSimpleState(ts) as last_seen
+		FROM {database:Identifier}.quic
+		GROUP BY ( fqdn, ip)
+	`); err != nil {
+		return err
+	}
+
 	if err := db.Conn.Exec(ctx, `--sql
 		CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.historical_first_seen_http_mv
 		TO metadatabase.historical_first_seen AS
@@ -438,6 +505,30 @@ func (db *DB) createPortInfoTable(ctx context.Context) error {
 		return err
 	}
 
+	// quic
+	if err := db.Conn.Exec(ctx, `--sql
+		CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.port_info_quic_mv
+		TO {database:Identifier}.port_info AS
+		SELECT
+			toStartOfHour(import_time) as import_hour,
+			toStartOfHour(ts) as hour,
+			hash,
+			src,
+			src_nuid,
+			server_name as fqdn,
+			dst_port,
+			proto,
+			service,
+			conn_state,
+			countState() as count,
+			sumState(src_ip_bytes) as bytes_sent,
+			sumState(dst_ip_bytes) as bytes_received
+		FROM {database:Identifier}.quic
+		GROUP BY (import_hour, hour, hash, src, src_nuid, fqdn, dst_port, proto, service, conn_state)
+	`); err != nil {
+		return err
+	}
+
 	return nil
 }
 
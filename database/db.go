@@ -245,6 +245,18 @@ func (db *DB) TruncateTmpLinkTables() error {
 	`); err != nil {
 		return err
 	}
+
+	if err := db.Conn.Exec(ctx, `--sql
+		TRUNCATE TABLE IF EXISTS {database:Identifier}.ssh_tmp
+	`); err != nil {
+		return err
+	}
+
+	if err := db.Conn.Exec(ctx, `--sql
+		TRUNCATE TABLE IF EXISTS {database:Identifier}.quic_tmp
+	`); err != nil {
+		return err
+	}
 	return nil
 }
 
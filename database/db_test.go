@@ -660,3 +660,70 @@ func (d *DatabaseTestSuite) TestGetNetworkSize() {
 	})
 
 }
+
+func (d *DatabaseTestSuite) TestMigrateSensorDB() {
+	d.Run("Dataset Created By An Older Version", func() {
+		t := d.T()
+		_, err := cmd.RunImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "migrateDB", false, true)
+		require.NoError(t, err, "importing data should not produce an error")
+
+		db, err := database.ConnectToDB(context.Background(), "migrateDB", d.cfg, nil)
+		require.NoError(t, err, "connecting to database should not produce an error")
+
+		// remove a table and columns that datasets created by older versions don't have
+		ctx := db.QueryParameters(clickhouse.Parameters{"database": "migrateDB"})
+		require.NoError(t, db.Conn.Exec(ctx, `DROP TABLE {database:Identifier}.dhcp`), "dropping the dhcp table should not produce an error")
+		require.NoError(t, db.Conn.Exec(ctx, `ALTER TABLE {database:Identifier}.threat_mixtape DROP COLUMN exfiltration_score`), "dropping a threat_mixtape column should not produce an error")
+		require.NoError(t, db.Conn.Exec(ctx, `ALTER TABLE {database:Identifier}.pdns_raw DROP COLUMN ttl`), "dropping the pdns_raw ttl column should not produce an error")
+
+		// migrating twice should not fail on the tables and columns that already exist
+		require.NoError(t, db.MigrateSensorDB(), "migrating the database should not produce an error")
+		require.NoError(t, db.MigrateSensorDB(), "migrating an up to date database should not produce an error")
+
+		var count uint64
+		err = db.Conn.QueryRow(ctx, `
+			SELECT count() FROM system.tables WHERE database = {database:String} AND name = 'dhcp'
+		`).Scan(&count)
+		require.NoError(t, err, "querying system.tables should not produce an error")
+		require.EqualValues(t, 1, count, "dhcp table should be created")
+
+		err = db.Conn.QueryRow(ctx, `
+			SELECT count() FROM system.columns
+			WHERE database = {database:String} AND (table, name) IN (('threat_mixtape', 'exfiltration_score'), ('pdns_raw', 'ttl'))
+		`).Scan(&count)
+		require.NoError(t, err, "querying system.columns should not produce an error")
+		require.EqualValues(t, 2, count, "dropped columns should be added back")
+
+		// the dataset can still be read from after the migration
+		err = db.Conn.QueryRow(ctx, `SELECT count() FROM {database:Identifier}.threat_mixtape`).Scan(&count)
+		require.NoError(t, err, "querying threat_mixtape should not produce an error")
+		require.Greater(t, count, uint64(0), "threat_mixtape should keep its records")
+	})
+}
+
+func (d *DatabaseTestSuite) TestDNSLexicalHexLabels() {
+	t := d.T()
+	_, err := cmd.RunImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "lexicalDB", false, true)
+	require.NoError(t, err, "importing data should not produce an error")
+
+	db, err := database.ConnectToDB(context.Background(), "lexicalDB", d.cfg, nil)
+	require.NoError(t, err, "connecting to database should not produce an error")
+
+	ctx := db.QueryParameters(clickhouse.Parameters{"database": "lexicalDB"})
+	err = db.Conn.Exec(ctx, `
+		INSERT INTO {database:Identifier}.dns (import_time, ts, query, query_type_name) VALUES
+			(now(), now(), '4f9a0c2e71.hex-label-test.com', 'A'),
+			(now(), now(), '8005551234.hex-label-test.com', 'A'),
+			(now(), now(), 'deadbeefcafe.hex-label-test.com', 'A')
+	`)
+	require.NoError(t, err, "inserting dns records should not produce an error")
+
+	var labels, hexLabels uint64
+	err = db.Conn.QueryRow(ctx, `
+		SELECT sumMerge(labels), sumMerge(hex_labels) FROM {database:Identifier}.dns_lexical
+		WHERE tld = 'hex-label-test.com'
+	`).Scan(&labels, &hexLabels)
+	require.NoError(t, err, "querying dns_lexical should not produce an error")
+	require.EqualValues(t, 3, labels, "each subdomain label should be counted")
+	require.EqualValues(t, 1, hexLabels, "only the label that mixes digits and hex letters should be counted as hex, not the numeric or the all letter label")
+}
@@ -0,0 +1,6 @@
+package database
+
+// Refresh records a heartbeat for the lock right away instead of waiting for the next one
+func (lock *ImportLock) Refresh() error {
+	return lock.refresh()
+}
@@ -0,0 +1,337 @@
+package database
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/logger"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+)
+
+var ErrImportLocked = errors.New("another import is already running against this database")
+var ErrImportLockLost = errors.New("the import lock was taken over by another import after this import stopped refreshing it")
+
+const (
+	// importLockHeartbeat is how often a held import lock is refreshed
+	importLockHeartbeat = 30 * time.Second
+	// importLockStaleAfter is how long a lock can go without a heartbeat before it is considered abandoned,
+	// ie, the import holding it crashed or its container was killed
+	importLockStaleAfter = 5 * time.Minute
+	// errCodeTableAlreadyExists is the ClickHouse error code returned when creating a table that already exists
+	errCodeTableAlreadyExists = 57
+	// errCodeUnknownTable is the ClickHouse error code returned when querying a table that doesn't exist
+	errCodeUnknownTable = 60
+)
+
+// ImportLock prevents two imports from running against the same dataset at the same time. The lock is a table in the
+// metadatabase, so it is shared by every process (or container) that imports into the same ClickHouse server.
+type ImportLock struct {
+	server *ServerConn
+	table  string
+	holder string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// AcquireImportLock takes the import lock of the database, returning ErrImportLocked if another import holds it
+func AcquireImportLock(ctx context.Context, cfg *config.Config, dbName string) (*ImportLock, error) {
+	if cfg == nil {
+		return nil, ErrMissingConfig
+	}
+
+	if dbName == "" {
+		return nil, ErrDatabaseNameEmpty
+	}
+
+	server, err := ConnectToServer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.Conn.Exec(server.ctx, `
+		CREATE DATABASE IF NOT EXISTS metadatabase
+	`)
+	if err != nil {
+		server.Conn.Close()
+		return nil, err
+	}
+
+	// the holder is unique to this lock, so that a lock can tell whether it was taken over even by the same process
+	hostname, _ := os.Hostname()
+	lock := &ImportLock{
+		server: server,
+		table:  "import_lock_" + dbName,
+		holder: fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.NewString()),
+		stop:   make(chan struct{}),
+	}
+	lock.ctx, lock.cancel = context.WithCancelCause(ctx)
+
+	acquired, err := lock.tryAcquire()
+	if err != nil {
+		server.Conn.Close()
+		return nil, err
+	}
+
+	// take over the lock if its holder stopped refreshing it
+	if !acquired {
+		acquired, err = lock.takeOverIfStale(dbName)
+		if err != nil {
+			server.Conn.Close()
+			return nil, err
+		}
+		if !acquired {
+			server.Conn.Close()
+			return nil, ErrImportLocked
+		}
+	}
+
+	lock.wg.Add(1)
+	go lock.heartbeat()
+
+	return lock, nil
+}
+
+// takeOverIfStale replaces the lock if its holder stopped refreshing it. Every import that finds the same stale lock
+// races to create a takeover guard that is named after it, so only one of them can replace it.
+func (lock *ImportLock) takeOverIfStale(dbName string) (bool, error) {
+	logger := logger.GetLogger()
+
+	stale, err := lock.current()
+	// the lock was released (or replaced by another takeover) since it was found to be held
+	if isClickHouseError(err, errCodeUnknownTable) {
+		return lock.tryAcquire()
+	}
+	if err != nil {
+		return false, err
+	}
+	if !stale.isStale() {
+		return false, nil
+	}
+
+	// the guard is also named after the current stale period so that a guard left behind by an import that was killed
+	// while taking over the lock doesn't block every later takeover
+	guard := fmt.Sprintf("%s_takeover_%x_%d", lock.table, stale.hash(), stale.now.Truncate(importLockStaleAfter).Unix())
+	guarded, err := lock.create(guard)
+	if err != nil || !guarded {
+		return false, err
+	}
+	defer func() {
+		if err := lock.dropTable(guard); err != nil {
+			logger.Warn().Err(err).Str("table", guard).Msg("failed to remove import lock takeover guard")
+		}
+	}()
+
+	// only drop the lock if it is still the stale one, since another import may have already replaced it
+	current, err := lock.current()
+	if isClickHouseError(err, errCodeUnknownTable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if current.holder != stale.holder || !current.heartbeat.Equal(stale.heartbeat) {
+		return false, nil
+	}
+
+	logger.Warn().Str("database", dbName).Str("holder", stale.holder).Msg("removing stale import lock left behind by an import that did not finish")
+	if err := lock.drop(); err != nil {
+		return false, err
+	}
+
+	// another import may create the lock before us once it is dropped
+	acquired, err := lock.tryAcquire()
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	current, err = lock.current()
+	if err != nil {
+		return false, err
+	}
+	return current.holder == lock.holder, nil
+}
+
+// Release stops refreshing the lock and removes it so that the next import can run. A lock that was taken over by
+// another import is left in place, since it belongs to that import now.
+func (lock *ImportLock) Release() error {
+	close(lock.stop)
+	lock.wg.Wait()
+	defer lock.server.Conn.Close()
+	defer lock.cancel(context.Canceled)
+
+	state, err := lock.current()
+	if isClickHouseError(err, errCodeUnknownTable) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if state.holder != lock.holder {
+		return nil
+	}
+	return lock.drop()
+}
+
+// Context returns a context that is cancelled with ErrImportLockLost if another import takes over the lock, so that
+// this import stops writing to the dataset
+func (lock *ImportLock) Context() context.Context {
+	return lock.ctx
+}
+
+// Guard makes the queries of the import's database connection use the context of the lock, so that an import that
+// lost its lock stops writing to the dataset that was taken over
+func (lock *ImportLock) Guard(db *DB) {
+	db.ctx = lock.ctx
+}
+
+// Err returns ErrImportLockLost if another import took over the lock
+func (lock *ImportLock) Err() error {
+	if errors.Is(context.Cause(lock.ctx), ErrImportLockLost) {
+		return ErrImportLockLost
+	}
+	return nil
+}
+
+// tryAcquire creates the lock table, which fails if it already exists, so that only one import can ever create it
+func (lock *ImportLock) tryAcquire() (bool, error) {
+	return lock.create(lock.table)
+}
+
+// create creates a table holding the lock's holder, returning false if the table already exists
+func (lock *ImportLock) create(table string) (bool, error) {
+	ctx := lock.server.QueryParameters(clickhouse.Parameters{
+		"table":  table,
+		"holder": lock.holder,
+	})
+
+	err := lock.server.Conn.Exec(ctx, `
+		CREATE TABLE metadatabase.{table:Identifier}
+		ENGINE = Memory
+		AS SELECT {holder:String} AS holder, now() AS heartbeat
+	`)
+
+	if isClickHouseError(err, errCodeTableAlreadyExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// lockState is the holder of a lock and the last time that it refreshed the lock, along with the time on the server
+// when the lock was read, so that the age of a lock doesn't depend on the clock of the host reading it
+type lockState struct {
+	holder    string
+	heartbeat time.Time
+	now       time.Time
+}
+
+// isStale returns whether the holder of the lock stopped refreshing it
+func (state lockState) isStale() bool {
+	return state.now.Sub(state.heartbeat) > importLockStaleAfter
+}
+
+// hash identifies the lock state in the name of a takeover guard
+func (state lockState) hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(state.holder))
+	h.Write([]byte(state.heartbeat.UTC().Format(time.RFC3339)))
+	return h.Sum64()
+}
+
+// current returns the holder of the lock and the last time that it refreshed the lock. An empty lock table returns
+// the zero time, which is always stale.
+func (lock *ImportLock) current() (lockState, error) {
+	ctx := lock.server.QueryParameters(clickhouse.Parameters{"table": lock.table})
+
+	var state lockState
+	err := lock.server.Conn.QueryRow(ctx, `
+		SELECT argMax(holder, heartbeat), max(heartbeat), now() FROM metadatabase.{table:Identifier}
+	`).Scan(&state.holder, &state.heartbeat, &state.now)
+	return state, err
+}
+
+// heartbeat refreshes the lock until it is released or lost
+func (lock *ImportLock) heartbeat() {
+	defer lock.wg.Done()
+	logger := logger.GetLogger()
+
+	ticker := time.NewTicker(importLockHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+			err := lock.refresh()
+			if errors.Is(err, ErrImportLockLost) {
+				logger.Error().Err(err).Str("table", lock.table).Msg("stopping import")
+				return
+			}
+			if err != nil {
+				logger.Warn().Err(err).Str("table", lock.table).Msg("failed to refresh import lock")
+			}
+		}
+	}
+}
+
+// refresh records a heartbeat if this import still holds the lock, or cancels the context of the lock if another
+// import took it over, ie, after this import stalled for longer than the lock takes to go stale
+func (lock *ImportLock) refresh() error {
+	ctx := lock.server.QueryParameters(clickhouse.Parameters{
+		"table":  lock.table,
+		"holder": lock.holder,
+	})
+
+	// only add a heartbeat to a lock that is still held by this import, so that the holder of a lock that was taken
+	// over can't flip back to this import
+	err := lock.server.Conn.Exec(ctx, `
+		INSERT INTO metadatabase.{table:Identifier}
+		SELECT {holder:String}, now() FROM metadatabase.{table:Identifier}
+		HAVING argMax(holder, heartbeat) = {holder:String}
+	`)
+	if err != nil && !isClickHouseError(err, errCodeUnknownTable) {
+		return err
+	}
+
+	state, err := lock.current()
+	if err != nil && !isClickHouseError(err, errCodeUnknownTable) {
+		return err
+	}
+	if err == nil && state.holder == lock.holder {
+		return nil
+	}
+
+	lock.cancel(ErrImportLockLost)
+	return ErrImportLockLost
+}
+
+// drop removes the lock table
+func (lock *ImportLock) drop() error {
+	return lock.dropTable(lock.table)
+}
+
+// dropTable removes a table that was created by the lock
+func (lock *ImportLock) dropTable(table string) error {
+	ctx := lock.server.QueryParameters(clickhouse.Parameters{"table": table})
+	return lock.server.Conn.Exec(ctx, `
+		DROP TABLE IF EXISTS metadatabase.{table:Identifier}
+	`)
+}
+
+// isClickHouseError returns whether err is a ClickHouse exception with the given error code
+func isClickHouseError(err error, code int32) bool {
+	var exception *clickhouse.Exception
+	return errors.As(err, &exception) && exception.Code == code
+}
@@ -0,0 +1,133 @@
+package database_test
+
+import (
+	"activecm/rita/database"
+	"context"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func (d *DatabaseTestSuite) TestImportLock() {
+	d.Run("Lock Held By Another Import", func() {
+		t := d.T()
+
+		lock, err := database.AcquireImportLock(context.Background(), d.cfg, "lock_test")
+		require.NoError(t, err, "acquiring an unheld lock should not produce an error")
+
+		// a second import against the same dataset is rejected while the lock is held
+		_, err = database.AcquireImportLock(context.Background(), d.cfg, "lock_test")
+		require.ErrorIs(t, err, database.ErrImportLocked, "acquiring a held lock should fail")
+
+		// imports against other datasets are not affected
+		other, err := database.AcquireImportLock(context.Background(), d.cfg, "lock_test_other")
+		require.NoError(t, err, "acquiring the lock of another dataset should not produce an error")
+		require.NoError(t, other.Release(), "releasing the lock should not produce an error")
+
+		// the lock can be acquired again once it is released
+		require.NoError(t, lock.Release(), "releasing the lock should not produce an error")
+		lock, err = database.AcquireImportLock(context.Background(), d.cfg, "lock_test")
+		require.NoError(t, err, "acquiring a released lock should not produce an error")
+		require.NoError(t, lock.Release(), "releasing the lock should not produce an error")
+	})
+
+	d.Run("Stale Lock", func() {
+		t := d.T()
+
+		err := d.server.Conn.Exec(context.Background(), "CREATE DATABASE IF NOT EXISTS metadatabase")
+		require.NoError(t, err, "creating the metadatabase should not produce an error")
+
+		// simulate an import that was killed without releasing its lock
+		err = d.server.Conn.Exec(context.Background(), `
+			CREATE TABLE metadatabase.import_lock_lock_test_stale ENGINE = Memory
+			AS SELECT 'killed' AS holder, now() - INTERVAL 1 HOUR AS heartbeat
+		`)
+		require.NoError(t, err, "creating the stale lock should not produce an error")
+
+		lock, err := database.AcquireImportLock(context.Background(), d.cfg, "lock_test_stale")
+		require.NoError(t, err, "acquiring a stale lock should not produce an error")
+		require.NoError(t, lock.Release(), "releasing the lock should not produce an error")
+	})
+
+	d.Run("Concurrent Takeovers Of A Stale Lock", func() {
+		t := d.T()
+
+		err := d.server.Conn.Exec(context.Background(), "CREATE DATABASE IF NOT EXISTS metadatabase")
+		require.NoError(t, err, "creating the metadatabase should not produce an error")
+
+		for round := 0; round < 5; round++ {
+			err = d.server.Conn.Exec(context.Background(), `
+				CREATE TABLE metadatabase.import_lock_lock_test_race ENGINE = Memory
+				AS SELECT 'killed' AS holder, now() - INTERVAL 1 HOUR AS heartbeat
+			`)
+			require.NoError(t, err, "creating the stale lock should not produce an error")
+
+			// several imports find the same stale lock at the same time
+			const imports = 4
+			locks := make([]*database.ImportLock, imports)
+			errs := make([]error, imports)
+			var wg sync.WaitGroup
+			for i := 0; i < imports; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					locks[i], errs[i] = database.AcquireImportLock(context.Background(), d.cfg, "lock_test_race")
+				}(i)
+			}
+			wg.Wait()
+
+			// only one of them may take over the lock, the rest must back off
+			var held *database.ImportLock
+			for i := 0; i < imports; i++ {
+				if errs[i] == nil {
+					require.Nil(t, held, "only one import should take over the stale lock")
+					held = locks[i]
+					continue
+				}
+				require.ErrorIs(t, errs[i], database.ErrImportLocked, "imports that lose the takeover should find the lock held")
+			}
+			require.NotNil(t, held, "one import should take over the stale lock")
+			require.NoError(t, held.Release(), "releasing the lock should not produce an error")
+		}
+	})
+
+	d.Run("Stalled Holder Recovers", func() {
+		t := d.T()
+
+		stalled, err := database.AcquireImportLock(context.Background(), d.cfg, "lock_test_stall")
+		require.NoError(t, err, "acquiring an unheld lock should not produce an error")
+
+		// simulate an import that stalled for longer than the lock takes to go stale
+		var holder string
+		err = d.server.Conn.QueryRow(context.Background(), "SELECT any(holder) FROM metadatabase.import_lock_lock_test_stall").Scan(&holder)
+		require.NoError(t, err, "reading the holder of the lock should not produce an error")
+		err = d.server.Conn.Exec(context.Background(), "TRUNCATE TABLE metadatabase.import_lock_lock_test_stall")
+		require.NoError(t, err, "clearing the lock should not produce an error")
+		err = d.server.Conn.Exec(clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{"holder": holder})), `
+			INSERT INTO metadatabase.import_lock_lock_test_stall SELECT {holder:String}, now() - INTERVAL 1 HOUR
+		`)
+		require.NoError(t, err, "backdating the lock should not produce an error")
+
+		// another import takes over the stale lock
+		takeover, err := database.AcquireImportLock(context.Background(), d.cfg, "lock_test_stall")
+		require.NoError(t, err, "acquiring a stale lock should not produce an error")
+
+		// the stalled import recovers and finds that it lost the lock instead of taking it back
+		require.ErrorIs(t, stalled.Refresh(), database.ErrImportLockLost, "refreshing a lost lock should fail")
+		require.ErrorIs(t, stalled.Err(), database.ErrImportLockLost, "lost lock should report that it was lost")
+		require.Error(t, stalled.Context().Err(), "context of a lost lock should be cancelled")
+		require.NoError(t, takeover.Refresh(), "refreshing a held lock should not produce an error")
+		require.NoError(t, takeover.Err(), "held lock should not report that it was lost")
+
+		// releasing the lost lock leaves the lock of the import that took it over in place
+		require.NoError(t, stalled.Release(), "releasing a lost lock should not produce an error")
+		_, err = database.AcquireImportLock(context.Background(), d.cfg, "lock_test_stall")
+		require.ErrorIs(t, err, database.ErrImportLocked, "lock should still be held by the import that took it over")
+
+		require.NoError(t, takeover.Release(), "releasing the lock should not produce an error")
+		lock, err := database.AcquireImportLock(context.Background(), d.cfg, "lock_test_stall")
+		require.NoError(t, err, "acquiring a released lock should not produce an error")
+		require.NoError(t, lock.Release(), "releasing the lock should not produce an error")
+	})
+}
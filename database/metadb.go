@@ -3,7 +3,10 @@ package database
 import (
 	"activecm/rita/config"
 	"activecm/rita/util"
+	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
@@ -33,6 +36,52 @@ type MetaDBImportRecord struct {
 	MaxOpenTimestamp time.Time         `ch:"max_open_timestamp"`
 }
 
+// Checkpoint records that an hour chunk of an import finished one of its stages, along with the time range that the hour
+// chunk is analyzed over so that an interrupted import can be resumed with the same time range
+type Checkpoint struct {
+	ImportID       util.FixedString `ch:"import_id"`
+	Database       string           `ch:"database"`
+	Stage          string           `ch:"stage"`
+	Timestamp      time.Time        `ch:"ts"`
+	StartedAt      time.Time        `ch:"started_at"`
+	MinTS          time.Time        `ch:"min_ts"`
+	MaxTS          time.Time        `ch:"max_ts"`
+	MinTSBeacon    time.Time        `ch:"min_ts_beacon"`
+	MaxTSBeacon    time.Time        `ch:"max_ts_beacon"`
+	UseCurrentTime bool             `ch:"use_current_time"`
+	SkipBeaconing  bool             `ch:"skip_beaconing"`
+}
+
+// Stages of an hour chunk of an import, in the order that they are completed. An import that was interrupted before
+// reaching CheckpointImported has no checkpoint.
+const (
+	CheckpointImported = "imported"
+	CheckpointAnalyzed = "analyzed"
+	CheckpointModified = "modified"
+	CheckpointFinished = "finished"
+)
+
+// CheckpointStages lists the stages of an hour chunk of an import in the order that they are completed
+var CheckpointStages = []string{CheckpointImported, CheckpointAnalyzed, CheckpointModified, CheckpointFinished}
+
+// CheckpointAbandoned marks an interrupted import that can't be resumed, either because its files were imported
+// again under a new import or because a later import reset the temporary tables that it needs
+const CheckpointAbandoned = "abandoned"
+
+// MetaDBRejectedLine is a log line that failed to parse during an import. Lines that were skipped entirely are left
+// out of the import, while lines with an invalid field are imported with that field unset.
+type MetaDBRejectedLine struct {
+	ImportID   util.FixedString `ch:"import_id"`
+	Database   string           `ch:"database"`
+	Timestamp  time.Time        `ch:"ts"`
+	Path       string           `ch:"path"`
+	LineNumber uint64           `ch:"line_number"`
+	Field      string           `ch:"field"`
+	Line       string           `ch:"line"`
+	Error      string           `ch:"error"`
+	Skipped    bool             `ch:"skipped"`
+}
+
 // createMetaDatabase creates the metadatabase and its tables if any part of it doesn't exist
 func (server *ServerConn) createMetaDatabase() error {
 	err := server.Conn.Exec(server.ctx, `
@@ -57,6 +106,16 @@ func (server *ServerConn) createMetaDatabase() error {
 		return err
 	}
 
+	err = server.createMetaDatabaseRejectedLinesTable()
+	if err != nil {
+		return err
+	}
+
+	err = server.createMetaDatabaseCheckpointsTable()
+	if err != nil {
+		return err
+	}
+
 	err = server.createThreatIntelTables()
 	if err != nil {
 		return err
@@ -93,6 +152,51 @@ func (server *ServerConn) createMetaDatabaseFilesTable() error {
 	return err
 }
 
+// createMetaDatabaseRejectedLinesTable creates the metadatabase.rejected_lines table
+func (server *ServerConn) createMetaDatabaseRejectedLinesTable() error {
+	err := server.Conn.Exec(server.ctx, `
+		CREATE TABLE IF NOT EXISTS metadatabase.rejected_lines (
+			import_id FixedString(16),
+			database String,
+			ts DateTime(),
+			path String,
+			line_number UInt64,
+			field String,
+			line String,
+			error String,
+			skipped Bool
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (database, import_id, path, line_number)
+	`)
+
+	return err
+}
+
+// createMetaDatabaseCheckpointsTable creates the metadatabase.checkpoints table
+func (server *ServerConn) createMetaDatabaseCheckpointsTable() error {
+	err := server.Conn.Exec(server.ctx, `
+		CREATE TABLE IF NOT EXISTS metadatabase.checkpoints (
+			import_id FixedString(16),
+			database String,
+			stage LowCardinality(String),
+			ts DateTime(),
+			-- started_at is measured in Microseconds, matching metadatabase.imports
+			started_at DateTime64(6),
+			min_ts DateTime(),
+			max_ts DateTime(),
+			min_ts_beacon DateTime(),
+			max_ts_beacon DateTime(),
+			use_current_time Bool,
+			skip_beaconing Bool
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (database, import_id, ts)
+	`)
+
+	return err
+}
+
 // createMetaDatabaseImportsTable creates the metadatabase.imports table
 func (server *ServerConn) createMetaDatabaseImportsTable() error {
 	err := server.Conn.Exec(server.ctx, `
@@ -183,6 +287,27 @@ func (db *DB) MarkFileImportedInMetaDB(hash util.FixedString, importID util.Fixe
 	return err
 }
 
+// AddRejectedLinesToMetaDB adds the lines that failed to parse during an import to the metadatabase.rejected_lines table
+func (db *DB) AddRejectedLinesToMetaDB(lines []MetaDBRejectedLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	batch, err := db.Conn.PrepareBatch(db.GetContext(), "INSERT INTO metadatabase.rejected_lines")
+	if err != nil {
+		return err
+	}
+
+	for i := range lines {
+		lines[i].Database = db.selected
+		if err := batch.AppendStruct(&lines[i]); err != nil {
+			return err
+		}
+	}
+
+	return batch.Send()
+}
+
 /* *** TRACKING IMPORTS ***
 Data in ClickHouse is meant to be append-only. This means that we cannot easily update records.
 The metadatabase.imports table acts as a log of events for imports. In order to track the start and completion
@@ -249,6 +374,156 @@ func (db *DB) AddImportFinishedRecordToMetaDB(importID util.FixedString, minTS,
 	return err
 }
 
+// AddCheckpointToMetaDB inserts a record into the metadatabase.checkpoints table to mark that an hour chunk of an import finished a stage
+func (db *DB) AddCheckpointToMetaDB(checkpoint Checkpoint) error {
+	batch, err := db.Conn.PrepareBatch(db.GetContext(), "INSERT INTO metadatabase.checkpoints")
+	if err != nil {
+		return err
+	}
+
+	checkpoint.Database = db.selected
+	checkpoint.Timestamp = time.Now().UTC()
+	if err := batch.AppendStruct(&checkpoint); err != nil {
+		return err
+	}
+
+	return batch.Send()
+}
+
+// GetIncompleteImports returns the last checkpoint of each import into the selected database that started but never
+// finished and wasn't abandoned, ordered by when the imports started. Imports that were interrupted before their hour
+// chunk was imported are returned with an empty stage.
+func (db *DB) GetIncompleteImports() ([]Checkpoint, error) {
+	ctx := db.QueryParameters(clickhouse.Parameters{
+		"database":  db.selected,
+		"abandoned": CheckpointAbandoned,
+	})
+
+	// the finish record of an import is the only record with ended_at set
+	var imports []struct {
+		ImportID  util.FixedString `ch:"import_id"`
+		StartedAt time.Time        `ch:"started_at"`
+	}
+	err := db.Conn.Select(ctx, &imports, `
+		SELECT import_id, min(started_at) AS started_at FROM metadatabase.imports
+		WHERE database = {database:String} AND import_id NOT IN (
+			SELECT import_id FROM metadatabase.checkpoints WHERE database = {database:String} AND stage = {abandoned:String}
+		)
+		GROUP BY import_id
+		HAVING max(ended_at) = toDateTime(0)
+		ORDER BY started_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(imports) == 0 {
+		return nil, nil
+	}
+
+	var checkpoints []Checkpoint
+	err = db.Conn.Select(ctx, &checkpoints, `
+		SELECT * FROM metadatabase.checkpoints
+		WHERE database = {database:String} AND import_id IN (
+			SELECT import_id FROM metadatabase.imports WHERE database = {database:String}
+			GROUP BY import_id
+			HAVING max(ended_at) = toDateTime(0)
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the latest stage that each import completed
+	lastCheckpoints := make(map[[16]byte]Checkpoint)
+	for _, checkpoint := range checkpoints {
+		last, ok := lastCheckpoints[checkpoint.ImportID.Data]
+		if !ok || slices.Index(CheckpointStages, checkpoint.Stage) > slices.Index(CheckpointStages, last.Stage) {
+			lastCheckpoints[checkpoint.ImportID.Data] = checkpoint
+		}
+	}
+
+	incomplete := make([]Checkpoint, 0, len(imports))
+	for _, i := range imports {
+		checkpoint, ok := lastCheckpoints[i.ImportID.Data]
+		if !ok {
+			checkpoint = Checkpoint{ImportID: i.ImportID, Database: db.selected, StartedAt: i.StartedAt}
+		}
+		incomplete = append(incomplete, checkpoint)
+	}
+
+	return incomplete, nil
+}
+
+// GetLatestImportID returns the ID of the import into the selected database that started most recently
+func (db *DB) GetLatestImportID() (util.FixedString, error) {
+	ctx := db.QueryParameters(clickhouse.Parameters{
+		"database": db.selected,
+	})
+
+	var importID util.FixedString
+	err := db.Conn.QueryRow(ctx, `
+		SELECT import_id FROM metadatabase.imports
+		WHERE database = {database:String}
+		ORDER BY started_at DESC
+		LIMIT 1
+	`).Scan(&importID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return importID, ErrNoMetaDBImportRecordForDatabase
+	}
+
+	return importID, err
+}
+
+// ClearInterruptedImport deletes the records of an import that was interrupted before its hour chunk was imported, so
+// that its files can be imported again without duplicating them. The DNS, x509, DHCP and lateral movement records that
+// are written straight to their tables are found by the import time that they were stamped with, while the file and
+// rejected line records are deleted from the metadatabase.
+func (db *DB) ClearInterruptedImport(importID util.FixedString, startedAt time.Time) error {
+	ctx := db.QueryParameters(clickhouse.Parameters{
+		"database":   db.selected,
+		"importID":   importID.Hex(),
+		"importTime": strconv.FormatInt(startedAt.Unix(), 10),
+	})
+
+	for _, table := range []string{"dns", "pdns_raw", "x509", "dhcp", "lateral_movement"} {
+		err := db.Conn.Exec(ctx, fmt.Sprintf(`
+			DELETE FROM {database:Identifier}.%s WHERE import_time = fromUnixTimestamp({importTime:Int64})
+		`, table))
+		if err != nil {
+			return err
+		}
+	}
+
+	err := db.Conn.Exec(ctx, `
+		DELETE FROM metadatabase.files WHERE database = {database:String} AND import_id = unhex({importID:String})
+	`)
+	if err != nil {
+		return err
+	}
+
+	err = db.Conn.Exec(ctx, `
+		DELETE FROM metadatabase.rejected_lines WHERE database = {database:String} AND import_id = unhex({importID:String})
+	`)
+	return err
+}
+
+// ClearAnalysisResults deletes the threat mixtape records written by an import, so that an interrupted analysis can be run again.
+// If modifiersOnly is set, only the records written by the modifiers are deleted.
+func (db *DB) ClearAnalysisResults(importID util.FixedString, modifiersOnly bool) error {
+	ctx := db.QueryParameters(clickhouse.Parameters{
+		"database":      db.selected,
+		"importID":      importID.Hex(),
+		"modifiersOnly": strconv.FormatBool(modifiersOnly),
+	})
+
+	err := db.Conn.Exec(ctx, `
+		DELETE FROM {database:Identifier}.threat_mixtape
+		WHERE import_id = unhex({importID:String}) AND (NOT {modifiersOnly:Bool} OR modifier_name != '')
+	`)
+	return err
+}
+
 // CheckIfFilesWereAlreadyImported calls checkFileHashes for each log type
 func (db *DB) CheckIfFilesWereAlreadyImported(fileMap map[string][]string) (int, error) {
 	totalFileCount := 0
@@ -325,6 +600,14 @@ func (server *ServerConn) ClearMetaDBEntriesForDatabase(database string) error {
 		if err := server.clearDatabaseFromMetaDB(database); err != nil {
 			return err
 		}
+
+		if err := server.clearRejectedLinesFromMetaDB(database); err != nil {
+			return err
+		}
+
+		if err := server.clearCheckpointsFromMetaDB(database); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -346,3 +629,31 @@ func (server *ServerConn) clearDatabaseFromMetaDB(database string) error {
 	`, database)
 	return err
 }
+
+// clearRejectedLinesFromMetaDB deletes entries in rejected_lines table for specified database
+func (server *ServerConn) clearRejectedLinesFromMetaDB(database string) error {
+	// the table doesn't exist if no import has run since it was added to the metadatabase
+	if err := server.createMetaDatabaseRejectedLinesTable(); err != nil {
+		return err
+	}
+
+	ctx := clickhouse.Context(server.ctx, clickhouse.WithParameters(clickhouse.Parameters{"database": database}))
+	err := server.Conn.Exec(ctx, `
+		DELETE FROM metadatabase.rejected_lines WHERE database = {database:String}
+	`)
+	return err
+}
+
+// clearCheckpointsFromMetaDB deletes entries in checkpoints table for specified database
+func (server *ServerConn) clearCheckpointsFromMetaDB(database string) error {
+	// the table doesn't exist if no import has run since it was added to the metadatabase
+	if err := server.createMetaDatabaseCheckpointsTable(); err != nil {
+		return err
+	}
+
+	ctx := clickhouse.Context(server.ctx, clickhouse.WithParameters(clickhouse.Parameters{"database": database}))
+	err := server.Conn.Exec(ctx, `
+		DELETE FROM metadatabase.checkpoints WHERE database = {database:String}
+	`)
+	return err
+}
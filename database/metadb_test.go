@@ -0,0 +1,178 @@
+package database_test
+
+import (
+	"activecm/rita/cmd"
+	"activecm/rita/database"
+	"activecm/rita/importer"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func (d *DatabaseTestSuite) TestResumeImport() {
+	d.Run("Interrupted After Analysis", func() {
+		t := d.T()
+
+		results, err := cmd.RunImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, true)
+		require.NoError(t, err, "importing data should not produce an error")
+		require.Len(t, results.ImportID, 1, "the import should have one hour chunk")
+		importID := results.ImportID[0]
+
+		db, err := database.ConnectToDB(context.Background(), "resume_test", d.cfg, nil)
+		require.NoError(t, err, "connecting to database should not produce an error")
+
+		incomplete, err := db.GetIncompleteImports()
+		require.NoError(t, err, "getting incomplete imports should not produce an error")
+		require.Empty(t, incomplete, "a finished import should not be incomplete")
+
+		// every stage of the hour chunk should have been checkpointed
+		var stages []string
+		rows, err := d.server.Conn.Query(context.Background(), "SELECT stage FROM metadatabase.checkpoints WHERE database = 'resume_test' ORDER BY ts")
+		require.NoError(t, err, "querying checkpoints should not produce an error")
+		for rows.Next() {
+			var stage string
+			require.NoError(t, rows.Scan(&stage), "scanning checkpoint should not produce an error")
+			stages = append(stages, stage)
+		}
+		require.Equal(t, database.CheckpointStages, stages, "all stages should have been checkpointed in order")
+
+		// simulate an import that was killed while running the modifiers
+		ctx := clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{"importID": importID.Hex()}))
+		err = d.server.Conn.Exec(ctx, "DELETE FROM metadatabase.imports WHERE import_id = unhex({importID:String}) AND ended_at > toDateTime(0)")
+		require.NoError(t, err, "deleting the finish record should not produce an error")
+		err = d.server.Conn.Exec(ctx, "DELETE FROM metadatabase.checkpoints WHERE import_id = unhex({importID:String}) AND stage IN ('modified', 'finished')")
+		require.NoError(t, err, "deleting checkpoints should not produce an error")
+
+		incomplete, err = db.GetIncompleteImports()
+		require.NoError(t, err, "getting incomplete imports should not produce an error")
+		require.Len(t, incomplete, 1, "the interrupted import should be incomplete")
+		require.Equal(t, importID.Data, incomplete[0].ImportID.Data, "the interrupted import should be returned")
+		require.Equal(t, database.CheckpointAnalyzed, incomplete[0].Stage, "the interrupted import should have stopped after analysis")
+
+		latestImportID, err := db.GetLatestImportID()
+		require.NoError(t, err, "getting the latest import should not produce an error")
+		require.Equal(t, importID.Data, latestImportID.Data, "the interrupted import should be the latest import")
+
+		var mixtapeCount uint64
+		err = d.server.Conn.QueryRow(context.Background(), "SELECT count() FROM resume_test.threat_mixtape").Scan(&mixtapeCount)
+		require.NoError(t, err, "counting threat mixtape records should not produce an error")
+
+		// resuming runs the modifiers again without importing the logs again
+		results, err = cmd.RunResumeImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, cmd.WalkFiles)
+		require.NoError(t, err, "resuming the import should not produce an error")
+		require.Len(t, results.ImportID, 1, "only the interrupted import should have been run")
+		require.Equal(t, importID.Data, results.ImportID[0].Data, "the interrupted import should have been resumed")
+
+		incomplete, err = db.GetIncompleteImports()
+		require.NoError(t, err, "getting incomplete imports should not produce an error")
+		require.Empty(t, incomplete, "the resumed import should have finished")
+
+		var resumedMixtapeCount uint64
+		err = d.server.Conn.QueryRow(context.Background(), "SELECT count() FROM resume_test.threat_mixtape").Scan(&resumedMixtapeCount)
+		require.NoError(t, err, "counting threat mixtape records should not produce an error")
+		require.Equal(t, mixtapeCount, resumedMixtapeCount, "the modifier records should have been replaced rather than duplicated")
+	})
+
+	d.Run("Interrupted Before Import Finished", func() {
+		t := d.T()
+
+		results, err := cmd.RunImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, true)
+		require.NoError(t, err, "importing data should not produce an error")
+		importID := results.ImportID[0]
+
+		db, err := database.ConnectToDB(context.Background(), "resume_test", d.cfg, nil)
+		require.NoError(t, err, "connecting to database should not produce an error")
+
+		// simulate an import that was killed while its logs were being imported
+		ctx := clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{"importID": importID.Hex()}))
+		err = d.server.Conn.Exec(ctx, "DELETE FROM metadatabase.imports WHERE import_id = unhex({importID:String}) AND ended_at > toDateTime(0)")
+		require.NoError(t, err, "deleting the finish record should not produce an error")
+		err = d.server.Conn.Exec(ctx, "DELETE FROM metadatabase.checkpoints WHERE import_id = unhex({importID:String})")
+		require.NoError(t, err, "deleting checkpoints should not produce an error")
+
+		incomplete, err := db.GetIncompleteImports()
+		require.NoError(t, err, "getting incomplete imports should not produce an error")
+		require.Len(t, incomplete, 1, "the interrupted import should be incomplete")
+		require.Empty(t, incomplete[0].Stage, "the interrupted import should not have a checkpoint")
+
+		counts := countRecordsWrittenDuringImport(t, d, "resume_test")
+
+		// the files of the interrupted import are imported again
+		results, err = cmd.RunResumeImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, cmd.WalkFiles)
+		require.NoError(t, err, "resuming the import should not produce an error")
+		require.Len(t, results.ImportID, 1, "the logs should have been imported again")
+		require.NotEqual(t, importID.Data, results.ImportID[0].Data, "the logs should have been imported under a new import")
+
+		require.Equal(t, counts, countRecordsWrittenDuringImport(t, d, "resume_test"), "the records of the interrupted import should have been replaced rather than duplicated")
+
+		// the interrupted import was abandoned, so it isn't picked up again
+		incomplete, err = db.GetIncompleteImports()
+		require.NoError(t, err, "getting incomplete imports should not produce an error")
+		require.Empty(t, incomplete, "the interrupted import should have been abandoned")
+	})
+
+	d.Run("Interrupted Before Latest Import", func() {
+		t := d.T()
+
+		results, err := cmd.RunImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, true)
+		require.NoError(t, err, "importing data should not produce an error")
+		importID := results.ImportID[0]
+
+		db, err := database.ConnectToDB(context.Background(), "resume_test", d.cfg, nil)
+		require.NoError(t, err, "connecting to database should not produce an error")
+
+		// simulate an import that was killed during analysis and then followed by another import
+		ctx := clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{"importID": importID.Hex()}))
+		err = d.server.Conn.Exec(ctx, "DELETE FROM metadatabase.imports WHERE import_id = unhex({importID:String}) AND ended_at > toDateTime(0)")
+		require.NoError(t, err, "deleting the finish record should not produce an error")
+		err = d.server.Conn.Exec(ctx, "DELETE FROM metadatabase.checkpoints WHERE import_id = unhex({importID:String}) AND stage != 'imported'")
+		require.NoError(t, err, "deleting checkpoints should not produce an error")
+
+		_, err = cmd.RunImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/open_conns/open", "resume_test", false, false)
+		require.NoError(t, err, "importing more data should not produce an error")
+
+		// the interrupted import can't be resumed, so it is abandoned instead of being reported on every resume
+		results, err = cmd.RunResumeImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, cmd.WalkFiles)
+		require.ErrorIs(t, err, importer.ErrAllFilesPreviouslyImported, "nothing should have been imported")
+		require.Empty(t, results.ImportID, "the interrupted import should not have been resumed")
+
+		incomplete, err := db.GetIncompleteImports()
+		require.NoError(t, err, "getting incomplete imports should not produce an error")
+		require.Empty(t, incomplete, "the interrupted import should have been abandoned")
+
+		var stage string
+		err = d.server.Conn.QueryRow(ctx, "SELECT argMax(stage, ts) FROM metadatabase.checkpoints WHERE import_id = unhex({importID:String})").Scan(&stage)
+		require.NoError(t, err, "querying checkpoints should not produce an error")
+		require.Equal(t, database.CheckpointAbandoned, stage, "the interrupted import should have been marked as abandoned")
+	})
+
+	d.Run("Resume With Rebuild", func() {
+		t := d.T()
+
+		_, err := cmd.RunResumeImportCmd(time.Now(), d.cfg, afero.NewOsFs(), "../test_data/valid_tsv", "resume_test", false, cmd.WalkFiles)
+		require.NoError(t, err, "resuming an import into a new dataset should not produce an error")
+
+		_, err = database.SetUpResumedImport(afero.NewOsFs(), d.cfg, "resume_test", false, true)
+		require.ErrorIs(t, err, database.ErrResumeWithRebuild, "resuming with rebuild should produce an error")
+	})
+}
+
+// countRecordsWrittenDuringImport counts the records in the tables that an import writes to directly instead of
+// through the temporary tables
+func countRecordsWrittenDuringImport(t *testing.T, d *DatabaseTestSuite, dbName string) map[string]uint64 {
+	t.Helper()
+
+	counts := make(map[string]uint64)
+	for _, table := range []string{"dns", "pdns_raw", "x509", "dhcp", "lateral_movement"} {
+		var count uint64
+		err := d.server.Conn.QueryRow(context.Background(), fmt.Sprintf("SELECT count() FROM %s.%s", dbName, table)).Scan(&count)
+		require.NoError(t, err, "counting %s records should not produce an error", table)
+		counts[table] = count
+	}
+	return counts
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"fmt"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// sensorDBColumns lists the columns that were added to the tables of a dataset after the table was first created,
+// by table. Datasets created before a column was added are migrated by adding it with its default value.
+var sensorDBColumns = []struct {
+	table   string
+	columns []string
+}{
+	{table: "ssl_tmp", columns: []string{"server_cert_fps Array(String)", "client_cert_fps Array(String)"}},
+	{table: "openssl_tmp", columns: []string{"server_cert_fps Array(String)", "client_cert_fps Array(String)"}},
+	{table: "ssl", columns: []string{"server_cert_fps Array(String)", "client_cert_fps Array(String)"}},
+	{table: "openssl", columns: []string{"server_cert_fps Array(String)", "client_cert_fps Array(String)"}},
+	{table: "pdns_raw", columns: []string{"ttl Nullable(UInt32)"}},
+	{table: "threat_mixtape", columns: []string{
+		// DNS TUNNELING
+		"label_entropy Float32",
+		"avg_label_length Float32",
+		"hex_label_ratio Float32",
+		"base32_label_ratio Float32",
+		"base64_label_ratio Float32",
+		"txt_null_query_ratio Float32",
+		"dns_tunneling_score Float32",
+
+		// DGA
+		"dga_score Float32",
+		"nxdomain_count UInt64",
+		"dga_domains UInt64",
+		"dga_sample_domains Array(String)",
+
+		// EXFILTRATION
+		"bytes_sent Int64",
+		"bytes_received Int64",
+		"off_hours_bytes_sent Int64",
+		"exfiltration_score Float32",
+
+		// PORT SCAN
+		"port_scan_score Float32",
+		"scan_type LowCardinality(String)",
+		"scanned_hosts UInt64",
+		"scanned_ports UInt64",
+
+		// SSH
+		"ssh_brute_force_score Float32",
+		"ssh_auth_attempts UInt64",
+		"ssh_login_after_failures_score Float32",
+		"ssh_failed_logins UInt64",
+
+		// LATERAL MOVEMENT
+		"lateral_movement_score Float32",
+		"lateral_hosts UInt64",
+		"admin_share_hosts UInt64",
+		"service_control_hosts UInt64",
+		"new_service_tickets UInt64",
+
+		// CERTIFICATE ANOMALIES
+		"cert_subject String",
+		"cert_issuer String",
+		"cert_not_valid_before DateTime()",
+		"cert_not_valid_after DateTime()",
+
+		// FAST FLUX
+		"resolved_ip_count UInt64",
+		"resolved_ip_networks UInt64",
+		"resolved_ip_rate Float32",
+		"resolved_ip_ttl UInt32",
+		"resolved_ips Array(IPv6)",
+	}},
+}
+
+// MigrateSensorDB brings a dataset created by an older version of RITA up to date by creating the tables it is missing
+// and adding the columns that its tables are missing, so that it can be viewed and imported into. The records that
+// were imported before the migration don't have the details that are stored in the new tables and columns, so the
+// dataset has to be rebuilt for them to be filled in.
+func (db *DB) MigrateSensorDB() error {
+	rolling, err := GetRollingStatus(db.GetContext(), db.Conn, db.selected)
+	if err != nil {
+		return err
+	}
+	db.Rolling = rolling
+
+	if err := db.createSensorDBTables(); err != nil {
+		return err
+	}
+
+	if err := db.createSensorDBAnalysisTables(); err != nil {
+		return err
+	}
+
+	if err := db.addSensorDBColumns(); err != nil {
+		return err
+	}
+
+	// the tables that were created have to be cleaned up along with the rest of a rolling dataset
+	if db.Rolling {
+		if err := db.createLogTableTTLs(); err != nil {
+			return err
+		}
+
+		if err := db.createSnapshotTableTTLs(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addSensorDBColumns adds the columns that were added to the tables of a dataset after it was created
+func (db *DB) addSensorDBColumns() error {
+	for _, table := range sensorDBColumns {
+		ctx := db.QueryParameters(clickhouse.Parameters{
+			"database": db.selected,
+			"table":    table.table,
+		})
+
+		for _, column := range table.columns {
+			if err := db.Conn.Exec(ctx, `ALTER TABLE {database:Identifier}.{table:Identifier} ADD COLUMN IF NOT EXISTS `+column); err != nil {
+				return fmt.Errorf("could not add column to %s table: %w", table.table, err)
+			}
+		}
+	}
+
+	return nil
+}
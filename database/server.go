@@ -25,12 +25,27 @@ var ErrNoMetaDBImportRecordForDatabase = errors.New("no import record found for
 var ErrDatabaseNotFound = errors.New("database does not exist")
 var ErrDatabaseNameEmpty = errors.New("database name cannot be empty")
 var ErrMissingConfig = errors.New("config cannot be nil")
+var ErrResumeWithRebuild = errors.New("cannot resume an import into a database that is being rebuilt")
 var errImportTwiceNonRolling = errors.New("cannot import more than once to a non-rolling database")
 var errRollingStatusFailure = errors.New("failed to detect rolling status of given import database")
 var errRollingFlagMissing = errors.New("cannot import non-rolling data to a rolling database")
 
 // SetUpNewImport creates the database requested for this import and returns a new DB struct for connection to said database
 func SetUpNewImport(afs afero.Fs, cfg *config.Config, dbName string, rollingFlag bool, rebuildFlag bool) (*DB, error) {
+	return setUpImport(afs, cfg, dbName, rollingFlag, rebuildFlag, false)
+}
+
+// SetUpResumedImport is like SetUpNewImport, but keeps the temporary tables of the import that was interrupted last so
+// that its analysis can be resumed
+func SetUpResumedImport(afs afero.Fs, cfg *config.Config, dbName string, rollingFlag bool, rebuildFlag bool) (*DB, error) {
+	if rebuildFlag {
+		return nil, ErrResumeWithRebuild
+	}
+	return setUpImport(afs, cfg, dbName, rollingFlag, rebuildFlag, true)
+}
+
+// setUpImport creates the database requested for this import and returns a new DB struct for connection to said database
+func setUpImport(afs afero.Fs, cfg *config.Config, dbName string, rollingFlag bool, rebuildFlag bool, resume bool) (*DB, error) {
 	logger := logger.GetLogger()
 
 	// validate parameters
@@ -80,9 +95,12 @@ func SetUpNewImport(afs afero.Fs, cfg *config.Config, dbName string, rollingFlag
 		return nil, err
 	}
 
-	err = db.ResetTemporaryTables()
-	if err != nil {
-		return nil, err
+	// the temporary tables hold the data that an interrupted analysis needs
+	if !resume {
+		err = db.ResetTemporaryTables()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	err = server.syncThreatIntelFeedsFromConfig(afs, cfg)
@@ -110,6 +128,59 @@ func SetUpNewImport(afs afero.Fs, cfg *config.Config, dbName string, rollingFlag
 
 }
 
+// ConnectForDryRun returns the server connection along with a DB struct for checking which files were already imported into
+// the requested database, without creating or modifying any databases. A nil DB is returned if none of the files could have
+// been imported yet, either because the metadatabase doesn't exist or because the database would be rebuilt. The caller
+// must close the server connection once the dry run is done.
+func ConnectForDryRun(cfg *config.Config, dbName string, rollingFlag bool, rebuildFlag bool) (*ServerConn, *DB, error) {
+	// validate parameters
+	if cfg == nil {
+		return nil, nil, ErrMissingConfig
+	}
+
+	if dbName == "" {
+		return nil, nil, ErrDatabaseNameEmpty
+	}
+
+	ctx := context.Background()
+
+	// connect to ClickHouse server
+	server, err := ConnectToServer(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// nothing has been imported if the metadatabase does not exist yet
+	exists, err := DatabaseExists(server.Conn, server.ctx, "metadatabase")
+	if err != nil {
+		server.Conn.Close()
+		return nil, nil, err
+	}
+	if !exists {
+		return server, nil, nil
+	}
+
+	// make sure that the import wouldn't fail due to the rolling status of an existing database
+	rolling, err := server.checkRolling(dbName, rollingFlag, rebuildFlag)
+	if err != nil {
+		server.Conn.Close()
+		return nil, nil, err
+	}
+
+	// every file is imported again when the database is rebuilt
+	if rebuildFlag {
+		return server, nil, nil
+	}
+
+	return server, &DB{
+		Conn:     server.Conn,
+		ctx:      server.ctx,
+		cancel:   server.cancel,
+		selected: dbName,
+		Rolling:  rolling,
+	}, nil
+}
+
 // QueryParameters generates ClickHouse query parameters by creating a context with the specified parameters in it
 func (server *ServerConn) QueryParameters(params clickhouse.Parameters) context.Context {
 	return clickhouse.Context(server.ctx, clickhouse.WithParameters(params))
@@ -184,6 +255,15 @@ func (server *ServerConn) createSensorDatabase(cfg *config.Config, dbName string
 		return nil, err
 	}
 
+	// add the columns that were added to the tables of datasets created by older versions
+	err = db.addSensorDBColumns()
+	if err != nil {
+		logger.Err(err).Str("database", dbName).
+			Str("database connection", cfg.DBConnection).
+			Msg("failed to add missing columns to import database")
+		return nil, err
+	}
+
 	// if the database is rolling, create the necessary TTLs on the tables for cleanup
 	if db.Rolling {
 		if err := db.createLogTableTTLs(); err != nil {
@@ -2,11 +2,76 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"strconv"
+	"sync"
 
 	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
 )
 
+var errSensorTableAlreadyRegistered = errors.New("table is already registered")
+
+// DefaultSensorTableTTL is the TTL that rolling datasets delete the rows of registered tables by if the registration
+// doesn't set one, which keeps them as long as the built-in logs
+const DefaultSensorTableTTL = "import_time + INTERVAL 26 HOURS"
+
+// sensorTable is a table registered with RegisterSensorTable
+type sensorTable struct {
+	createTable string
+	ttl         string
+}
+
+var (
+	sensorTablesMu sync.RWMutex
+	// sensorTables holds the tables registered with RegisterSensorTable, by table name
+	sensorTables = make(map[string]sensorTable)
+)
+
+// RegisterSensorTable registers a table that is created in every dataset along with the built-in tables, such as the
+// table of a log type that was registered with the importer. The statement refers to the dataset as {database:Identifier}.
+// Rolling datasets delete the rows of the table by the ttl expression, or by DefaultSensorTableTTL if ttl is empty, in
+// which case the table must have an import_time column.
+func RegisterSensorTable(name string, createTable string, ttl string) error {
+	sensorTablesMu.Lock()
+	defer sensorTablesMu.Unlock()
+
+	if _, ok := sensorTables[name]; ok {
+		return fmt.Errorf("%w: %s", errSensorTableAlreadyRegistered, name)
+	}
+
+	if ttl == "" {
+		ttl = DefaultSensorTableTTL
+	}
+	sensorTables[name] = sensorTable{createTable: createTable, ttl: ttl}
+	return nil
+}
+
+// registeredSensorTableNames returns the sorted names of the tables registered with RegisterSensorTable, sensorTablesMu
+// must be held by the caller
+func registeredSensorTableNames() []string {
+	names := make([]string, 0, len(sensorTables))
+	for name := range sensorTables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// createRegisteredSensorTables creates the tables registered with RegisterSensorTable, sorted by name
+func (db *DB) createRegisteredSensorTables(ctx context.Context) error {
+	sensorTablesMu.RLock()
+	defer sensorTablesMu.RUnlock()
+
+	for _, name := range registeredSensorTableNames() {
+		if err := db.Conn.Exec(ctx, sensorTables[name].createTable); err != nil {
+			return fmt.Errorf("could not create registered table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
 func (db *DB) createMinMaxMaterializedView() error {
 	ctx := db.QueryParameters(clickhouse.Parameters{
 		"database": db.selected,
@@ -285,6 +350,21 @@ func (db *DB) createBigOlHistogramTable(ctx context.Context) error {
 		return err
 	}
 
+	if err := db.Conn.Exec(ctx, `--sql
+		CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.big_ol_histogram_quic_mv 
+		TO {database:Identifier}.big_ol_histogram AS
+		SELECT
+			toStartOfHour(import_time) as import_hour,
+			hash,
+			toStartOfFifteenMinutes(ts) as bucket,
+			sumSimpleState(src_ip_bytes) as src_ip_bytes,
+			countState() as count
+		FROM {database:Identifier}.quic 
+		GROUP BY (import_hour, hash, bucket)
+	`); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -768,6 +848,8 @@ func (db *DB) createSSLTmpTable(ctx context.Context) error {
 			established Bool,
 			server_cert_fuids Array(String),
 			client_cert_fuids Array(String),
+			server_cert_fps Array(String),
+			client_cert_fps Array(String),
 			server_subject String,
 			server_issuer String,
 			client_subject String,
@@ -817,6 +899,8 @@ func (db *DB) createOpenSSLTmpTable(ctx context.Context) error {
 			established Bool,
 			server_cert_fuids Array(String),
 			client_cert_fuids Array(String),
+			server_cert_fps Array(String),
+			client_cert_fps Array(String),
 			server_subject String,
 			server_issuer String,
 			client_subject String,
@@ -866,6 +950,8 @@ func (db *DB) createSSLTable(ctx context.Context) error {
 			established Bool,
 			server_cert_fuids Array(String),
 			client_cert_fuids Array(String),
+			server_cert_fps Array(String),
+			client_cert_fps Array(String),
 			server_subject String,
 			server_issuer String,
 			client_subject String,
@@ -1076,6 +1162,46 @@ func (db *DB) createUSNIConnTable(ctx context.Context) error {
 	WHERE h.multi_request == false
 	GROUP BY (import_hour, hour, src, src_nuid, src_local, dst_local, dst, dst_nuid, fqdn, hash, proxy);
 	`)
+	if err != nil {
+		return err
+	}
+
+	// quic connections are keyed by the same hash as ssl connections, so QUIC and TLS connections
+	// to the same server name are combined into a single SNI connection
+	err = db.Conn.Exec(ctx, `
+	CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.usni_quic_mv
+	TO {database:Identifier}.usni AS
+	SELECT
+		toStartOfHour(import_time) as import_hour,
+		toStartOfHour(ts) as hour,
+		hash,
+		src,
+		dst,
+		src_nuid,
+		dst_nuid,
+		server_name as fqdn,
+		src_local,
+		dst_local,
+		false as http,
+		false as proxy,
+		countState() as count,
+		uniqExactState(ts) as unique_ts_count,
+		groupArrayState(86400)(toUnixTimestamp(ts)) as ts_list,
+		groupArrayState(86400)(q.src_ip_bytes) as src_ip_bytes_list,
+		sumState(q.src_ip_bytes) as total_src_ip_bytes,
+		sumState(q.dst_ip_bytes) as total_dst_ip_bytes,
+		sumState(q.src_bytes) as total_src_bytes,
+		sumState(q.dst_bytes) as total_dst_bytes,
+		sumState(q.src_ip_bytes + q.dst_ip_bytes) as total_ip_bytes,
+		sumState(q.src_packets) as total_src_packets,
+		sumState(q.dst_packets) as total_dst_packets,
+		sumState(duration) as total_duration,
+		groupUniqArrayState(10)(dst) as server_ips,
+		minState(ts) as first_seen,
+		maxState(ts) as last_seen
+	FROM {database:Identifier}.quic q
+	GROUP BY (import_hour, hour, src, src_nuid, src_local, dst_local, dst, dst_nuid, fqdn, hash);
+	`)
 
 	return err
 }
@@ -1115,6 +1241,8 @@ func (db *DB) createOpenSSLTable(ctx context.Context) error {
 			established Bool,
 			server_cert_fuids Array(String),
 			client_cert_fuids Array(String),
+			server_cert_fps Array(String),
+			client_cert_fps Array(String),
 			server_subject String,
 			server_issuer String,
 			client_subject String,
@@ -1179,6 +1307,21 @@ func (db *DB) createSNIConnTmpImportTable(ctx context.Context) error {
 		return err
 	}
 
+	err = db.Conn.Exec(ctx, `--sql
+	CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.sniconn_quic_tmp_mv 
+	TO {database:Identifier}.sniconn_tmp AS
+	SELECT
+		'quic' as conn_type,
+		hash AS hash,
+		zeek_uid,
+		countState() as count
+	FROM {database:Identifier}.quic
+	GROUP BY (conn_type, hash, zeek_uid)
+	`)
+	if err != nil {
+		return err
+	}
+
 	return err
 }
 
@@ -1279,6 +1422,48 @@ func (db *DB) createUDNSTable(ctx context.Context) error {
 
 }
 
+// createNXDomainTable creates the table that keeps the domains that internal hosts got NXDOMAIN answers for, by hour
+func (db *DB) createNXDomainTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.nxdomain (
+			import_hour DateTime(),
+			hour DateTime(),
+			src IPv6,
+			src_nuid UUID,
+			tld String,
+			fqdn String,
+			count AggregateFunction(count, UInt64),
+			first_seen AggregateFunction(min, DateTime()),
+			last_seen AggregateFunction(max, DateTime())
+		)
+		ENGINE = AggregatingMergeTree()
+		PRIMARY KEY (hour, src_nuid, src, tld, fqdn)
+	`)
+	if err != nil {
+		return err
+	}
+
+	err = db.Conn.Exec(ctx, `--sql
+	CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.nxdomain_mv
+	TO {database:Identifier}.nxdomain AS
+	SELECT
+		toStartOfHour(import_time) as import_hour,
+		toStartOfHour(ts) as hour,
+		src,
+		src_nuid,
+		cutToFirstSignificantSubdomain(query) as tld,
+		query as fqdn,
+		countState() as count,
+		minState(ts) as first_seen,
+		maxState(ts) as last_seen
+	FROM {database:Identifier}.dns
+	WHERE response_code_name = 'NXDOMAIN' AND src_local AND tld != '' AND NOT endsWith(tld, '.arpa') AND NOT endsWith(tld, '.local')
+	GROUP BY (import_hour, hour, src, src_nuid, tld, fqdn)
+	`)
+
+	return err
+}
+
 func (db *DB) createPDNSRawTable(ctx context.Context) error {
 	err := db.Conn.Exec(ctx, `--sql
 		CREATE TABLE IF NOT EXISTS {database:Identifier}.pdns_raw (
@@ -1307,6 +1492,7 @@ func (db *DB) createPDNSRawTable(ctx context.Context) error {
 			recursion_available Bool,
 			z UInt8,
 			resolved_ip IPv6,
+			ttl Nullable(UInt32),
 			ttls Array(UInt32),
 		)
 		ENGINE = MergeTree()
@@ -1446,6 +1632,335 @@ func (db *DB) createExplodedDNSTable(ctx context.Context) error {
 	return err
 }
 
+// createDNSLexicalTable creates the table that totals the lexical features of the subdomains that were queried under
+// each domain, which are used to score domains for DNS tunneling
+func (db *DB) createDNSLexicalTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+	CREATE TABLE IF NOT EXISTS {database:Identifier}.dns_lexical (
+		import_hour DateTime(),
+		hour DateTime(),
+		tld String,
+		queries AggregateFunction(count, UInt64),
+		txt_null_queries AggregateFunction(sum, UInt64),
+		subdomain_queries AggregateFunction(sum, UInt64),
+		entropy AggregateFunction(sum, Float64),
+		labels AggregateFunction(sum, UInt64),
+		label_chars AggregateFunction(sum, UInt64),
+		hex_labels AggregateFunction(sum, UInt64),
+		base32_labels AggregateFunction(sum, UInt64),
+		base64_labels AggregateFunction(sum, UInt64)
+	) ENGINE = AggregatingMergeTree()
+	PRIMARY KEY (hour, tld)
+	`)
+	if err != nil {
+		return err
+	}
+
+	err = db.Conn.Exec(ctx, `--sql
+	CREATE MATERIALIZED VIEW IF NOT EXISTS {database:Identifier}.dns_lexical_mv
+	TO {database:Identifier}.dns_lexical AS
+	SELECT
+		import_hour,
+		hour,
+		tld,
+		countState() as queries,
+		sumState(toUInt64(query_type_name IN ('TXT', 'NULL'))) as txt_null_queries,
+		sumState(toUInt64(subdomain != '')) as subdomain_queries,
+		-- shannon entropy of the characters of the subdomain, without the dots between its labels
+		sumState(arraySum(c -> -(countEqual(chars, c) / length(chars)) * log2(countEqual(chars, c) / length(chars)), arrayDistinct(chars))) as entropy,
+		sumState(toUInt64(length(labels))) as labels,
+		sumState(toUInt64(arraySum(l -> length(l), labels))) as label_chars,
+		-- hex labels mix digits and the letters a-f, so that numbers and words made of the letters a-f aren't counted
+		sumState(toUInt64(arrayCount(l -> match(l, '^[0-9a-fA-F]{8,}$') AND match(l, '[0-9]') AND match(l, '[a-fA-F]'), labels))) as hex_labels,
+		sumState(toUInt64(arrayCount(l -> match(l, '^[a-zA-Z2-7]{16,}$') AND match(l, '[2-7]'), labels))) as base32_labels,
+		sumState(toUInt64(arrayCount(l -> match(l, '^[a-zA-Z0-9_-]{16,}$') AND match(l, '[0-9]') AND match(l, '[a-zA-Z]'), labels))) as base64_labels
+	FROM (
+		SELECT
+			toStartOfHour(import_time) as import_hour,
+			toStartOfHour(ts) as hour,
+			query_type_name,
+			cutToFirstSignificantSubdomain(query) as tld,
+			-- the part of the query in front of the domain
+			if(length(query) > length(tld) + 1, substring(query, 1, length(query) - length(tld) - 1), '') as subdomain,
+			arrayFilter(l -> l != '', splitByChar('.', subdomain)) as labels,
+			splitByString('', replaceAll(subdomain, '.', '')) as chars
+		FROM {database:Identifier}.dns
+	)
+	WHERE tld != '' AND NOT endsWith(tld, '.arpa') AND NOT endsWith(tld, '.local')
+	GROUP BY (import_hour, hour, tld)
+	`)
+
+	return err
+}
+
+func (db *DB) createX509Table(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.x509 (
+			import_time DateTime(),
+			ts DateTime(),
+			fuid String,
+			fingerprint String,
+			version UInt8,
+			serial String,
+			subject String,
+			issuer String,
+			not_valid_before DateTime(),
+			not_valid_after DateTime(),
+			key_alg LowCardinality(String),
+			sig_alg LowCardinality(String),
+			key_type LowCardinality(String),
+			key_length UInt32,
+			exponent String,
+			curve LowCardinality(String),
+			san_dns Array(String),
+			san_uri Array(String),
+			san_email Array(String),
+			san_ip Array(String),
+			basic_constraints_ca Bool,
+			basic_constraints_path_len UInt32,
+			host_cert Bool,
+			client_cert Bool
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (fuid, fingerprint)
+		ORDER BY (fuid, fingerprint, ts)
+	`)
+
+	return err
+}
+
+func (db *DB) createSSHTmpTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.ssh_tmp (
+			import_time DateTime(),
+			zeek_uid FixedString(16),
+			hash FixedString(16),
+			ts DateTime(),
+			src IPv6,
+			dst IPv6,
+			src_nuid UUID,
+			dst_nuid UUID,
+			src_port UInt16,
+			dst_port UInt16,
+			duration Float64,
+			src_local Bool,
+			dst_local Bool,
+			src_bytes Int64,
+			src_ip_bytes Int64,
+			dst_bytes Int64,
+			dst_ip_bytes Int64,
+			src_packets Int64,
+			dst_packets Int64,
+			conn_state LowCardinality(String),
+			proto LowCardinality(String),
+			service LowCardinality(String),
+			version UInt8,
+			auth_success Bool,
+			auth_attempts UInt32,
+			client String,
+			server String,
+			cipher_alg LowCardinality(String),
+			mac_alg LowCardinality(String),
+			compression_alg LowCardinality(String),
+			kex_alg LowCardinality(String),
+			host_key_alg LowCardinality(String),
+			host_key String,
+			hassh String,
+			hassh_server String
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (dst_nuid, src_nuid, src, dst, zeek_uid)
+	`)
+
+	return err
+}
+
+func (db *DB) createSSHTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.ssh (
+			import_time DateTime(),
+			zeek_uid FixedString(16),
+			hash FixedString(16),
+			ts DateTime(),
+			src IPv6,
+			dst IPv6,
+			src_nuid UUID,
+			dst_nuid UUID,
+			src_port UInt16,
+			dst_port UInt16,
+			duration Float64,
+			src_local Bool,
+			dst_local Bool,
+			src_bytes Int64,
+			src_ip_bytes Int64,
+			dst_bytes Int64,
+			dst_ip_bytes Int64,
+			src_packets Int64,
+			dst_packets Int64,
+			conn_state LowCardinality(String),
+			proto LowCardinality(String),
+			service LowCardinality(String),
+			version UInt8,
+			auth_success Bool,
+			auth_attempts UInt32,
+			client String,
+			server String,
+			cipher_alg LowCardinality(String),
+			mac_alg LowCardinality(String),
+			compression_alg LowCardinality(String),
+			kex_alg LowCardinality(String),
+			host_key_alg LowCardinality(String),
+			host_key String,
+			hassh String,
+			hassh_server String
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (dst_nuid, src_nuid, src, dst, hash)
+		ORDER BY (dst_nuid, src_nuid, src, dst, hash, ts)
+	`)
+
+	return err
+}
+
+func (db *DB) createQUICTmpTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.quic_tmp (
+			import_time DateTime(),
+			zeek_uid FixedString(16),
+			hash FixedString(16),
+			ts DateTime(),
+			src IPv6,
+			dst IPv6,
+			src_nuid UUID,
+			dst_nuid UUID,
+			src_port UInt16,
+			dst_port UInt16,
+			duration Float64,
+			src_local Bool,
+			dst_local Bool,
+			src_bytes Int64,
+			src_ip_bytes Int64,
+			dst_bytes Int64,
+			dst_ip_bytes Int64,
+			src_packets Int64,
+			dst_packets Int64,
+			conn_state LowCardinality(String),
+			proto LowCardinality(String),
+			service LowCardinality(String),
+			version LowCardinality(String),
+			client_initial_dcid String,
+			client_scid String,
+			server_scid String,
+			server_name String,
+			client_protocol LowCardinality(String),
+			history String
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (dst_nuid, src_nuid, src, server_name, dst, zeek_uid)
+	`)
+
+	return err
+}
+
+func (db *DB) createQUICTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.quic (
+			import_time DateTime(),
+			zeek_uid FixedString(16),
+			hash FixedString(16),
+			ts DateTime(),
+			src IPv6,
+			dst IPv6,
+			src_nuid UUID,
+			dst_nuid UUID,
+			src_port UInt16,
+			dst_port UInt16,
+			duration Float64,
+			src_local Bool,
+			dst_local Bool,
+			src_bytes Int64,
+			src_ip_bytes Int64,
+			dst_bytes Int64,
+			dst_ip_bytes Int64,
+			src_packets Int64,
+			dst_packets Int64,
+			conn_state LowCardinality(String),
+			proto LowCardinality(String),
+			service LowCardinality(String),
+			version LowCardinality(String),
+			client_initial_dcid String,
+			client_scid String,
+			server_scid String,
+			server_name String,
+			client_protocol LowCardinality(String),
+			history String
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (dst_nuid, src_nuid, src, server_name, dst, hash)
+		ORDER BY (dst_nuid, src_nuid, src, server_name, dst, hash, ts)
+	`)
+
+	return err
+}
+
+// dhcp leases for resolving internal hosts to their hostname and MAC address at a point in time
+func (db *DB) createDHCPTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.dhcp (
+			import_time DateTime(),
+			ip IPv6,
+			nuid UUID,
+			mac String,
+			host_name String,
+			client_fqdn String,
+			domain String,
+			server_addr IPv6,
+			lease_start DateTime(),
+			lease_end DateTime(),
+			lease_time Float64
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (nuid, ip)
+		ORDER BY (nuid, ip, lease_start)
+	`)
+
+	return err
+}
+
+// smb, dce_rpc, ntlm and kerberos activity between internal hosts
+func (db *DB) createLateralMovementTable(ctx context.Context) error {
+	err := db.Conn.Exec(ctx, `--sql
+		CREATE TABLE IF NOT EXISTS {database:Identifier}.lateral_movement (
+			import_time DateTime(),
+			zeek_uid FixedString(16),
+			hash FixedString(16),
+			ts DateTime(),
+			src IPv6,
+			dst IPv6,
+			src_nuid UUID,
+			dst_nuid UUID,
+			src_port UInt16,
+			dst_port UInt16,
+			log_type LowCardinality(String),
+			share String,
+			admin_share Bool,
+			named_pipe LowCardinality(String),
+			endpoint LowCardinality(String),
+			operation LowCardinality(String),
+			service_control Bool,
+			request_type LowCardinality(String),
+			service String,
+			username String,
+			success Bool
+		)
+		ENGINE = MergeTree()
+		PRIMARY KEY (src_nuid, src, dst_nuid, dst)
+		ORDER BY (src_nuid, src, dst_nuid, dst, ts)
+	`)
+
+	return err
+}
+
 func (db *DB) createSensorDBTables() error {
 	ctx := db.QueryParameters(clickhouse.Parameters{
 		"database": db.selected,
@@ -1469,6 +1984,12 @@ func (db *DB) createSensorDBTables() error {
 	if err := db.createOpenHTTPTmpTable(ctx); err != nil {
 		return err
 	}
+	if err := db.createSSHTmpTable(ctx); err != nil {
+		return err
+	}
+	if err := db.createQUICTmpTable(ctx); err != nil {
+		return err
+	}
 
 	if err := db.createConnTable(ctx); err != nil {
 		return err
@@ -1508,6 +2029,11 @@ func (db *DB) createSensorDBTables() error {
 		return err
 	}
 
+	err = db.createQUICTable(ctx)
+	if err != nil {
+		return err
+	}
+
 	err = db.createUSNIConnTable(ctx)
 	if err != nil {
 		return err
@@ -1553,6 +2079,11 @@ func (db *DB) createSensorDBTables() error {
 		return err
 	}
 
+	err = db.createNXDomainTable(ctx)
+	if err != nil {
+		return err
+	}
+
 	err = db.createPDNSRawTable(ctx)
 	if err != nil {
 		return err
@@ -1568,6 +2099,36 @@ func (db *DB) createSensorDBTables() error {
 		return err
 	}
 
+	err = db.createDNSLexicalTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.createX509Table(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.createSSHTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.createDHCPTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.createLateralMovementTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.createRegisteredSensorTables(ctx)
+	if err != nil {
+		return err
+	}
+
 	if err := db.createMinMaxMaterializedView(); err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -15,12 +16,12 @@ import (
 // WHERE database='chickenstrip' and table = 'conn'
 
 var LogTableTTLs = []string{"conn", "http", "ssl", "dns", "pdns_raw"}
-var LogTableViewsHourTTLs = []string{"usni", "udns", "uconn", "mime_type_uris"}
+var LogTableViewsHourTTLs = []string{"usni", "udns", "nxdomain", "uconn", "mime_type_uris"}
 var LogTableViewsDayTTLs = []string{"pdns"}
-var AnalysisSnapshotHourTTLs = []string{"big_ol_histogram", "tls_proto", "http_proto", "exploded_dns", "rare_signatures", "port_info"}
+var AnalysisSnapshotHourTTLs = []string{"big_ol_histogram", "tls_proto", "http_proto", "exploded_dns", "dns_lexical", "rare_signatures", "port_info"}
 var AnalysisSnapshotAnalyzedAtTTLs = []string{"threat_mixtape"}
-var MetaDatabaseTTLs = []string{"historical_first_seen", "files"}
-var MetaDatabaseYearTTLS = []string{"imports"}
+var MetaDatabaseTTLs = []string{"historical_first_seen", "files", "rejected_lines"}
+var MetaDatabaseYearTTLS = []string{"imports", "checkpoints"}
 
 func (db *DB) createLogTableTTLs() error {
 	if !db.Rolling {
@@ -60,6 +61,38 @@ func (db *DB) createLogTableTTLs() error {
 		return err
 	}
 
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.x509 MODIFY TTL import_time + INTERVAL 26 HOURS`)
+	if err != nil {
+		return err
+	}
+
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.ssh MODIFY TTL import_time + INTERVAL 26 HOURS`)
+	if err != nil {
+		return err
+	}
+
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.quic MODIFY TTL import_time + INTERVAL 26 HOURS`)
+	if err != nil {
+		return err
+	}
+
+	// dhcp leases are kept as long as the threat mixtape so that the hosts in older results can still be resolved
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.dhcp MODIFY TTL lease_end + INTERVAL 2 WEEKS`)
+	if err != nil {
+		return err
+	}
+
+	// lateral movement is kept longer than the other logs so that new activity can be told apart from the usual activity
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.lateral_movement MODIFY TTL import_time + INTERVAL 2 WEEKS`)
+	if err != nil {
+		return err
+	}
+
 	// tables populated by materialized views [ TTL on import_hour ]
 	err = db.Conn.Exec(ctx, `--sql
 		ALTER TABLE {database:Identifier}.usni MODIFY TTL import_hour + INTERVAL 26 HOURS`)
@@ -73,6 +106,12 @@ func (db *DB) createLogTableTTLs() error {
 		return err
 	}
 
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.nxdomain MODIFY TTL import_hour + INTERVAL 26 HOURS`)
+	if err != nil {
+		return err
+	}
+
 	err = db.Conn.Exec(ctx, `--sql
 		ALTER TABLE {database:Identifier}.uconn MODIFY TTL import_hour + INTERVAL 26 HOURS`)
 	if err != nil {
@@ -91,6 +130,20 @@ func (db *DB) createLogTableTTLs() error {
 		return err
 	}
 
+	return db.createRegisteredSensorTableTTLs(ctx)
+}
+
+// createRegisteredSensorTableTTLs sets the TTLs of the tables registered with RegisterSensorTable
+func (db *DB) createRegisteredSensorTableTTLs(ctx context.Context) error {
+	sensorTablesMu.RLock()
+	defer sensorTablesMu.RUnlock()
+
+	for _, name := range registeredSensorTableNames() {
+		err := db.Conn.Exec(ctx, fmt.Sprintf("ALTER TABLE {database:Identifier}.%s MODIFY TTL %s", name, sensorTables[name].ttl))
+		if err != nil {
+			return fmt.Errorf("could not set TTL of registered table %s: %w", name, err)
+		}
+	}
 	return nil
 }
 
@@ -126,6 +179,12 @@ func (db *DB) createSnapshotTableTTLs() error {
 		return err
 	}
 
+	err = db.Conn.Exec(ctx, `--sql
+		ALTER TABLE {database:Identifier}.dns_lexical MODIFY TTL import_hour + INTERVAL 2 WEEKS`)
+	if err != nil {
+		return err
+	}
+
 	err = db.Conn.Exec(ctx, `--sql
 		ALTER TABLE {database:Identifier}.rare_signatures MODIFY TTL import_hour + INTERVAL 2 WEEKS`)
 	if err != nil {
@@ -164,6 +223,12 @@ func (server *ServerConn) createMetaDatabaseTTLs(monthsToKeepHistoricalFirstSeen
 		return err
 	}
 
+	err = server.Conn.Exec(ctx, `--sql
+		ALTER TABLE metadatabase.rejected_lines MODIFY TTL ts + INTERVAL 180 DAYS`)
+	if err != nil {
+		return err
+	}
+
 	// DO NOT SET TTL ON ended_at, WILL BREAK
 	err = server.Conn.Exec(ctx, `--sql
 		ALTER TABLE metadatabase.imports MODIFY TTL toDateTime(started_at) + INTERVAL 1 YEAR`)
@@ -171,5 +236,11 @@ func (server *ServerConn) createMetaDatabaseTTLs(monthsToKeepHistoricalFirstSeen
 		return err
 	}
 
+	err = server.Conn.Exec(ctx, `--sql
+		ALTER TABLE metadatabase.checkpoints MODIFY TTL toDateTime(started_at) + INTERVAL 1 YEAR`)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,381 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/afero/zipfs"
+)
+
+// ArchiveFs is a filesystem that exposes the tar and zip archives of the filesystem it wraps as read-only directories,
+// so that the logs inside of them can be walked and imported without unpacking them by hand first. The path of a file
+// inside of an archive is the path of the archive joined with the member name (ie, /logs/bundle.tar.gz/2024-04-19/conn.log.gz),
+// so files are still tracked separately in the metadatabase and importing the same archive again skips them.
+//
+// Logs that span many hours can also be split into a file per hour while they are walked, see SplitLogHours.
+type ArchiveFs struct {
+	afero.Fs
+	mu       sync.Mutex
+	archives map[string]*openArchive
+	// hourLogs maps the hour paths of split logs to the file that each hour was written to in hourDir
+	hourLogs     map[string]string
+	hourDir      string
+	hourLogCount int
+}
+
+// openArchive is an archive that was opened by an ArchiveFs
+type openArchive struct {
+	path string
+	fs   afero.Fs
+	info os.FileInfo
+	// file is the archive file, which is kept open for zip archives since their members are read on demand
+	file afero.File
+	// dir is the temporary directory that the members of a tar archive were unpacked into
+	dir string
+}
+
+// archiveInfo describes an archive as a directory
+type archiveInfo struct {
+	os.FileInfo
+}
+
+// archiveMemberInfo describes a file inside of an archive, which can be read as long as the archive can be read
+type archiveMemberInfo struct {
+	os.FileInfo
+}
+
+// NewArchiveFs returns a filesystem that exposes the tar and zip archives of afs as directories
+func NewArchiveFs(afs afero.Fs) *ArchiveFs {
+	return &ArchiveFs{
+		Fs:       afs,
+		archives: make(map[string]*openArchive),
+		hourLogs: make(map[string]string),
+	}
+}
+
+// IsArchiveFile returns whether the file is a tar or zip archive, based on its file extension. Tar archives may
+// also be compressed (ie, logs.tar.gz or logs.tgz).
+func IsArchiveFile(path string) bool {
+	return strings.HasSuffix(path, ".zip") || strings.HasSuffix(path, ".tgz") || strings.HasSuffix(TrimCompressionExtension(path), ".tar")
+}
+
+func (afs *ArchiveFs) Name() string { return "ArchiveFs" }
+
+func (afs *ArchiveFs) Open(name string) (afero.File, error) {
+	archive, member, err := afs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return afs.Fs.Open(name)
+	}
+	return archive.fs.Open(member)
+}
+
+func (afs *ArchiveFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	archive, member, err := afs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return afs.Fs.OpenFile(name, flag, perm)
+	}
+
+	// archives are read-only
+	if flag != os.O_RDONLY {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EPERM}
+	}
+	return archive.fs.Open(member)
+}
+
+func (afs *ArchiveFs) Stat(name string) (os.FileInfo, error) {
+	archive, member, err := afs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return afs.Fs.Stat(name)
+	}
+
+	if member == string(filepath.Separator) {
+		return archiveInfo{archive.info}, nil
+	}
+
+	info, err := archive.fs.Stat(member)
+	if err != nil {
+		return nil, err
+	}
+	return archiveMemberInfo{info}, nil
+}
+
+// LstatIfPossible calls Lstat on the wrapped filesystem if it supports it, so that walking a directory with
+// afero.Walk doesn't follow symlinks outside of archives
+func (afs *ArchiveFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	archive, _, err := afs.resolve(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if lstater, ok := afs.Fs.(afero.Lstater); ok && archive == nil {
+		return lstater.LstatIfPossible(name)
+	}
+
+	info, err := afs.Stat(name)
+	return info, false, err
+}
+
+// FileID identifies the files inside of an archive by the identity that the wrapped filesystem gives to the archive
+// joined with the member name, and all other files by the identity given to them by the wrapped filesystem
+func (afs *ArchiveFs) FileID(name string) (string, error) {
+	archive, member, err := afs.resolve(name)
+	if err != nil {
+		return "", err
+	}
+
+	if archive == nil {
+		return GetFileID(afs.Fs, name)
+	}
+
+	archiveID, err := GetFileID(afs.Fs, archive.path)
+	if err != nil {
+		return "", err
+	}
+	return archiveID + member, nil
+}
+
+// Close closes the archives that were opened and removes the hours of the logs that were split
+func (afs *ArchiveFs) Close() error {
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	var errs []error
+	for _, archive := range afs.archives {
+		errs = append(errs, archive.close())
+	}
+	afs.archives = make(map[string]*openArchive)
+
+	if afs.hourDir != "" {
+		errs = append(errs, os.RemoveAll(afs.hourDir))
+		afs.hourDir = ""
+	}
+	afs.hourLogs = make(map[string]string)
+
+	return errors.Join(errs...)
+}
+
+// CloseArchive closes a single archive that was opened, so that the space it takes up can be freed once all of its
+// files have been imported. The archive is opened again if any of its files are accessed afterwards.
+func (afs *ArchiveFs) CloseArchive(path string) error {
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	archive, ok := afs.archives[path]
+	if !ok {
+		return nil
+	}
+	delete(afs.archives, path)
+
+	return archive.close()
+}
+
+// ArchivePath returns the path of the archive that contains the named file, or false if the file isn't inside of an archive
+func (afs *ArchiveFs) ArchivePath(name string) (string, bool) {
+	archive, _, err := afs.resolve(name)
+	if err != nil || archive == nil {
+		return "", false
+	}
+	return archive.path, true
+}
+
+// resolve returns the archive that contains the named file along with the name of the file inside of the archive,
+// or a nil archive if the file isn't inside of an archive
+func (afs *ArchiveFs) resolve(name string) (*openArchive, string, error) {
+	name = filepath.Clean(name)
+
+	for i := 1; i <= len(name); i++ {
+		// only check the paths of the directories leading up to the file and the file itself
+		if i < len(name) && name[i] != filepath.Separator {
+			continue
+		}
+
+		archivePath := name[:i]
+		if !IsArchiveFile(archivePath) {
+			continue
+		}
+
+		archive, err := afs.openArchive(archivePath)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// directories can also have archive extensions
+		if archive == nil {
+			continue
+		}
+
+		return archive, string(filepath.Separator) + strings.TrimPrefix(name[i:], string(filepath.Separator)), nil
+	}
+
+	return nil, "", nil
+}
+
+// openArchive opens the archive at path, returning a nil archive if the path isn't a file in the wrapped filesystem
+func (afs *ArchiveFs) openArchive(path string) (*openArchive, error) {
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	if archive, ok := afs.archives[path]; ok {
+		return archive, nil
+	}
+
+	info, err := afs.Fs.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, nil //nolint:nilerr // the error is returned by the wrapped filesystem when the path is accessed
+	}
+
+	archive := &openArchive{path: path, info: info}
+	if strings.HasSuffix(path, ".zip") {
+		archive.fs, archive.file, err = afs.openZipArchive(path, info.Size())
+	} else {
+		archive.fs, archive.dir, err = afs.openTarArchive(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open archive %s: %w", path, err)
+	}
+
+	afs.archives[path] = archive
+	return archive, nil
+}
+
+// openZipArchive opens a zip archive, whose members are decompressed as they are read
+func (afs *ArchiveFs) openZipArchive(path string, size int64) (afero.Fs, afero.File, error) {
+	file, err := afs.Fs.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	// not every zip tool writes entries for directories, which are needed to walk the archive
+	members := len(reader.File)
+	dirs := make(map[string]bool)
+	for _, member := range reader.File[:members] {
+		for dir := filepath.Dir(strings.TrimSuffix(member.Name, "/")); dir != "." && dir != "/" && !dirs[dir]; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+			reader.File = append(reader.File, &zip.File{FileHeader: zip.FileHeader{Name: dir + "/", Modified: member.Modified}})
+		}
+	}
+
+	return zipfs.New(reader), file, nil
+}
+
+// openTarArchive unpacks a tar archive into a temporary directory, since the members of a tar archive can't be read on
+// demand. The archive is streamed, so only one member is held at a time no matter how large the archive is.
+func (afs *ArchiveFs) openTarArchive(path string) (afero.Fs, string, error) {
+	reader, closeFile, err := openLogFile(afs.Fs, path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer closeFile()
+
+	// tgz is a shorthand for tar.gz, which isn't a compression extension on its own
+	if strings.HasSuffix(path, ".tgz") {
+		gzipReader, err := gzipDecompressor(reader)
+		if err != nil {
+			return nil, "", err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	dir, err := os.MkdirTemp("", "rita-archive-")
+	if err != nil {
+		return nil, "", err
+	}
+	dirFs := afero.NewBasePathFs(afero.NewOsFs(), dir)
+
+	if err := unpackTarArchive(dirFs, reader); err != nil {
+		return nil, "", errors.Join(err, os.RemoveAll(dir))
+	}
+
+	return dirFs, dir, nil
+}
+
+// unpackTarArchive copies each directory and file of a tar archive into dirFs
+func unpackTarArchive(dirFs afero.Fs, reader io.Reader) error {
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Join(string(filepath.Separator), header.Name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = dirFs.MkdirAll(name, 0o755)
+		case tar.TypeReg:
+			err = writeArchiveMember(dirFs, name, tarReader)
+		default:
+			// links and special files can't hold logs
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// keep the modification time, which is used to pick between duplicate logs
+		if err := dirFs.Chtimes(name, header.ModTime, header.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+// writeArchiveMember copies a file out of an archive into the directory that the archive is unpacked into
+func writeArchiveMember(dirFs afero.Fs, name string, reader io.Reader) error {
+	if err := dirFs.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	file, err := dirFs.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, reader)
+	return err
+}
+
+// close closes the archive file of a zip archive, or removes the directory that a tar archive was unpacked into
+func (archive *openArchive) close() error {
+	if archive.file != nil {
+		return archive.file.Close()
+	}
+	if archive.dir != "" {
+		return os.RemoveAll(archive.dir)
+	}
+	return nil
+}
+
+func (info archiveInfo) Mode() os.FileMode { return os.ModeDir | info.FileInfo.Mode().Perm() }
+
+func (info archiveInfo) IsDir() bool { return true }
+
+func (info archiveMemberInfo) Mode() os.FileMode { return info.FileInfo.Mode() | 0o444 }
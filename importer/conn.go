@@ -235,3 +235,16 @@ func (importer *Importer) writeUnfilteredConns(progress *tea.Program, open bool,
 	progress.Send(progressbar.ProgressSpinnerMsg(spinnerID))
 	return err
 }
+
+// minHeaderSize returns the minimum size of the ip and transport headers of a packet for the given protocol,
+// used to estimate payload bytes for flow records that only count bytes including headers
+func minHeaderSize(proto string) int64 {
+	switch proto {
+	case "tcp":
+		return 40
+	case "udp", "icmp":
+		return 28
+	default:
+		return 20
+	}
+}
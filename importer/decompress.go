@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Decompressor wraps a reader of compressed data in a reader that returns the decompressed data
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	// decompressors holds the decompressor for each compressed file extension
+	decompressors = map[string]Decompressor{
+		".gz":   gzipDecompressor,
+		".zst":  zstdDecompressor,
+		".zstd": zstdDecompressor,
+		".bz2":  bzip2Decompressor,
+		".xz":   xzDecompressor,
+	}
+)
+
+// RegisterDecompressor registers the decompressor used to read files ending in the given extension (ie, ".lz4"),
+// replacing any decompressor that was already registered for it
+func RegisterDecompressor(ext string, decompressor Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors[strings.ToLower(ext)] = decompressor
+}
+
+// getDecompressor returns the decompressor registered for the extension of the file, if any
+func getDecompressor(path string) (Decompressor, bool) {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+	decompressor, ok := decompressors[strings.ToLower(filepath.Ext(path))]
+	return decompressor, ok
+}
+
+// IsCompressedFile returns whether the file extension insinuates that the file is compressed
+func IsCompressedFile(path string) bool {
+	_, ok := getDecompressor(path)
+	return ok
+}
+
+// TrimCompressionExtension removes the compression extension from the path of a compressed file (ie, conn.log.zst
+// becomes conn.log), so that the compressed variants of a file can be matched with each other
+func TrimCompressionExtension(path string) string {
+	if !IsCompressedFile(path) {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+func gzipDecompressor(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func zstdDecompressor(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+func bzip2Decompressor(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(bzip2.NewReader(r)), nil
+}
+
+func xzDecompressor(r io.Reader) (io.ReadCloser, error) {
+	reader, err := xz.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(reader), nil
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer/zeektypes"
+	"activecm/rita/util"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errMissingAssignedAddr = errors.New("blank or missing assigned_addr field in dhcp log entry, skipping entry")
+
+type DHCPEntry struct {
+	ImportTime time.Time `ch:"import_time"`
+	IP         net.IP    `ch:"ip"`
+	NUID       uuid.UUID `ch:"nuid"`
+	MAC        string    `ch:"mac"`
+	HostName   string    `ch:"host_name"`
+	ClientFQDN string    `ch:"client_fqdn"`
+	Domain     string    `ch:"domain"`
+	ServerAddr net.IP    `ch:"server_addr"`
+	LeaseStart time.Time `ch:"lease_start"`
+	LeaseEnd   time.Time `ch:"lease_end"`
+	LeaseTime  float64   `ch:"lease_time"`
+}
+
+// parseDHCP listens on a channel of raw dhcp log records, formats them into leases and sends them to be written to the database
+func parseDHCP(dhcp <-chan zeektypes.DHCP, output chan<- database.Data, importTime time.Time, numDHCP *uint64) {
+	// loop over raw dhcp channel
+	for d := range dhcp {
+
+		// parse raw record as a dhcp lease
+		entry, err := formatDHCPRecord(&d, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numDHCP, 1)
+	}
+}
+
+// formatDHCPRecord takes a raw dhcp record and formats it into the lease structure needed by the database.
+// Only transactions that assigned an address to the client are leases, all other transactions return an error.
+func formatDHCPRecord(parseDHCP *zeektypes.DHCP, importTime time.Time) (*DHCPEntry, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(parseDHCP.AssignedAddr)
+	if ip == nil || ip.IsUnspecified() {
+		return nil, errMissingAssignedAddr
+	}
+
+	if cfg.Filter.FilterSingleIP(ip) {
+		return nil, nil
+	}
+
+	leaseStart := time.Unix(int64(parseDHCP.TimeStamp), 0)
+	if parseDHCP.LeaseTime < 0 || math.IsNaN(parseDHCP.LeaseTime) {
+		return nil, fmt.Errorf("invalid lease time %v for dhcp lease of %s", parseDHCP.LeaseTime, ip)
+	}
+
+	// leases without a lease time are kept until the address is assigned again
+	leaseEnd := leaseStart.Add(time.Duration(parseDHCP.LeaseTime * float64(time.Second)))
+
+	entry := &DHCPEntry{
+		ImportTime: importTime,
+		IP:         ip,
+		NUID:       util.ParseNetworkID(ip, parseDHCP.AgentUUID),
+		MAC:        parseDHCP.MAC,
+		HostName:   parseDHCP.HostName,
+		ClientFQDN: parseDHCP.ClientFQDN,
+		Domain:     parseDHCP.Domain,
+		ServerAddr: net.ParseIP(parseDHCP.ServerAddr),
+		LeaseStart: leaseStart,
+		LeaseEnd:   leaseEnd,
+		LeaseTime:  parseDHCP.LeaseTime,
+	}
+
+	// the server address isn't always logged, but the column can't be null
+	if entry.ServerAddr == nil {
+		entry.ServerAddr = net.IPv6unspecified
+	}
+
+	return entry, nil
+}
@@ -45,8 +45,9 @@ type DNSEntry struct {
 	Answers             []string         `ch:"answers"`
 	TTLs                []float64        `ch:"ttls"`
 	Rejected            bool             `ch:"rejected"`
-	// PDNS field
-	ResolvedIP net.IP `ch:"resolved_ip"`
+	// PDNS fields
+	ResolvedIP net.IP  `ch:"resolved_ip"`
+	TTL        *uint32 `ch:"ttl"` // TTL of the answer with the resolved IP, nil if the answer had no TTL
 }
 
 type UniqueFQDN struct {
@@ -191,7 +192,7 @@ func parsePDNSRecord(dnsRecord *DNSEntry, writeChan chan<- database.Data, numDNS
 
 		// storing resolved IPs as an IPv6 column instead of an Array(IPv6) column significantly improves the
 		// lookup time of resolved IPs
-		for _, answer := range dnsRecord.Answers {
+		for i, answer := range dnsRecord.Answers {
 			answerIP := net.ParseIP(answer)
 			// Check if answer is an IP address and store it if it is
 			if answerIP != nil {
@@ -200,6 +201,11 @@ func parsePDNSRecord(dnsRecord *DNSEntry, writeChan chan<- database.Data, numDNS
 				// assigning the resolved IP and sending it to the writer
 				newEntry := *dnsRecord
 				newEntry.ResolvedIP = answerIP.To16()
+				// the ttls are listed in the same order as the answers
+				if i < len(dnsRecord.TTLs) {
+					ttl := uint32(dnsRecord.TTLs[i])
+					newEntry.TTL = &ttl
+				}
 				writeChan <- &newEntry
 				atomic.AddUint64(numDNS, 1) // increment pdns counter
 			}
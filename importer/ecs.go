@@ -0,0 +1,406 @@
+package importer
+
+import (
+	"activecm/rita/importer/zeektypes"
+	zerolog "activecm/rita/logger"
+	"activecm/rita/util"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/afero"
+)
+
+var ErrECSExportHasNoRecords = errors.New("ECS export does not contain any records with a valid @timestamp")
+
+// ECSFileExtensions are the file extensions of Elastic Common Schema NDJSON exports
+var ECSFileExtensions = []string{".ndjson", ".json"}
+
+// IsECSFile returns whether the file is an Elastic Common Schema NDJSON export (ie, ecs-zeek.ndjson, ecs_2024-04-19.json.gz)
+func IsECSFile(path string) bool {
+	base := TrimCompressionExtension(filepath.Base(path))
+	if !strings.HasPrefix(base, ECSPrefix) {
+		return false
+	}
+	for _, ext := range ECSFileExtensions {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetECSHours returns each hour that contains records in an ECS export, using the @timestamp field of each record
+func GetECSHours(afs afero.Fs, path string) ([]time.Time, error) {
+	return readLogHours(afs, path, ecsLineHour, nil, ErrECSExportHasNoRecords)
+}
+
+// ecsLineHour reads the hour of a record of an ECS export from its @timestamp field
+func ecsLineHour(line []byte) (time.Time, bool, error) {
+	var record struct {
+		TimeStamp zeektypes.ECSTimestamp `json:"@timestamp"`
+	}
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(line, &record); err != nil || record.TimeStamp == 0 {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(int64(record.TimeStamp.Unix()), 0).UTC().Truncate(time.Hour), true, nil
+}
+
+// parseECSFile scans through an Elastic Common Schema NDJSON export, converting each zeek conn, dns, http and ssl
+// record into its zeek equivalent and sending it on the matching entry channel. Records of other datasets are ignored.
+// Exports usually span many hours, so only the records of the hour in the hour path are sent, see HourPath.
+func parseECSFile(afs afero.Fs, hourPath string, entryChannels EntryChans, errc chan<- error, rejectedLines chan<- RejectedLine, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
+	logger := zerolog.GetLogger()
+
+	// exports that weren't split into hours are imported in full
+	path, hour, hourErr := splitHourPath(hourPath)
+	if hourErr != nil {
+		path = hourPath
+	}
+
+	// open file for reading
+	empty, err := afero.IsEmpty(afs, path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("could not determine if file is empty")
+		return
+	}
+
+	// skip file if it is empty and log a warning
+	if empty {
+		logger.Warn().Str("path", path).Msg("failed to parse log file: file is empty")
+		return
+	}
+
+	fileHash, err := util.NewFixedStringHash(hourPath)
+	if err != nil {
+		logger.Err(err).Str("path", hourPath).Msg("could not hash file path")
+		return
+	}
+
+	metaDBFileEntry := MetaDBFile{
+		importID: importID,
+		database: database,
+		fileHash: fileHash,
+		path:     hourPath,
+	}
+
+	scanner, closeFile, err := newLogScanner(afs, hourPath)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse log file: could not open file")
+		return
+	}
+	defer closeFile()
+
+	// the file is only marked as imported once a valid ECS record has been read from it
+	isECS := false
+
+	// create line error counter which will allow us to stop scanning in lines from
+	// a file that had more than a certain amount of errors
+	lineErrorCounter := 0
+
+	previousLineHadError := false
+
+	// iterate over lines in file
+	for scanner.Scan() {
+		// track the line number so that rejected lines can be found in the file
+		lineNumber := scanner.LineNumber()
+
+		// skip empty lines
+		if len(scanner.Bytes()) < 1 {
+			continue
+		}
+
+		// verify that the file is JSON before parsing any records
+		if !isECS {
+			if scanner.Bytes()[0] != '{' || !jsoniter.ConfigCompatibleWithStandardLibrary.Valid(scanner.Bytes()) {
+				logger.Err(errUnknownFileType).Str("path", path).Send()
+				errc <- errUnknownFileType
+				return
+			}
+			isECS = true
+			metaDBChan <- metaDBFileEntry
+		}
+
+		previousLineHadError = false
+
+		var record zeektypes.ECS
+		if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(scanner.Bytes(), &record); err != nil {
+			logger.Err(err).Str("path", path).Bytes("record", scanner.Bytes()).Msg("failed to unmarshal line from JSON")
+			rejectedLines <- RejectedLine{path: path, lineNumber: lineNumber, line: scanner.Text(), err: err, skipped: true}
+			lineErrorCounter++
+			previousLineHadError = true
+			if lineErrorCounter > lineErrorLimit {
+				logger.Warn().Str("path", path).Msg("failed to parse log file: file is potentially corrupted")
+				rejectedLines <- RejectedLine{path: path, lineNumber: lineNumber, line: scanner.Text(), err: errTooManyLineErrors, skipped: true}
+				// set this flag to false so that we don't log that this file could be truncated
+				previousLineHadError = false
+				break
+			}
+			continue
+		}
+		record.SetLogPath(path)
+
+		// skip records that belong to a different hour of the export
+		if hourErr == nil && !timestampInHour(record.TimeStamp.Unix(), hour) {
+			continue
+		}
+
+		// send the converted record to its appropriate channel
+		switch ecsDataset(&record) {
+		case ConnPrefix:
+			entryChannels.Conn <- ecsToConn(&record)
+		case DNSPrefix:
+			entryChannels.DNS <- ecsToDNS(&record)
+		case HTTPPrefix:
+			entryChannels.HTTP <- ecsToHTTP(&record)
+		case SSLPrefix:
+			entryChannels.SSL <- ecsToSSL(&record)
+		}
+	}
+
+	// handle error from scanner
+	if err := scanner.Err(); err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse log file: could not scan the file")
+		return
+	}
+
+	// if last line of log had an error, indicate that file may be truncated
+	if previousLineHadError {
+		logger.Err(errTruncated).Str("path", path).Send()
+		errc <- errTruncated
+	}
+}
+
+// ecsDataset returns the prefix of the zeek log that an ECS record came from, or an empty string if it didn't come
+// from one of the logs that can be converted. Records without a dataset are matched by the fields that they contain.
+func ecsDataset(record *zeektypes.ECS) string {
+	switch record.Event.Dataset {
+	case "zeek.connection", "zeek.conn":
+		return ConnPrefix
+	case "zeek.dns":
+		if record.DNS != nil && record.DNS.Question.Name != "" {
+			return DNSPrefix
+		}
+		return ""
+	case "zeek.http":
+		return HTTPPrefix
+	case "zeek.ssl":
+		return SSLPrefix
+	}
+
+	// records of any other dataset, such as the other zeek logs or other integrations, are ignored
+	if record.Event.Dataset != "" {
+		return ""
+	}
+
+	switch {
+	case record.Source.IP == "" || record.Destination.IP == "":
+		return ""
+	case record.DNS != nil && record.DNS.Question.Name != "":
+		return DNSPrefix
+	case record.HTTP != nil || record.URL != nil:
+		return HTTPPrefix
+	case record.TLS != nil:
+		return SSLPrefix
+	case record.Network.Transport != "":
+		return ConnPrefix
+	default:
+		return ""
+	}
+}
+
+// ecsUID returns the zeek uid of an ECS record, falling back to the event id if the zeek fields weren't exported
+func ecsUID(record *zeektypes.ECS) string {
+	if record.Zeek.SessionID != "" {
+		return record.Zeek.SessionID
+	}
+	return string(record.Event.ID)
+}
+
+// ecsHostname returns the name of the sensor that produced an ECS record
+func ecsHostname(record *zeektypes.ECS) string {
+	for _, name := range []string{record.Observer.Hostname, record.Observer.Name, record.Host.Hostname, record.Host.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// ecsToConn converts an ECS zeek.connection record into a zeek conn record
+func ecsToConn(record *zeektypes.ECS) zeektypes.Conn {
+	conn := zeektypes.Conn{
+		TimeStamp:       record.TimeStamp.Unix(),
+		UID:             ecsUID(record),
+		Source:          record.Source.IP,
+		SourcePort:      record.Source.Port,
+		Destination:     record.Destination.IP,
+		DestinationPort: record.Destination.Port,
+		Proto:           eveProto(record.Network.Transport),
+		Service:         record.Network.Protocol,
+		Duration:        max(0, record.Event.Duration/float64(time.Second)),
+		OrigBytes:       record.Source.Bytes,
+		RespBytes:       record.Destination.Bytes,
+		ConnState:       record.Zeek.Connection.State,
+		LocalOrigin:     record.Zeek.Connection.LocalOrig,
+		LocalResponse:   record.Zeek.Connection.LocalResp,
+		MissedBytes:     record.Zeek.Connection.MissedBytes,
+		History:         record.Zeek.Connection.History,
+		OrigPackets:     record.Source.Packets,
+		OrigIPBytes:     record.Zeek.Connection.OrigIPBytes,
+		RespPackets:     record.Destination.Packets,
+		RespIPBytes:     record.Zeek.Connection.RespIPBytes,
+		AgentHostname:   ecsHostname(record),
+		LogPath:         record.LogPath,
+	}
+
+	// the ip level byte counts aren't part of ECS, so estimate them by adding the minimum header size of each packet
+	// if they weren't exported
+	if conn.OrigIPBytes == 0 && conn.OrigPackets > 0 {
+		conn.OrigIPBytes = conn.OrigBytes + conn.OrigPackets*minHeaderSize(conn.Proto)
+	}
+	if conn.RespIPBytes == 0 && conn.RespPackets > 0 {
+		conn.RespIPBytes = conn.RespBytes + conn.RespPackets*minHeaderSize(conn.Proto)
+	}
+
+	// zeek logs the icmp type and code in place of the ports, which ECS exports without ports
+	if conn.Proto == "icmp" && conn.SourcePort == 0 && conn.DestinationPort == 0 {
+		conn.SourcePort = record.Zeek.Connection.ICMP.Type
+		conn.DestinationPort = record.Zeek.Connection.ICMP.Code
+	}
+
+	return conn
+}
+
+// ecsToDNS converts an ECS zeek.dns record into a zeek dns record
+func ecsToDNS(record *zeektypes.ECS) zeektypes.DNS {
+	dns := zeektypes.DNS{
+		TimeStamp:       record.TimeStamp.Unix(),
+		UID:             ecsUID(record),
+		Source:          record.Source.IP,
+		SourcePort:      record.Source.Port,
+		Destination:     record.Destination.IP,
+		DestinationPort: record.Destination.Port,
+		Proto:           eveProto(record.Network.Transport),
+		TransID:         record.DNS.ID.Int(),
+		Query:           record.DNS.Question.Name,
+		QClass:          1,
+		QClassName:      "C_INTERNET",
+		QType:           dnsQueryTypeCodes[record.DNS.Question.Type],
+		QTypeName:       record.DNS.Question.Type,
+		RCode:           dnsResponseCodes[record.DNS.ResponseCode],
+		RCodeName:       record.DNS.ResponseCode,
+		AA:              slices.Contains(record.DNS.HeaderFlags, "AA"),
+		TC:              slices.Contains(record.DNS.HeaderFlags, "TC"),
+		RD:              slices.Contains(record.DNS.HeaderFlags, "RD"),
+		RA:              slices.Contains(record.DNS.HeaderFlags, "RA"),
+		AgentHostname:   ecsHostname(record),
+		LogPath:         record.LogPath,
+	}
+
+	// the class is only exported by some integrations, in which case it is usually IN
+	if class := record.DNS.Question.Class; class != "" && class != "IN" {
+		dns.QClass, dns.QClassName = 0, class
+	}
+
+	for _, answer := range record.DNS.Answers {
+		if answer.Data == "" {
+			continue
+		}
+		dns.Answers = append(dns.Answers, answer.Data)
+		dns.TTLs = append(dns.TTLs, answer.TTL)
+	}
+
+	// fall back to the resolved addresses if the answers weren't exported
+	if len(dns.Answers) == 0 {
+		dns.Answers = slices.Clone(record.DNS.ResolvedIP)
+	}
+
+	return dns
+}
+
+// ecsToHTTP converts an ECS zeek.http record into a zeek http record
+func ecsToHTTP(record *zeektypes.ECS) zeektypes.HTTP {
+	http := zeektypes.HTTP{
+		TimeStamp:       record.TimeStamp.Unix(),
+		UID:             ecsUID(record),
+		Source:          record.Source.IP,
+		SourcePort:      record.Source.Port,
+		Destination:     record.Destination.IP,
+		DestinationPort: record.Destination.Port,
+		AgentHostname:   ecsHostname(record),
+		LogPath:         record.LogPath,
+	}
+
+	if record.URL != nil {
+		http.Host = record.URL.Domain
+		http.URI = record.URL.Original
+	}
+
+	if record.UserAgent != nil {
+		http.UserAgent = record.UserAgent.Original
+	}
+
+	if record.HTTP != nil {
+		http.Method = record.HTTP.Request.Method
+		http.Referrer = record.HTTP.Request.Referrer
+		http.Version = record.HTTP.Version
+		http.ReqLen = record.HTTP.Request.Body.Bytes
+		http.RespLen = record.HTTP.Response.Body.Bytes
+		http.StatusCode = record.HTTP.Response.StatusCode
+
+		// strip any parameters from the mime type (ie, text/html; charset=UTF-8)
+		if mimeType, _, _ := strings.Cut(record.HTTP.Response.MimeType, ";"); strings.TrimSpace(mimeType) != "" {
+			http.RespMimeTypes = []string{strings.TrimSpace(mimeType)}
+		}
+	}
+
+	return http
+}
+
+// ecsToSSL converts an ECS zeek.ssl record into a zeek ssl record
+func ecsToSSL(record *zeektypes.ECS) zeektypes.SSL {
+	ssl := zeektypes.SSL{
+		TimeStamp:       record.TimeStamp.Unix(),
+		UID:             ecsUID(record),
+		Source:          record.Source.IP,
+		SourcePort:      record.Source.Port,
+		Destination:     record.Destination.IP,
+		DestinationPort: record.Destination.Port,
+		AgentHostname:   ecsHostname(record),
+		LogPath:         record.LogPath,
+	}
+
+	if record.TLS != nil {
+		ssl.Version = ecsTLSVersion(record.TLS.VersionProtocol, record.TLS.Version)
+		ssl.Cipher = record.TLS.Cipher
+		ssl.ServerName = record.TLS.Client.ServerName
+		ssl.Resumed = record.TLS.Resumed
+		ssl.Established = record.TLS.Established
+		ssl.Subject = record.TLS.Server.Subject
+		ssl.Issuer = record.TLS.Server.Issuer
+		ssl.JA3 = record.TLS.Client.JA3
+		ssl.JA3S = record.TLS.Server.JA3S
+	}
+
+	return ssl
+}
+
+// ecsTLSVersion converts an ECS tls version (ie, tls and 1.2) into the version names used by zeek (ie, TLSv12)
+func ecsTLSVersion(protocol, version string) string {
+	if version == "" {
+		return ""
+	}
+
+	switch strings.ToLower(protocol) {
+	case "", "tls":
+		return "TLSv" + strings.ReplaceAll(version, ".", "")
+	case "ssl":
+		return "SSLv" + strings.ReplaceAll(version, ".", "")
+	default:
+		return version
+	}
+}
@@ -0,0 +1,404 @@
+package importer
+
+import (
+	"activecm/rita/importer/zeektypes"
+	zerolog "activecm/rita/logger"
+	"activecm/rita/util"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/afero"
+)
+
+var ErrEVELogHasNoRecords = errors.New("EVE log does not contain any events with a valid timestamp")
+
+// dnsQueryTypeCodes maps the record type names logged by Suricata to their numeric codes
+var dnsQueryTypeCodes = map[string]int64{
+	"A": 1, "NS": 2, "CNAME": 5, "SOA": 6, "NULL": 10, "PTR": 12, "HINFO": 13, "MX": 15, "TXT": 16,
+	"AAAA": 28, "SRV": 33, "NAPTR": 35, "DS": 43, "RRSIG": 46, "NSEC": 47, "DNSKEY": 48,
+	"SVCB": 64, "HTTPS": 65, "ANY": 255,
+}
+
+// dnsResponseCodes maps the response code names logged by Suricata to their numeric codes
+var dnsResponseCodes = map[string]int64{
+	"NOERROR": 0, "FORMERR": 1, "SERVFAIL": 2, "NXDOMAIN": 3, "NOTIMP": 4, "REFUSED": 5,
+	"YXDOMAIN": 6, "YXRRSET": 7, "NXRRSET": 8, "NOTAUTH": 9, "NOTZONE": 10,
+}
+
+// parseEVEFile scans through a Suricata EVE JSON log, converting each flow, dns, http and tls event into its
+// equivalent zeek record and sending it on the matching entry channel. All other event types are ignored.
+// EVE logs often span many hours, so only the events of the hour in the hour path are sent, see HourPath.
+func parseEVEFile(afs afero.Fs, hourPath string, entryChannels EntryChans, errc chan<- error, rejectedLines chan<- RejectedLine, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
+	logger := zerolog.GetLogger()
+
+	// logs that weren't split into hours are imported in full
+	path, hour, hourErr := splitHourPath(hourPath)
+	if hourErr != nil {
+		path = hourPath
+	}
+
+	// open file for reading
+	empty, err := afero.IsEmpty(afs, path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("could not determine if file is empty")
+		return
+	}
+
+	// skip file if it is empty and log a warning
+	if empty {
+		logger.Warn().Str("path", path).Msg("failed to parse log file: file is empty")
+		return
+	}
+
+	fileHash, err := util.NewFixedStringHash(hourPath)
+	if err != nil {
+		logger.Err(err).Str("path", hourPath).Msg("could not hash file path")
+		return
+	}
+
+	metaDBFileEntry := MetaDBFile{
+		importID: importID,
+		database: database,
+		fileHash: fileHash,
+		path:     hourPath,
+	}
+
+	scanner, closeFile, err := newLogScanner(afs, hourPath)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse log file: could not open file")
+		return
+	}
+	defer closeFile()
+
+	// the file is only marked as imported once a valid EVE record has been read from it
+	isEVE := false
+
+	// create line error counter which will allow us to stop scanning in lines from
+	// a file that had more than a certain amount of errors
+	lineErrorCounter := 0
+
+	previousLineHadError := false
+
+	// iterate over lines in file
+	for scanner.Scan() {
+		// track the line number so that rejected lines can be found in the file
+		lineNumber := scanner.LineNumber()
+
+		// skip empty lines
+		if len(scanner.Bytes()) < 1 {
+			continue
+		}
+
+		// verify that the file is JSON before parsing any records
+		if !isEVE {
+			if scanner.Bytes()[0] != '{' || !jsoniter.ConfigCompatibleWithStandardLibrary.Valid(scanner.Bytes()) {
+				logger.Err(errUnknownFileType).Str("path", path).Send()
+				errc <- errUnknownFileType
+				return
+			}
+			isEVE = true
+			metaDBChan <- metaDBFileEntry
+		}
+
+		previousLineHadError = false
+
+		var event zeektypes.EVE
+		if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(scanner.Bytes(), &event); err != nil {
+			logger.Err(err).Str("path", path).Bytes("record", scanner.Bytes()).Msg("failed to unmarshal line from JSON")
+			rejectedLines <- RejectedLine{path: path, lineNumber: lineNumber, line: scanner.Text(), err: err, skipped: true}
+			lineErrorCounter++
+			previousLineHadError = true
+			if lineErrorCounter > lineErrorLimit {
+				logger.Warn().Str("path", path).Msg("failed to parse log file: file is potentially corrupted")
+				rejectedLines <- RejectedLine{path: path, lineNumber: lineNumber, line: scanner.Text(), err: errTooManyLineErrors, skipped: true}
+				// set this flag to false so that we don't log that this file could be truncated
+				previousLineHadError = false
+				break
+			}
+			continue
+		}
+		event.SetLogPath(path)
+
+		// skip events that belong to a different hour of the log
+		if hourErr == nil && !timestampInHour(event.TimeStamp.Unix(), hour) {
+			continue
+		}
+
+		// send the converted event to its appropriate channel
+		switch event.EventType {
+		case "flow":
+			if event.Flow != nil {
+				entryChannels.Conn <- eveToConn(&event)
+			}
+		case "dns":
+			if dns, ok := eveToDNS(&event); ok {
+				entryChannels.DNS <- dns
+			}
+		case "http":
+			if event.HTTP != nil {
+				entryChannels.HTTP <- eveToHTTP(&event)
+			}
+		case "tls":
+			if event.TLS != nil {
+				entryChannels.SSL <- eveToSSL(&event)
+			}
+		}
+	}
+
+	// handle error from scanner
+	if err := scanner.Err(); err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse log file: could not scan the file")
+		return
+	}
+
+	// if last line of log had an error, indicate that file may be truncated
+	if previousLineHadError {
+		logger.Err(errTruncated).Str("path", path).Send()
+		errc <- errTruncated
+	}
+}
+
+// GetEVEHours returns each hour that contains events in a Suricata EVE JSON log, using the timestamp field of each event
+func GetEVEHours(afs afero.Fs, path string) ([]time.Time, error) {
+	return readLogHours(afs, path, eveLineHour, nil, ErrEVELogHasNoRecords)
+}
+
+// eveLineHour reads the hour of an event of an EVE log from its timestamp field
+func eveLineHour(line []byte) (time.Time, bool, error) {
+	var event struct {
+		TimeStamp zeektypes.EVETimestamp `json:"timestamp"`
+	}
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(line, &event); err != nil || event.TimeStamp == 0 {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(int64(event.TimeStamp.Unix()), 0).UTC().Truncate(time.Hour), true, nil
+}
+
+// eveToConn converts an EVE flow event into a zeek conn record
+func eveToConn(event *zeektypes.EVE) zeektypes.Conn {
+	conn := zeektypes.Conn{
+		TimeStamp:       event.Flow.Start.Unix(),
+		UID:             strconv.FormatUint(event.FlowID, 10),
+		Source:          event.Source,
+		SourcePort:      event.SourcePort,
+		Destination:     event.Destination,
+		DestinationPort: event.DestinationPort,
+		Proto:           eveProto(event.Proto),
+		Service:         eveService(event.AppProto),
+		Duration:        float64(event.Flow.End - event.Flow.Start),
+		OrigIPBytes:     event.Flow.BytesToServer,
+		RespIPBytes:     event.Flow.BytesToClient,
+		OrigPackets:     event.Flow.PktsToServer,
+		RespPackets:     event.Flow.PktsToClient,
+		ConnState:       eveConnState(event),
+		AgentHostname:   event.Host,
+		LogPath:         event.LogPath,
+	}
+
+	// EVE flows only count bytes including headers, so estimate the payload bytes by subtracting
+	// the minimum header size of each packet
+	conn.OrigBytes = max(0, conn.OrigIPBytes-conn.OrigPackets*minHeaderSize(conn.Proto))
+	conn.RespBytes = max(0, conn.RespIPBytes-conn.RespPackets*minHeaderSize(conn.Proto))
+
+	// zeek logs the icmp type and code in place of the ports
+	if conn.Proto == "icmp" {
+		conn.SourcePort = event.ICMPType
+		conn.DestinationPort = event.ICMPCode
+	}
+
+	if conn.Duration < 0 {
+		conn.Duration = 0
+	}
+
+	return conn
+}
+
+// eveToDNS converts an EVE dns event into a zeek dns record. Only responses are converted, since
+// Suricata logs each answered query a second time as a response containing the answers.
+func eveToDNS(event *zeektypes.EVE) (zeektypes.DNS, bool) {
+	if event.DNS == nil || (event.DNS.Type != "answer" && event.DNS.Type != "response") {
+		return zeektypes.DNS{}, false
+	}
+
+	// version 2 logs the question at the top level, version 3 logs a list of questions
+	query, queryType := event.DNS.RRName, event.DNS.RRType
+	if query == "" && len(event.DNS.Queries) > 0 {
+		query, queryType = event.DNS.Queries[0].RRName, event.DNS.Queries[0].RRType
+	}
+
+	dns := zeektypes.DNS{
+		TimeStamp:       event.TimeStamp.Unix(),
+		UID:             strconv.FormatUint(event.FlowID, 10),
+		Source:          event.Source,
+		SourcePort:      event.SourcePort,
+		Destination:     event.Destination,
+		DestinationPort: event.DestinationPort,
+		Proto:           eveProto(event.Proto),
+		TransID:         event.DNS.ID,
+		Query:           query,
+		QClass:          1,
+		QClassName:      "C_INTERNET",
+		QType:           dnsQueryTypeCodes[queryType],
+		QTypeName:       queryType,
+		RCode:           dnsResponseCodes[event.DNS.RCode],
+		RCodeName:       event.DNS.RCode,
+		AA:              event.DNS.AA,
+		TC:              event.DNS.TC,
+		RD:              event.DNS.RD,
+		RA:              event.DNS.RA,
+		AgentHostname:   event.Host,
+		LogPath:         event.LogPath,
+	}
+
+	// answers are either logged individually (detailed format) or grouped by record type (grouped format)
+	for _, answer := range event.DNS.Answers {
+		if answer.RData == "" {
+			continue
+		}
+		dns.Answers = append(dns.Answers, answer.RData)
+		dns.TTLs = append(dns.TTLs, float64(answer.TTL))
+	}
+
+	if len(dns.Answers) == 0 && len(event.DNS.Grouped) > 0 {
+		recordTypes := make([]string, 0, len(event.DNS.Grouped))
+		for recordType := range event.DNS.Grouped {
+			recordTypes = append(recordTypes, recordType)
+		}
+		slices.Sort(recordTypes)
+
+		for _, recordType := range recordTypes {
+			for _, rdata := range event.DNS.Grouped[recordType] {
+				// only plain record data can be stored as an answer, skip structured records (ie, SOA)
+				if answer, ok := rdata.(string); ok && answer != "" {
+					dns.Answers = append(dns.Answers, answer)
+				}
+			}
+		}
+	}
+
+	return dns, true
+}
+
+// eveToHTTP converts an EVE http event into a zeek http record
+func eveToHTTP(event *zeektypes.EVE) zeektypes.HTTP {
+	http := zeektypes.HTTP{
+		TimeStamp:       event.TimeStamp.Unix(),
+		UID:             strconv.FormatUint(event.FlowID, 10),
+		Source:          event.Source,
+		SourcePort:      event.SourcePort,
+		Destination:     event.Destination,
+		DestinationPort: event.DestinationPort,
+		Method:          event.HTTP.Method,
+		Host:            event.HTTP.Hostname,
+		URI:             event.HTTP.URL,
+		Referrer:        event.HTTP.Referrer,
+		Version:         strings.TrimPrefix(event.HTTP.Protocol, "HTTP/"),
+		UserAgent:       event.HTTP.UserAgent,
+		RespLen:         event.HTTP.Length,
+		StatusCode:      event.HTTP.Status,
+		AgentHostname:   event.Host,
+		LogPath:         event.LogPath,
+	}
+
+	// strip any parameters from the content type to get the mime type (ie, text/html; charset=UTF-8)
+	if mimeType, _, _ := strings.Cut(event.HTTP.ContentType, ";"); strings.TrimSpace(mimeType) != "" {
+		http.RespMimeTypes = []string{strings.TrimSpace(mimeType)}
+	}
+
+	return http
+}
+
+// eveToSSL converts an EVE tls event into a zeek ssl record
+func eveToSSL(event *zeektypes.EVE) zeektypes.SSL {
+	ssl := zeektypes.SSL{
+		TimeStamp:       event.TimeStamp.Unix(),
+		UID:             strconv.FormatUint(event.FlowID, 10),
+		Source:          event.Source,
+		SourcePort:      event.SourcePort,
+		Destination:     event.Destination,
+		DestinationPort: event.DestinationPort,
+		Version:         eveTLSVersion(event.TLS.Version),
+		ServerName:      event.TLS.SNI,
+		Subject:         event.TLS.Subject,
+		Issuer:          event.TLS.Issuer,
+		AgentHostname:   event.Host,
+		LogPath:         event.LogPath,
+	}
+
+	// Suricata logs tls events once the handshake has been parsed, so the session is
+	// considered established unless the version couldn't be determined
+	ssl.Established = event.TLS.Version != "" && event.TLS.Version != "UNDETERMINED"
+
+	if event.TLS.JA3 != nil {
+		ssl.JA3 = event.TLS.JA3.Hash
+	}
+	if event.TLS.JA3S != nil {
+		ssl.JA3S = event.TLS.JA3S.Hash
+	}
+
+	return ssl
+}
+
+// eveProto converts an EVE protocol name into the protocol names used by zeek
+func eveProto(proto string) string {
+	proto = strings.ToLower(proto)
+	if proto == "ipv6-icmp" {
+		return "icmp"
+	}
+	return proto
+}
+
+// eveService converts an EVE app_proto into the service names used by zeek
+func eveService(appProto string) string {
+	switch appProto {
+	case "", "failed", "unknown":
+		return ""
+	case "tls":
+		return "ssl"
+	default:
+		return appProto
+	}
+}
+
+// eveTLSVersion converts an EVE tls version (ie, TLS 1.2) into the version names used by zeek (ie, TLSv12)
+func eveTLSVersion(version string) string {
+	if v, ok := strings.CutPrefix(version, "TLS "); ok {
+		return "TLSv" + strings.ReplaceAll(v, ".", "")
+	}
+	if version == "UNDETERMINED" {
+		return ""
+	}
+	return version
+}
+
+// eveConnState approximates the zeek conn_state of an EVE flow event
+func eveConnState(event *zeektypes.EVE) string {
+	if event.TCP == nil {
+		// other protocols have no connection state, so mimic zeek's behavior of marking them as attempted or seen
+		if event.Flow.PktsToClient == 0 {
+			return "S0"
+		}
+		return "SF"
+	}
+
+	switch {
+	// connection attempt seen, no reply
+	case event.Flow.PktsToClient == 0:
+		return "S0"
+	// connection reset
+	case event.TCP.RST:
+		return "RSTO"
+	// normal establishment and termination
+	case event.TCP.State == "closed" || event.TCP.FIN:
+		return "SF"
+	// connection established, not terminated
+	case event.TCP.SYN && event.TCP.ACK:
+		return "S1"
+	default:
+		return "OTH"
+	}
+}
@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"activecm/rita/config"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// logTypePrefix returns the prefix of the built-in or registered log type that the file at path belongs to, using the
+// longest prefix that the name of the file starts with
+func logTypePrefix(path string) (string, bool) {
+	recordTypesMu.RLock()
+	prefixes := slices.Concat(builtinPrefixes, registeredPrefixes())
+	recordTypesMu.RUnlock()
+
+	name := filepath.Base(path)
+	var logType string
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(logType) {
+			logType = prefix
+		}
+	}
+	return logType, logType != ""
+}
+
+// fieldMapping maps the names that the fields of a log are recorded under to the names of the RITA fields that they
+// are parsed into. The fields of JSON records can be nested objects, which are named by the dotted path of their keys.
+type fieldMapping struct {
+	names map[string]string
+	// objects holds the paths of the JSON objects that a mapped field is nested in, ie "source" for "source.ip"
+	objects map[string]bool
+}
+
+// newFieldMapping returns the field mapping for the names, or nil if there are none
+func newFieldMapping(names map[string]string) *fieldMapping {
+	if len(names) == 0 {
+		return nil
+	}
+
+	objects := make(map[string]bool)
+	for name := range names {
+		for i, c := range name {
+			if c == '.' {
+				objects[name[:i]] = true
+			}
+		}
+	}
+	return &fieldMapping{names: names, objects: objects}
+}
+
+// fieldMappingFor returns the field mapping configured for the log type of the file at path. Logs are parsed without
+// a mapping if none is configured for their log type or the config hasn't been loaded.
+func fieldMappingFor(path string) *fieldMapping {
+	logType, ok := logTypePrefix(path)
+	if !ok {
+		return nil
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil || cfg == nil {
+		return nil
+	}
+	return newFieldMapping(cfg.FieldMapping[logType])
+}
+
+// mapFieldNames renames the fields of a TSV header using the field mapping. A field that has the name that another
+// field in the header is renamed to is ignored, so that the renamed field takes its place.
+func (mapping *fieldMapping) mapFieldNames(fields []string) {
+	if mapping == nil {
+		return
+	}
+
+	// only the fields that are renamed in this header take the place of another field
+	targets := make(map[string]bool, len(mapping.names))
+	for _, name := range fields {
+		if to, ok := mapping.names[name]; ok {
+			targets[to] = true
+		}
+	}
+
+	for i, name := range fields {
+		switch to, ok := mapping.names[name]; {
+		case ok:
+			fields[i] = to
+		case targets[name]:
+			fields[i] = ""
+		}
+	}
+}
+
+// unmarshalJSON decodes a JSON record into the struct that record points to, renaming its keys using the field mapping
+// as they are read. A key that has the name that another key is renamed to is ignored, so that the renamed key takes its
+// place. Records are decoded as usual if there is no mapping.
+func (mapping *fieldMapping) unmarshalJSON(line []byte, record any) error {
+	if mapping == nil {
+		return jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(line, record)
+	}
+
+	value := reflect.ValueOf(record).Elem()
+	fields := jsonFieldIndexes(value.Type())
+
+	iter := jsoniter.ConfigCompatibleWithStandardLibrary.BorrowIterator(line)
+	defer jsoniter.ConfigCompatibleWithStandardLibrary.ReturnIterator(iter)
+
+	mapped := make(map[string]bool)
+	mapping.readObject(iter, "", value, fields, mapped)
+	if iter.Error != nil && !errors.Is(iter.Error, io.EOF) {
+		return iter.Error
+	}
+	return nil
+}
+
+// readObject decodes the keys of the JSON object at path into the fields of record, descending into the objects that
+// mapped fields are nested in. mapped tracks the fields that were set from a renamed key.
+func (mapping *fieldMapping) readObject(iter *jsoniter.Iterator, path string, record reflect.Value, fields map[string][]int, mapped map[string]bool) {
+	iter.ReadObjectCB(func(iter *jsoniter.Iterator, key string) bool {
+		name := path + key
+		to, renamed := mapping.names[name]
+
+		switch {
+		case renamed && fields[to] != nil:
+			iter.ReadVal(record.FieldByIndex(fields[to]).Addr().Interface())
+			mapped[to] = true
+		case mapping.objects[name] && iter.WhatIsNext() == jsoniter.ObjectValue:
+			mapping.readObject(iter, name+".", record, fields, mapped)
+		case path == "" && !renamed && !mapped[name] && fields[name] != nil:
+			iter.ReadVal(record.FieldByIndex(fields[name]).Addr().Interface())
+		default:
+			iter.Skip()
+		}
+		return iter.Error == nil
+	})
+}
+
+// jsonFields caches the JSON field indexes of the struct types that records are decoded into, see jsonFieldIndexes
+var jsonFields sync.Map
+
+// jsonFieldIndexes returns the indexes of the fields of the struct type by the name of their JSON key
+func jsonFieldIndexes(structType reflect.Type) map[string][]int {
+	if fields, ok := jsonFields.Load(structType); ok {
+		return fields.(map[string][]int)
+	}
+
+	fields := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(structType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		fields[name] = field.Index
+	}
+
+	jsonFields.Store(structType, fields)
+	return fields
+}
@@ -0,0 +1,641 @@
+package importer
+
+import (
+	"activecm/rita/importer/zeektypes"
+	zerolog "activecm/rita/logger"
+	"activecm/rita/util"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var errUnsupportedFlowVersion = errors.New("unsupported flow export version, expected NetFlow v9 or IPFIX")
+var errMalformedFlowMessage = errors.New("malformed flow export message")
+
+// FlowFileExtensions are the file extensions of raw NetFlow v9 / IPFIX capture files, which consist of
+// export messages written back to back (ie, RFC 5655 IPFIX files)
+var FlowFileExtensions = []string{".ipfix", ".netflow"}
+
+const (
+	netflowV9Version = 9
+	ipfixVersion     = 10
+
+	netflowV9HeaderLength = 20
+	ipfixHeaderLength     = 16
+
+	// set ids of template sets, all other set ids below 256 are options templates or reserved
+	netflowV9TemplateSetID = 0
+	ipfixTemplateSetID     = 2
+	minDataSetID           = 256
+
+	// ipfixVariableLength marks a variable length field in an IPFIX template
+	ipfixVariableLength = 65535
+
+	// ipfixReversePEN is the enterprise number of the reverse information elements of bidirectional flows (RFC 5103)
+	ipfixReversePEN = 29305
+)
+
+// information element ids used to build conn records, shared by NetFlow v9 and IPFIX
+const (
+	ieOctetDeltaCount            = 1
+	iePacketDeltaCount           = 2
+	ieProtocolIdentifier         = 4
+	ieTCPControlBits             = 6
+	ieSourceTransportPort        = 7
+	ieSourceIPv4Address          = 8
+	ieDestinationTransportPort   = 11
+	ieDestinationIPv4Address     = 12
+	ieFlowEndSysUpTime           = 21
+	ieFlowStartSysUpTime         = 22
+	ieSourceIPv6Address          = 27
+	ieDestinationIPv6Address     = 28
+	ieICMPTypeCodeIPv4           = 32
+	ieOctetTotalCount            = 85
+	iePacketTotalCount           = 86
+	ieICMPTypeCodeIPv6           = 139
+	ieFlowStartSeconds           = 150
+	ieFlowEndSeconds             = 151
+	ieFlowStartMilliseconds      = 152
+	ieFlowEndMilliseconds        = 153
+	ieSystemInitTimeMilliseconds = 160
+)
+
+// tcp control bits
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpRST = 0x04
+	tcpACK = 0x10
+)
+
+type flowTemplateKey struct {
+	domain uint32
+	id     uint16
+}
+
+type flowTemplateField struct {
+	id         uint16
+	length     uint16
+	enterprise uint32
+}
+
+// flowMessageHeader holds the fields of a NetFlow v9 / IPFIX message header needed to resolve flow timestamps
+type flowMessageHeader struct {
+	version    uint16
+	exportTime time.Time
+	sysUptime  uint32 // NetFlow v9 only
+	domain     uint32
+}
+
+// flowRecord holds the fields of a single flow record needed to build a conn record
+type flowRecord struct {
+	src          net.IP
+	dst          net.IP
+	srcPort      int
+	dstPort      int
+	proto        uint8
+	bytes        int64
+	packets      int64
+	revBytes     int64
+	revPackets   int64
+	tcpFlags     uint16
+	icmpTypeCode uint16
+	start        time.Time
+	end          time.Time
+	startUptime  int64
+	endUptime    int64
+	sysInitTime  int64
+}
+
+// flowDecoder decodes a stream of NetFlow v9 / IPFIX export messages, tracking the templates announced in the stream
+type flowDecoder struct {
+	reader    *bufio.Reader
+	templates map[flowTemplateKey][]flowTemplateField
+}
+
+func newFlowDecoder(reader io.Reader) *flowDecoder {
+	return &flowDecoder{
+		reader:    bufio.NewReader(reader),
+		templates: make(map[flowTemplateKey][]flowTemplateField),
+	}
+}
+
+// parseFlowFile decodes a NetFlow v9 / IPFIX capture file and sends a conn record for each flow record on the conn channel.
+// Flow exports don't contain any application layer data, so only conn records are produced.
+func parseFlowFile(afs afero.Fs, path string, entryChan chan<- zeektypes.Conn, errc chan<- error, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
+	logger := zerolog.GetLogger()
+
+	// open file for reading
+	empty, err := afero.IsEmpty(afs, path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("could not determine if file is empty")
+		return
+	}
+
+	// skip file if it is empty and log a warning
+	if empty {
+		logger.Warn().Str("path", path).Msg("failed to parse log file: file is empty")
+		return
+	}
+
+	fileHash, err := util.NewFixedStringHash(path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("could not hash file path")
+		return
+	}
+
+	metaDBFileEntry := MetaDBFile{
+		importID: importID,
+		database: database,
+		fileHash: fileHash,
+		path:     path,
+	}
+
+	reader, closeFile, err := openLogFile(afs, path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse log file: could not open file")
+		return
+	}
+	defer closeFile()
+
+	decoder := newFlowDecoder(reader)
+
+	numMessages, numRecords := 0, 0
+	for {
+		records, err := decoder.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			// the file can't be read any further since the start of the next message is unknown
+			if numMessages == 0 {
+				logger.Err(err).Str("path", path).Msg("failed to parse log file: is file a NetFlow v9 or IPFIX capture?")
+				errc <- errUnknownFileType
+				return
+			}
+			logger.Err(err).Str("path", path).Int("message", numMessages).Msg("failed to parse flow export message")
+			errc <- errTruncated
+			return
+		}
+
+		// mark the file as imported once the first message was decoded successfully
+		if numMessages == 0 {
+			metaDBChan <- metaDBFileEntry
+		}
+		numMessages++
+
+		for i := range records {
+			entryChan <- flowToConn(&records[i], path, numRecords)
+			numRecords++
+		}
+	}
+
+	// report templates that were never announced in the file, since their records had to be skipped
+	if numRecords == 0 && numMessages > 0 {
+		logger.Warn().Str("path", path).Msg("no flow records could be decoded from file, are the templates exported in the same file?")
+	}
+}
+
+// GetFlowFirstTimestamp returns the export time of the first message in a NetFlow v9 / IPFIX capture file.
+// It is used to determine which hour a capture file belongs to when its filename doesn't contain the hour.
+func GetFlowFirstTimestamp(afs afero.Fs, path string) (time.Time, error) {
+	reader, closeFile, err := openLogFile(afs, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer closeFile()
+
+	header, _, err := newFlowDecoder(reader).readMessage()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return header.exportTime.UTC(), nil
+}
+
+// IsFlowFile returns whether the file is a NetFlow v9 / IPFIX capture file, based on its file extension
+func IsFlowFile(path string) bool {
+	trimmed := TrimCompressionExtension(path)
+	for _, ext := range FlowFileExtensions {
+		if strings.HasSuffix(trimmed, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// readMessage reads the next message in the stream, returning its header and its sets
+func (d *flowDecoder) readMessage() (flowMessageHeader, [][]byte, error) {
+	var header flowMessageHeader
+
+	versionBytes, err := d.reader.Peek(2)
+	if err != nil {
+		if len(versionBytes) == 0 {
+			return header, nil, io.EOF
+		}
+		return header, nil, errMalformedFlowMessage
+	}
+
+	header.version = binary.BigEndian.Uint16(versionBytes)
+
+	switch header.version {
+	case netflowV9Version:
+		buf := make([]byte, netflowV9HeaderLength)
+		if _, err := io.ReadFull(d.reader, buf); err != nil {
+			return header, nil, errMalformedFlowMessage
+		}
+		header.sysUptime = binary.BigEndian.Uint32(buf[4:8])
+		header.exportTime = time.Unix(int64(binary.BigEndian.Uint32(buf[8:12])), 0)
+		header.domain = binary.BigEndian.Uint32(buf[16:20])
+
+		sets, err := d.readNetflowV9Sets()
+		return header, sets, err
+	case ipfixVersion:
+		buf := make([]byte, ipfixHeaderLength)
+		if _, err := io.ReadFull(d.reader, buf); err != nil {
+			return header, nil, errMalformedFlowMessage
+		}
+		header.exportTime = time.Unix(int64(binary.BigEndian.Uint32(buf[4:8])), 0)
+		header.domain = binary.BigEndian.Uint32(buf[12:16])
+
+		// the message length includes the header
+		length := int(binary.BigEndian.Uint16(buf[2:4]))
+		if length < ipfixHeaderLength {
+			return header, nil, errMalformedFlowMessage
+		}
+		body := make([]byte, length-ipfixHeaderLength)
+		if _, err := io.ReadFull(d.reader, body); err != nil {
+			return header, nil, errMalformedFlowMessage
+		}
+
+		sets, err := splitIPFIXSets(body)
+		return header, sets, err
+	default:
+		return header, nil, fmt.Errorf("%w: %d", errUnsupportedFlowVersion, header.version)
+	}
+}
+
+// next decodes the next message in the stream, returning the flow records it contains
+func (d *flowDecoder) next() ([]flowRecord, error) {
+	header, sets, err := d.readMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []flowRecord
+	for _, set := range sets {
+		setID := binary.BigEndian.Uint16(set[0:2])
+		body := set[4:]
+
+		switch {
+		case setID == netflowV9TemplateSetID && header.version == netflowV9Version,
+			setID == ipfixTemplateSetID && header.version == ipfixVersion:
+			if err := d.parseTemplateSet(header, body); err != nil {
+				return nil, err
+			}
+		case setID >= minDataSetID:
+			template, ok := d.templates[flowTemplateKey{domain: header.domain, id: setID}]
+			// skip data sets whose template hasn't been seen yet
+			if !ok {
+				continue
+			}
+			setRecords, err := parseDataSet(header, template, body)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, setRecords...)
+		}
+		// options templates and their data sets are skipped
+	}
+
+	return records, nil
+}
+
+// splitIPFIXSets splits the body of an IPFIX message into its sets
+func splitIPFIXSets(body []byte) ([][]byte, error) {
+	var sets [][]byte
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return nil, errMalformedFlowMessage
+		}
+		length := int(binary.BigEndian.Uint16(body[2:4]))
+		if length < 4 || length > len(body) {
+			return nil, errMalformedFlowMessage
+		}
+		sets = append(sets, body[:length])
+		body = body[length:]
+	}
+	return sets, nil
+}
+
+// readNetflowV9Sets reads all of the sets of the current NetFlow v9 message. NetFlow v9 messages don't contain their
+// length, so sets are read until the start of the next message (a set id of 9 is reserved) or the end of the stream.
+func (d *flowDecoder) readNetflowV9Sets() ([][]byte, error) {
+	var sets [][]byte
+	for {
+		next, err := d.reader.Peek(2)
+		if len(next) < 2 || binary.BigEndian.Uint16(next) == netflowV9Version {
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return sets, nil
+		}
+
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(d.reader, header); err != nil {
+			return nil, errMalformedFlowMessage
+		}
+
+		length := int(binary.BigEndian.Uint16(header[2:4]))
+		if length < 4 {
+			return nil, errMalformedFlowMessage
+		}
+
+		set := make([]byte, length)
+		copy(set, header)
+		if _, err := io.ReadFull(d.reader, set[4:]); err != nil {
+			return nil, errMalformedFlowMessage
+		}
+		sets = append(sets, set)
+	}
+}
+
+// parseTemplateSet stores the templates announced in a template set
+func (d *flowDecoder) parseTemplateSet(header flowMessageHeader, body []byte) error {
+	for len(body) >= 4 {
+		templateID := binary.BigEndian.Uint16(body[0:2])
+		fieldCount := int(binary.BigEndian.Uint16(body[2:4]))
+		body = body[4:]
+
+		// a template withdrawal or padding at the end of the set
+		if fieldCount == 0 {
+			delete(d.templates, flowTemplateKey{domain: header.domain, id: templateID})
+			continue
+		}
+
+		fields := make([]flowTemplateField, 0, fieldCount)
+		for i := 0; i < fieldCount; i++ {
+			if len(body) < 4 {
+				return errMalformedFlowMessage
+			}
+			field := flowTemplateField{
+				id:     binary.BigEndian.Uint16(body[0:2]),
+				length: binary.BigEndian.Uint16(body[2:4]),
+			}
+			body = body[4:]
+
+			// the enterprise bit is only used by IPFIX, indicating that the enterprise number follows the field
+			if header.version == ipfixVersion && field.id&0x8000 != 0 {
+				if len(body) < 4 {
+					return errMalformedFlowMessage
+				}
+				field.id &= 0x7fff
+				field.enterprise = binary.BigEndian.Uint32(body[0:4])
+				body = body[4:]
+			}
+			fields = append(fields, field)
+		}
+
+		d.templates[flowTemplateKey{domain: header.domain, id: templateID}] = fields
+	}
+
+	return nil
+}
+
+// parseDataSet decodes all of the records of a data set using its template
+func parseDataSet(header flowMessageHeader, template []flowTemplateField, body []byte) ([]flowRecord, error) {
+	// the minimum length of a record is used to detect the padding at the end of the set
+	minLength := 0
+	for _, field := range template {
+		if field.length == ipfixVariableLength {
+			minLength++
+		} else {
+			minLength += int(field.length)
+		}
+	}
+	if minLength == 0 {
+		return nil, errMalformedFlowMessage
+	}
+
+	var records []flowRecord
+	for len(body) >= minLength {
+		var record flowRecord
+		for _, field := range template {
+			length := int(field.length)
+
+			// variable length fields are prefixed by their length (RFC 7011 section 7)
+			if field.length == ipfixVariableLength {
+				if len(body) < 1 {
+					return nil, errMalformedFlowMessage
+				}
+				length, body = int(body[0]), body[1:]
+				if length == 255 {
+					if len(body) < 2 {
+						return nil, errMalformedFlowMessage
+					}
+					length, body = int(binary.BigEndian.Uint16(body[0:2])), body[2:]
+				}
+			}
+
+			if len(body) < length {
+				return nil, errMalformedFlowMessage
+			}
+			record.setField(field, body[:length])
+			body = body[length:]
+		}
+
+		record.resolveTimestamps(header)
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// setField sets the value of a single field of a flow record
+func (r *flowRecord) setField(field flowTemplateField, value []byte) {
+	// reverse information elements of bidirectional flows describe the traffic from the destination
+	if field.enterprise == ipfixReversePEN {
+		switch field.id {
+		case ieOctetDeltaCount, ieOctetTotalCount:
+			r.revBytes = int64(decodeUnsigned(value))
+		case iePacketDeltaCount, iePacketTotalCount:
+			r.revPackets = int64(decodeUnsigned(value))
+		}
+		return
+	}
+
+	// skip other enterprise specific fields
+	if field.enterprise != 0 {
+		return
+	}
+
+	switch field.id {
+	case ieOctetDeltaCount, ieOctetTotalCount:
+		r.bytes = int64(decodeUnsigned(value))
+	case iePacketDeltaCount, iePacketTotalCount:
+		r.packets = int64(decodeUnsigned(value))
+	case ieProtocolIdentifier:
+		r.proto = uint8(decodeUnsigned(value))
+	case ieTCPControlBits:
+		r.tcpFlags = uint16(decodeUnsigned(value))
+	case ieSourceTransportPort:
+		r.srcPort = int(decodeUnsigned(value))
+	case ieDestinationTransportPort:
+		r.dstPort = int(decodeUnsigned(value))
+	case ieSourceIPv4Address, ieSourceIPv6Address:
+		r.src = decodeIP(value)
+	case ieDestinationIPv4Address, ieDestinationIPv6Address:
+		r.dst = decodeIP(value)
+	case ieICMPTypeCodeIPv4, ieICMPTypeCodeIPv6:
+		r.icmpTypeCode = uint16(decodeUnsigned(value))
+	case ieFlowStartSeconds:
+		r.start = time.Unix(int64(decodeUnsigned(value)), 0)
+	case ieFlowEndSeconds:
+		r.end = time.Unix(int64(decodeUnsigned(value)), 0)
+	case ieFlowStartMilliseconds:
+		r.start = time.UnixMilli(int64(decodeUnsigned(value)))
+	case ieFlowEndMilliseconds:
+		r.end = time.UnixMilli(int64(decodeUnsigned(value)))
+	case ieFlowStartSysUpTime:
+		r.startUptime = int64(decodeUnsigned(value))
+	case ieFlowEndSysUpTime:
+		r.endUptime = int64(decodeUnsigned(value))
+	case ieSystemInitTimeMilliseconds:
+		r.sysInitTime = int64(decodeUnsigned(value))
+	}
+}
+
+// resolveTimestamps sets the start and end time of the flow from the fields that were exported for it,
+// falling back to the export time of the message
+func (r *flowRecord) resolveTimestamps(header flowMessageHeader) {
+	if r.start.IsZero() && (r.startUptime > 0 || r.endUptime > 0) {
+		// uptime fields are relative to the boot time of the exporter, which is derived from the
+		// header in NetFlow v9 and exported as a separate field in IPFIX
+		var bootTime int64
+		switch {
+		case header.version == netflowV9Version:
+			bootTime = header.exportTime.UnixMilli() - int64(header.sysUptime)
+		case r.sysInitTime > 0:
+			bootTime = r.sysInitTime
+		}
+
+		if bootTime > 0 {
+			r.start = time.UnixMilli(bootTime + r.startUptime)
+			r.end = time.UnixMilli(bootTime + r.endUptime)
+		}
+	}
+
+	if r.start.IsZero() {
+		r.start = header.exportTime
+	}
+	if r.end.IsZero() || r.end.Before(r.start) {
+		r.end = r.start
+	}
+}
+
+// flowToConn converts a flow record into a zeek conn record. Flow records don't have a uid, so one is
+// generated from the path of the file and the position of the record in the file.
+func flowToConn(record *flowRecord, path string, index int) zeektypes.Conn {
+	conn := zeektypes.Conn{
+		TimeStamp:       zeektypes.Timestamp(record.start.Unix()),
+		UID:             path + ":" + strconv.Itoa(index),
+		SourcePort:      record.srcPort,
+		DestinationPort: record.dstPort,
+		Proto:           flowProto(record.proto),
+		Duration:        record.end.Sub(record.start).Seconds(),
+		OrigIPBytes:     record.bytes,
+		RespIPBytes:     record.revBytes,
+		OrigPackets:     record.packets,
+		RespPackets:     record.revPackets,
+		ConnState:       flowConnState(record),
+		LogPath:         path,
+	}
+
+	if record.src != nil {
+		conn.Source = record.src.String()
+	}
+	if record.dst != nil {
+		conn.Destination = record.dst.String()
+	}
+
+	// flow exports only count bytes including headers, so estimate the payload bytes by subtracting
+	// the minimum header size of each packet
+	conn.OrigBytes = max(0, conn.OrigIPBytes-conn.OrigPackets*minHeaderSize(conn.Proto))
+	conn.RespBytes = max(0, conn.RespIPBytes-conn.RespPackets*minHeaderSize(conn.Proto))
+
+	// zeek logs the icmp type and code in place of the ports
+	if conn.Proto == "icmp" {
+		conn.SourcePort = int(record.icmpTypeCode >> 8)
+		conn.DestinationPort = int(record.icmpTypeCode & 0xff)
+	}
+
+	return conn
+}
+
+// flowProto converts an ip protocol number into the protocol names used by zeek
+func flowProto(proto uint8) string {
+	switch proto {
+	case 1, 58:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	default:
+		return "unknown_transport"
+	}
+}
+
+// flowConnState approximates the zeek conn_state of a flow record. Unless the exporter creates bidirectional flows,
+// the responses are exported as separate flow records, so a missing response can only be detected for tcp.
+func flowConnState(record *flowRecord) string {
+	if record.proto != 6 {
+		return "OTH"
+	}
+
+	switch {
+	// connection reset
+	case record.tcpFlags&tcpRST != 0:
+		return "RSTO"
+	// connection attempt seen, no reply
+	case record.tcpFlags&tcpSYN != 0 && record.tcpFlags&tcpACK == 0:
+		return "S0"
+	// normal establishment and termination
+	case record.tcpFlags&tcpFIN != 0:
+		return "SF"
+	// connection established, not terminated
+	case record.tcpFlags&tcpSYN != 0:
+		return "S1"
+	default:
+		return "OTH"
+	}
+}
+
+// decodeUnsigned decodes a big endian unsigned integer of up to 8 bytes, since exporters may use
+// reduced size encoding for counters (RFC 7011 section 6.2)
+func decodeUnsigned(value []byte) uint64 {
+	var n uint64
+	for _, b := range value {
+		n = n<<8 | uint64(b)
+	}
+	return n
+}
+
+// decodeIP decodes an ipv4 or ipv6 address
+func decodeIP(value []byte) net.IP {
+	if len(value) != net.IPv4len && len(value) != net.IPv6len {
+		return nil
+	}
+	ip := make(net.IP, len(value))
+	copy(ip, value)
+	return ip
+}
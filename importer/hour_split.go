@@ -0,0 +1,379 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/afero"
+)
+
+const (
+	// hourLogBufferSize is the number of bytes of an hour that are buffered before they are written to its file
+	hourLogBufferSize = 1024 * 1024 // 1MiB
+	// hourSplitBufferSize is the number of bytes of all hours that are buffered before every hour is written to its file
+	hourSplitBufferSize = 64 * 1024 * 1024 // 64MiB
+)
+
+// lineHourFunc returns the hour of the record on a line of a log, or false if the line isn't a record with a valid timestamp
+type lineHourFunc func(line []byte) (time.Time, bool, error)
+
+// hourSplitter writes the lines of a log that spans many hours into a zstd compressed file per hour as the log is read.
+// Each line is prefixed with its line number in the log, so that rejected lines can still be found in the log.
+type hourSplitter struct {
+	afs      *ArchiveFs
+	encoder  *zstd.Encoder
+	hours    map[time.Time]*hourLog
+	header   []byte   // the comment lines of the log, which are copied into each hour
+	current  *hourLog // the hour of the last record, which the lines without a valid timestamp belong to
+	pending  []byte   // the lines before the first record, which belong to the hour of the first record
+	buffered int
+}
+
+// hourLog is the file of a single hour of a log that was split into hours
+type hourLog struct {
+	path    string
+	buffer  bytes.Buffer
+	written bool
+}
+
+// logScanner is a line scanner for a log or for a single hour of a log that was split into hours, which tracks the
+// line number in the log of each line that is scanned
+type logScanner struct {
+	*bufio.Scanner
+	split      bool
+	line       []byte
+	lineNumber uint64
+}
+
+// SplitLogHours returns each hour that contains records in a log. Logs that span many hours are split into a file per
+// hour while they are read when afs is an ArchiveFs, so that importing each hour only reads the records of that hour
+// instead of reading the whole log again.
+func SplitLogHours(afs afero.Fs, path string, prefix string) ([]time.Time, error) {
+	lineHour, errNoRecords := zeekLineHour(path), ErrLogHasNoRecords
+	switch prefix {
+	case ECSPrefix:
+		lineHour, errNoRecords = ecsLineHour, ErrECSExportHasNoRecords
+	case EVEPrefix:
+		lineHour, errNoRecords = eveLineHour, ErrEVELogHasNoRecords
+	}
+
+	archiveFs, ok := afs.(*ArchiveFs)
+	if !ok {
+		return readLogHours(afs, path, lineHour, nil, errNoRecords)
+	}
+
+	splitter := &hourSplitter{afs: archiveFs, hours: make(map[time.Time]*hourLog)}
+	hours, err := readLogHours(afs, path, lineHour, splitter, errNoRecords)
+	if err != nil {
+		return nil, errors.Join(err, splitter.remove())
+	}
+
+	// logs with records in a single hour are read directly
+	if len(hours) == 1 {
+		return hours, splitter.remove()
+	}
+
+	if err := splitter.close(); err != nil {
+		return nil, errors.Join(err, splitter.remove())
+	}
+
+	archiveFs.mu.Lock()
+	defer archiveFs.mu.Unlock()
+	for hour, log := range splitter.hours {
+		archiveFs.hourLogs[HourPath(path, hour)] = log.path
+	}
+
+	return hours, nil
+}
+
+// readLogHours reads the hour of each line of a log, passing each line to the splitter if there is one
+func readLogHours(afs afero.Fs, path string, lineHour lineHourFunc, splitter *hourSplitter, errNoRecords error) ([]time.Time, error) {
+	reader, closeFile, err := openLogFile(afs, path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var hours []time.Time
+	var lineNumber uint64
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) < 1 {
+			continue
+		}
+
+		hour, ok, err := lineHour(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok && !slices.ContainsFunc(hours, hour.Equal) {
+			hours = append(hours, hour)
+		}
+
+		if splitter != nil {
+			if err := splitter.write(lineNumber, line, hour, ok); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the hours read before a scanner error can still be imported
+	if len(hours) == 0 {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errNoRecords
+	}
+
+	slices.SortFunc(hours, func(a, b time.Time) int { return a.Compare(b) })
+	return hours, nil
+}
+
+// write adds a line of the log to the hour that it belongs to. Comment lines belong to every hour.
+func (s *hourSplitter) write(lineNumber uint64, line []byte, hour time.Time, ok bool) error {
+	framed := strconv.AppendUint(nil, lineNumber, 10)
+	framed = append(framed, '\t')
+	framed = append(framed, line...)
+	framed = append(framed, '\n')
+
+	if line[0] == '#' {
+		s.header = append(s.header, framed...)
+		for _, log := range s.hours {
+			log.buffer.Write(framed)
+			s.buffered += len(framed)
+		}
+		return s.flushIfFull(nil)
+	}
+
+	if ok {
+		if _, exists := s.hours[hour]; !exists {
+			if err := s.addHour(hour); err != nil {
+				return err
+			}
+		}
+		s.current = s.hours[hour]
+		s.current.buffer.Write(s.pending)
+		s.buffered += len(s.pending)
+		s.pending = nil
+	}
+
+	if s.current == nil {
+		s.pending = append(s.pending, framed...)
+		return nil
+	}
+
+	s.current.buffer.Write(framed)
+	s.buffered += len(framed)
+	return s.flushIfFull(s.current)
+}
+
+// addHour starts the file of an hour with the comment lines read so far
+func (s *hourSplitter) addHour(hour time.Time) error {
+	path, err := s.afs.newHourLogPath()
+	if err != nil {
+		return err
+	}
+
+	log := &hourLog{path: path}
+	log.buffer.Write(s.header)
+	s.buffered += len(s.header)
+	s.hours[hour] = log
+	return nil
+}
+
+// flushIfFull writes the buffered lines of an hour to its file once they reach the buffer size, or the buffered lines of
+// every hour once they reach the total buffer size. The lines of a log are kept buffered while it only has a single
+// hour, since that log is read directly if no other hour is found.
+func (s *hourSplitter) flushIfFull(log *hourLog) error {
+	switch {
+	case s.buffered >= hourSplitBufferSize:
+		for _, log := range s.hours {
+			if err := s.flush(log); err != nil {
+				return err
+			}
+		}
+	case log != nil && len(s.hours) > 1 && log.buffer.Len() >= hourLogBufferSize:
+		return s.flush(log)
+	}
+	return nil
+}
+
+// flush appends the buffered lines of an hour to its file as a zstd frame
+func (s *hourSplitter) flush(log *hourLog) error {
+	if log.buffer.Len() == 0 {
+		return nil
+	}
+
+	if s.encoder == nil {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		if err != nil {
+			return err
+		}
+		s.encoder = encoder
+	}
+
+	file, err := os.OpenFile(log.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(s.encoder.EncodeAll(log.buffer.Bytes(), nil)); err != nil {
+		return errors.Join(err, file.Close())
+	}
+
+	s.buffered -= log.buffer.Len()
+	log.buffer.Reset()
+	log.written = true
+	return file.Close()
+}
+
+// close writes the lines that are still buffered to the file of their hour
+func (s *hourSplitter) close() error {
+	for _, log := range s.hours {
+		if err := s.flush(log); err != nil {
+			return err
+		}
+	}
+	if s.encoder != nil {
+		return s.encoder.Close()
+	}
+	return nil
+}
+
+// remove removes the files of each hour
+func (s *hourSplitter) remove() error {
+	if s.encoder != nil {
+		s.encoder.Close()
+	}
+
+	var errs []error
+	for _, log := range s.hours {
+		if !log.written {
+			continue
+		}
+		if err := os.Remove(log.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newLogScanner opens a log for reading and returns a line scanner for it along with a function that closes the file.
+// The hour paths of logs that were split into hours are read from the file of their hour, see SplitLogHours.
+func newLogScanner(afs afero.Fs, path string) (*logScanner, func(), error) {
+	readFs, readPath, split := afs, path, false
+	if archiveFs, ok := afs.(*ArchiveFs); ok {
+		if hourLog, ok := archiveFs.hourLog(path); ok {
+			readFs, readPath, split = afero.NewOsFs(), hourLog, true
+		}
+	}
+
+	// logs that weren't split are read in full
+	if !split {
+		if filePath, _, err := splitHourPath(path); err == nil {
+			readPath = filePath
+		}
+	}
+
+	reader, closeFile, err := openLogFile(readFs, readPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scanner := bufio.NewScanner(reader)
+
+	// set a buffer for the scanner
+	initialBufferSize := 64 * 1024 // 64KiB
+	maxBufferSize := 1024 * 1024   // 1MiB
+	if split {
+		// leave room for the line number that prefixes each line
+		maxBufferSize += 32
+	}
+	scanner.Buffer(make([]byte, 0, initialBufferSize), maxBufferSize)
+
+	return &logScanner{Scanner: scanner, split: split}, closeFile, nil
+}
+
+// Scan advances to the next line, removing the line number that prefixes the lines of an hour that was split from a log
+func (s *logScanner) Scan() bool {
+	if !s.Scanner.Scan() {
+		return false
+	}
+
+	s.line = s.Scanner.Bytes()
+	if !s.split {
+		s.lineNumber++
+		return true
+	}
+
+	number, line, _ := bytes.Cut(s.line, []byte{'\t'})
+	lineNumber, err := strconv.ParseUint(string(number), 10, 64)
+	if err != nil {
+		s.lineNumber++
+		return true
+	}
+
+	s.line, s.lineNumber = line, lineNumber
+	return true
+}
+
+// Bytes returns the current line
+func (s *logScanner) Bytes() []byte { return s.line }
+
+// Text returns the current line as a string
+func (s *logScanner) Text() string { return string(s.line) }
+
+// LineNumber returns the line number of the current line in the log
+func (s *logScanner) LineNumber() uint64 { return s.lineNumber }
+
+// newHourLogPath returns the path of a new file in the directory that the hours of split logs are written to
+func (afs *ArchiveFs) newHourLogPath() (string, error) {
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	if afs.hourDir == "" {
+		dir, err := os.MkdirTemp("", "rita-hours-")
+		if err != nil {
+			return "", err
+		}
+		afs.hourDir = dir
+	}
+
+	afs.hourLogCount++
+	return filepath.Join(afs.hourDir, strconv.Itoa(afs.hourLogCount)+".log.zst"), nil
+}
+
+// hourLog returns the file that the hour of a split log was written to
+func (afs *ArchiveFs) hourLog(hourPath string) (string, bool) {
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	path, ok := afs.hourLogs[hourPath]
+	return path, ok
+}
+
+// RemoveHourLog removes the file that the hour of a split log was written to, so that the space it takes up can be
+// freed once the hour has been imported. The hour is read from the whole log if it is accessed afterwards.
+func (afs *ArchiveFs) RemoveHourLog(hourPath string) error {
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	path, ok := afs.hourLogs[hourPath]
+	if !ok {
+		return nil
+	}
+	delete(afs.hourLogs, hourPath)
+
+	return os.Remove(path)
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"activecm/rita/importer/zeektypes"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var ErrLogHasNoRecords = errors.New("log file does not contain any records with a valid timestamp")
+var errInvalidHourPath = errors.New("invalid hour path")
+
+const (
+	// hourPathSeparator separates the path of a file from the hour of the records to import from it
+	hourPathSeparator = "#"
+	hourPathFormat    = "2006-01-02T15"
+)
+
+// HourPath returns the path used to import the records of a single hour of a file. Files that aren't rotated hourly,
+// such as packet captures or day long zeek logs, can span many hours, so each hour is imported and marked as imported
+// in the metadatabase on its own, just like an hourly log.
+func HourPath(path string, hour time.Time) string {
+	return path + hourPathSeparator + hour.UTC().Format(hourPathFormat)
+}
+
+// splitHourPath returns the path of the file and the hour of an hour path
+func splitHourPath(hourPath string) (string, time.Time, error) {
+	index := strings.LastIndex(hourPath, hourPathSeparator)
+	if index == -1 {
+		return "", time.Time{}, errInvalidHourPath
+	}
+
+	hour, err := time.Parse(hourPathFormat, hourPath[index+1:])
+	if err != nil {
+		return "", time.Time{}, errors.Join(errInvalidHourPath, err)
+	}
+
+	return hourPath[:index], hour, nil
+}
+
+// timestampInHour returns whether a record's timestamp falls within the hour starting at hour
+func timestampInHour(ts zeektypes.Timestamp, hour time.Time) bool {
+	t := time.Unix(int64(ts), 0)
+	return !t.Before(hour) && t.Before(hour.Add(time.Hour))
+}
+
+// GetLogHours returns each hour that contains records in a zeek log, using the ts field of each record
+func GetLogHours(afs afero.Fs, path string) ([]time.Time, error) {
+	return readLogHours(afs, path, zeekLineHour(path), nil, ErrLogHasNoRecords)
+}
+
+// zeekLineHour returns a function that reads the hour of each record of a zeek log from its ts field, which has to be
+// given each line of the log in order so that the ts field can be found in the header of TSV logs
+func zeekLineHour(path string) lineHourFunc {
+	// only the separator and field order of the header are needed to find the ts field
+	var header ZeekHeader[zeektypes.Conn]
+	header.fieldMapping = fieldMappingFor(path)
+	tsIndex := -1
+
+	return func(line []byte) (time.Time, bool, error) {
+		var ts zeektypes.Timestamp
+		switch {
+		case line[0] == '#':
+			if _, err := header.parseHeader(string(line)); err != nil {
+				return time.Time{}, false, err
+			}
+			tsIndex = slices.Index(header.fieldOrder, "ts")
+			return time.Time{}, false, nil
+
+		case line[0] == '{':
+			var record struct {
+				TimeStamp zeektypes.Timestamp `json:"ts"`
+			}
+			if err := header.fieldMapping.unmarshalJSON(line, &record); err != nil || record.TimeStamp == 0 {
+				return time.Time{}, false, nil
+			}
+			ts = record.TimeStamp
+
+		default:
+			if tsIndex == -1 || header.separator == "" {
+				return time.Time{}, false, nil
+			}
+			fields := strings.Split(string(line), header.separator)
+			if tsIndex >= len(fields) {
+				return time.Time{}, false, nil
+			}
+			seconds, err := strconv.ParseFloat(fields[tsIndex], 64)
+			if err != nil {
+				return time.Time{}, false, nil
+			}
+			ts = zeektypes.Timestamp(seconds)
+		}
+
+		return time.Unix(int64(ts), 0).UTC().Truncate(time.Hour), true, nil
+	}
+}
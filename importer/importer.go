@@ -27,10 +27,19 @@ import (
 	"golang.org/x/time/rate"
 )
 
-var errAllFilesPreviouslyImported = errors.New("all files were previously imported")
+var ErrAllFilesPreviouslyImported = errors.New("all files were previously imported")
 
-type zeekRecord interface {
-	zeektypes.Conn | zeektypes.DNS | zeektypes.HTTP | zeektypes.SSL
+// FileIdentifier is implemented by filesystems whose files are tracked in the metadatabase by something other than
+// their path, such as objects in S3, which are identified by their key and ETag
+type FileIdentifier interface {
+	FileID(path string) (string, error)
+}
+
+// zeekRecord is the pointer type P of a record Z parsed from a zeek log, which is either one of the types in the zeektypes
+// package or the record struct of a log type registered with RegisterRecordType
+type zeekRecord[Z any] interface {
+	*Z
+	SetLogPath(path string)
 }
 
 type Importer struct {
@@ -40,6 +49,7 @@ type Importer struct {
 	FileMap                  map[string][]string
 	EntryChannels            EntryChans
 	MetaDBChannel            chan MetaDBFile
+	RejectedLineChannel      chan RejectedLine
 	Paths                    chan string
 	ErrChannel               chan error
 	TotalFileCount           int
@@ -61,38 +71,73 @@ type Importer struct {
 	startWritersCallback     func(int)
 	closeWritersCallback     func()
 	markFileImportedCallback func(util.FixedString, util.FixedString, string) error
+	addRejectedLinesCallback func([]database.MetaDBRejectedLine) error
+	// fileIDs holds the identity of each file being imported, see FileIdentifier
+	fileIDs map[string]string
 }
 
 type EntryChans struct {
-	Conn     chan zeektypes.Conn
-	OpenConn chan zeektypes.Conn
-	DNS      chan zeektypes.DNS
-	HTTP     chan zeektypes.HTTP
-	OpenHTTP chan zeektypes.HTTP
-	SSL      chan zeektypes.SSL
-	OpenSSL  chan zeektypes.SSL
+	Conn       chan zeektypes.Conn
+	OpenConn   chan zeektypes.Conn
+	DNS        chan zeektypes.DNS
+	HTTP       chan zeektypes.HTTP
+	OpenHTTP   chan zeektypes.HTTP
+	SSL        chan zeektypes.SSL
+	OpenSSL    chan zeektypes.SSL
+	X509       chan zeektypes.X509
+	SSH        chan zeektypes.SSH
+	QUIC       chan zeektypes.QUIC
+	DHCP       chan zeektypes.DHCP
+	SMBFiles   chan zeektypes.SMBFiles
+	SMBMapping chan zeektypes.SMBMapping
+	DCERPC     chan zeektypes.DCERPC
+	NTLM       chan zeektypes.NTLM
+	Kerberos   chan zeektypes.Kerberos
+	// Custom holds the pipelines of the log types registered with RegisterRecordType, by prefix
+	Custom map[string]recordPipeline
 }
 
 type writers struct {
-	ConnTmp     *database.BulkWriter
-	OpenConnTmp *database.BulkWriter
-	DNS         *database.BulkWriter
-	PDNS        *database.BulkWriter
-	HTTPTmp     *database.BulkWriter
-	OpenHTTPTmp *database.BulkWriter
-	SSLTmp      *database.BulkWriter
-	OpenSSLTmp  *database.BulkWriter
+	ConnTmp         *database.BulkWriter
+	OpenConnTmp     *database.BulkWriter
+	DNS             *database.BulkWriter
+	PDNS            *database.BulkWriter
+	HTTPTmp         *database.BulkWriter
+	OpenHTTPTmp     *database.BulkWriter
+	SSLTmp          *database.BulkWriter
+	OpenSSLTmp      *database.BulkWriter
+	X509            *database.BulkWriter
+	SSHTmp          *database.BulkWriter
+	QUICTmp         *database.BulkWriter
+	DHCP            *database.BulkWriter
+	LateralMovement *database.BulkWriter
+	// Custom holds the writers to the tables of the log types registered with RegisterRecordType, by prefix
+	Custom map[string]*database.BulkWriter
 }
 
 type DoneChans struct {
-	filesDone chan struct{}
-	conn      chan struct{}
-	openconn  chan struct{}
-	http      chan struct{}
-	openhttp  chan struct{}
-	dns       chan struct{}
-	ssl       chan struct{}
-	openssl   chan struct{}
+	filesDone  chan struct{}
+	conn       chan struct{}
+	openconn   chan struct{}
+	http       chan struct{}
+	openhttp   chan struct{}
+	dns        chan struct{}
+	ssl        chan struct{}
+	openssl    chan struct{}
+	x509       chan struct{}
+	ssh        chan struct{}
+	quic       chan struct{}
+	dhcp       chan struct{}
+	smbfiles   chan struct{}
+	smbmapping chan struct{}
+	dcerpc     chan struct{}
+	ntlm       chan struct{}
+	kerberos   chan struct{}
+	eve        chan struct{}
+	ecs        chan struct{}
+	flow       chan struct{}
+	pcap       chan struct{}
+	custom     chan struct{}
 }
 
 type ResultCounts struct {
@@ -108,18 +153,46 @@ type ResultCounts struct {
 	PDNSRaw        uint64
 	SSL            uint64
 	OpenSSL        uint64
+	X509           uint64
+	SSH            uint64
+	QUIC           uint64
+	DHCP           uint64
+	SMBFiles       uint64
+	SMBMapping     uint64
+	DCERPC         uint64
+	NTLM           uint64
+	Kerberos       uint64
+	// records of the log types registered with RegisterRecordType, by prefix
+	Custom map[string]uint64
+	// lines that failed to parse and were left out of the import
+	RejectedLines uint64
+	// lines that were imported without the fields that failed to parse
+	InvalidFields uint64
+	// files that had too many lines fail to parse and were only partially imported
+	AbandonedFiles uint64
 }
 
 type WaitGroups struct {
-	Digester sync.WaitGroup
-	MetaDB   sync.WaitGroup
-	OpenConn sync.WaitGroup
-	Conn     sync.WaitGroup
-	DNS      sync.WaitGroup
-	HTTP     sync.WaitGroup
-	OpenHTTP sync.WaitGroup
-	SSL      sync.WaitGroup
-	OpenSSL  sync.WaitGroup
+	Digester   sync.WaitGroup
+	MetaDB     sync.WaitGroup
+	Rejected   sync.WaitGroup
+	OpenConn   sync.WaitGroup
+	Conn       sync.WaitGroup
+	DNS        sync.WaitGroup
+	HTTP       sync.WaitGroup
+	OpenHTTP   sync.WaitGroup
+	SSL        sync.WaitGroup
+	OpenSSL    sync.WaitGroup
+	X509       sync.WaitGroup
+	SSH        sync.WaitGroup
+	QUIC       sync.WaitGroup
+	DHCP       sync.WaitGroup
+	SMBFiles   sync.WaitGroup
+	SMBMapping sync.WaitGroup
+	DCERPC     sync.WaitGroup
+	NTLM       sync.WaitGroup
+	Kerberos   sync.WaitGroup
+	Custom     sync.WaitGroup
 }
 
 // NewImporter creates and returns a new Importer object
@@ -128,25 +201,49 @@ func NewImporter(db *database.DB, cfg *config.Config, importStartedAt time.Time,
 
 	// create channels to hold the network traffic entries
 	entryChannels := EntryChans{
-		Conn:     make(chan zeektypes.Conn, 1000),
-		OpenConn: make(chan zeektypes.Conn, 1000),
-		DNS:      make(chan zeektypes.DNS, 1000),
-		HTTP:     make(chan zeektypes.HTTP, 1000),
-		OpenHTTP: make(chan zeektypes.HTTP, 1000),
-		SSL:      make(chan zeektypes.SSL, 1000),
-		OpenSSL:  make(chan zeektypes.SSL, 1000),
+		Conn:       make(chan zeektypes.Conn, 1000),
+		OpenConn:   make(chan zeektypes.Conn, 1000),
+		DNS:        make(chan zeektypes.DNS, 1000),
+		HTTP:       make(chan zeektypes.HTTP, 1000),
+		OpenHTTP:   make(chan zeektypes.HTTP, 1000),
+		SSL:        make(chan zeektypes.SSL, 1000),
+		OpenSSL:    make(chan zeektypes.SSL, 1000),
+		X509:       make(chan zeektypes.X509, 1000),
+		SSH:        make(chan zeektypes.SSH, 1000),
+		QUIC:       make(chan zeektypes.QUIC, 1000),
+		DHCP:       make(chan zeektypes.DHCP, 1000),
+		SMBFiles:   make(chan zeektypes.SMBFiles, 1000),
+		SMBMapping: make(chan zeektypes.SMBMapping, 1000),
+		DCERPC:     make(chan zeektypes.DCERPC, 1000),
+		NTLM:       make(chan zeektypes.NTLM, 1000),
+		Kerberos:   make(chan zeektypes.Kerberos, 1000),
+		Custom:     make(map[string]recordPipeline),
 	}
 
 	// create channels to keep track of log files being successfully imported
 	doneChannels := DoneChans{
-		filesDone: make(chan struct{}),
-		conn:      make(chan struct{}, numDigesters),
-		openconn:  make(chan struct{}, numDigesters),
-		http:      make(chan struct{}, numDigesters),
-		openhttp:  make(chan struct{}, numDigesters),
-		dns:       make(chan struct{}, numDigesters),
-		ssl:       make(chan struct{}, numDigesters),
-		openssl:   make(chan struct{}, numDigesters),
+		filesDone:  make(chan struct{}),
+		conn:       make(chan struct{}, numDigesters),
+		openconn:   make(chan struct{}, numDigesters),
+		http:       make(chan struct{}, numDigesters),
+		openhttp:   make(chan struct{}, numDigesters),
+		dns:        make(chan struct{}, numDigesters),
+		ssl:        make(chan struct{}, numDigesters),
+		openssl:    make(chan struct{}, numDigesters),
+		x509:       make(chan struct{}, numDigesters),
+		ssh:        make(chan struct{}, numDigesters),
+		quic:       make(chan struct{}, numDigesters),
+		dhcp:       make(chan struct{}, numDigesters),
+		smbfiles:   make(chan struct{}, numDigesters),
+		smbmapping: make(chan struct{}, numDigesters),
+		dcerpc:     make(chan struct{}, numDigesters),
+		ntlm:       make(chan struct{}, numDigesters),
+		kerberos:   make(chan struct{}, numDigesters),
+		eve:        make(chan struct{}, numDigesters),
+		ecs:        make(chan struct{}, numDigesters),
+		flow:       make(chan struct{}, numDigesters),
+		pcap:       make(chan struct{}, numDigesters),
+		custom:     make(chan struct{}, numDigesters),
 	}
 
 	// create a rate limiter to control the rate of writing to the database
@@ -154,14 +251,26 @@ func NewImporter(db *database.DB, cfg *config.Config, importStartedAt time.Time,
 
 	// create writer objects to write output data to the individual log collections
 	writers := writers{
-		ConnTmp:     database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "conn_tmp", "INSERT INTO {database:Identifier}.conn_tmp", limiter, false),
-		OpenConnTmp: database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "openconn_tmp", "INSERT INTO {database:Identifier}.openconn_tmp", limiter, false),
-		DNS:         database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "dns", "INSERT INTO {database:Identifier}.dns", limiter, false),
-		PDNS:        database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "pdns", "INSERT INTO {database:Identifier}.pdns_raw", limiter, false),
-		HTTPTmp:     database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "http_tmp", "INSERT INTO {database:Identifier}.http_tmp", limiter, false),
-		OpenHTTPTmp: database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "openhttp_tmp", "INSERT INTO {database:Identifier}.openhttp_tmp", limiter, false),
-		SSLTmp:      database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "ssl_tmp", "INSERT INTO {database:Identifier}.ssl_tmp", limiter, false),
-		OpenSSLTmp:  database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "openssl_tmp", "INSERT INTO {database:Identifier}.openssl_tmp", limiter, false),
+		ConnTmp:         database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "conn_tmp", "INSERT INTO {database:Identifier}.conn_tmp", limiter, false),
+		OpenConnTmp:     database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "openconn_tmp", "INSERT INTO {database:Identifier}.openconn_tmp", limiter, false),
+		DNS:             database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "dns", "INSERT INTO {database:Identifier}.dns", limiter, false),
+		PDNS:            database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "pdns", "INSERT INTO {database:Identifier}.pdns_raw", limiter, false),
+		HTTPTmp:         database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "http_tmp", "INSERT INTO {database:Identifier}.http_tmp", limiter, false),
+		OpenHTTPTmp:     database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "openhttp_tmp", "INSERT INTO {database:Identifier}.openhttp_tmp", limiter, false),
+		SSLTmp:          database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "ssl_tmp", "INSERT INTO {database:Identifier}.ssl_tmp", limiter, false),
+		OpenSSLTmp:      database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "openssl_tmp", "INSERT INTO {database:Identifier}.openssl_tmp", limiter, false),
+		X509:            database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "x509", "INSERT INTO {database:Identifier}.x509", limiter, false),
+		SSHTmp:          database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "ssh_tmp", "INSERT INTO {database:Identifier}.ssh_tmp", limiter, false),
+		QUICTmp:         database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "quic_tmp", "INSERT INTO {database:Identifier}.quic_tmp", limiter, false),
+		DHCP:            database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "dhcp", "INSERT INTO {database:Identifier}.dhcp", limiter, false),
+		LateralMovement: database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), "lateral_movement", "INSERT INTO {database:Identifier}.lateral_movement", limiter, false),
+		Custom:          make(map[string]*database.BulkWriter),
+	}
+
+	// set up the pipeline and writer of each registered log type
+	for _, r := range registeredRecordTypes() {
+		entryChannels.Custom[r.prefix()] = r.newPipeline()
+		writers.Custom[r.prefix()] = database.NewBulkWriter(db, cfg, numWriters, db.GetSelectedDB(), r.table(), fmt.Sprintf("INSERT INTO {database:Identifier}.%s", r.table()), limiter, false)
 	}
 
 	// create progress bar
@@ -187,6 +296,7 @@ func NewImporter(db *database.DB, cfg *config.Config, importStartedAt time.Time,
 		FileMap:                  make(map[string][]string),
 		EntryChannels:            entryChannels,
 		MetaDBChannel:            make(chan MetaDBFile),
+		RejectedLineChannel:      make(chan RejectedLine, 100),
 		Paths:                    make(chan string, 10),
 		ErrChannel:               make(chan error, 100),
 		DoneChannels:             doneChannels,
@@ -203,6 +313,7 @@ func NewImporter(db *database.DB, cfg *config.Config, importStartedAt time.Time,
 		startWritersCallback:     writers.startWriters,
 		closeWritersCallback:     writers.closeWriters,
 		markFileImportedCallback: db.MarkFileImportedInMetaDB,
+		addRejectedLinesCallback: db.AddRejectedLinesToMetaDB,
 	}, nil
 }
 
@@ -212,20 +323,41 @@ func (importer *Importer) Import(afs afero.Fs, files map[string][]string) error
 	// record the hourlyImportStart time of this import chunk
 	hourlyImportStart := time.Now()
 
+	// files are checked against the metadatabase by the identity that their filesystem gives them
+	fileMap := make(map[string][]string, len(files))
+	paths := make(map[string]string)
+	importer.fileIDs = make(map[string]string)
+	for logType, logList := range files {
+		for _, path := range logList {
+			id, err := GetFileID(afs, path)
+			if err != nil {
+				return err
+			}
+			fileMap[logType] = append(fileMap[logType], id)
+			paths[id] = path
+			importer.fileIDs[path] = id
+		}
+	}
+
 	// check if files have already been imported make a map of the remaining files
-	totalFileCount, err := importer.validateLogFilesCallback(files)
+	totalFileCount, err := importer.validateLogFilesCallback(fileMap)
 	if err != nil {
 		return err
 	}
 
 	// verify that there are still files left to import and set file count
 	if totalFileCount < 1 {
-		return errAllFilesPreviouslyImported
+		return ErrAllFilesPreviouslyImported
 	}
 	importer.TotalFileCount = totalFileCount
 
 	// set up the file map with the remaining files
-	importer.FileMap = files
+	importer.FileMap = make(map[string][]string, len(fileMap))
+	for logType, ids := range fileMap {
+		for _, id := range ids {
+			importer.FileMap[logType] = append(importer.FileMap[logType], paths[id])
+		}
+	}
 
 	// add import started record to metadatabase
 	err = importer.importStartedCallback(importer.ImportID)
@@ -268,6 +400,21 @@ func (importer *Importer) Import(afs afero.Fs, files map[string][]string) error
 	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.OpenHTTP)).Msg("Imported open http records")
 	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.SSL)).Msg("Imported ssl records")
 	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.OpenSSL)).Msg("Imported open ssl records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.X509)).Msg("Imported x509 records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.SSH)).Msg("Imported ssh records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.QUIC)).Msg("Imported quic records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.DHCP)).Msg("Imported dhcp leases")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.SMBFiles)).Msg("Imported smb_files records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.SMBMapping)).Msg("Imported smb_mapping records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.DCERPC)).Msg("Imported dce_rpc records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.NTLM)).Msg("Imported ntlm records")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.Kerberos)).Msg("Imported kerberos records")
+	for prefix, count := range importer.ResultCounts.Custom {
+		logger.Debug().Str("count", p.Sprintf("%d", count)).Msgf("Imported %s records", prefix)
+	}
+
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.RejectedLines)).Msg("Skipped lines that failed to parse")
+	logger.Debug().Str("count", p.Sprintf("%d", importer.ResultCounts.InvalidFields)).Msg("Imported lines with fields that failed to parse")
 
 	return nil
 }
@@ -286,6 +433,9 @@ func (importer *Importer) process(afs afero.Fs) {
 	// start goroutine to mark files as imported in MetaDB
 	importer.startMetaDBFileTracker()
 
+	// start goroutine to record the lines that failed to parse in MetaDB
+	importer.startRejectedLineTracker()
+
 	// start listener routine and feed initial files in to paths channel
 	importer.feedAndListenForFileCompletion()
 
@@ -302,11 +452,25 @@ func (importer *Importer) process(afs afero.Fs) {
 		close(importer.EntryChannels.OpenHTTP)
 		close(importer.EntryChannels.SSL)
 		close(importer.EntryChannels.OpenSSL)
+		close(importer.EntryChannels.X509)
+		close(importer.EntryChannels.SSH)
+		close(importer.EntryChannels.QUIC)
+		close(importer.EntryChannels.DHCP)
+		close(importer.EntryChannels.SMBFiles)
+		close(importer.EntryChannels.SMBMapping)
+		close(importer.EntryChannels.DCERPC)
+		close(importer.EntryChannels.NTLM)
+		close(importer.EntryChannels.Kerberos)
+		for _, pipeline := range importer.EntryChannels.Custom {
+			pipeline.close()
+		}
 
 		// close paths channel
 		close(importer.Paths)
 		// close metadb channel
 		close(importer.MetaDBChannel)
+		// close rejected line channel
+		close(importer.RejectedLineChannel)
 	}()
 
 	// wait for log routine groups
@@ -317,6 +481,21 @@ func (importer *Importer) process(afs afero.Fs) {
 	importer.wg.OpenHTTP.Wait()
 	importer.wg.SSL.Wait()
 	importer.wg.OpenSSL.Wait()
+	importer.wg.X509.Wait()
+	importer.wg.SSH.Wait()
+	importer.wg.QUIC.Wait()
+	importer.wg.DHCP.Wait()
+	importer.wg.SMBFiles.Wait()
+	importer.wg.SMBMapping.Wait()
+	importer.wg.DCERPC.Wait()
+	importer.wg.NTLM.Wait()
+	importer.wg.Kerberos.Wait()
+	importer.wg.Custom.Wait()
+
+	importer.ResultCounts.Custom = make(map[string]uint64, len(importer.EntryChannels.Custom))
+	for prefix, pipeline := range importer.EntryChannels.Custom {
+		importer.ResultCounts.Custom[prefix] = pipeline.count()
+	}
 
 	close(importer.DoneChannels.conn)
 	close(importer.DoneChannels.openconn)
@@ -325,10 +504,27 @@ func (importer *Importer) process(afs afero.Fs) {
 	close(importer.DoneChannels.ssl)
 	close(importer.DoneChannels.openssl)
 	close(importer.DoneChannels.dns)
+	close(importer.DoneChannels.x509)
+	close(importer.DoneChannels.ssh)
+	close(importer.DoneChannels.quic)
+	close(importer.DoneChannels.dhcp)
+	close(importer.DoneChannels.smbfiles)
+	close(importer.DoneChannels.smbmapping)
+	close(importer.DoneChannels.dcerpc)
+	close(importer.DoneChannels.ntlm)
+	close(importer.DoneChannels.kerberos)
+	close(importer.DoneChannels.eve)
+	close(importer.DoneChannels.ecs)
+	close(importer.DoneChannels.flow)
+	close(importer.DoneChannels.pcap)
+	close(importer.DoneChannels.custom)
 	close(importer.DoneChannels.filesDone)
 
 	close(importer.ErrChannel)
 
+	// wait for the rejected lines to be recorded
+	importer.wg.Rejected.Wait()
+
 	// close writers
 	importer.closeWritersCallback()
 }
@@ -342,6 +538,16 @@ func (importer *Importer) startParseRoutines() {
 	importer.wg.OpenHTTP.Add(importer.NumParsers)
 	importer.wg.SSL.Add(importer.NumParsers)
 	importer.wg.OpenSSL.Add(importer.NumParsers)
+	importer.wg.X509.Add(importer.NumParsers)
+	importer.wg.SSH.Add(importer.NumParsers)
+	importer.wg.QUIC.Add(importer.NumParsers)
+	importer.wg.DHCP.Add(importer.NumParsers)
+	importer.wg.SMBFiles.Add(importer.NumParsers)
+	importer.wg.SMBMapping.Add(importer.NumParsers)
+	importer.wg.DCERPC.Add(importer.NumParsers)
+	importer.wg.NTLM.Add(importer.NumParsers)
+	importer.wg.Kerberos.Add(importer.NumParsers)
+	importer.wg.Custom.Add(importer.NumParsers * len(importer.EntryChannels.Custom))
 
 	for i := 0; i < importer.NumParsers; i++ {
 		go func(_ int) {
@@ -383,6 +589,58 @@ func (importer *Importer) startParseRoutines() {
 			parseSSL(importer.EntryChannels.OpenSSL, importer.Writers.OpenSSLTmp.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.OpenSSL)
 			importer.wg.OpenSSL.Done()
 		}(i)
+
+		go func(_ int) {
+			parseX509(importer.EntryChannels.X509, importer.Writers.X509.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.X509)
+			importer.wg.X509.Done()
+		}(i)
+
+		go func(_ int) {
+			parseSSH(importer.EntryChannels.SSH, importer.Writers.SSHTmp.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.SSH)
+			importer.wg.SSH.Done()
+		}(i)
+
+		go func(_ int) {
+			parseQUIC(importer.EntryChannels.QUIC, importer.Writers.QUICTmp.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.QUIC)
+			importer.wg.QUIC.Done()
+		}(i)
+
+		go func(_ int) {
+			parseDHCP(importer.EntryChannels.DHCP, importer.Writers.DHCP.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.DHCP)
+			importer.wg.DHCP.Done()
+		}(i)
+
+		go func(_ int) {
+			parseSMBFiles(importer.EntryChannels.SMBFiles, importer.Writers.LateralMovement.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.SMBFiles)
+			importer.wg.SMBFiles.Done()
+		}(i)
+
+		go func(_ int) {
+			parseSMBMapping(importer.EntryChannels.SMBMapping, importer.Writers.LateralMovement.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.SMBMapping)
+			importer.wg.SMBMapping.Done()
+		}(i)
+
+		go func(_ int) {
+			parseDCERPC(importer.EntryChannels.DCERPC, importer.Writers.LateralMovement.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.DCERPC)
+			importer.wg.DCERPC.Done()
+		}(i)
+
+		go func(_ int) {
+			parseNTLM(importer.EntryChannels.NTLM, importer.Writers.LateralMovement.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.NTLM)
+			importer.wg.NTLM.Done()
+		}(i)
+
+		go func(_ int) {
+			parseKerberos(importer.EntryChannels.Kerberos, importer.Writers.LateralMovement.WriteChannel, importer.Database.ImportStartedAt, &importer.ResultCounts.Kerberos)
+			importer.wg.Kerberos.Done()
+		}(i)
+
+		for prefix, pipeline := range importer.EntryChannels.Custom {
+			go func(_ int) {
+				pipeline.parse(importer.Writers.Custom[prefix].WriteChannel, importer.Database.ImportStartedAt)
+				importer.wg.Custom.Done()
+			}(i)
+		}
 	}
 }
 
@@ -391,7 +649,7 @@ func (importer *Importer) startDigesters(afs afero.Fs) {
 	importer.wg.Digester.Add(importer.NumDigesters)
 	for i := 0; i < importer.NumDigesters; i++ {
 		go func(_ int) {
-			digester(afs, importer.DoneChannels, importer.Paths, importer.ErrChannel, importer.EntryChannels, importer.MetaDBChannel, importer.Database.GetSelectedDB(), importer.ImportID, importer.ProgressLogger)
+			digester(afs, importer.DoneChannels, importer.Paths, importer.ErrChannel, importer.RejectedLineChannel, importer.EntryChannels, importer.MetaDBChannel, importer.Database.GetSelectedDB(), importer.ImportID, importer.ProgressLogger)
 			importer.wg.Digester.Done()
 		}(i)
 	}
@@ -403,6 +661,16 @@ func (importer *Importer) startMetaDBFileTracker() {
 	importer.wg.MetaDB.Add(1)
 	go func() {
 		for metaDB := range importer.MetaDBChannel {
+			// files that their filesystem identifies by something other than their path are tracked by that identity
+			if id, ok := importer.fileIDs[metaDB.path]; ok && id != metaDB.path {
+				hash, err := util.NewFixedStringHash(id)
+				if err != nil {
+					importer.ProgressLogger.Println("[WARNING] could not hash file identity, path:", metaDB.path, err)
+					continue
+				}
+				metaDB.fileHash, metaDB.path = hash, id
+			}
+
 			err := importer.markFileImportedCallback(metaDB.fileHash, metaDB.importID, metaDB.path)
 			if err != nil {
 				importer.ProgressLogger.Println("[WARNING] could not mark file as imported, path:", metaDB.path, err)
@@ -413,6 +681,43 @@ func (importer *Importer) startMetaDBFileTracker() {
 
 }
 
+// startRejectedLineTracker starts a goroutine to count the lines that failed to parse and record them in MetaDB
+// once all files have been parsed
+func (importer *Importer) startRejectedLineTracker() {
+
+	importer.wg.Rejected.Add(1)
+	go func() {
+		var lines []database.MetaDBRejectedLine
+		for rejected := range importer.RejectedLineChannel {
+			switch {
+			case errors.Is(rejected.err, errTooManyLineErrors):
+				importer.ResultCounts.AbandonedFiles++
+			case rejected.skipped:
+				importer.ResultCounts.RejectedLines++
+			default:
+				importer.ResultCounts.InvalidFields++
+			}
+
+			lines = append(lines, database.MetaDBRejectedLine{
+				ImportID:   importer.ImportID,
+				Timestamp:  time.Now().UTC(),
+				Path:       rejected.path,
+				LineNumber: rejected.lineNumber,
+				Field:      rejected.field,
+				Line:       rejected.line,
+				Error:      rejected.err.Error(),
+				Skipped:    rejected.skipped,
+			})
+		}
+
+		if err := importer.addRejectedLinesCallback(lines); err != nil {
+			importer.ProgressLogger.Println("[WARNING] could not record lines that failed to parse:", err)
+		}
+		importer.wg.Rejected.Done()
+	}()
+
+}
+
 // feedAndListenForFileCompletion feeds files to the paths channel and listens for the completion of each log type
 // to orchestrate feeding other log types into the paths channel
 func (importer *Importer) feedAndListenForFileCompletion() {
@@ -429,6 +734,20 @@ func (importer *Importer) feedAndListenForFileCompletion() {
 			case <-importer.DoneChannels.ssl:
 			case <-importer.DoneChannels.openssl:
 			case <-importer.DoneChannels.dns:
+			case <-importer.DoneChannels.x509:
+			case <-importer.DoneChannels.ssh:
+			case <-importer.DoneChannels.quic:
+			case <-importer.DoneChannels.dhcp:
+			case <-importer.DoneChannels.smbfiles:
+			case <-importer.DoneChannels.smbmapping:
+			case <-importer.DoneChannels.dcerpc:
+			case <-importer.DoneChannels.ntlm:
+			case <-importer.DoneChannels.kerberos:
+			case <-importer.DoneChannels.eve:
+			case <-importer.DoneChannels.ecs:
+			case <-importer.DoneChannels.flow:
+			case <-importer.DoneChannels.pcap:
+			case <-importer.DoneChannels.custom:
 
 			// increment progress bar
 			case <-importer.DoneChannels.filesDone:
@@ -439,6 +758,22 @@ func (importer *Importer) feedAndListenForFileCompletion() {
 		}
 	}()
 
+	// EVE logs contain flow, dns, http and tls events together, so they can be fed independently of the zeek logs
+	for _, eveLog := range importer.FileMap[EVEPrefix] {
+		importer.Paths <- eveLog
+	}
+	// ECS exports contain converted conn, dns, http and ssl records together, just like EVE logs
+	for _, ecsExport := range importer.FileMap[ECSPrefix] {
+		importer.Paths <- ecsExport
+	}
+	// flow exports only produce conn records, so they don't need to be linked to any other logs
+	for _, flowLog := range importer.FileMap[FlowPrefix] {
+		importer.Paths <- flowLog
+	}
+	// packet captures produce all of their conn, dns, http and ssl records together, just like EVE logs
+	for _, pcapHour := range importer.FileMap[PCAPPrefix] {
+		importer.Paths <- pcapHour
+	}
 	if len(importer.FileMap[ConnPrefix]) > 0 {
 		for _, connLog := range importer.FileMap[ConnPrefix] {
 			importer.Paths <- connLog
@@ -449,6 +784,12 @@ func (importer *Importer) feedAndListenForFileCompletion() {
 		for _, sslLog := range importer.FileMap[SSLPrefix] {
 			importer.Paths <- sslLog
 		}
+		for _, sshLog := range importer.FileMap[SSHPrefix] {
+			importer.Paths <- sshLog
+		}
+		for _, quicLog := range importer.FileMap[QUICPrefix] {
+			importer.Paths <- quicLog
+		}
 	}
 	if len(importer.FileMap[OpenConnPrefix]) > 0 {
 		for _, openConnLog := range importer.FileMap[OpenConnPrefix] {
@@ -464,10 +805,40 @@ func (importer *Importer) feedAndListenForFileCompletion() {
 	for _, dnsLog := range importer.FileMap[DNSPrefix] {
 		importer.Paths <- dnsLog
 	}
+	for _, x509Log := range importer.FileMap[X509Prefix] {
+		importer.Paths <- x509Log
+	}
+	// dhcp leases aren't linked to any connections, so they can be fed independently of the conn logs
+	for _, dhcpLog := range importer.FileMap[DHCPPrefix] {
+		importer.Paths <- dhcpLog
+	}
+	// lateral movement logs only record activity between internal hosts, which is filtered out of the conn logs,
+	// so they can be fed independently of the conn logs as well
+	for _, smbFilesLog := range importer.FileMap[SMBFilesPrefix] {
+		importer.Paths <- smbFilesLog
+	}
+	for _, smbMappingLog := range importer.FileMap[SMBMappingPrefix] {
+		importer.Paths <- smbMappingLog
+	}
+	for _, dceRPCLog := range importer.FileMap[DCERPCPrefix] {
+		importer.Paths <- dceRPCLog
+	}
+	for _, ntlmLog := range importer.FileMap[NTLMPrefix] {
+		importer.Paths <- ntlmLog
+	}
+	for _, kerberosLog := range importer.FileMap[KerberosPrefix] {
+		importer.Paths <- kerberosLog
+	}
+	// registered log types are written to their own tables, so they aren't linked to any other logs
+	for prefix := range importer.EntryChannels.Custom {
+		for _, customLog := range importer.FileMap[prefix] {
+			importer.Paths <- customLog
+		}
+	}
 }
 
 // digester loops over the paths, checks the file prefix, and sends each path to the parser with its corresponding entryChannel until either paths or done is closed.
-func digester(afs afero.Fs, done DoneChans, paths <-chan string, errc chan error, entryChannels EntryChans, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString, progressLogger *log.Logger) {
+func digester(afs afero.Fs, done DoneChans, paths <-chan string, errc chan error, rejectedLines chan<- RejectedLine, entryChannels EntryChans, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString, progressLogger *log.Logger) {
 	// errc := make(chan error)
 
 	// read entries from err channel, handle specific errors if necessary
@@ -482,27 +853,71 @@ func digester(afs afero.Fs, done DoneChans, paths <-chan string, errc chan error
 	for path := range paths {
 		progressLogger.Println("[-] Parsing: ", path)
 		switch {
+		case isPCAPHourPath(path):
+			parsePCAPFile(afs, path, entryChannels, errc, metaDBChan, database, importID)
+			done.pcap <- struct{}{}
+		case IsFlowFile(path):
+			parseFlowFile(afs, path, entryChannels.Conn, errc, metaDBChan, database, importID)
+			done.flow <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), EVEPrefix):
+			parseEVEFile(afs, path, entryChannels, errc, rejectedLines, metaDBChan, database, importID)
+			done.eve <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), ECSPrefix):
+			parseECSFile(afs, path, entryChannels, errc, rejectedLines, metaDBChan, database, importID)
+			done.ecs <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), ConnPrefix):
-			parseFile(afs, path, entryChannels.Conn, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.Conn, errc, rejectedLines, metaDBChan, database, importID)
 			done.conn <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), OpenConnPrefix):
-			parseFile(afs, path, entryChannels.OpenConn, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.OpenConn, errc, rejectedLines, metaDBChan, database, importID)
 			done.openconn <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), DNSPrefix):
-			parseFile(afs, path, entryChannels.DNS, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.DNS, errc, rejectedLines, metaDBChan, database, importID)
 			done.dns <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), HTTPPrefix):
-			parseFile(afs, path, entryChannels.HTTP, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.HTTP, errc, rejectedLines, metaDBChan, database, importID)
 			done.http <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), OpenHTTPPrefix):
-			parseFile(afs, path, entryChannels.OpenHTTP, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.OpenHTTP, errc, rejectedLines, metaDBChan, database, importID)
 			done.openhttp <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), SSLPrefix):
-			parseFile(afs, path, entryChannels.SSL, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.SSL, errc, rejectedLines, metaDBChan, database, importID)
 			done.ssl <- struct{}{}
 		case strings.HasPrefix(filepath.Base(path), OpenSSLPrefix):
-			parseFile(afs, path, entryChannels.OpenSSL, errc, metaDBChan, database, importID)
+			parseFile(afs, path, entryChannels.OpenSSL, errc, rejectedLines, metaDBChan, database, importID)
 			done.openssl <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), X509Prefix):
+			parseFile(afs, path, entryChannels.X509, errc, rejectedLines, metaDBChan, database, importID)
+			done.x509 <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), SSHPrefix):
+			parseFile(afs, path, entryChannels.SSH, errc, rejectedLines, metaDBChan, database, importID)
+			done.ssh <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), QUICPrefix):
+			parseFile(afs, path, entryChannels.QUIC, errc, rejectedLines, metaDBChan, database, importID)
+			done.quic <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), DHCPPrefix):
+			parseFile(afs, path, entryChannels.DHCP, errc, rejectedLines, metaDBChan, database, importID)
+			done.dhcp <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), SMBFilesPrefix):
+			parseFile(afs, path, entryChannels.SMBFiles, errc, rejectedLines, metaDBChan, database, importID)
+			done.smbfiles <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), SMBMappingPrefix):
+			parseFile(afs, path, entryChannels.SMBMapping, errc, rejectedLines, metaDBChan, database, importID)
+			done.smbmapping <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), DCERPCPrefix):
+			parseFile(afs, path, entryChannels.DCERPC, errc, rejectedLines, metaDBChan, database, importID)
+			done.dcerpc <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), NTLMPrefix):
+			parseFile(afs, path, entryChannels.NTLM, errc, rejectedLines, metaDBChan, database, importID)
+			done.ntlm <- struct{}{}
+		case strings.HasPrefix(filepath.Base(path), KerberosPrefix):
+			parseFile(afs, path, entryChannels.Kerberos, errc, rejectedLines, metaDBChan, database, importID)
+			done.kerberos <- struct{}{}
+		default:
+			if prefix, ok := RegisteredRecordTypePrefix(path); ok && entryChannels.Custom[prefix] != nil {
+				entryChannels.Custom[prefix].digest(afs, path, errc, rejectedLines, metaDBChan, database, importID)
+				done.custom <- struct{}{}
+			}
 		}
 		done.filesDone <- struct{}{}
 	}
@@ -519,6 +934,14 @@ func (writer *writers) startWriters(numWriters int) {
 		writer.OpenHTTPTmp.Start(i)
 		writer.SSLTmp.Start(i)
 		writer.OpenSSLTmp.Start(i)
+		writer.X509.Start(i)
+		writer.SSHTmp.Start(i)
+		writer.QUICTmp.Start(i)
+		writer.DHCP.Start(i)
+		writer.LateralMovement.Start(i)
+		for _, customWriter := range writer.Custom {
+			customWriter.Start(i)
+		}
 	}
 }
 
@@ -532,9 +955,17 @@ func (writer *writers) closeWriters() {
 	writer.OpenHTTPTmp.Close()
 	writer.SSLTmp.Close()
 	writer.OpenSSLTmp.Close()
+	writer.X509.Close()
+	writer.SSHTmp.Close()
+	writer.QUICTmp.Close()
+	writer.DHCP.Close()
+	writer.LateralMovement.Close()
+	for _, customWriter := range writer.Custom {
+		customWriter.Close()
+	}
 }
 
-// season links the http & ssl logs with the conn logs and adds data to those connections
+// season links the http, ssl, ssh & quic logs with the conn logs and adds data to those connections
 func (importer *Importer) season() error {
 	logger := zerolog.GetLogger()
 	cfg, err := config.GetConfig()
@@ -550,6 +981,8 @@ func (importer *Importer) season() error {
 	connWriter := database.NewBulkWriter(importer.Database, cfg, writerWorkers, importer.Database.GetSelectedDB(), "conn", "INSERT INTO {database:Identifier}.conn", limiter, false)
 	openHTTPWriter := database.NewBulkWriter(importer.Database, cfg, writerWorkers, importer.Database.GetSelectedDB(), "openhttp", "INSERT INTO {database:Identifier}.openhttp", limiter, false)
 	openConnWriter := database.NewBulkWriter(importer.Database, cfg, writerWorkers, importer.Database.GetSelectedDB(), "openconn", "INSERT INTO {database:Identifier}.openconn", limiter, false)
+	sshWriter := database.NewBulkWriter(importer.Database, cfg, writerWorkers, importer.Database.GetSelectedDB(), "ssh", "INSERT INTO {database:Identifier}.ssh", limiter, false)
+	quicWriter := database.NewBulkWriter(importer.Database, cfg, writerWorkers, importer.Database.GetSelectedDB(), "quic", "INSERT INTO {database:Identifier}.quic", limiter, false)
 
 	for i := 0; i < writerWorkers; i++ {
 		sslWriter.Start(i)
@@ -558,6 +991,8 @@ func (importer *Importer) season() error {
 		openHTTPWriter.Start(i)
 		connWriter.Start(i)
 		openConnWriter.Start(i)
+		sshWriter.Start(i)
+		quicWriter.Start(i)
 	}
 
 	linkingErrGroup, ctx := errgroup.WithContext(context.Background())
@@ -568,6 +1003,8 @@ func (importer *Importer) season() error {
 	var spinners []progressbar.Spinner
 	sslBarName := "🧂 Seasoning SSL connections "
 	httpBarName := "🧂 Seasoning HTTP connections"
+	sshBarName := "🧂 Seasoning SSH connections "
+	quicBarName := "🧂 Seasoning QUIC connections"
 	connSpinnerID := 0
 	openConnSpinnerID := 0
 	const (
@@ -575,6 +1012,8 @@ func (importer *Importer) season() error {
 		openHTTPID
 		sslID
 		openSSLID
+		sshID
+		quicID
 	)
 
 	if importer.ResultCounts.OpenConn > 0 {
@@ -588,10 +1027,20 @@ func (importer *Importer) season() error {
 			barList = append(barList, progressbar.NewBar("🧂 Seasoning open HTTP connections", openHTTPID, progress.New(gradient)))
 			httpBarName += "     "
 		}
+		if importer.ResultCounts.OpenSSL > 0 || importer.ResultCounts.OpenHTTP > 0 {
+			sshBarName += "     "
+			quicBarName += "     "
+		}
 	}
 
 	barList = append(barList, progressbar.NewBar(sslBarName, sslID, progress.New(gradient)))
 	barList = append(barList, progressbar.NewBar(httpBarName, httpID, progress.New(gradient)))
+	if importer.ResultCounts.SSH > 0 {
+		barList = append(barList, progressbar.NewBar(sshBarName, sshID, progress.New(gradient)))
+	}
+	if importer.ResultCounts.QUIC > 0 {
+		barList = append(barList, progressbar.NewBar(quicBarName, quicID, progress.New(gradient)))
+	}
 	spinners = append(spinners, progressbar.NewSpinner("Sifting IP connections...", connSpinnerID))
 	bars := progressbar.New(ctx, barList, spinners)
 
@@ -620,6 +1069,26 @@ func (importer *Importer) season() error {
 		return err
 	})
 
+	if importer.ResultCounts.SSH > 0 {
+		linkingErrGroup.Go(func() error {
+			err := importer.writeLinkedSSH(ctx, bars, sshID, sshWriter)
+			if err != nil {
+				logger.Error().Err(err).Msg("unable to link ssh connections")
+			}
+			return err
+		})
+	}
+
+	if importer.ResultCounts.QUIC > 0 {
+		linkingErrGroup.Go(func() error {
+			err := importer.writeLinkedQUIC(ctx, bars, quicID, quicWriter)
+			if err != nil {
+				logger.Error().Err(err).Msg("unable to link quic connections")
+			}
+			return err
+		})
+	}
+
 	linkingErrGroup.Go(func() error {
 		err := importer.writeUnfilteredConns(bars, false, connSpinnerID)
 		if err != nil {
@@ -666,6 +1135,8 @@ func (importer *Importer) season() error {
 	openHTTPWriter.Close()
 	connWriter.Close()
 	openConnWriter.Close()
+	sshWriter.Close()
+	quicWriter.Close()
 
 	// // don't truncate tmp tables in debug mode
 	// // these tables should be truncated before each import
@@ -674,3 +1145,24 @@ func (importer *Importer) season() error {
 	}
 	return importer.Database.TruncateTmpLinkTables()
 }
+
+// GetFileID returns the identity that a file is tracked by in the metadatabase, which is its path unless its
+// filesystem implements FileIdentifier
+func GetFileID(afs afero.Fs, path string) (string, error) {
+	identifier, ok := afs.(FileIdentifier)
+	if !ok {
+		return path, nil
+	}
+
+	// the hour of an hour path isn't part of the file, so it is kept as is
+	filePath, hour, err := splitHourPath(path)
+	if err != nil {
+		return identifier.FileID(path)
+	}
+
+	id, err := identifier.FileID(filePath)
+	if err != nil {
+		return "", err
+	}
+	return HourPath(id, hour), nil
+}
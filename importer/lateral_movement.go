@@ -0,0 +1,295 @@
+package importer
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer/zeektypes"
+	"activecm/rita/util"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// adminShareRegex matches the hidden administrative shares (ie, \\HOST\ADMIN$, \\HOST\C$)
+var adminShareRegex = regexp.MustCompile(`(?i)\\(admin|[a-z])\$$`)
+
+// LateralMovementEntry is the common structure that smb_files, smb_mapping, dce_rpc, ntlm and kerberos records
+// are formatted into so that internal to internal activity can be analyzed from a single table
+type LateralMovementEntry struct {
+	ImportTime     time.Time        `ch:"import_time"`
+	ZeekUID        util.FixedString `ch:"zeek_uid"`
+	Hash           util.FixedString `ch:"hash"`
+	Timestamp      time.Time        `ch:"ts"`
+	Src            net.IP           `ch:"src"`
+	Dst            net.IP           `ch:"dst"`
+	SrcNUID        uuid.UUID        `ch:"src_nuid"`
+	DstNUID        uuid.UUID        `ch:"dst_nuid"`
+	SrcPort        uint16           `ch:"src_port"`
+	DstPort        uint16           `ch:"dst_port"`
+	LogType        string           `ch:"log_type"`
+	Share          string           `ch:"share"`
+	AdminShare     bool             `ch:"admin_share"`
+	NamedPipe      string           `ch:"named_pipe"`
+	Endpoint       string           `ch:"endpoint"`
+	Operation      string           `ch:"operation"`
+	ServiceControl bool             `ch:"service_control"`
+	RequestType    string           `ch:"request_type"`
+	Service        string           `ch:"service"`
+	Username       string           `ch:"username"`
+	Success        bool             `ch:"success"`
+}
+
+// parseSMBFiles listens on a channel of raw smb_files log records, formats them and sends them to be written to the database
+func parseSMBFiles(smbFiles <-chan zeektypes.SMBFiles, output chan<- database.Data, importTime time.Time, numSMBFiles *uint64) {
+	// loop over raw smb_files channel
+	for s := range smbFiles {
+
+		// parse raw record as a lateral movement entry
+		entry, err := formatSMBFilesRecord(&s, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numSMBFiles, 1)
+	}
+}
+
+// parseSMBMapping listens on a channel of raw smb_mapping log records, formats them and sends them to be written to the database
+func parseSMBMapping(smbMapping <-chan zeektypes.SMBMapping, output chan<- database.Data, importTime time.Time, numSMBMapping *uint64) {
+	// loop over raw smb_mapping channel
+	for s := range smbMapping {
+
+		// parse raw record as a lateral movement entry
+		entry, err := formatSMBMappingRecord(&s, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numSMBMapping, 1)
+	}
+}
+
+// parseDCERPC listens on a channel of raw dce_rpc log records, formats them and sends them to be written to the database
+func parseDCERPC(dceRPC <-chan zeektypes.DCERPC, output chan<- database.Data, importTime time.Time, numDCERPC *uint64) {
+	// loop over raw dce_rpc channel
+	for d := range dceRPC {
+
+		// parse raw record as a lateral movement entry
+		entry, err := formatDCERPCRecord(&d, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numDCERPC, 1)
+	}
+}
+
+// parseNTLM listens on a channel of raw ntlm log records, formats them and sends them to be written to the database
+func parseNTLM(ntlm <-chan zeektypes.NTLM, output chan<- database.Data, importTime time.Time, numNTLM *uint64) {
+	// loop over raw ntlm channel
+	for n := range ntlm {
+
+		// parse raw record as a lateral movement entry
+		entry, err := formatNTLMRecord(&n, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numNTLM, 1)
+	}
+}
+
+// parseKerberos listens on a channel of raw kerberos log records, formats them and sends them to be written to the database
+func parseKerberos(kerberos <-chan zeektypes.Kerberos, output chan<- database.Data, importTime time.Time, numKerberos *uint64) {
+	// loop over raw kerberos channel
+	for k := range kerberos {
+
+		// parse raw record as a lateral movement entry
+		entry, err := formatKerberosRecord(&k, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numKerberos, 1)
+	}
+}
+
+// formatSMBFilesRecord takes a raw smb_files record and formats it into the structure needed by the database
+func formatSMBFilesRecord(parseSMBFiles *zeektypes.SMBFiles, importTime time.Time) (*LateralMovementEntry, error) {
+	entry, err := newLateralMovementEntry(zeektypes.EntryTypeSMBFiles, parseSMBFiles.TimeStamp, parseSMBFiles.UID,
+		parseSMBFiles.Source, parseSMBFiles.Destination, parseSMBFiles.SourcePort, parseSMBFiles.DestinationPort, parseSMBFiles.AgentUUID, importTime)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+
+	entry.Share = parseSMBFiles.Path
+	entry.AdminShare = isAdminShare(parseSMBFiles.Path)
+	entry.Operation = parseSMBFiles.Action
+	// opening the service control manager's pipe shows up as a file named svcctl on the IPC$ share
+	entry.ServiceControl = isServiceControlPipe(parseSMBFiles.Name)
+
+	return entry, nil
+}
+
+// formatSMBMappingRecord takes a raw smb_mapping record and formats it into the structure needed by the database
+func formatSMBMappingRecord(parseSMBMapping *zeektypes.SMBMapping, importTime time.Time) (*LateralMovementEntry, error) {
+	entry, err := newLateralMovementEntry(zeektypes.EntryTypeSMBMapping, parseSMBMapping.TimeStamp, parseSMBMapping.UID,
+		parseSMBMapping.Source, parseSMBMapping.Destination, parseSMBMapping.SourcePort, parseSMBMapping.DestinationPort, parseSMBMapping.AgentUUID, importTime)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+
+	entry.Share = parseSMBMapping.Path
+	entry.AdminShare = isAdminShare(parseSMBMapping.Path)
+
+	return entry, nil
+}
+
+// formatDCERPCRecord takes a raw dce_rpc record and formats it into the structure needed by the database
+func formatDCERPCRecord(parseDCERPC *zeektypes.DCERPC, importTime time.Time) (*LateralMovementEntry, error) {
+	entry, err := newLateralMovementEntry(zeektypes.EntryTypeDCERPC, parseDCERPC.TimeStamp, parseDCERPC.UID,
+		parseDCERPC.Source, parseDCERPC.Destination, parseDCERPC.SourcePort, parseDCERPC.DestinationPort, parseDCERPC.AgentUUID, importTime)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+
+	entry.NamedPipe = parseDCERPC.NamedPipe
+	entry.Endpoint = parseDCERPC.Endpoint
+	entry.Operation = parseDCERPC.Operation
+	entry.ServiceControl = isServiceControlPipe(parseDCERPC.Endpoint) || isServiceControlPipe(parseDCERPC.NamedPipe)
+
+	return entry, nil
+}
+
+// formatNTLMRecord takes a raw ntlm record and formats it into the structure needed by the database
+func formatNTLMRecord(parseNTLM *zeektypes.NTLM, importTime time.Time) (*LateralMovementEntry, error) {
+	entry, err := newLateralMovementEntry(zeektypes.EntryTypeNTLM, parseNTLM.TimeStamp, parseNTLM.UID,
+		parseNTLM.Source, parseNTLM.Destination, parseNTLM.SourcePort, parseNTLM.DestinationPort, parseNTLM.AgentUUID, importTime)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+
+	entry.Username = parseNTLM.Username
+	if parseNTLM.DomainName != "" && parseNTLM.Username != "" {
+		entry.Username = parseNTLM.DomainName + `\` + parseNTLM.Username
+	}
+	entry.Success = parseNTLM.Success
+
+	return entry, nil
+}
+
+// formatKerberosRecord takes a raw kerberos record and formats it into the structure needed by the database
+func formatKerberosRecord(parseKerberos *zeektypes.Kerberos, importTime time.Time) (*LateralMovementEntry, error) {
+	entry, err := newLateralMovementEntry(zeektypes.EntryTypeKerberos, parseKerberos.TimeStamp, parseKerberos.UID,
+		parseKerberos.Source, parseKerberos.Destination, parseKerberos.SourcePort, parseKerberos.DestinationPort, parseKerberos.AgentUUID, importTime)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+
+	entry.RequestType = parseKerberos.RequestType
+	entry.Service = parseKerberos.Service
+	entry.Username = parseKerberos.Client
+	entry.Success = parseKerberos.Success
+
+	return entry, nil
+}
+
+// newLateralMovementEntry formats the connection details that are shared between all of the lateral movement log types.
+// A nil entry is returned if the connection pair is filtered.
+func newLateralMovementEntry(logType string, ts zeektypes.Timestamp, uid, src, dst string, srcPort, dstPort int, agentUUID string, importTime time.Time) (*LateralMovementEntry, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// parse source and destination
+	srcIP := net.ParseIP(src)
+	dstIP := net.ParseIP(dst)
+
+	// verify that both addresses were parsed successfully
+	if (srcIP == nil) || (dstIP == nil) {
+		return nil, errors.New(errParseSrcDst)
+	}
+
+	if cfg.Filter.FilterLateralPair(srcIP, dstIP) {
+		return nil, nil
+	}
+
+	srcNUID := util.ParseNetworkID(srcIP, agentUUID)
+	dstNUID := util.ParseNetworkID(dstIP, agentUUID)
+
+	zeekUID, err := util.NewFixedStringHash(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := util.NewFixedStringHash(srcIP.To16().String(), srcNUID.String(), dstIP.To16().String(), dstNUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LateralMovementEntry{
+		ImportTime: importTime,
+		ZeekUID:    zeekUID,
+		Hash:       hash,
+		Timestamp:  time.Unix(int64(ts), 0),
+		Src:        srcIP,
+		Dst:        dstIP,
+		SrcNUID:    srcNUID,
+		DstNUID:    dstNUID,
+		SrcPort:    uint16(srcPort),
+		DstPort:    uint16(dstPort),
+		LogType:    logType,
+	}, nil
+}
+
+// isAdminShare returns true if the share path is one of the hidden administrative shares
+func isAdminShare(path string) bool {
+	return adminShareRegex.MatchString(path)
+}
+
+// isServiceControlPipe returns true if the pipe or endpoint is the remote service control manager
+func isServiceControlPipe(name string) bool {
+	name = strings.ToLower(name)
+	return name == "svcctl" || strings.HasSuffix(name, `\svcctl`)
+}
@@ -1,12 +1,12 @@
 package importer
 
 import (
+	"activecm/rita/importer/zeektypes"
 	zerolog "activecm/rita/logger"
 	"activecm/rita/util"
-	"bufio"
-	"compress/gzip"
 	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 	"reflect"
 	"strconv"
@@ -20,9 +20,10 @@ import (
 var errTruncated = errors.New("log file is potentially truncated")
 var errUnknownFileType = errors.New("failed to parse log file: unknown file type or malformed header")
 var errMismatchedPathField = errors.New("TSV 'path' field does not match file pathname prefix")
+var errTooManyLineErrors = errors.New("too many lines failed to parse, the rest of the file was skipped")
 
 // ZeekHeader stores vars in the header of the zeek log
-type ZeekHeader[Z zeekRecord] struct {
+type ZeekHeader[Z any] struct {
 	separator             string
 	setSeparator          string
 	emptyField            string
@@ -36,6 +37,8 @@ type ZeekHeader[Z zeekRecord] struct {
 	isJSON                bool
 	headerToStructMapping map[string]int
 	fsPath                string // actual file system path of log
+	// fieldMapping renames the fields of the log before they are mapped to the struct, see fieldMappingFor
+	fieldMapping *fieldMapping
 }
 
 type MetaDBFile struct {
@@ -45,6 +48,29 @@ type MetaDBFile struct {
 	path     string
 }
 
+// RejectedLine is a log line that failed to parse. Lines are either skipped entirely or, if only some of their
+// fields failed to parse, imported with those fields unset.
+type RejectedLine struct {
+	path       string
+	lineNumber uint64
+	field      string
+	line       string
+	err        error
+	skipped    bool
+}
+
+// lineRejecter sends the lines of a file that failed to parse on the rejected lines channel. Files that were bucketed
+// into hours are parsed once for each hour, so lines are only reported while parsing the hour of the record before
+// them, or the hour of the first record if they come before any record, so that each line is only reported once.
+type lineRejecter struct {
+	rejectedLines chan<- RejectedLine
+	byHour        bool
+	hour          time.Time
+	positionKnown bool // whether the hour of the current position in the file is known
+	inHour        bool // whether the current position in the file is within the hour being imported
+	pending       []RejectedLine
+}
+
 // ZeekDateTimeFmt is the common format for zeek header datetimes
 const ZeekDateTimeFmt = "2006-01-02-15-04-05"
 
@@ -55,6 +81,19 @@ const HTTPPrefix = "http"
 const OpenHTTPPrefix = "open_http"
 const SSLPrefix = "ssl"
 const OpenSSLPrefix = "open_ssl"
+const X509Prefix = "x509"
+const SSHPrefix = "ssh"
+const QUICPrefix = "quic"
+const DHCPPrefix = "dhcp"
+const SMBFilesPrefix = "smb_files"
+const SMBMappingPrefix = "smb_mapping"
+const DCERPCPrefix = "dce_rpc"
+const NTLMPrefix = "ntlm"
+const KerberosPrefix = "kerberos"
+const EVEPrefix = "eve"
+const ECSPrefix = "ecs"
+const FlowPrefix = "flow"
+const PCAPPrefix = "pcap"
 const ConnSummaryPrefixUnderscore = "conn_summary"
 const ConnSummaryPrefixHyphen = "conn-summary"
 
@@ -63,11 +102,18 @@ const lineErrorLimit = 25
 // parseFile is a generic function that determines if a passed in path belongs to a tsv or json file, parses the file header and scans through each subsequent line,
 // parsing/unmarshaling it into its associated zeektype and sending it on the passed in generic channel. The generic type is based on the path's prefix in the calling
 // function.
-func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc chan<- error, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
+func parseFile[Z any, P zeekRecord[Z]](afs afero.Fs, path string, entryChan chan<- Z, errc chan<- error, rejectedLines chan<- RejectedLine, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
 	logger := zerolog.GetLogger()
 
+	// logs that were bucketed into hours by record timestamp are imported one hour at a time, see HourPath
+	filePath, hour, hourErr := splitHourPath(path)
+	byHour := hourErr == nil
+	if !byHour {
+		filePath = path
+	}
+
 	// open file for reading
-	empty, err := afero.IsEmpty(afs, path)
+	empty, err := afero.IsEmpty(afs, filePath)
 	if err != nil {
 		logger.Err(err).Str("path", path).Msg("could not determine if file is empty")
 		return
@@ -79,12 +125,13 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 		return
 	}
 
-	file, err := afs.Open(path)
+	// open the file, or the file of its hour if it was split into hours, decompressing it if the file extension insinuates that it is compressed
+	scanner, closeFile, err := newLogScanner(afs, path)
 	if err != nil {
 		logger.Err(err).Str("path", path).Msg("could not open file for parsing")
 		return
 	}
-	defer file.Close()
+	defer closeFile()
 
 	fileHash, err := util.NewFixedStringHash(path)
 	if err != nil {
@@ -99,29 +146,10 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 		path:     path,
 	}
 
-	// set up a new scanner to read from file
-	var scanner *bufio.Scanner
-	if strings.HasSuffix(path, ".gz") {
-		// create gzip reader if the file extension insinuates that the file is compressed
-		gzipReader, err := gzip.NewReader(file)
-		if err != nil { // handle error from scanner
-			logger.Err(err).Str("path", path).Msg("failed to parse log file: could not open compressed file")
-			return
-		}
-		scanner = bufio.NewScanner(gzipReader)
-		defer gzipReader.Close()
-	} else {
-		scanner = bufio.NewScanner(file)
-	}
-
-	// set a buffer for the scanner
-	initialBufferSize := 64 * 1024 // 64KiB
-	maxBufferSize := 1024 * 1024   // 1MiB
-	scanner.Buffer(make([]byte, 0, initialBufferSize), maxBufferSize)
-
 	// declare new header object for parsing tsv headers
 	var header ZeekHeader[Z]
 	header.headerToStructMapping = make(map[string]int)
+	header.fieldMapping = fieldMappingFor(filePath)
 
 	var typeArr []string
 
@@ -132,6 +160,8 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 	// a file that had more than a certain amount of errors
 	lineErrorCounter := 0
 
+	rejecter := lineRejecter{rejectedLines: rejectedLines, byHour: byHour, hour: hour}
+
 	previousLineHadError := false
 
 	// iterate over lines in file
@@ -142,6 +172,9 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 			return
 		}
 
+		// track the line number so that rejected lines can be found in the file
+		lineNumber := scanner.LineNumber()
+
 		// skip empty lines
 		if len(scanner.Bytes()) < 1 {
 			continue
@@ -178,7 +211,7 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 					header.isTSV = true
 
 					// check & warn if path field doesn't match filename prefix
-					header.fsPath = path
+					header.fsPath = filePath
 					err := header.validatePathPrefix()
 					if err != nil {
 						logger.Error().Str("path", path).Err(err).Send()
@@ -204,13 +237,16 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 		// parse this line as JSON if we've determined this file is in JSON format
 		if header.isJSON {
 			previousLineHadError = false
-			// unmarshal line
-			if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// unmarshal line, renaming the configured fields
+			err := header.fieldMapping.unmarshalJSON(scanner.Bytes(), &entry)
+			if err != nil {
 				logger.Err(err).Str("path", path).Bytes("record", scanner.Bytes()).Msg("failed to unmarshal line from JSON")
+				rejecter.reject(RejectedLine{path: filePath, lineNumber: lineNumber, line: scanner.Text(), err: err, skipped: true})
 				lineErrorCounter++
 				previousLineHadError = true
 				if lineErrorCounter > lineErrorLimit {
 					logger.Warn().Str("path", path).Msg("failed to parse log file: file is potentially corrupted")
+					rejecter.reject(RejectedLine{path: filePath, lineNumber: lineNumber, line: scanner.Text(), err: errTooManyLineErrors, skipped: true})
 					// set this flag to false so that we don't log that this file could be truncated
 					previousLineHadError = false
 					break
@@ -218,9 +254,17 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 				continue
 			}
 
-			// set log path field
 			data := reflect.ValueOf(&entry).Elem()
-			data.FieldByName("LogPath").SetString(path)
+			rejecter.position(zeektypes.Timestamp(data.FieldByName("TimeStamp").Int()))
+
+			// skip records that belong to another hour of the file
+			if byHour && !timestampInHour(zeektypes.Timestamp(data.FieldByName("TimeStamp").Int()), hour) {
+				resetZeekRecord(&entry)
+				continue
+			}
+
+			// set log path field
+			P(&entry).SetLogPath(path)
 
 			// send parsed entry to its appropriate channel
 			entryChan <- entry
@@ -243,10 +287,14 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 
 			// scan in line
 			line := scanner.Text()
+			rawLine := line
 
 			// track whether or not this line had an error when parsing any fields
 			lineHadError := false
 
+			// track the fields that failed to parse, which are reported once the hour of the record is known
+			var fieldErrors []RejectedLine
+
 			// set the end index of the field itself to the index of the next tab (or separator)
 			fieldEndIndex := strings.Index(line, header.separator)
 
@@ -273,6 +321,7 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 								Str("field_name", header.fieldOrder[idx]).
 								Str("field_value", line).
 								Msg("failed to parse field in TSV Zeek log")
+							fieldErrors = append(fieldErrors, RejectedLine{path: filePath, lineNumber: lineNumber, field: header.fieldOrder[idx], line: rawLine, err: err})
 							lineHadError = true
 							previousLineHadError = true
 						}
@@ -289,6 +338,7 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 
 			if fieldEndIndex == -1 && idx < len(header.fieldOrder)-2 {
 				logger.Err(errTruncated).Str("path", path).Send()
+				rejecter.reject(RejectedLine{path: filePath, lineNumber: lineNumber, line: rawLine, err: errTruncated, skipped: true})
 				errc <- errTruncated
 				break
 			}
@@ -307,11 +357,17 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 						Str("field_name", header.fieldOrder[idx]).
 						Str("field_value", line).
 						Msg("failed to parse field in TSV Zeek log")
+					fieldErrors = append(fieldErrors, RejectedLine{path: filePath, lineNumber: lineNumber, field: header.fieldOrder[idx], line: rawLine, err: err})
 					lineHadError = true
 					previousLineHadError = true
 				}
 			}
 
+			// the timestamp of a record that failed to parse can't be used to locate the line
+			if ts := zeektypes.Timestamp(data.FieldByName("TimeStamp").Int()); ts != 0 {
+				rejecter.position(ts)
+			}
+
 			// increment file parsing error count if there were errors during field parsing
 			if lineHadError {
 				lineErrorCounter++
@@ -320,13 +376,25 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 			// return if parsing error limit for file was reached
 			if lineErrorCounter > lineErrorLimit {
 				logger.Warn().Str("path", path).Msg("log file is potentially corrupted")
+				rejecter.reject(RejectedLine{path: filePath, lineNumber: lineNumber, line: rawLine, err: errTooManyLineErrors, skipped: true})
 				// set this flag to false so that we don't log that this file could be truncated
 				previousLineHadError = false
 				break
 			}
 
+			// the line is still imported without the fields that failed to parse
+			for _, fieldError := range fieldErrors {
+				rejecter.reject(fieldError)
+			}
+
+			// skip records that belong to another hour of the file
+			if byHour && !timestampInHour(zeektypes.Timestamp(data.FieldByName("TimeStamp").Int()), hour) {
+				resetZeekRecord(&entry)
+				continue
+			}
+
 			// set log path field
-			data.FieldByName("LogPath").SetString(path)
+			P(&entry).SetLogPath(path)
 
 			// send parsed entry to its appropriate channel
 			entryChan <- entry
@@ -343,6 +411,58 @@ func parseFile[Z zeekRecord](afs afero.Fs, path string, entryChan chan<- Z, errc
 	}
 }
 
+// reject reports a line that failed to parse
+func (r *lineRejecter) reject(line RejectedLine) {
+	switch {
+	case !r.byHour || (r.positionKnown && r.inHour):
+		r.rejectedLines <- line
+	case !r.positionKnown:
+		r.pending = append(r.pending, line)
+	}
+}
+
+// position updates the current position in the file with the timestamp of a parsed record
+func (r *lineRejecter) position(ts zeektypes.Timestamp) {
+	r.inHour = timestampInHour(ts, r.hour)
+	if r.positionKnown {
+		return
+	}
+
+	// the lines before the first record belong to the hour of the first record
+	r.positionKnown = true
+	if r.inHour {
+		for _, line := range r.pending {
+			r.rejectedLines <- line
+		}
+	}
+	r.pending = nil
+}
+
+// openLogFile opens a log file for reading, decompressing it if the file extension insinuates that it is compressed,
+// and returns a reader for its contents along with a function that closes the file
+func openLogFile(afs afero.Fs, path string) (io.Reader, func(), error) {
+	file, err := afs.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decompressor, ok := getDecompressor(path)
+	if !ok {
+		return file, func() { file.Close() }, nil
+	}
+
+	reader, err := decompressor(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return reader, func() {
+		reader.Close()
+		file.Close()
+	}, nil
+}
+
 // parseHeader parses the header of a Zeek log in TSV format
 func (header *ZeekHeader[Z]) parseHeader(line string) (typeArr []string, err error) {
 
@@ -381,6 +501,9 @@ func (header *ZeekHeader[Z]) parseHeader(line string) (typeArr []string, err err
 		splitFields = splitFields[1:]
 		splitTypes = splitTypes[1:]
 
+		// rename the fields that the log records under other names than RITA
+		header.fieldMapping.mapFieldNames(splitFields)
+
 		if len(splitTypes) == len(splitFields) {
 			typeArr = make([]string, len(splitFields))
 			for idx := range splitFields {
@@ -497,6 +620,8 @@ func (header *ZeekHeader[Z]) parseField(value string, zeekType string, resultFie
 	case "set[enum]":
 		fallthrough
 	case "vector[string]":
+		fallthrough
+	case "vector[addr]":
 		strsSplit := strings.Split(value, header.setSeparator)
 		tval := reflect.ValueOf(strsSplit)
 		resultField.Set(tval)
@@ -549,6 +674,46 @@ func (header *ZeekHeader[Z]) validatePathPrefix() (err error) {
 		if header.path != OpenSSLPrefix {
 			return errMismatchedPathField
 		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), X509Prefix):
+		if header.path != X509Prefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), SSHPrefix):
+		if header.path != SSHPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), QUICPrefix):
+		if header.path != QUICPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), DHCPPrefix):
+		if header.path != DHCPPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), SMBFilesPrefix):
+		if header.path != SMBFilesPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), SMBMappingPrefix):
+		if header.path != SMBMappingPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), DCERPCPrefix):
+		if header.path != DCERPCPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), NTLMPrefix):
+		if header.path != NTLMPrefix {
+			return errMismatchedPathField
+		}
+	case strings.HasPrefix(filepath.Base(header.fsPath), KerberosPrefix):
+		if header.path != KerberosPrefix {
+			return errMismatchedPathField
+		}
+	default:
+		if prefix, ok := RegisteredRecordTypePrefix(header.fsPath); ok && header.path != prefix {
+			return errMismatchedPathField
+		}
 	}
 	return nil
 }
@@ -1,10 +1,17 @@
 package importer
 
 import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer/pcap"
 	"activecm/rita/importer/zeektypes"
 	"activecm/rita/util"
+	"archive/tar"
+	"bytes"
 	"errors"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,7 +33,7 @@ func TestTruncatedTSV(t *testing.T) {
 	require.NoError(t, err)
 
 	go func() {
-		parseFile(afero.NewOsFs(), path, entries, errc, metaDBChan, "test", importID)
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
 		close(errc)
 		close(entries)
 		close(metaDBChan)
@@ -72,7 +79,7 @@ func TestTruncatedHeader(t *testing.T) {
 	require.NoError(t, err)
 
 	go func() {
-		parseFile(afero.NewOsFs(), path, entries, errc, metaDBChan, "test", importID)
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
 		close(errc)
 		close(entries)
 		close(metaDBChan)
@@ -116,7 +123,7 @@ func TestTruncatedJSON(t *testing.T) {
 	require.NoError(t, err)
 
 	go func() {
-		parseFile(afero.NewOsFs(), path, entries, errc, metaDBChan, "test", importID)
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
 		close(errc)
 		close(entries)
 		close(metaDBChan)
@@ -163,7 +170,7 @@ func TestHasUnknownFieldTSV(t *testing.T) {
 	require.NoError(t, err)
 
 	go func() {
-		parseFile(afero.NewOsFs(), path, entries, errc, metaDBChan, "test", importID)
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
 		close(errc)
 		close(entries)
 		close(metaDBChan)
@@ -214,7 +221,7 @@ func TestPlainTextFile(t *testing.T) {
 	require.NoError(t, err)
 
 	go func() {
-		parseFile(afero.NewOsFs(), path, entries, errc, metaDBChan, "test", importID)
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
 		close(errc)
 		close(entries)
 		close(metaDBChan)
@@ -246,3 +253,1612 @@ func TestPlainTextFile(t *testing.T) {
 	}
 	require.True(t, receivedErr, "should receive unknown file type error")
 }
+
+func TestParseX509TSV(t *testing.T) {
+	path := "../test_data/x509/x509.log"
+
+	entries := make(chan zeektypes.X509)
+	errc := make(chan error)
+	metaDBChan := make(chan MetaDBFile)
+
+	// get the current time in microseconds
+	start := time.Now().UTC().UnixMicro()
+
+	// create a unique import id using the start time
+	importID, err := util.NewFixedStringHash(strconv.FormatInt(start, 10))
+	require.NoError(t, err)
+
+	go func() {
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
+		close(errc)
+		close(entries)
+		close(metaDBChan)
+	}()
+
+	receivedErr := false
+	openChannels := 3
+	var records []zeektypes.X509
+	for openChannels > 0 {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				openChannels--
+			} else {
+				records = append(records, entry)
+			}
+		case _, ok := <-metaDBChan:
+			if !ok {
+				openChannels--
+			}
+		case err, ok := <-errc:
+			if !ok {
+				openChannels--
+			} else if err != nil {
+				receivedErr = true
+			}
+		}
+	}
+
+	require.False(t, receivedErr, "parsing x509 log should not produce an error")
+	require.Len(t, records, 3, "number of x509 records")
+
+	// verify that the self-signed certificate was parsed correctly
+	cert := records[1]
+	require.Equal(t, "FHb3dp1ZQZ3Ag9CUC4", cert.FUID, "file id should match expected value")
+	require.Equal(t, "CN=beacon.evil.test", cert.Subject, "subject should match expected value")
+	require.Equal(t, cert.Subject, cert.Issuer, "issuer should match subject")
+	require.EqualValues(t, 1713456000, cert.NotValidBefore, "not valid before should match expected value")
+	require.EqualValues(t, 1714060800, cert.NotValidAfter, "not valid after should match expected value")
+	require.EqualValues(t, 256, cert.KeyLength, "key length should match expected value")
+	require.Equal(t, "prime256v1", cert.Curve, "curve should match expected value")
+	require.Equal(t, []string{"beacon.evil.test"}, cert.SANDNS, "SAN DNS entries should match expected value")
+	require.Equal(t, []string{"10.55.100.103", "192.168.88.2"}, cert.SANIP, "SAN IP entries should match expected value")
+	require.True(t, cert.BasicConstraintsCA, "basic constraints CA should be set")
+	require.True(t, cert.HostCert, "host cert should be set")
+	require.Equal(t, path, cert.LogPath, "log path should be set")
+
+	// verify that the record can be formatted for the database
+	entry, err := formatX509Record(&cert, time.Unix(start/1e6, 0))
+	require.NoError(t, err, "formatting x509 record should not produce an error")
+	require.Equal(t, time.Unix(1714060800, 0), entry.NotValidAfter, "not valid after should match expected value")
+	require.Equal(t, []string{"10.55.100.103", "192.168.88.2"}, entry.SANIP, "SAN IP entries should match expected value")
+}
+
+func TestParseSSHTSV(t *testing.T) {
+	path := "../test_data/ssh/ssh.log"
+
+	entries := make(chan zeektypes.SSH)
+	errc := make(chan error)
+	metaDBChan := make(chan MetaDBFile)
+
+	// get the current time in microseconds
+	start := time.Now().UTC().UnixMicro()
+
+	// create a unique import id using the start time
+	importID, err := util.NewFixedStringHash(strconv.FormatInt(start, 10))
+	require.NoError(t, err)
+
+	go func() {
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
+		close(errc)
+		close(entries)
+		close(metaDBChan)
+	}()
+
+	receivedErr := false
+	openChannels := 3
+	var records []zeektypes.SSH
+	for openChannels > 0 {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				openChannels--
+			} else {
+				records = append(records, entry)
+			}
+		case _, ok := <-metaDBChan:
+			if !ok {
+				openChannels--
+			}
+		case err, ok := <-errc:
+			if !ok {
+				openChannels--
+			} else if err != nil {
+				receivedErr = true
+			}
+		}
+	}
+
+	require.False(t, receivedErr, "parsing ssh log should not produce an error")
+	require.Len(t, records, 3, "number of ssh records")
+
+	// verify that the successful login was parsed correctly
+	login := records[1]
+	require.Equal(t, "C3iTkR1ZsJ2fWy7bXb", login.UID, "uid should match expected value")
+	require.Equal(t, "10.55.100.105", login.Source, "source should match expected value")
+	require.Equal(t, "203.0.113.77", login.Destination, "destination should match expected value")
+	require.Equal(t, 22, login.DestinationPort, "destination port should match expected value")
+	require.EqualValues(t, 2, login.Version, "version should match expected value")
+	require.True(t, login.AuthSuccess, "auth success should be set")
+	require.EqualValues(t, 7, login.AuthAttempts, "auth attempts should match expected value")
+	require.Equal(t, "SSH-2.0-libssh2_1.10.0", login.Client, "client should match expected value")
+	require.Equal(t, "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", login.Server, "server should match expected value")
+	require.Equal(t, "curve25519-sha256", login.KexAlgorithm, "key exchange algorithm should match expected value")
+	require.Equal(t, path, login.LogPath, "log path should be set")
+
+	// verify that unset fields are left empty
+	internal := records[2]
+	require.False(t, internal.AuthSuccess, "unset auth success should be false")
+	require.Zero(t, internal.AuthAttempts, "unset auth attempts should be zero")
+	require.Empty(t, internal.MACAlgorithm, "unset mac algorithm should be empty")
+
+	// verify that the record is tracked by the same hash as the connection between the two hosts
+	entry, err := formatSSHRecord(&login, time.Unix(start/1e6, 0))
+	require.NoError(t, err, "formatting ssh record should not produce an error")
+	connHash, err := util.NewFixedStringHash(entry.Src.To16().String(), entry.SrcNUID.String(), entry.Dst.To16().String(), entry.DstNUID.String())
+	require.NoError(t, err)
+	require.Equal(t, connHash, entry.Hash, "hash should match the connection hash")
+	require.EqualValues(t, 7, entry.AuthAttempts, "auth attempts should match expected value")
+}
+
+func TestParseEVE(t *testing.T) {
+	path := "../test_data/eve/eve.json"
+
+	entryChannels := EntryChans{
+		Conn: make(chan zeektypes.Conn, 10),
+		DNS:  make(chan zeektypes.DNS, 10),
+		HTTP: make(chan zeektypes.HTTP, 10),
+		SSL:  make(chan zeektypes.SSL, 10),
+	}
+	errc := make(chan error, 10)
+	metaDBChan := make(chan MetaDBFile, 10)
+
+	// get the current time in microseconds
+	start := time.Now().UTC().UnixMicro()
+
+	// create a unique import id using the start time
+	importID, err := util.NewFixedStringHash(strconv.FormatInt(start, 10))
+	require.NoError(t, err)
+
+	parseEVEFile(afero.NewOsFs(), path, entryChannels, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
+	close(entryChannels.Conn)
+	close(entryChannels.DNS)
+	close(entryChannels.HTTP)
+	close(entryChannels.SSL)
+	close(errc)
+	close(metaDBChan)
+
+	for err := range errc {
+		require.NoError(t, err, "parsing EVE log should not produce an error")
+	}
+	require.Len(t, metaDBChan, 1, "file should be marked as imported once")
+
+	var conns []zeektypes.Conn
+	for c := range entryChannels.Conn {
+		conns = append(conns, c)
+	}
+	var dns []zeektypes.DNS
+	for d := range entryChannels.DNS {
+		dns = append(dns, d)
+	}
+	var http []zeektypes.HTTP
+	for h := range entryChannels.HTTP {
+		http = append(http, h)
+	}
+	var ssl []zeektypes.SSL
+	for s := range entryChannels.SSL {
+		ssl = append(ssl, s)
+	}
+
+	// alert and stats events are ignored
+	require.Len(t, conns, 4, "number of conn records")
+	require.Len(t, dns, 2, "number of dns records, queries without answers are skipped")
+	require.Len(t, http, 1, "number of http records")
+	require.Len(t, ssl, 1, "number of ssl records")
+
+	// verify that the tls flow was converted into a conn record
+	conn := conns[0]
+	require.Equal(t, "1880582341203042", conn.UID, "uid should be the flow id")
+	require.EqualValues(t, 1713542401, conn.TimeStamp, "timestamp should be the start of the flow")
+	require.InDelta(t, 2.5, conn.Duration, 0.001, "duration should match expected value")
+	require.Equal(t, "tcp", conn.Proto, "proto should be lowercase")
+	require.Equal(t, "ssl", conn.Service, "tls service should be renamed to ssl")
+	require.EqualValues(t, 1880, conn.OrigIPBytes, "orig ip bytes should match expected value")
+	require.EqualValues(t, 6440, conn.RespIPBytes, "resp ip bytes should match expected value")
+	require.EqualValues(t, 1880-12*40, conn.OrigBytes, "orig bytes should exclude headers")
+	require.EqualValues(t, 10, conn.RespPackets, "resp packets should match expected value")
+	require.Equal(t, "SF", conn.ConnState, "conn state should match expected value")
+	require.Equal(t, path, conn.LogPath, "log path should be set")
+
+	// verify that icmp type and code are stored in place of the ports
+	require.Equal(t, "icmp", conns[3].Proto, "proto should be icmp")
+	require.Equal(t, 8, conns[3].SourcePort, "source port should be the icmp type")
+	require.Equal(t, 0, conns[3].DestinationPort, "destination port should be the icmp code")
+
+	// verify that the version 2 dns answer was converted
+	require.Equal(t, "1180582341203041", dns[0].UID, "uid should be the flow id")
+	require.Equal(t, "www.example.com", dns[0].Query, "query should match expected value")
+	require.EqualValues(t, 1, dns[0].QType, "query type should match expected value")
+	require.Equal(t, "A", dns[0].QTypeName, "query type name should match expected value")
+	require.Equal(t, []string{"example.com", "93.184.216.34"}, dns[0].Answers, "answers should match expected value")
+	require.Equal(t, []float64{300, 60}, dns[0].TTLs, "ttls should match expected value")
+	require.Equal(t, "udp", dns[0].Proto, "proto should be lowercase")
+
+	// verify that the version 3 dns response was converted
+	require.Equal(t, "qz7xk2m.badomain.test", dns[1].Query, "query should match expected value")
+	require.Equal(t, "TXT", dns[1].QTypeName, "query type name should match expected value")
+	require.EqualValues(t, 3, dns[1].RCode, "response code should match expected value")
+	require.Equal(t, "NXDOMAIN", dns[1].RCodeName, "response code name should match expected value")
+	require.Empty(t, dns[1].Answers, "NXDOMAIN response should have no answers")
+
+	// verify that the http event was converted
+	require.Equal(t, conns[1].UID, http[0].UID, "http uid should match its flow")
+	require.Equal(t, "www.example.com", http[0].Host, "host should match expected value")
+	require.Equal(t, "/index.html", http[0].URI, "uri should match expected value")
+	require.Equal(t, "curl/8.4.0", http[0].UserAgent, "useragent should match expected value")
+	require.Equal(t, "1.1", http[0].Version, "version should match expected value")
+	require.Equal(t, []string{"text/html"}, http[0].RespMimeTypes, "mime types should match expected value")
+	require.EqualValues(t, 200, http[0].StatusCode, "status code should match expected value")
+
+	// verify that the tls event was converted
+	require.Equal(t, conn.UID, ssl[0].UID, "ssl uid should match its flow")
+	require.Equal(t, "www.example.com", ssl[0].ServerName, "server name should match expected value")
+	require.Equal(t, "TLSv12", ssl[0].Version, "version should match expected value")
+	require.Equal(t, "e7d705a3286e19ea42f587b344ee6865", ssl[0].JA3, "ja3 should match expected value")
+	require.Equal(t, "ccc514751b175866924439bdbb5bba34", ssl[0].JA3S, "ja3s should match expected value")
+	require.True(t, ssl[0].Established, "ssl session should be established")
+
+	// verify that the hour is determined from the records
+	hours, err := GetEVEHours(afero.NewOsFs(), path)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)}, hours, "hours should match expected value")
+}
+
+func TestParseMultiHourEVE(t *testing.T) {
+	afs := afero.NewMemMapFs()
+	path := "/logs/eve.json"
+
+	// an EVE log that spans three hours, with its events slightly out of order
+	httpEvent := func(ts string, url string) string {
+		return `{"timestamp":"` + ts + `","flow_id":1980582341203043,"event_type":"http","src_ip":"10.55.100.104","src_port":50112,` +
+			`"dest_ip":"93.184.216.34","dest_port":80,"proto":"TCP","http":{"hostname":"www.example.com","url":"` + url + `","http_method":"GET","status":200}}` + "\n"
+	}
+	data := httpEvent("2024-04-19T16:59:59.000000+0000", "/16") +
+		httpEvent("2024-04-19T17:00:01.000000+0000", "/17") +
+		httpEvent("2024-04-19T16:59:59.500000+0000", "/16") +
+		httpEvent("2024-04-19T18:30:00.000000+0000", "/18")
+	require.NoError(t, afero.WriteFile(afs, path, []byte(data), os.FileMode(0o775)))
+
+	hours, err := GetEVEHours(afs, path)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 19, 17, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 19, 18, 0, 0, 0, time.UTC),
+	}, hours, "hours should match expected value")
+
+	// each hour only contains the events that were logged during it
+	expectedURIs := [][]string{{"/16", "/16"}, {"/17"}, {"/18"}}
+	for i, hour := range hours {
+		hourPath := HourPath(path, hour)
+
+		entryChannels := EntryChans{
+			Conn: make(chan zeektypes.Conn, 10),
+			DNS:  make(chan zeektypes.DNS, 10),
+			HTTP: make(chan zeektypes.HTTP, 10),
+			SSL:  make(chan zeektypes.SSL, 10),
+		}
+		errc := make(chan error, 10)
+		metaDBChan := make(chan MetaDBFile, 10)
+
+		parseEVEFile(afs, hourPath, entryChannels, errc, make(chan RejectedLine, 100), metaDBChan, "test", util.FixedString{})
+		close(entryChannels.HTTP)
+		close(errc)
+		close(metaDBChan)
+
+		for err := range errc {
+			require.NoError(t, err, "parsing EVE log should not produce an error")
+		}
+		require.Len(t, metaDBChan, 1, "hour should be marked as imported once")
+		require.Equal(t, hourPath, (<-metaDBChan).path, "hour path should be marked as imported")
+
+		var uris []string
+		for h := range entryChannels.HTTP {
+			require.Equal(t, path, h.LogPath, "log path should not include the hour")
+			uris = append(uris, h.URI)
+		}
+		require.Equal(t, expectedURIs[i], uris, "http records should match the hour %s", hour)
+	}
+
+	// logs without any valid timestamps can't be split into hours
+	require.NoError(t, afero.WriteFile(afs, "/logs/eve-empty.json", []byte(`{"event_type":"stats"}`+"\n"), os.FileMode(0o775)))
+	_, err = GetEVEHours(afs, "/logs/eve-empty.json")
+	require.ErrorIs(t, err, ErrEVELogHasNoRecords)
+}
+
+func TestParseECS(t *testing.T) {
+	path := "../test_data/ecs/ecs-zeek.ndjson"
+
+	// the export spans two hours
+	hours, err := GetECSHours(afero.NewOsFs(), path)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 19, 17, 0, 0, 0, time.UTC),
+	}, hours, "hours should match expected value")
+
+	parseHour := func(hourPath string) ([]zeektypes.Conn, []zeektypes.DNS, []zeektypes.HTTP, []zeektypes.SSL) {
+		t.Helper()
+
+		entryChannels := EntryChans{
+			Conn: make(chan zeektypes.Conn, 10),
+			DNS:  make(chan zeektypes.DNS, 10),
+			HTTP: make(chan zeektypes.HTTP, 10),
+			SSL:  make(chan zeektypes.SSL, 10),
+		}
+		errc := make(chan error, 10)
+		metaDBChan := make(chan MetaDBFile, 10)
+
+		parseECSFile(afero.NewOsFs(), hourPath, entryChannels, errc, make(chan RejectedLine, 100), metaDBChan, "test", util.FixedString{})
+		close(entryChannels.Conn)
+		close(entryChannels.DNS)
+		close(entryChannels.HTTP)
+		close(entryChannels.SSL)
+		close(errc)
+		close(metaDBChan)
+
+		for err := range errc {
+			require.NoError(t, err, "parsing ECS export should not produce an error")
+		}
+		require.Len(t, metaDBChan, 1, "hour should be marked as imported once")
+		require.Equal(t, hourPath, (<-metaDBChan).path, "hour path should be marked as imported")
+
+		var conns []zeektypes.Conn
+		for c := range entryChannels.Conn {
+			conns = append(conns, c)
+		}
+		var dns []zeektypes.DNS
+		for d := range entryChannels.DNS {
+			dns = append(dns, d)
+		}
+		var http []zeektypes.HTTP
+		for h := range entryChannels.HTTP {
+			http = append(http, h)
+		}
+		var ssl []zeektypes.SSL
+		for s := range entryChannels.SSL {
+			ssl = append(ssl, s)
+		}
+		return conns, dns, http, ssl
+	}
+
+	// x509 and non-zeek records are ignored
+	conns, dns, http, ssl := parseHour(HourPath(path, hours[0]))
+	require.Len(t, conns, 1, "number of conn records")
+	require.Len(t, dns, 1, "number of dns records")
+	require.Empty(t, http, "http record belongs to the next hour")
+	require.Len(t, ssl, 1, "number of ssl records")
+
+	// verify that the nested conn record was converted
+	conn := conns[0]
+	require.Equal(t, "CHhAvVGS1DHFjwGM9", conn.UID, "uid should be the session id")
+	require.EqualValues(t, 1713542401, conn.TimeStamp, "timestamp should match expected value")
+	require.InDelta(t, 2.5, conn.Duration, 0.001, "duration should be converted from nanoseconds")
+	require.Equal(t, "10.55.100.103", conn.Source, "source should match expected value")
+	require.Equal(t, 443, conn.DestinationPort, "destination port should match expected value")
+	require.Equal(t, "tcp", conn.Proto, "proto should match expected value")
+	require.Equal(t, "ssl", conn.Service, "service should match expected value")
+	require.EqualValues(t, 1400, conn.OrigBytes, "orig bytes should match expected value")
+	require.EqualValues(t, 1400+12*40, conn.OrigIPBytes, "orig ip bytes should include headers")
+	require.EqualValues(t, 10, conn.RespPackets, "resp packets should match expected value")
+	require.Equal(t, "SF", conn.ConnState, "conn state should match expected value")
+	require.True(t, conn.LocalOrigin, "local orig should match expected value")
+	require.Equal(t, "sensor1", conn.AgentHostname, "agent hostname should be the observer")
+	require.Equal(t, path, conn.LogPath, "log path should be set")
+
+	// verify that the dotted dns record was converted
+	require.Equal(t, "C8aBhs1bBoiWlf6g2e", dns[0].UID, "uid should be the session id")
+	require.Equal(t, "www.example.com", dns[0].Query, "query should match expected value")
+	require.EqualValues(t, 40192, dns[0].TransID, "transaction id should match expected value")
+	require.EqualValues(t, 1, dns[0].QType, "query type should match expected value")
+	require.Equal(t, "NOERROR", dns[0].RCodeName, "response code name should match expected value")
+	require.True(t, dns[0].RD, "rd flag should be set")
+	require.False(t, dns[0].AA, "aa flag should not be set")
+	require.Equal(t, []string{"example.com", "93.184.216.34"}, dns[0].Answers, "answers should match expected value")
+	require.Equal(t, []float64{300, 60}, dns[0].TTLs, "ttls should match expected value")
+
+	// verify that the ssl record was converted
+	require.Equal(t, conn.UID, ssl[0].UID, "ssl uid should match its connection")
+	require.Equal(t, "www.example.com", ssl[0].ServerName, "server name should match expected value")
+	require.Equal(t, "TLSv12", ssl[0].Version, "version should match expected value")
+	require.Equal(t, "e7d705a3286e19ea42f587b344ee6865", ssl[0].JA3, "ja3 should match expected value")
+	require.Equal(t, "ccc514751b175866924439bdbb5bba34", ssl[0].JA3S, "ja3s should match expected value")
+	require.True(t, ssl[0].Established, "ssl session should be established")
+
+	conns, dns, http, ssl = parseHour(HourPath(path, hours[1]))
+	require.Len(t, conns, 1, "number of conn records")
+	require.Empty(t, dns, "dns record belongs to the previous hour")
+	require.Len(t, http, 1, "number of http records")
+	require.Empty(t, ssl, "ssl record belongs to the previous hour")
+
+	// verify that the http record was converted
+	require.Equal(t, "www.example.com", http[0].Host, "host should match expected value")
+	require.Equal(t, "/index.html", http[0].URI, "uri should match expected value")
+	require.Equal(t, "GET", http[0].Method, "method should match expected value")
+	require.Equal(t, "curl/8.4.0", http[0].UserAgent, "useragent should match expected value")
+	require.Equal(t, []string{"text/html"}, http[0].RespMimeTypes, "mime types should match expected value")
+	require.EqualValues(t, 200, http[0].StatusCode, "status code should match expected value")
+	require.EqualValues(t, 1256, http[0].RespLen, "response length should match expected value")
+
+	// verify that a record without a dataset is matched by its fields
+	require.Equal(t, "tqZWHY8BPFaKN1mtbBbn", conns[0].UID, "uid should fall back to the event id")
+	require.Equal(t, "icmp", conns[0].Proto, "proto should be icmp")
+	require.Equal(t, 8, conns[0].SourcePort, "source port should be the icmp type")
+	require.Equal(t, 0, conns[0].DestinationPort, "destination port should be the icmp code")
+}
+
+func TestParseFlowFile(t *testing.T) {
+	exportTime := time.Date(2024, 4, 19, 17, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []zeektypes.Conn
+	}{
+		{
+			name: "IPFIX",
+			path: "../test_data/ipfix/sample.ipfix",
+			expected: []zeektypes.Conn{
+				// unidirectional flow with millisecond timestamps
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() + 1), Source: "10.55.100.103", SourcePort: 49812,
+					Destination: "93.184.216.34", DestinationPort: 443, Proto: "tcp", Duration: 2.5,
+					OrigBytes: 6200 - 22*40, OrigIPBytes: 6200, OrigPackets: 22, ConnState: "SF",
+				},
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() + 2), Source: "10.55.100.104", SourcePort: 51234,
+					Destination: "165.227.88.15", DestinationPort: 80, Proto: "tcp", Duration: 0,
+					OrigBytes: 180 - 3*40, OrigIPBytes: 180, OrigPackets: 3, ConnState: "S0",
+				},
+				// bidirectional flow with reverse counters and a variable length field
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() + 5), Source: "10.55.100.105", SourcePort: 41234,
+					Destination: "8.8.8.8", DestinationPort: 53, Proto: "udp", Duration: 1,
+					OrigBytes: 75 - 28, RespBytes: 123 - 28, OrigIPBytes: 75, RespIPBytes: 123, OrigPackets: 1, RespPackets: 1, ConnState: "OTH",
+				},
+				// ipv6 icmp flow, type and code are stored in place of the ports
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() + 10), Source: "fd00::10", SourcePort: 128,
+					Destination: "2001:4860:4860::8888", DestinationPort: 0, Proto: "icmp", Duration: 2,
+					OrigBytes: 312 - 3*28, OrigIPBytes: 312, OrigPackets: 3, ConnState: "OTH",
+				},
+			},
+		},
+		{
+			name: "NetFlow v9",
+			path: "../test_data/ipfix/sample.netflow",
+			expected: []zeektypes.Conn{
+				// timestamps are relative to the uptime of the exporter
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() - 60), Source: "10.55.100.106", SourcePort: 55000,
+					Destination: "45.33.32.156", DestinationPort: 8443, Proto: "tcp", Duration: 30,
+					OrigBytes: 4500 - 12*40, OrigIPBytes: 4500, OrigPackets: 12, ConnState: "OTH",
+				},
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() - 20), Source: "10.55.100.106", SourcePort: 55001,
+					Destination: "45.33.32.156", DestinationPort: 8443, Proto: "tcp", Duration: 0.5,
+					OrigBytes: 300 - 4*40, OrigIPBytes: 300, OrigPackets: 4, ConnState: "OTH",
+				},
+				{
+					TimeStamp: zeektypes.Timestamp(exportTime.Unix() - 1), Source: "10.55.100.107", SourcePort: 3333,
+					Destination: "1.1.1.1", DestinationPort: 53, Proto: "udp", Duration: 0,
+					OrigBytes: 70 - 28, OrigIPBytes: 70, OrigPackets: 1, ConnState: "OTH",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, path := range []string{test.path, "../test_data/ipfix/flows.202404191600.netflow.gz"} {
+				// the compressed capture only contains the NetFlow v9 records
+				if path != test.path && test.name != "NetFlow v9" {
+					continue
+				}
+
+				entries := make(chan zeektypes.Conn, 10)
+				errc := make(chan error, 10)
+				metaDBChan := make(chan MetaDBFile, 10)
+
+				parseFlowFile(afero.NewOsFs(), path, entries, errc, metaDBChan, "test", util.FixedString{})
+				close(entries)
+				close(errc)
+				close(metaDBChan)
+
+				for err := range errc {
+					require.NoError(t, err, "parsing flow file should not produce an error")
+				}
+				require.Len(t, metaDBChan, 1, "file should be marked as imported once")
+
+				var records []zeektypes.Conn
+				for entry := range entries {
+					require.Equal(t, path, entry.LogPath, "log path should be set")
+					require.NotEmpty(t, entry.UID, "uid should be generated")
+
+					// verify that the record passes through the same formatting and filtering as zeek conn records
+					_, err := formatConnRecord(&entry, util.FixedString{}, time.Now())
+					require.NoError(t, err, "formatting conn record should not produce an error")
+
+					entry.LogPath, entry.UID = "", ""
+					records = append(records, entry)
+				}
+				require.Equal(t, test.expected, records, "flow records should match expected values")
+			}
+		})
+	}
+
+	// verify that the hour can be determined from the export time of the first message
+	ts, err := GetFlowFirstTimestamp(afero.NewOsFs(), "../test_data/ipfix/sample.ipfix")
+	require.NoError(t, err)
+	require.Equal(t, exportTime, ts, "first timestamp should match expected value")
+
+	// verify that other files are rejected
+	errc := make(chan error, 10)
+	parseFlowFile(afero.NewOsFs(), "../test_data/eve/eve.json", make(chan zeektypes.Conn), errc, make(chan MetaDBFile), "test", util.FixedString{})
+	close(errc)
+	require.ErrorIs(t, <-errc, errUnknownFileType, "non-flow file should produce an unknown file type error")
+}
+
+func TestParsePCAPFile(t *testing.T) {
+	path := "../test_data/pcap/sample.pcap"
+	firstHour := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)
+
+	// verify that the hours are determined from the packet timestamps
+	hours, err := GetPCAPHours(afero.NewOsFs(), path)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{firstHour, firstHour.Add(time.Hour)}, hours, "capture should contain packets in two hours")
+
+	tests := []struct {
+		name          string
+		hour          time.Time
+		expectedConns int
+		expectedDNS   int
+		expectedHTTP  int
+		expectedSSL   int
+	}{
+		{name: "First Hour", hour: firstHour, expectedConns: 3, expectedDNS: 1, expectedHTTP: 1, expectedSSL: 1},
+		{name: "Second Hour", hour: firstHour.Add(time.Hour), expectedConns: 2},
+		{name: "Hour Without Packets", hour: firstHour.Add(2 * time.Hour)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hourPath := HourPath(path, test.hour)
+			require.True(t, isPCAPHourPath(hourPath), "hour path should be recognized")
+
+			entryChans := EntryChans{
+				Conn: make(chan zeektypes.Conn, 10),
+				DNS:  make(chan zeektypes.DNS, 10),
+				HTTP: make(chan zeektypes.HTTP, 10),
+				SSL:  make(chan zeektypes.SSL, 10),
+			}
+			errc := make(chan error, 10)
+			metaDBChan := make(chan MetaDBFile, 10)
+
+			parsePCAPFile(afero.NewOsFs(), hourPath, entryChans, errc, metaDBChan, "test", util.FixedString{})
+			close(errc)
+			close(metaDBChan)
+
+			for err := range errc {
+				require.NoError(t, err, "parsing packet capture should not produce an error")
+			}
+
+			// each hour of the capture is marked as imported on its own
+			require.Len(t, metaDBChan, 1, "hour should be marked as imported once")
+			require.Equal(t, hourPath, (<-metaDBChan).path, "hour path should be marked as imported")
+
+			require.Len(t, entryChans.Conn, test.expectedConns)
+			require.Len(t, entryChans.DNS, test.expectedDNS)
+			require.Len(t, entryChans.HTTP, test.expectedHTTP)
+			require.Len(t, entryChans.SSL, test.expectedSSL)
+
+			// verify that the records pass through the same formatting as zeek records
+			for len(entryChans.Conn) > 0 {
+				entry := <-entryChans.Conn
+				require.Equal(t, hourPath, entry.LogPath, "log path should be set")
+				_, err := formatConnRecord(&entry, util.FixedString{}, time.Now())
+				require.NoError(t, err, "formatting conn record should not produce an error")
+			}
+			for len(entryChans.DNS) > 0 {
+				entry := <-entryChans.DNS
+				_, err := formatDNSRecord(&entry, time.Now())
+				require.NoError(t, err, "formatting dns record should not produce an error")
+			}
+			for len(entryChans.HTTP) > 0 {
+				entry := <-entryChans.HTTP
+				_, err := formatHTTPRecord(&entry, time.Now())
+				require.NoError(t, err, "formatting http record should not produce an error")
+			}
+			for len(entryChans.SSL) > 0 {
+				entry := <-entryChans.SSL
+				_, err := formatSSLRecord(&entry, time.Now())
+				require.NoError(t, err, "formatting ssl record should not produce an error")
+			}
+		})
+	}
+
+	// verify that other files are rejected
+	errc := make(chan error, 10)
+	parsePCAPFile(afero.NewOsFs(), HourPath("../test_data/eve/eve.json", firstHour), EntryChans{}, errc, make(chan MetaDBFile), "test", util.FixedString{})
+	close(errc)
+	require.ErrorIs(t, <-errc, errUnknownFileType, "non-capture file should produce an unknown file type error")
+
+	_, err = GetPCAPHours(afero.NewOsFs(), "../test_data/eve/eve.json")
+	require.ErrorIs(t, err, pcap.ErrUnknownCaptureFormat, "non-capture file should not have any hours")
+}
+
+func TestParseFileByHour(t *testing.T) {
+	firstHour := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)
+	ts := func(offset time.Duration) string {
+		return strconv.FormatInt(firstHour.Add(offset).Unix(), 10) + ".123456"
+	}
+
+	// a day long log that wasn't rotated hourly, with records in the first and third hour
+	tsvLog := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n" +
+		"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\n" +
+		"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\n" +
+		ts(10*time.Minute) + "\tC1\t10.0.0.1\t50000\t8.8.8.8\t53\tudp\n" +
+		ts(59*time.Minute+59*time.Second) + "\tC2\t10.0.0.1\t50001\t8.8.8.8\t53\tudp\n" +
+		ts(2*time.Hour) + "\tC3\t10.0.0.1\t50002\t8.8.8.8\t53\tudp\n"
+	jsonLog := `{"ts":` + ts(10*time.Minute) + `,"uid":"C1","id.orig_h":"10.0.0.1","id.orig_p":50000,"id.resp_h":"8.8.8.8","id.resp_p":53,"proto":"udp"}` + "\n" +
+		`{"ts":` + ts(59*time.Minute+59*time.Second) + `,"uid":"C2","id.orig_h":"10.0.0.1","id.orig_p":50001,"id.resp_h":"8.8.8.8","id.resp_p":53,"proto":"udp"}` + "\n" +
+		`{"ts":"` + firstHour.Add(2*time.Hour).Format(time.RFC3339) + `","uid":"C3","id.orig_h":"10.0.0.1","id.orig_p":50002,"id.resp_h":"8.8.8.8","id.resp_p":53,"proto":"udp"}` + "\n"
+
+	for name, contents := range map[string]string{"TSV": tsvLog, "JSON": jsonLog} {
+		t.Run(name, func(t *testing.T) {
+			afs := afero.NewMemMapFs()
+			path := "/logs/conn.log"
+			require.NoError(t, afero.WriteFile(afs, path, []byte(contents), 0o644))
+
+			// verify that the hours are determined from the record timestamps
+			hours, err := GetLogHours(afs, path)
+			require.NoError(t, err)
+			require.Equal(t, []time.Time{firstHour, firstHour.Add(2 * time.Hour)}, hours, "log should contain records in two hours")
+
+			tests := []struct {
+				hour         time.Time
+				expectedUIDs []string
+			}{
+				{hour: firstHour, expectedUIDs: []string{"C1", "C2"}},
+				{hour: firstHour.Add(time.Hour)},
+				{hour: firstHour.Add(2 * time.Hour), expectedUIDs: []string{"C3"}},
+			}
+
+			for _, test := range tests {
+				hourPath := HourPath(path, test.hour)
+				entries := make(chan zeektypes.Conn, 10)
+				errc := make(chan error, 10)
+				metaDBChan := make(chan MetaDBFile, 10)
+
+				parseFile(afs, hourPath, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", util.FixedString{})
+				close(entries)
+				close(errc)
+
+				for err := range errc {
+					require.NoError(t, err, "parsing log should not produce an error")
+				}
+
+				// each hour of the log is marked as imported on its own
+				require.Len(t, metaDBChan, 1, "hour should be marked as imported once")
+				require.Equal(t, hourPath, (<-metaDBChan).path, "hour path should be marked as imported")
+
+				var uids []string
+				for entry := range entries {
+					require.Equal(t, hourPath, entry.LogPath, "log path should be set")
+					uids = append(uids, entry.UID)
+				}
+				require.Equal(t, test.expectedUIDs, uids, "only the records of the hour should be parsed")
+			}
+		})
+	}
+
+	// verify that logs without any timestamps have no hours
+	afs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(afs, "/logs/conn.log", []byte("#separator \\x09\n#fields\tuid\n#types\tstring\nC1\n"), 0o644))
+	_, err := GetLogHours(afs, "/logs/conn.log")
+	require.ErrorIs(t, err, ErrLogHasNoRecords, "log without timestamps should not have any hours")
+}
+
+func TestRejectedLines(t *testing.T) {
+	firstHour := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)
+	ts := func(offset time.Duration) string {
+		return strconv.FormatInt(firstHour.Add(offset).Unix(), 10) + ".123456"
+	}
+	tsvHeader := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n" +
+		"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\n" +
+		"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\n"
+
+	// parse runs parseFile and returns the uids of the parsed records and the rejected lines
+	parse := func(t *testing.T, contents string, path string) ([]string, []RejectedLine) {
+		t.Helper()
+		afs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(afs, "/logs/conn.log", []byte(contents), 0o644))
+
+		entries := make(chan zeektypes.Conn, 100)
+		rejectedLines := make(chan RejectedLine, 100)
+		parseFile(afs, path, entries, make(chan error, 10), rejectedLines, make(chan MetaDBFile, 10), "test", util.FixedString{})
+		close(entries)
+		close(rejectedLines)
+
+		var uids []string
+		for entry := range entries {
+			uids = append(uids, entry.UID)
+		}
+		var rejected []RejectedLine
+		for line := range rejectedLines {
+			require.Equal(t, "/logs/conn.log", line.path, "rejected line should have the path of the file")
+			line.path, line.line = "", ""
+			rejected = append(rejected, line)
+		}
+		return uids, rejected
+	}
+
+	t.Run("Invalid TSV Field", func(t *testing.T) {
+		uids, rejected := parse(t, tsvHeader+
+			ts(0)+"\tC1\t10.0.0.1\t50000\t8.8.8.8\t53\tudp\n"+
+			ts(1)+"\tC2\t10.0.0.1\tnotaport\t8.8.8.8\t53\tudp\n", "/logs/conn.log")
+
+		// the line is still imported without the invalid field
+		require.Equal(t, []string{"C1", "C2"}, uids)
+		require.Len(t, rejected, 1)
+		require.Equal(t, uint64(9), rejected[0].lineNumber, "line number should include the header")
+		require.Equal(t, "id.orig_p", rejected[0].field)
+		require.False(t, rejected[0].skipped, "line with an invalid field should not be skipped")
+	})
+
+	t.Run("Truncated TSV Line", func(t *testing.T) {
+		uids, rejected := parse(t, tsvHeader+
+			ts(0)+"\tC1\t10.0.0.1\t50000\t8.8.8.8\t53\tudp\n"+
+			ts(1)+"\tC2\t10.0.0.1\n", "/logs/conn.log")
+
+		require.Equal(t, []string{"C1"}, uids)
+		require.Equal(t, []RejectedLine{{lineNumber: 9, err: errTruncated, skipped: true}}, rejected)
+	})
+
+	t.Run("Too Many Invalid JSON Lines", func(t *testing.T) {
+		contents := `{"ts":` + ts(0) + `,"uid":"C1"}` + "\n"
+		for i := 0; i <= lineErrorLimit+5; i++ {
+			contents += `{"ts":` + ts(1) + `,"uid":` + "\n"
+		}
+
+		uids, rejected := parse(t, contents, "/logs/conn.log")
+		require.Equal(t, []string{"C1"}, uids)
+
+		// every invalid line up to the error limit is skipped, followed by the rest of the file
+		require.Len(t, rejected, lineErrorLimit+2)
+		for i, line := range rejected[:lineErrorLimit+1] {
+			require.Equal(t, uint64(i+2), line.lineNumber)
+			require.True(t, line.skipped, "invalid json line should be skipped")
+			require.Error(t, line.err)
+		}
+		require.Equal(t, RejectedLine{lineNumber: lineErrorLimit + 2, err: errTooManyLineErrors, skipped: true}, rejected[lineErrorLimit+1])
+	})
+
+	t.Run("Bucketed By Hour", func(t *testing.T) {
+		// a line that failed to parse belongs to the hour of the record before it, or the first record if there isn't one
+		contents := tsvHeader +
+			ts(0) + "\tC1\t10.0.0.1\tnotaport\t8.8.8.8\t53\tudp\n" +
+			ts(time.Hour) + "\tC2\t10.0.0.1\t50001\t8.8.8.8\t53\tudp\n" +
+			ts(time.Hour+1) + "\tC3\t10.0.0.1\n"
+
+		for _, test := range []struct {
+			hour          time.Time
+			expectedUIDs  []string
+			expectedLines []uint64
+		}{
+			{hour: firstHour, expectedUIDs: []string{"C1"}, expectedLines: []uint64{8}},
+			{hour: firstHour.Add(time.Hour), expectedUIDs: []string{"C2"}, expectedLines: []uint64{10}},
+		} {
+			uids, rejected := parse(t, contents, HourPath("/logs/conn.log", test.hour))
+			require.Equal(t, test.expectedUIDs, uids)
+
+			var lines []uint64
+			for _, line := range rejected {
+				lines = append(lines, line.lineNumber)
+			}
+			require.Equal(t, test.expectedLines, lines, "each rejected line should only be reported for one hour")
+		}
+	})
+}
+
+func TestSplitLogHours(t *testing.T) {
+	firstHour := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)
+	ts := func(offset time.Duration) string {
+		return strconv.FormatInt(firstHour.Add(offset).Unix(), 10) + ".123456"
+	}
+
+	// a day long log with records in the first and third hour and lines that fail to parse in both of them
+	contents := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n" +
+		"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\n" +
+		"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\n" +
+		ts(0) + "\tC1\t10.0.0.1\tnotaport\t8.8.8.8\t53\tudp\n" +
+		ts(2*time.Hour) + "\tC2\t10.0.0.1\t50001\t8.8.8.8\t53\tudp\n" +
+		ts(10*time.Minute) + "\tC3\t10.0.0.1\t50002\t8.8.8.8\t53\tudp\n" +
+		ts(2*time.Hour+1) + "\tC4\t10.0.0.1\tnotaport\t8.8.8.8\t53\tudp\n" +
+		"#close\t2024-04-19-19-00-00\n"
+
+	afs := afero.NewMemMapFs()
+	path := "/logs/conn.log"
+	require.NoError(t, afero.WriteFile(afs, path, []byte(contents), 0o644))
+
+	archiveFs := NewArchiveFs(afs)
+	defer archiveFs.Close()
+
+	hours, err := SplitLogHours(archiveFs, path, ConnPrefix)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{firstHour, firstHour.Add(2 * time.Hour)}, hours, "log should contain records in two hours")
+	require.Len(t, archiveFs.hourLogs, 2, "each hour should be split into its own file")
+
+	// the hours are read from their own files, so the log itself isn't read again
+	require.NoError(t, afero.WriteFile(afs, path, []byte("testytesttestboop\n"), 0o644))
+
+	for _, test := range []struct {
+		hour          time.Time
+		expectedUIDs  []string
+		expectedLines []uint64
+	}{
+		{hour: firstHour, expectedUIDs: []string{"C1", "C3"}, expectedLines: []uint64{8}},
+		{hour: firstHour.Add(2 * time.Hour), expectedUIDs: []string{"C2", "C4"}, expectedLines: []uint64{11}},
+	} {
+		hourPath := HourPath(path, test.hour)
+		entries := make(chan zeektypes.Conn, 10)
+		errc := make(chan error, 10)
+		rejectedLines := make(chan RejectedLine, 10)
+		metaDBChan := make(chan MetaDBFile, 10)
+
+		parseFile(archiveFs, hourPath, entries, errc, rejectedLines, metaDBChan, "test", util.FixedString{})
+		close(entries)
+		close(errc)
+		close(rejectedLines)
+
+		for err := range errc {
+			require.NoError(t, err, "parsing split hour should not produce an error")
+		}
+		require.Len(t, metaDBChan, 1, "hour should be marked as imported once")
+		require.Equal(t, hourPath, (<-metaDBChan).path, "hour path should be marked as imported")
+
+		var uids []string
+		for entry := range entries {
+			require.Equal(t, hourPath, entry.LogPath, "log path should be set")
+			uids = append(uids, entry.UID)
+		}
+		require.Equal(t, test.expectedUIDs, uids, "only the records of the hour should be parsed")
+
+		// rejected lines keep their line number in the log
+		var lines []uint64
+		for line := range rejectedLines {
+			require.Equal(t, path, line.path, "rejected line should have the path of the log")
+			lines = append(lines, line.lineNumber)
+		}
+		require.Equal(t, test.expectedLines, lines, "rejected lines should match expected value")
+
+		// the file of an hour is removed once it's imported
+		file := archiveFs.hourLogs[hourPath]
+		require.FileExists(t, file, "hour should be written to a file")
+		require.NoError(t, archiveFs.RemoveHourLog(hourPath))
+		require.NoFileExists(t, file, "hour file should be removed")
+	}
+
+	// hours that are larger than the buffer are written in several frames
+	var eveLog strings.Builder
+	for i := 0; i < 20000; i++ {
+		for offset := range 3 {
+			eveLog.WriteString(`{"timestamp":"` + firstHour.Add(time.Duration(offset)*time.Hour+time.Duration(i)*time.Millisecond).Format("2006-01-02T15:04:05.000000-0700") +
+				`","flow_id":1980582341203043,"event_type":"http","src_ip":"10.55.100.104","src_port":50112,"dest_ip":"93.184.216.34","dest_port":80,"proto":"TCP",` +
+				`"http":{"hostname":"www.example.com","url":"/` + strconv.Itoa(offset) + `","http_method":"GET","status":200}}` + "\n")
+		}
+	}
+	require.NoError(t, afero.WriteFile(afs, "/logs/eve.json", []byte(eveLog.String()), 0o644))
+
+	hours, err = SplitLogHours(archiveFs, "/logs/eve.json", EVEPrefix)
+	require.NoError(t, err)
+	require.Len(t, hours, 3, "log should contain events in three hours")
+
+	for i, hour := range hours {
+		entryChannels := EntryChans{HTTP: make(chan zeektypes.HTTP, 20000)}
+		errc := make(chan error, 10)
+		parseEVEFile(archiveFs, HourPath("/logs/eve.json", hour), entryChannels, errc, make(chan RejectedLine, 10), make(chan MetaDBFile, 10), "test", util.FixedString{})
+		close(entryChannels.HTTP)
+		close(errc)
+
+		for err := range errc {
+			require.NoError(t, err, "parsing split hour should not produce an error")
+		}
+		require.Len(t, entryChannels.HTTP, 20000, "every event of the hour should be parsed")
+		require.Equal(t, "/"+strconv.Itoa(i), (<-entryChannels.HTTP).URI, "events should belong to their hour")
+	}
+
+	// logs with records in a single hour aren't split
+	require.NoError(t, afero.WriteFile(afs, "/logs/dns.log", []byte(`{"ts":`+ts(0)+`,"uid":"C1"}`+"\n"+`{"ts":`+ts(time.Minute)+`,"uid":"C2"}`+"\n"), 0o644))
+	hours, err = SplitLogHours(archiveFs, "/logs/dns.log", DNSPrefix)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{firstHour}, hours, "log should contain records in one hour")
+	_, ok := archiveFs.hourLog(HourPath("/logs/dns.log", firstHour))
+	require.False(t, ok, "log with a single hour should not be split")
+
+	// the directory that the hours were written to is removed once the filesystem is closed
+	dir := archiveFs.hourDir
+	require.DirExists(t, dir, "hours should be written to a temporary directory")
+	require.NoError(t, archiveFs.Close())
+	require.NoDirExists(t, dir, "temporary directory should be removed once the filesystem is closed")
+
+	// logs on other filesystems are only read for their hours
+	hours, err = SplitLogHours(afs, "/logs/eve.json", EVEPrefix)
+	require.NoError(t, err)
+	require.Len(t, hours, 3, "log should contain events in three hours")
+}
+
+func TestParseCompressedFile(t *testing.T) {
+	// the same log compressed with each of the supported compression formats
+	for _, path := range []string{
+		"../test_data/compressed/conn.log.gz",
+		"../test_data/compressed/conn.log.zst",
+		"../test_data/compressed/conn.log.bz2",
+		"../test_data/compressed/conn.log.xz",
+	} {
+		t.Run(path, func(t *testing.T) {
+			require.True(t, IsCompressedFile(path), "file should be recognized as compressed")
+			require.Equal(t, "../test_data/compressed/conn.log", TrimCompressionExtension(path), "compression extension should be removed")
+
+			entries := make(chan zeektypes.Conn, 10)
+			errc := make(chan error, 10)
+			metaDBChan := make(chan MetaDBFile, 10)
+
+			parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", util.FixedString{})
+			close(entries)
+			close(errc)
+
+			for err := range errc {
+				require.NoError(t, err, "parsing compressed log should not produce an error")
+			}
+			require.Len(t, metaDBChan, 1, "file should be marked as imported once")
+
+			var uids []string
+			for entry := range entries {
+				uids = append(uids, entry.UID)
+			}
+			require.Equal(t, []string{"C1", "C2", "C3"}, uids, "every record of the compressed log should be parsed")
+		})
+	}
+
+	// verify that uncompressed files are left untouched
+	require.False(t, IsCompressedFile("/logs/conn.log"), "uncompressed file should not be recognized as compressed")
+	require.Equal(t, "/logs/conn.log", TrimCompressionExtension("/logs/conn.log"), "uncompressed file path should not change")
+}
+
+func TestParseArchivedFile(t *testing.T) {
+	// a tar archive holding a compressed log
+	compressed, err := afero.ReadFile(afero.NewOsFs(), "../test_data/compressed/conn.log.gz")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	require.NoError(t, writer.WriteHeader(&tar.Header{Name: "2024-04-19/conn.log.gz", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(compressed))}))
+	_, err = writer.Write(compressed)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	afs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(afs, "/bundles/logs.tar", buf.Bytes(), 0o644))
+
+	archiveFs := NewArchiveFs(afs)
+	defer archiveFs.Close()
+
+	path := "/bundles/logs.tar/2024-04-19/conn.log.gz"
+	entries := make(chan zeektypes.Conn, 10)
+	errc := make(chan error, 10)
+	metaDBChan := make(chan MetaDBFile, 10)
+
+	parseFile(archiveFs, path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", util.FixedString{})
+	close(entries)
+	close(errc)
+
+	for err := range errc {
+		require.NoError(t, err, "parsing archived log should not produce an error")
+	}
+
+	// the log is marked as imported using the path of the archive and the name of the member
+	require.Len(t, metaDBChan, 1, "file should be marked as imported once")
+	require.Equal(t, path, (<-metaDBChan).path, "archive path and member name should be marked as imported")
+
+	var uids []string
+	for entry := range entries {
+		require.Equal(t, path, entry.LogPath, "log path should be set")
+		uids = append(uids, entry.UID)
+	}
+	require.Equal(t, []string{"C1", "C2", "C3"}, uids, "every record of the archived log should be parsed")
+
+	// tar archives are unpacked into a temporary directory, which is removed once the archive is closed
+	archivePath, ok := archiveFs.ArchivePath(path)
+	require.True(t, ok, "archived log should be inside of an archive")
+	require.Equal(t, "/bundles/logs.tar", archivePath, "archive path should match expected value")
+	dir := archiveFs.archives[archivePath].dir
+	require.DirExists(t, dir, "archive should be unpacked into a temporary directory")
+
+	require.NoError(t, archiveFs.CloseArchive(archivePath))
+	require.NoDirExists(t, dir, "temporary directory should be removed once the archive is closed")
+
+	// closed archives are opened again when their files are accessed
+	_, err = archiveFs.Stat(path)
+	require.NoError(t, err, "archived log should still be accessible after its archive was closed")
+
+	_, ok = archiveFs.ArchivePath("/bundles/conn.log")
+	require.False(t, ok, "files outside of archives should not have an archive path")
+
+	// archives are read-only
+	_, err = archiveFs.OpenFile(path, os.O_WRONLY, 0o644)
+	require.Error(t, err, "opening an archived log for writing should produce an error")
+
+	// files that merely have an archive extension can't be opened as archives
+	require.NoError(t, afero.WriteFile(afs, "/bundles/broken.zip", []byte("testytesttestboop"), 0o644))
+	_, err = archiveFs.Stat("/bundles/broken.zip")
+	require.Error(t, err, "opening an invalid archive should produce an error")
+}
+
+func TestParseDHCPTSV(t *testing.T) {
+	path := "../test_data/dhcp/dhcp.log"
+
+	entries := make(chan zeektypes.DHCP)
+	errc := make(chan error)
+	metaDBChan := make(chan MetaDBFile)
+
+	// get the current time in microseconds
+	start := time.Now().UTC().UnixMicro()
+
+	// create a unique import id using the start time
+	importID, err := util.NewFixedStringHash(strconv.FormatInt(start, 10))
+	require.NoError(t, err)
+
+	go func() {
+		parseFile(afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
+		close(errc)
+		close(entries)
+		close(metaDBChan)
+	}()
+
+	receivedErr := false
+	openChannels := 3
+	var records []zeektypes.DHCP
+	for openChannels > 0 {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				openChannels--
+			} else {
+				records = append(records, entry)
+			}
+		case _, ok := <-metaDBChan:
+			if !ok {
+				openChannels--
+			}
+		case err, ok := <-errc:
+			if !ok {
+				openChannels--
+			} else if err != nil {
+				receivedErr = true
+			}
+		}
+	}
+
+	require.False(t, receivedErr, "parsing dhcp log should not produce an error")
+	require.Len(t, records, 3, "number of dhcp records")
+
+	// verify that the full DORA transaction was parsed correctly
+	lease := records[0]
+	require.Equal(t, []string{"CtwHqR2zE5DLTMBq5a", "C0dXrm4qPiUmNSaDGk"}, lease.UIDs, "uids should match expected value")
+	require.Equal(t, "10.55.100.1", lease.ServerAddr, "server address should match expected value")
+	require.Equal(t, "00:1a:2b:3c:4d:5e", lease.MAC, "mac should match expected value")
+	require.Equal(t, "desktop-7h2kq", lease.HostName, "hostname should match expected value")
+	require.Equal(t, "desktop-7h2kq.corp.example.com", lease.ClientFQDN, "client fqdn should match expected value")
+	require.Equal(t, "10.55.100.105", lease.AssignedAddr, "assigned address should match expected value")
+	require.InDelta(t, 86400, lease.LeaseTime, 0.0001, "lease time should match expected value")
+	require.Equal(t, []string{"DISCOVER", "OFFER", "REQUEST", "ACK"}, lease.MsgTypes, "message types should match expected value")
+	require.Equal(t, path, lease.LogPath, "log path should be set")
+
+	// verify that the lease covers the lease time from the start of the transaction
+	entry, err := formatDHCPRecord(&lease, time.Unix(start/1e6, 0))
+	require.NoError(t, err, "formatting dhcp record should not produce an error")
+	require.Equal(t, "10.55.100.105", entry.IP.String(), "lease ip should be the assigned address")
+	require.Equal(t, time.Unix(1713542400, 0), entry.LeaseStart, "lease start should match the transaction timestamp")
+	require.Equal(t, time.Unix(1713542400+86400, 0), entry.LeaseEnd, "lease end should be the lease start plus the lease time")
+
+	// verify that a renewal without a client fqdn is still a lease
+	renewal, err := formatDHCPRecord(&records[1], time.Unix(start/1e6, 0))
+	require.NoError(t, err, "formatting dhcp renewal should not produce an error")
+	require.Equal(t, "laptop-jsmith", renewal.HostName, "hostname should match expected value")
+	require.Empty(t, renewal.ClientFQDN, "unset client fqdn should be empty")
+
+	// verify that a transaction which didn't assign an address is not a lease
+	_, err = formatDHCPRecord(&records[2], time.Unix(start/1e6, 0))
+	require.ErrorIs(t, err, errMissingAssignedAddr, "rejected transaction should not produce a lease")
+}
+
+func TestParseLateralMovementTSV(t *testing.T) {
+	// set the internal subnets so that only internal to internal records are kept
+	cfg, _ := config.LoadConfig(afero.NewOsFs(), "../config.hjson")
+	internalSubnets, err := util.ParseSubnets([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	originalSubnets := cfg.Filter.InternalSubnets
+	cfg.Filter.InternalSubnets = internalSubnets
+	t.Cleanup(func() { cfg.Filter.InternalSubnets = originalSubnets })
+
+	importTime := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("smb_mapping", func(t *testing.T) {
+		records := parseTestLog[zeektypes.SMBMapping](t, "../test_data/lateral_movement/smb_mapping.log")
+		require.Len(t, records, 3, "number of smb_mapping records")
+		require.Equal(t, "DISK", records[0].ShareType, "share type should match expected value")
+
+		// verify that mapping an admin share is flagged
+		entry, err := formatSMBMappingRecord(&records[0], importTime)
+		require.NoError(t, err)
+		require.True(t, entry.AdminShare, "ADMIN$ should be an admin share")
+		require.Equal(t, zeektypes.EntryTypeSMBMapping, entry.LogType, "log type should match expected value")
+
+		// verify that the IPC$ share used for named pipes isn't flagged
+		entry, err = formatSMBMappingRecord(&records[1], importTime)
+		require.NoError(t, err)
+		require.False(t, entry.AdminShare, "IPC$ should not be an admin share")
+
+		// verify that internal to external mappings are filtered out
+		entry, err = formatSMBMappingRecord(&records[2], importTime)
+		require.NoError(t, err)
+		require.Nil(t, entry, "internal to external mapping should be filtered")
+	})
+
+	t.Run("smb_files", func(t *testing.T) {
+		records := parseTestLog[zeektypes.SMBFiles](t, "../test_data/lateral_movement/smb_files.log")
+		require.Len(t, records, 2, "number of smb_files records")
+
+		// verify that opening the service control manager's pipe is flagged
+		entry, err := formatSMBFilesRecord(&records[0], importTime)
+		require.NoError(t, err)
+		require.True(t, entry.ServiceControl, "opening svcctl should be service control")
+		require.False(t, entry.AdminShare, "IPC$ should not be an admin share")
+
+		// verify that writing a file to an admin share is flagged
+		entry, err = formatSMBFilesRecord(&records[1], importTime)
+		require.NoError(t, err)
+		require.True(t, entry.AdminShare, "ADMIN$ should be an admin share")
+		require.Equal(t, "SMB::FILE_WRITE", entry.Operation, "operation should be the file action")
+		require.EqualValues(t, 381816, records[1].Size, "size should match expected value")
+	})
+
+	t.Run("dce_rpc", func(t *testing.T) {
+		records := parseTestLog[zeektypes.DCERPC](t, "../test_data/lateral_movement/dce_rpc.log")
+		require.Len(t, records, 2, "number of dce_rpc records")
+
+		entry, err := formatDCERPCRecord(&records[0], importTime)
+		require.NoError(t, err)
+		require.True(t, entry.ServiceControl, "svcctl endpoint should be service control")
+		require.Equal(t, "CreateServiceW", entry.Operation, "operation should match expected value")
+
+		entry, err = formatDCERPCRecord(&records[1], importTime)
+		require.NoError(t, err)
+		require.False(t, entry.ServiceControl, "epmapper endpoint should not be service control")
+	})
+
+	t.Run("ntlm", func(t *testing.T) {
+		records := parseTestLog[zeektypes.NTLM](t, "../test_data/lateral_movement/ntlm.log")
+		require.Len(t, records, 2, "number of ntlm records")
+
+		entry, err := formatNTLMRecord(&records[0], importTime)
+		require.NoError(t, err)
+		require.Equal(t, `CORP\administrator`, entry.Username, "username should include the domain")
+		require.True(t, entry.Success, "success should be set")
+
+		entry, err = formatNTLMRecord(&records[1], importTime)
+		require.NoError(t, err)
+		require.Equal(t, "jsmith", entry.Username, "username without a domain should match expected value")
+		require.False(t, entry.Success, "failed authentication should not be successful")
+	})
+
+	t.Run("kerberos", func(t *testing.T) {
+		records := parseTestLog[zeektypes.Kerberos](t, "../test_data/lateral_movement/kerberos.log")
+		require.Len(t, records, 2, "number of kerberos records")
+		require.True(t, records[0].Forwardable, "forwardable should be set")
+
+		entry, err := formatKerberosRecord(&records[1], importTime)
+		require.NoError(t, err)
+		require.Equal(t, "TGS", entry.RequestType, "request type should match expected value")
+		require.Equal(t, "cifs/fs01.corp.example.com", entry.Service, "service should match expected value")
+		require.Equal(t, "administrator/CORP.EXAMPLE.COM", entry.Username, "username should be the client principal")
+		require.True(t, entry.Success, "success should be set")
+
+		// verify that lateral movement records share the connection pair hash
+		connHash, err := util.NewFixedStringHash(entry.Src.To16().String(), entry.SrcNUID.String(), entry.Dst.To16().String(), entry.DstNUID.String())
+		require.NoError(t, err)
+		require.Equal(t, connHash, entry.Hash, "hash should match the connection hash")
+	})
+}
+
+func TestParseQUICTSV(t *testing.T) {
+	// set the internal subnets so that the SNI connections aren't filtered
+	cfg, _ := config.LoadConfig(afero.NewOsFs(), "../config.hjson")
+	internalSubnets, err := util.ParseSubnets([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	originalSubnets := cfg.Filter.InternalSubnets
+	cfg.Filter.InternalSubnets = internalSubnets
+	t.Cleanup(func() { cfg.Filter.InternalSubnets = originalSubnets })
+
+	path := "../test_data/quic/quic.log"
+	importTime := time.Now().UTC().Truncate(time.Second)
+
+	records := parseTestLog[zeektypes.QUIC](t, path)
+	require.Len(t, records, 3, "number of quic records")
+
+	// verify that the HTTP/3 connection was parsed correctly
+	h3 := records[0]
+	require.Equal(t, "CQx7Yb3Pj1qKfZ2mRa", h3.UID, "uid should match expected value")
+	require.Equal(t, "10.55.100.105", h3.Source, "source should match expected value")
+	require.Equal(t, "203.0.113.41", h3.Destination, "destination should match expected value")
+	require.Equal(t, 443, h3.DestinationPort, "destination port should match expected value")
+	require.Equal(t, "1", h3.Version, "version should match expected value")
+	require.Equal(t, "update.cdn-metrics.example", h3.ServerName, "server name should match expected value")
+	require.Equal(t, "h3", h3.ClientProtocol, "client protocol should match expected value")
+	require.Equal(t, "ISishIhHhhjH", h3.History, "history should match expected value")
+	require.Empty(t, h3.ClientSCID, "unset client scid should be empty")
+	require.Equal(t, path, h3.LogPath, "log path should be set")
+
+	// verify that the record is tracked by the same hash as ssl connections to the same server name
+	entry, err := formatQUICRecord(&h3, importTime)
+	require.NoError(t, err, "formatting quic record should not produce an error")
+	sniHash, err := util.NewFixedStringHash(entry.Src.To16().String(), entry.SrcNUID.String(), "update.cdn-metrics.example")
+	require.NoError(t, err)
+	require.Equal(t, sniHash, entry.Hash, "hash should match the sni connection hash")
+	require.True(t, entry.SrcLocal, "source should be local")
+	require.False(t, entry.DstLocal, "destination should not be local")
+
+	// verify that connections to the same server name share a hash
+	second, err := formatQUICRecord(&records[1], importTime)
+	require.NoError(t, err)
+	require.Equal(t, entry.Hash, second.Hash, "connections to the same server name should share a hash")
+
+	// verify that a connection without a server name can't be tracked by fqdn
+	_, err = formatQUICRecord(&records[2], importTime)
+	require.ErrorIs(t, err, errServerNameEmpty, "quic record without a server name should produce an error")
+}
+
+func TestSampleLogFile(t *testing.T) {
+	path := "../test_data/quic/quic.log"
+
+	internalSubnets, err := util.ParseSubnets([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	otherSubnets, err := util.ParseSubnets([]string{"192.168.0.0/16"})
+	require.NoError(t, err)
+
+	t.Run("Internal Subnets Set", func(t *testing.T) {
+		sample, err := SampleLogFile(afero.NewOsFs(), path, QUICPrefix, &config.Filter{InternalSubnets: internalSubnets}, 1000)
+		require.NoError(t, err)
+		require.Equal(t, 3, sample.Records, "all records should be sampled")
+		require.Empty(t, sample.Filtered, "no records should be filtered")
+	})
+
+	t.Run("Misconfigured Internal Subnets", func(t *testing.T) {
+		sample, err := SampleLogFile(afero.NewOsFs(), path, QUICPrefix, &config.Filter{InternalSubnets: otherSubnets}, 1000)
+		require.NoError(t, err)
+		require.Equal(t, 3, sample.Records, "all records should be sampled")
+		require.Equal(t, map[string]int{config.FilterRuleInternalSubnets: 3}, sample.Filtered, "external to external records should be filtered")
+	})
+
+	t.Run("Never Included Domain", func(t *testing.T) {
+		filter := &config.Filter{InternalSubnets: internalSubnets, NeverIncludedDomains: []string{"*.cdn-metrics.example"}}
+		sample, err := SampleLogFile(afero.NewOsFs(), path, QUICPrefix, filter, 1000)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{config.FilterRuleNeverIncludedDomains: 2}, sample.Filtered, "records to the never included domain should be filtered")
+	})
+
+	t.Run("Sample Limit", func(t *testing.T) {
+		sample, err := SampleLogFile(afero.NewOsFs(), path, QUICPrefix, &config.Filter{InternalSubnets: internalSubnets}, 1)
+		require.NoError(t, err)
+		require.Equal(t, 1, sample.Records, "sampling should stop at the limit")
+	})
+
+	t.Run("Hour Path", func(t *testing.T) {
+		sample, err := SampleLogFile(afero.NewOsFs(), HourPath(path, time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)), QUICPrefix, &config.Filter{InternalSubnets: internalSubnets}, 1000)
+		require.NoError(t, err)
+		require.Equal(t, 3, sample.Records, "records in the hour should be sampled")
+
+		sample, err = SampleLogFile(afero.NewOsFs(), HourPath(path, time.Date(2024, 4, 19, 17, 0, 0, 0, time.UTC)), QUICPrefix, &config.Filter{InternalSubnets: internalSubnets}, 1000)
+		require.NoError(t, err)
+		require.Equal(t, 0, sample.Records, "records outside of the hour should not be sampled")
+	})
+
+	t.Run("Mismatched Path Field", func(t *testing.T) {
+		data, err := afero.ReadFile(afero.NewOsFs(), path)
+		require.NoError(t, err)
+		afs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(afs, "/logs/dns.log", data, 0o644))
+
+		_, err = SampleLogFile(afs, "/logs/dns.log", DNSPrefix, &config.Filter{InternalSubnets: internalSubnets}, 1000)
+		require.ErrorIs(t, err, errMismatchedPathField, "quic log named as a dns log should fail validation")
+	})
+
+	t.Run("Unknown File Type", func(t *testing.T) {
+		afs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(afs, "/logs/conn.log", []byte("not a zeek log\n"), 0o644))
+
+		_, err := SampleLogFile(afs, "/logs/conn.log", ConnPrefix, &config.Filter{InternalSubnets: internalSubnets}, 1000)
+		require.ErrorIs(t, err, errUnknownFileType, "file without a header should fail validation")
+	})
+}
+
+// parseTestLog parses a log file and returns its records, failing the test if any errors were produced
+func parseTestLog[Z any, P zeekRecord[Z]](t *testing.T, path string) []Z {
+	t.Helper()
+
+	entries := make(chan Z)
+	errc := make(chan error)
+	metaDBChan := make(chan MetaDBFile)
+
+	importID, err := util.NewFixedStringHash(strconv.FormatInt(time.Now().UTC().UnixMicro(), 10))
+	require.NoError(t, err)
+
+	go func() {
+		parseFile[Z, P](afero.NewOsFs(), path, entries, errc, make(chan RejectedLine, 100), metaDBChan, "test", importID)
+		close(errc)
+		close(entries)
+		close(metaDBChan)
+	}()
+
+	receivedErr := false
+	openChannels := 3
+	var records []Z
+	for openChannels > 0 {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				openChannels--
+			} else {
+				records = append(records, entry)
+			}
+		case _, ok := <-metaDBChan:
+			if !ok {
+				openChannels--
+			}
+		case err, ok := <-errc:
+			if !ok {
+				openChannels--
+			} else if err != nil {
+				receivedErr = true
+			}
+		}
+	}
+
+	require.False(t, receivedErr, "parsing %s should not produce an error", path)
+	return records
+}
+
+// siteNotice is the record struct of a site-specific zeek log used to test registered record types
+type siteNotice struct {
+	TimeStamp zeektypes.Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	Source    string              `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	Note      string              `zeek:"note" zeektype:"string" json:"note"`
+	LogPath   string
+}
+
+func (s *siteNotice) SetLogPath(path string) { s.LogPath = path }
+
+// missingTimestamp is a record struct that can't be registered because it has no timestamp
+type missingTimestamp struct {
+	Note    string `zeek:"note" zeektype:"string" json:"note"`
+	LogPath string
+}
+
+func (m *missingTimestamp) SetLogPath(path string) { m.LogPath = path }
+
+// missingTag is a record struct that can't be registered because one of its fields has no zeektype tag
+type missingTag struct {
+	TimeStamp zeektypes.Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	Note      string              `zeek:"note" json:"note"`
+	LogPath   string
+}
+
+func (m *missingTag) SetLogPath(path string) { m.LogPath = path }
+
+type siteNoticeEntry struct {
+	ImportTime time.Time `ch:"import_time"`
+	Timestamp  time.Time `ch:"ts"`
+	Source     string    `ch:"src"`
+	Note       string    `ch:"note"`
+}
+
+func TestRegisterRecordType(t *testing.T) {
+	format := func(record *siteNotice, importTime time.Time) (database.Data, error) {
+		// records without a note are left out of the import
+		if record.Note == "" {
+			return nil, nil
+		}
+		return &siteNoticeEntry{ImportTime: importTime, Timestamp: time.Unix(int64(record.TimeStamp), 0), Source: record.Source, Note: record.Note}, nil
+	}
+	recordType := RecordType[siteNotice]{
+		Prefix:      "site_notice",
+		Table:       "site_notice",
+		CreateTable: "CREATE TABLE IF NOT EXISTS {database:Identifier}.site_notice (import_time DateTime(), ts DateTime(), src String, note String) ENGINE = MergeTree() ORDER BY ts",
+		Format:      format,
+	}
+
+	t.Run("Invalid Record Types", func(t *testing.T) {
+		conflicting := recordType
+		conflicting.Prefix = "conn_custom"
+		require.ErrorIs(t, RegisterRecordType(conflicting), errRecordTypePrefixConflict, "prefix starting with a built-in prefix should be rejected")
+
+		conflicting.Prefix = "ss"
+		require.ErrorIs(t, RegisterRecordType(conflicting), errRecordTypePrefixConflict, "prefix that starts a built-in prefix should be rejected")
+
+		invalidTable := recordType
+		invalidTable.Table = "site_notice; DROP TABLE conn"
+		require.ErrorIs(t, RegisterRecordType(invalidTable), errRecordTypeInvalidTable, "invalid table name should be rejected")
+
+		noFormat := recordType
+		noFormat.Format = nil
+		require.ErrorIs(t, RegisterRecordType(noFormat), errRecordTypeMissingFormat, "record type without a format function should be rejected")
+
+		err := RegisterRecordType(RecordType[missingTimestamp]{
+			Prefix: "site_missing_ts", Table: "site_missing_ts", CreateTable: recordType.CreateTable,
+			Format: func(*missingTimestamp, time.Time) (database.Data, error) { return nil, nil },
+		})
+		require.ErrorIs(t, err, errRecordTypeInvalidStruct, "record struct without a timestamp should be rejected")
+
+		err = RegisterRecordType(RecordType[missingTag]{
+			Prefix: "site_missing_tag", Table: "site_missing_tag", CreateTable: recordType.CreateTable,
+			Format: func(*missingTag, time.Time) (database.Data, error) { return nil, nil },
+		})
+		require.ErrorIs(t, err, errRecordTypeInvalidFieldTags, "record struct with a field missing its zeektype tag should be rejected")
+	})
+
+	require.NoError(t, RegisterRecordType(recordType), "registering record type should not produce an error")
+	require.ErrorIs(t, RegisterRecordType(recordType), errRecordTypeAlreadyRegistered, "registering record type twice should be rejected")
+
+	prefix, ok := RegisteredRecordTypePrefix("/logs/2024-04-19/site_notice.16:00:00-17:00:00.log.gz")
+	require.True(t, ok, "log of registered type should be matched")
+	require.Equal(t, "site_notice", prefix, "log of registered type should be matched by its prefix")
+	_, ok = RegisteredRecordTypePrefix("/logs/2024-04-19/notice.16:00:00-17:00:00.log")
+	require.False(t, ok, "log of unregistered type should not be matched")
+
+	tsvLog := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tsite_notice\n" +
+		"#fields\tts\tid.orig_h\tnote\textra\n" +
+		"#types\ttime\taddr\tstring\tstring\n" +
+		"1713542400.123456\t10.0.0.1\tScan::Port_Scan\tignored\n" +
+		"1713542460.123456\t10.0.0.2\t-\tignored\n"
+	jsonLog := `{"ts":1713542400.123456,"id.orig_h":"10.0.0.1","note":"Scan::Port_Scan"}` + "\n" +
+		`{"ts":1713542460.123456,"id.orig_h":"10.0.0.2"}` + "\n"
+
+	importTime := time.Unix(1713546000, 0)
+	for name, contents := range map[string]string{"TSV": tsvLog, "JSON": jsonLog} {
+		t.Run(name, func(t *testing.T) {
+			afs := afero.NewMemMapFs()
+			path := "/logs/site_notice.log"
+			require.NoError(t, afero.WriteFile(afs, path, []byte(contents), 0o644))
+
+			pipeline := registeredRecordType[siteNotice, *siteNotice]{recordType}.newPipeline()
+			errc := make(chan error, 10)
+			metaDBChan := make(chan MetaDBFile, 10)
+			output := make(chan database.Data, 10)
+
+			pipeline.digest(afs, path, errc, make(chan RejectedLine, 100), metaDBChan, "test", util.FixedString{})
+			pipeline.close()
+			pipeline.parse(output, importTime)
+			close(output)
+			close(errc)
+
+			for err := range errc {
+				require.NoError(t, err, "parsing log should not produce an error")
+			}
+			require.Len(t, metaDBChan, 1, "log should be marked as imported")
+
+			var entries []*siteNoticeEntry
+			for row := range output {
+				entries = append(entries, row.(*siteNoticeEntry))
+			}
+			require.Equal(t, []*siteNoticeEntry{{ImportTime: importTime, Timestamp: time.Unix(1713542400, 0), Source: "10.0.0.1", Note: "Scan::Port_Scan"}}, entries, "only the record with a note should be formatted")
+			require.EqualValues(t, 1, pipeline.count(), "formatted records should be counted")
+		})
+	}
+}
+
+func TestFieldMapping(t *testing.T) {
+	cfg, _ := config.LoadConfig(afero.NewOsFs(), "../config.hjson")
+	originalMapping := cfg.FieldMapping
+	cfg.FieldMapping = map[string]map[string]string{
+		ConnPrefix: {"timestamp": "ts", "source.ip": "id.orig_h", "sensor_name": "agent_hostname"},
+	}
+	t.Cleanup(func() { cfg.FieldMapping = originalMapping })
+
+	// the id.orig_h field of the log is replaced by the source.ip field that is mapped to it
+	tsvLog := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n" +
+		"#fields\ttimestamp\tuid\tid.orig_h\tsource.ip\tid.resp_h\tsensor_name\n" +
+		"#types\ttime\tstring\taddr\taddr\taddr\tstring\n" +
+		"1713542400.123456\tC1\t192.168.1.1\t10.0.0.1\t8.8.8.8\tsensor1\n"
+	jsonLog := `{"timestamp":1713542400.123456,"uid":"C1","id.orig_h":"192.168.1.1","source.ip":"10.0.0.1","id.resp_h":"8.8.8.8","sensor_name":"sensor1"}` + "\n"
+	// mapped fields can be nested in objects, and take the place of the field that they are mapped to wherever it is
+	nestedJSONLog := `{"timestamp":1713542400.123456,"uid":"C1","source":{"ip":"10.0.0.1","port":49152},"id.orig_h":"192.168.1.1","id.resp_h":"8.8.8.8","sensor_name":"sensor1"}` + "\n"
+
+	for name, contents := range map[string]string{"TSV": tsvLog, "JSON": jsonLog, "Nested JSON": nestedJSONLog} {
+		t.Run(name, func(t *testing.T) {
+			afs := afero.NewMemMapFs()
+			path := "/logs/conn.log"
+			require.NoError(t, afero.WriteFile(afs, path, []byte(contents), 0o644))
+
+			// verify that the renamed timestamp is used to find the hours of the log
+			hours, err := GetLogHours(afs, path)
+			require.NoError(t, err)
+			require.Equal(t, []time.Time{time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC)}, hours, "log should contain records in one hour")
+
+			entries := make(chan zeektypes.Conn, 10)
+			errc := make(chan error, 10)
+			parseFile(afs, path, entries, errc, make(chan RejectedLine, 100), make(chan MetaDBFile, 10), "test", util.FixedString{})
+			close(entries)
+			close(errc)
+
+			for err := range errc {
+				require.NoError(t, err, "parsing log should not produce an error")
+			}
+
+			var records []zeektypes.Conn
+			for entry := range entries {
+				records = append(records, entry)
+			}
+			require.Len(t, records, 1, "number of conn records")
+			require.EqualValues(t, 1713542400, records[0].TimeStamp, "timestamp should be parsed from the mapped field")
+			require.Equal(t, "10.0.0.1", records[0].Source, "source should be parsed from the mapped field")
+			require.Equal(t, "8.8.8.8", records[0].Destination, "unmapped fields should be parsed as usual")
+			require.Equal(t, "sensor1", records[0].AgentHostname, "agent hostname should be parsed from the mapped field")
+		})
+	}
+
+	// malformed records are rejected the same way as without a mapping
+	var conn zeektypes.Conn
+	require.Error(t, fieldMappingFor("/logs/conn.log").unmarshalJSON([]byte(`{"source":{"ip":"10.0.0.1"`), &conn), "truncated record should produce an error")
+
+	// the fields of other log types aren't renamed
+	require.Nil(t, fieldMappingFor("/logs/dns.log"), "dns logs should not have a field mapping")
+	require.Nil(t, fieldMappingFor("/logs/open_conn.log"), "open conn logs should not have a field mapping")
+}
+
+func TestParsePDNSRecord(t *testing.T) {
+	record := &DNSEntry{
+		Query:         "flux.example.com",
+		QueryTypeName: "A",
+		Answers:       []string{"flux-cdn.example.net", "93.184.216.34", "203.0.113.7", "198.51.100.20"},
+		TTLs:          []float64{3600, 60, 30},
+	}
+
+	output := make(chan database.Data, len(record.Answers))
+	var numPDNS uint64
+	parsePDNSRecord(record, output, &numPDNS)
+	close(output)
+
+	var entries []*DNSEntry
+	for entry := range output {
+		entries = append(entries, entry.(*DNSEntry))
+	}
+
+	// verify that an entry is created for each resolved ip, but not for the cname
+	require.Len(t, entries, 3, "number of pdns entries")
+	require.EqualValues(t, 3, numPDNS, "pdns count should match the number of resolved ips")
+
+	// verify that each entry has the ttl of its own answer
+	require.Equal(t, "93.184.216.34", entries[0].ResolvedIP.String(), "resolved ip should match expected value")
+	require.NotNil(t, entries[0].TTL, "ttl should be set")
+	require.EqualValues(t, 60, *entries[0].TTL, "ttl should be the ttl of the answer")
+	require.Equal(t, "203.0.113.7", entries[1].ResolvedIP.String(), "resolved ip should match expected value")
+	require.EqualValues(t, 30, *entries[1].TTL, "ttl should be the ttl of the answer")
+
+	// verify that an answer without a ttl has no ttl
+	require.Equal(t, "198.51.100.20", entries[2].ResolvedIP.String(), "resolved ip should match expected value")
+	require.Nil(t, entries[2].TTL, "ttl should not be set when the answer has no ttl")
+
+	// verify that the record itself is left unchanged
+	require.Nil(t, record.TTL, "ttl of the dns record should not be set")
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"activecm/rita/importer/pcap"
+	"activecm/rita/importer/zeektypes"
+	zerolog "activecm/rita/logger"
+	"activecm/rita/util"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var ErrCaptureHasNoPackets = errors.New("packet capture does not contain any packets")
+
+// PCAPFileExtensions are the file extensions of pcap and pcapng packet capture files, which may also be compressed
+var PCAPFileExtensions = []string{".pcap", ".pcapng", ".cap"}
+
+// pcapHourHandler sends the records produced from a packet capture to the entry channels, keeping only
+// the records whose timestamps fall within the hour being imported
+type pcapHourHandler struct {
+	hour          time.Time
+	path          string
+	entryChannels EntryChans
+}
+
+// IsPCAPFile returns whether the file is a packet capture file, based on its file extension
+func IsPCAPFile(path string) bool {
+	trimmed := TrimCompressionExtension(path)
+	for _, ext := range PCAPFileExtensions {
+		if strings.HasSuffix(trimmed, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPCAPHourPath returns whether the path is an hourly packet capture path
+func isPCAPHourPath(path string) bool {
+	capturePath, _, err := splitHourPath(path)
+	return err == nil && IsPCAPFile(capturePath)
+}
+
+// GetPCAPHours returns each hour that contains packets in a packet capture file
+func GetPCAPHours(afs afero.Fs, path string) ([]time.Time, error) {
+	reader, closeFile, err := openLogFile(afs, path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	capture, err := pcap.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var hours []time.Time
+	for {
+		packet, err := capture.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// the packets before the corruption can still be imported
+			if len(hours) > 0 {
+				break
+			}
+			return nil, err
+		}
+
+		hour := packet.Timestamp.Truncate(time.Hour)
+		if !slices.ContainsFunc(hours, hour.Equal) {
+			hours = append(hours, hour)
+		}
+	}
+
+	if len(hours) == 0 {
+		return nil, ErrCaptureHasNoPackets
+	}
+
+	slices.SortFunc(hours, func(a, b time.Time) int { return a.Compare(b) })
+	return hours, nil
+}
+
+// parsePCAPFile reassembles the packets of a capture file into flows and sends the conn, dns, http and ssl records
+// of the hour being imported to the entry channels. The whole capture is processed for each hour so that flows
+// which started in an earlier hour aren't mistaken for new flows.
+func parsePCAPFile(afs afero.Fs, hourPath string, entryChannels EntryChans, errc chan<- error, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
+	logger := zerolog.GetLogger()
+
+	path, hour, err := splitHourPath(hourPath)
+	if err != nil {
+		logger.Err(err).Str("path", hourPath).Msg("failed to parse packet capture: could not determine hour to import")
+		return
+	}
+
+	// open file for reading
+	empty, err := afero.IsEmpty(afs, path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("could not determine if file is empty")
+		return
+	}
+
+	// skip file if it is empty and log a warning
+	if empty {
+		logger.Warn().Str("path", path).Msg("failed to parse packet capture: file is empty")
+		return
+	}
+
+	fileHash, err := util.NewFixedStringHash(hourPath)
+	if err != nil {
+		logger.Err(err).Str("path", hourPath).Msg("could not hash file path")
+		return
+	}
+
+	metaDBFileEntry := MetaDBFile{
+		importID: importID,
+		database: database,
+		fileHash: fileHash,
+		path:     hourPath,
+	}
+
+	reader, closeFile, err := openLogFile(afs, path)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse packet capture: could not open file")
+		return
+	}
+	defer closeFile()
+
+	capture, err := pcap.NewReader(reader)
+	if err != nil {
+		logger.Err(err).Str("path", path).Msg("failed to parse packet capture: is file a pcap or pcapng capture?")
+		errc <- errUnknownFileType
+		return
+	}
+
+	processor := pcap.NewProcessor(&pcapHourHandler{
+		hour:          hour,
+		path:          hourPath,
+		entryChannels: entryChannels,
+	})
+
+	numPackets := 0
+	for {
+		packet, err := capture.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		// the capture can't be read any further, but the flows of the packets read so far are still imported
+		if err != nil {
+			logger.Err(err).Str("path", path).Int("packet", numPackets).Msg("failed to read packet, capture is potentially truncated")
+			errc <- errTruncated
+			break
+		}
+
+		// mark the hour as imported once the first packet was read successfully
+		if numPackets == 0 {
+			metaDBChan <- metaDBFileEntry
+		}
+		numPackets++
+
+		processor.Process(packet)
+	}
+
+	processor.Flush()
+}
+
+func (h *pcapHourHandler) Conn(conn zeektypes.Conn) {
+	if timestampInHour(conn.TimeStamp, h.hour) {
+		conn.SetLogPath(h.path)
+		h.entryChannels.Conn <- conn
+	}
+}
+
+func (h *pcapHourHandler) DNS(dns zeektypes.DNS) {
+	if timestampInHour(dns.TimeStamp, h.hour) {
+		dns.SetLogPath(h.path)
+		h.entryChannels.DNS <- dns
+	}
+}
+
+func (h *pcapHourHandler) HTTP(http zeektypes.HTTP) {
+	if timestampInHour(http.TimeStamp, h.hour) {
+		http.SetLogPath(h.path)
+		h.entryChannels.HTTP <- http
+	}
+}
+
+func (h *pcapHourHandler) SSL(ssl zeektypes.SSL) {
+	if timestampInHour(ssl.TimeStamp, h.hour) {
+		ssl.SetLogPath(h.path)
+		h.entryChannels.SSL <- ssl
+	}
+}
@@ -0,0 +1,317 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"time"
+
+	"activecm/rita/importer/zeektypes"
+)
+
+// dnsSession tracks the dns transactions of a flow
+type dnsSession struct {
+	pending map[uint16]pendingDNSQuery
+	streams [2]dnsStream // length prefixed messages of dns over tcp, indexed by direction
+}
+
+// pendingDNSQuery is a query that hasn't been answered yet
+type pendingDNSQuery struct {
+	ts  time.Time
+	msg dnsMessage
+}
+
+// dnsStream splits one direction of a dns over tcp stream into messages
+type dnsStream struct {
+	buf     []byte
+	stopped bool
+}
+
+// tlsSession tracks the handshake of a tls flow
+type tlsSession struct {
+	client  *clientHello
+	server  *serverHello
+	buffers [2][]byte
+	done    [2]bool
+}
+
+// httpSession tracks the requests and responses of an http flow
+type httpSession struct {
+	requests  httpStream
+	responses httpStream
+	pending   []httpRequest // requests that haven't been answered yet
+	depth     int64
+}
+
+// direction returns the index used for per direction state
+func direction(fromOrig bool) int {
+	if fromOrig {
+		return 0
+	}
+	return 1
+}
+
+// handleStream passes reassembled tcp data to the parser of the flow's application layer protocol, detecting
+// the protocol from the first data seen
+func (f *flow) handleStream(p *Processor, fromOrig bool, data []byte, ts time.Time) {
+	if !f.appChecked {
+		f.appChecked = true
+		switch {
+		case dnsPorts[f.respPort]:
+			f.dns = &dnsSession{pending: make(map[uint16]pendingDNSQuery)}
+		case fromOrig && isTLSHandshake(data):
+			f.tls = &tlsSession{}
+		case fromOrig && isHTTPRequest(data):
+			f.http = &httpSession{responses: httpStream{response: true}}
+		}
+	}
+
+	switch {
+	case f.dns != nil:
+		for _, message := range f.dns.streams[direction(fromOrig)].feed(data) {
+			if msg, err := parseDNSMessage(message); err == nil {
+				f.handleDNSMessage(p, msg, ts)
+			}
+		}
+	case f.tls != nil:
+		f.tls.feed(fromOrig, data)
+		if f.tls.client != nil {
+			f.service = "ssl"
+		}
+	case f.http != nil:
+		f.handleHTTP(p, fromOrig, data, ts)
+	}
+}
+
+// stopApp stops parsing one direction of the application layer after data was missed
+func (f *flow) stopApp(fromOrig bool) {
+	// the protocol can't be detected from the middle of a stream
+	f.appChecked = true
+
+	switch {
+	case f.dns != nil:
+		f.dns.streams[direction(fromOrig)].stop()
+	case f.tls != nil:
+		f.tls.done[direction(fromOrig)] = true
+		f.tls.buffers[direction(fromOrig)] = nil
+	case f.http != nil:
+		if fromOrig {
+			f.http.requests.stop()
+		} else {
+			f.http.responses.stop()
+		}
+	}
+}
+
+// handleDNS parses the payload of a udp dns packet
+func (f *flow) handleDNS(p *Processor, payload []byte, ts time.Time) {
+	msg, err := parseDNSMessage(payload)
+	if err != nil {
+		return
+	}
+	if f.dns == nil {
+		f.dns = &dnsSession{pending: make(map[uint16]pendingDNSQuery)}
+	}
+	f.handleDNSMessage(p, msg, ts)
+}
+
+// handleDNSMessage matches dns responses to their queries, sending a dns record for each answered query
+func (f *flow) handleDNSMessage(p *Processor, msg dnsMessage, ts time.Time) {
+	f.service = "dns"
+
+	if !msg.response {
+		// a repeated transaction id replaces the previous query, which was never answered
+		if previous, ok := f.dns.pending[msg.id]; ok {
+			p.handler.DNS(f.dnsRecord(previous.ts, previous.msg, nil, 0))
+		}
+		f.dns.pending[msg.id] = pendingDNSQuery{ts: ts, msg: msg}
+		return
+	}
+
+	query, ok := f.dns.pending[msg.id]
+	if !ok {
+		// the query wasn't captured, so the response is logged on its own
+		p.handler.DNS(f.dnsRecord(ts, msg, &msg, 0))
+		return
+	}
+	delete(f.dns.pending, msg.id)
+	p.handler.DNS(f.dnsRecord(query.ts, query.msg, &msg, ts.Sub(query.ts)))
+}
+
+// dnsRecord returns the dns record of a query and its response, if it was answered
+func (f *flow) dnsRecord(ts time.Time, query dnsMessage, response *dnsMessage, rtt time.Duration) zeektypes.DNS {
+	record := zeektypes.DNS{
+		TimeStamp:       zeektypes.Timestamp(ts.Unix()),
+		UID:             f.uid,
+		Source:          f.origIP.String(),
+		SourcePort:      int(f.origPort),
+		Destination:     f.respIP.String(),
+		DestinationPort: int(f.respPort),
+		Proto:           protoName(f.proto),
+		TransID:         int64(query.id),
+		Query:           query.query,
+		QClass:          int64(query.qclass),
+		QClassName:      dnsClassName(query.qclass),
+		QType:           int64(query.qtype),
+		QTypeName:       dnsTypeName(query.qtype),
+		RD:              query.rd,
+	}
+
+	if response != nil {
+		record.RTT = rtt.Seconds()
+		record.RCode = int64(response.rcode)
+		record.RCodeName = dnsResponseCodeName(response.rcode)
+		record.AA = response.aa
+		record.TC = response.tc
+		record.RA = response.ra
+		record.Z = int64(response.z)
+		record.Answers = response.answers
+		record.TTLs = response.ttls
+		record.Rejected = response.rcode == 5
+	}
+
+	return record
+}
+
+// feed adds stream data and returns all messages that were completed by it
+func (s *dnsStream) feed(data []byte) [][]byte {
+	if s.stopped {
+		return nil
+	}
+	s.buf = append(s.buf, data...)
+
+	var messages [][]byte
+	for len(s.buf) >= 2 {
+		length := int(binary.BigEndian.Uint16(s.buf[0:2]))
+		if len(s.buf) < 2+length {
+			break
+		}
+		messages = append(messages, s.buf[2:2+length])
+		s.buf = s.buf[2+length:]
+	}
+
+	if len(s.buf) > maxAppBuffer {
+		s.stop()
+	}
+	return messages
+}
+
+func (s *dnsStream) stop() {
+	s.stopped = true
+	s.buf = nil
+}
+
+// feed buffers one direction of the handshake until its hello message can be parsed
+func (s *tlsSession) feed(fromOrig bool, data []byte) {
+	i := direction(fromOrig)
+	if s.done[i] {
+		return
+	}
+	s.buffers[i] = append(s.buffers[i], data...)
+
+	msgType, body, status := readHandshakeMessage(s.buffers[i])
+	switch status {
+	case handshakeIncomplete:
+		if len(s.buffers[i]) <= maxAppBuffer {
+			return
+		}
+	case handshakeComplete:
+		if fromOrig && msgType == tlsClientHello {
+			if hello, ok := parseClientHello(body); ok {
+				s.client = &hello
+			}
+		} else if !fromOrig && msgType == tlsServerHello {
+			if hello, ok := parseServerHello(body); ok {
+				s.server = &hello
+			}
+		}
+	}
+
+	// only the first handshake message of each direction is needed
+	s.done[i], s.buffers[i] = true, nil
+}
+
+// sslRecord returns the ssl record of a tls flow
+func (f *flow) sslRecord() zeektypes.SSL {
+	record := zeektypes.SSL{
+		TimeStamp:       zeektypes.Timestamp(f.start.Unix()),
+		UID:             f.uid,
+		Source:          f.origIP.String(),
+		SourcePort:      int(f.origPort),
+		Destination:     f.respIP.String(),
+		DestinationPort: int(f.respPort),
+		ServerName:      f.tls.client.serverName,
+		JA3:             f.tls.client.ja3Hash,
+	}
+
+	// the handshake is considered established once the server accepted the client's hello
+	if f.tls.server != nil {
+		record.Version = f.tls.server.version
+		record.JA3S = f.tls.server.ja3sHash
+		record.Established = true
+	}
+
+	return record
+}
+
+// handleHTTP parses the requests and responses of an http flow, sending an http record for each answered request
+func (f *flow) handleHTTP(p *Processor, fromOrig bool, data []byte, ts time.Time) {
+	s := f.http
+
+	if fromOrig {
+		for _, lines := range s.requests.feed(data) {
+			request, ok := parseRequest(lines, ts)
+			if !ok {
+				continue
+			}
+			f.service = "http"
+			s.depth++
+			request.depth = s.depth
+			s.pending = append(s.pending, request)
+			// the responses need to know the method of their request to determine whether they have a body
+			s.responses.methods = append(s.responses.methods, request.method)
+		}
+		return
+	}
+
+	for _, lines := range s.responses.feed(data) {
+		response, ok := parseResponse(lines)
+		// informational responses are followed by the actual response
+		if !ok || response.statusCode < 200 || len(s.pending) == 0 {
+			continue
+		}
+		request := s.pending[0]
+		s.pending = s.pending[1:]
+		p.handler.HTTP(f.httpRecord(request, &response))
+	}
+}
+
+// httpRecord returns the http record of a request and its response, if it was answered
+func (f *flow) httpRecord(request httpRequest, response *httpResponse) zeektypes.HTTP {
+	record := zeektypes.HTTP{
+		TimeStamp:       zeektypes.Timestamp(request.ts.Unix()),
+		UID:             f.uid,
+		Source:          f.origIP.String(),
+		SourcePort:      int(f.origPort),
+		Destination:     f.respIP.String(),
+		DestinationPort: int(f.respPort),
+		TransDepth:      request.depth,
+		Method:          request.method,
+		Host:            request.host,
+		URI:             request.uri,
+		Referrer:        request.referrer,
+		Version:         request.version,
+		UserAgent:       request.userAgent,
+		Origin:          request.origin,
+		ReqLen:          request.bodyLen,
+	}
+
+	if response != nil {
+		record.RespLen = response.bodyLen
+		record.StatusCode = response.statusCode
+		record.StatusMsg = response.statusMsg
+		if response.mimeType != "" {
+			record.RespMimeTypes = []string{response.mimeType}
+		}
+	}
+
+	return record
+}
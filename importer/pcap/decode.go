@@ -0,0 +1,249 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// link layer types of the supported capture interfaces
+const (
+	linkTypeNull      = 0
+	linkTypeEthernet  = 1
+	linkTypeRaw       = 101
+	linkTypeRawAlt    = 12
+	linkTypeLoop      = 108
+	linkTypeLinuxSLL  = 113
+	linkTypeLinuxSLL2 = 276
+	linkTypeIPv4      = 228
+	linkTypeIPv6      = 229
+)
+
+// ether types
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+)
+
+// ip protocol numbers
+const (
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+)
+
+// tcp flags
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpRST = 0x04
+	tcpACK = 0x10
+)
+
+// decodedPacket holds the network and transport layer fields of a packet
+type decodedPacket struct {
+	src      net.IP
+	dst      net.IP
+	proto    uint8
+	srcPort  uint16
+	dstPort  uint16
+	ipLength int // length of the ip packet, including headers, as reported by the ip header
+	// payloadLength is the length of the transport layer payload as reported by the headers, which can be larger
+	// than the captured payload if the capture was truncated by the snap length
+	payloadLength int
+	payload       []byte
+	tcpFlags      uint8
+	seq           uint32
+	icmpType      uint8
+	icmpCode      uint8
+}
+
+// decodePacket decodes the link, network and transport layers of a packet. Packets that aren't ip, as well as
+// non-initial ip fragments, can't be assigned to a flow and are not decoded.
+func decodePacket(linkType uint32, data []byte) (decodedPacket, bool) {
+	var packet decodedPacket
+
+	var etherType uint16
+	switch linkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return packet, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:14]), data[14:]
+		// skip any vlan tags
+		for (etherType == etherTypeVLAN || etherType == etherTypeQinQ) && len(data) >= 4 {
+			etherType, data = binary.BigEndian.Uint16(data[2:4]), data[4:]
+		}
+	case linkTypeNull, linkTypeLoop:
+		// the address family is 4 bytes in the byte order of the capturing host, so only the version is checked
+		if len(data) < 4 {
+			return packet, false
+		}
+		data = data[4:]
+		etherType = ipEtherType(data)
+	case linkTypeRaw, linkTypeRawAlt, linkTypeIPv4, linkTypeIPv6:
+		etherType = ipEtherType(data)
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return packet, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:16]), data[16:]
+	case linkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return packet, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[0:2]), data[20:]
+	default:
+		return packet, false
+	}
+
+	var transport []byte
+	var ok bool
+	switch etherType {
+	case etherTypeIPv4:
+		transport, ok = packet.decodeIPv4(data)
+	case etherTypeIPv6:
+		transport, ok = packet.decodeIPv6(data)
+	}
+	if !ok {
+		return packet, false
+	}
+
+	return packet, packet.decodeTransport(transport)
+}
+
+// ipEtherType determines the ether type of a raw ip packet from its version
+func ipEtherType(data []byte) uint16 {
+	if len(data) < 1 {
+		return 0
+	}
+	switch data[0] >> 4 {
+	case 4:
+		return etherTypeIPv4
+	case 6:
+		return etherTypeIPv6
+	}
+	return 0
+}
+
+// decodeIPv4 decodes an ipv4 header, returning the transport layer
+func (p *decodedPacket) decodeIPv4(data []byte) ([]byte, bool) {
+	if len(data) < 20 {
+		return nil, false
+	}
+	headerLength := int(data[0]&0x0f) * 4
+	totalLength := int(binary.BigEndian.Uint16(data[2:4]))
+	if headerLength < 20 || len(data) < headerLength || totalLength < headerLength {
+		return nil, false
+	}
+
+	// non-initial fragments don't contain the transport header
+	if binary.BigEndian.Uint16(data[6:8])&0x1fff != 0 {
+		return nil, false
+	}
+
+	p.src = net.IP(append([]byte(nil), data[12:16]...))
+	p.dst = net.IP(append([]byte(nil), data[16:20]...))
+	p.proto = data[9]
+	p.ipLength = totalLength
+	p.payloadLength = totalLength - headerLength
+
+	// trim any ethernet padding
+	if len(data) > totalLength {
+		data = data[:totalLength]
+	}
+	return data[headerLength:], true
+}
+
+// decodeIPv6 decodes an ipv6 header and its extension headers, returning the transport layer
+func (p *decodedPacket) decodeIPv6(data []byte) ([]byte, bool) {
+	if len(data) < 40 {
+		return nil, false
+	}
+	payloadLength := int(binary.BigEndian.Uint16(data[4:6]))
+	nextHeader := data[6]
+
+	p.src = net.IP(append([]byte(nil), data[8:24]...))
+	p.dst = net.IP(append([]byte(nil), data[24:40]...))
+	p.ipLength = payloadLength + 40
+	p.payloadLength = payloadLength
+
+	data = data[40:]
+	if len(data) > payloadLength {
+		data = data[:payloadLength]
+	}
+
+	// skip extension headers
+	for {
+		switch nextHeader {
+		case 0, 43, 60: // hop-by-hop, routing, destination options
+			if len(data) < 8 {
+				return nil, false
+			}
+			length := (int(data[1]) + 1) * 8
+			if len(data) < length {
+				return nil, false
+			}
+			nextHeader, data = data[0], data[length:]
+			p.payloadLength -= length
+		case 44: // fragment
+			if len(data) < 8 {
+				return nil, false
+			}
+			// non-initial fragments don't contain the transport header
+			if binary.BigEndian.Uint16(data[2:4])&0xfff8 != 0 {
+				return nil, false
+			}
+			nextHeader, data = data[0], data[8:]
+			p.payloadLength -= 8
+		default:
+			p.proto = nextHeader
+			return data, true
+		}
+	}
+}
+
+// decodeTransport decodes the tcp, udp or icmp header of a packet
+func (p *decodedPacket) decodeTransport(data []byte) bool {
+	switch p.proto {
+	case protoTCP:
+		if len(data) < 20 {
+			return false
+		}
+		headerLength := int(data[12]>>4) * 4
+		if headerLength < 20 || len(data) < headerLength {
+			return false
+		}
+		p.srcPort = binary.BigEndian.Uint16(data[0:2])
+		p.dstPort = binary.BigEndian.Uint16(data[2:4])
+		p.seq = binary.BigEndian.Uint32(data[4:8])
+		p.tcpFlags = data[13]
+		p.payloadLength -= headerLength
+		p.payload = data[headerLength:]
+	case protoUDP:
+		if len(data) < 8 {
+			return false
+		}
+		p.srcPort = binary.BigEndian.Uint16(data[0:2])
+		p.dstPort = binary.BigEndian.Uint16(data[2:4])
+		p.payloadLength -= 8
+		p.payload = data[8:]
+	case protoICMP, protoICMPv6:
+		if len(data) < 4 {
+			return false
+		}
+		p.icmpType = data[0]
+		p.icmpCode = data[1]
+		// the header includes 4 bytes that depend on the message type (ie, the identifier and sequence of an echo)
+		p.payloadLength -= 8
+	default:
+		// other protocols are tracked by address only
+	}
+
+	if p.payloadLength < 0 {
+		p.payloadLength = 0
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var errMalformedDNS = errors.New("malformed dns message")
+
+// dnsTypeNames maps dns record types to the names used by zeek
+var dnsTypeNames = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 10: "NULL", 12: "PTR", 13: "HINFO", 15: "MX", 16: "TXT",
+	28: "AAAA", 33: "SRV", 35: "NAPTR", 43: "DS", 46: "RRSIG", 47: "NSEC", 48: "DNSKEY",
+	64: "SVCB", 65: "HTTPS", 255: "*",
+}
+
+// dnsResponseCodeNames maps dns response codes to the names used by zeek
+var dnsResponseCodeNames = map[uint8]string{
+	0: "NOERROR", 1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+// dnsMessage holds the fields of a dns message that are logged by zeek
+type dnsMessage struct {
+	id       uint16
+	response bool
+	aa       bool
+	tc       bool
+	rd       bool
+	ra       bool
+	z        uint8
+	rcode    uint8
+	query    string
+	qclass   uint16
+	qtype    uint16
+	answers  []string
+	ttls     []float64
+}
+
+// parseDNSMessage parses a dns message, only decoding the first question and the answer section
+func parseDNSMessage(data []byte) (dnsMessage, error) {
+	var msg dnsMessage
+	if len(data) < 12 {
+		return msg, errMalformedDNS
+	}
+
+	msg.id = binary.BigEndian.Uint16(data[0:2])
+	flags := binary.BigEndian.Uint16(data[2:4])
+	msg.response = flags&0x8000 != 0
+	msg.aa = flags&0x0400 != 0
+	msg.tc = flags&0x0200 != 0
+	msg.rd = flags&0x0100 != 0
+	msg.ra = flags&0x0080 != 0
+	msg.z = uint8(flags>>4) & 0x07
+	msg.rcode = uint8(flags & 0x000f)
+
+	questionCount := int(binary.BigEndian.Uint16(data[4:6]))
+	answerCount := int(binary.BigEndian.Uint16(data[6:8]))
+
+	offset := 12
+	for i := 0; i < questionCount; i++ {
+		name, next, err := readDNSName(data, offset)
+		if err != nil || next+4 > len(data) {
+			return msg, errMalformedDNS
+		}
+		if i == 0 {
+			msg.query = name
+			msg.qtype = binary.BigEndian.Uint16(data[next : next+2])
+			msg.qclass = binary.BigEndian.Uint16(data[next+2 : next+4])
+		}
+		offset = next + 4
+	}
+
+	for i := 0; i < answerCount; i++ {
+		_, next, err := readDNSName(data, offset)
+		if err != nil || next+10 > len(data) {
+			return msg, errMalformedDNS
+		}
+		recordType := binary.BigEndian.Uint16(data[next : next+2])
+		ttl := binary.BigEndian.Uint32(data[next+4 : next+8])
+		length := int(binary.BigEndian.Uint16(data[next+8 : next+10]))
+		rdataOffset := next + 10
+		if rdataOffset+length > len(data) {
+			return msg, errMalformedDNS
+		}
+
+		if answer, ok := formatDNSAnswer(data, recordType, rdataOffset, length); ok {
+			msg.answers = append(msg.answers, answer)
+			msg.ttls = append(msg.ttls, float64(ttl))
+		}
+		offset = rdataOffset + length
+	}
+
+	return msg, nil
+}
+
+// formatDNSAnswer formats the data of an answer record the way zeek logs it
+func formatDNSAnswer(data []byte, recordType uint16, offset int, length int) (string, bool) {
+	rdata := data[offset : offset+length]
+	switch recordType {
+	case 1: // A
+		if length != net.IPv4len {
+			return "", false
+		}
+		return net.IP(rdata).String(), true
+	case 28: // AAAA
+		if length != net.IPv6len {
+			return "", false
+		}
+		return net.IP(rdata).String(), true
+	case 2, 5, 12: // NS, CNAME, PTR
+		name, _, err := readDNSName(data, offset)
+		return name, err == nil
+	case 15: // MX
+		if length < 3 {
+			return "", false
+		}
+		name, _, err := readDNSName(data, offset+2)
+		return name, err == nil
+	case 16: // TXT
+		var sb strings.Builder
+		sb.WriteString("TXT ")
+		for i := 0; i < len(rdata); {
+			n := int(rdata[i])
+			if i+1+n > len(rdata) {
+				return "", false
+			}
+			sb.WriteString(strconv.Itoa(n))
+			sb.WriteString(" ")
+			sb.Write(rdata[i+1 : i+1+n])
+			i += 1 + n
+		}
+		return sb.String(), true
+	default:
+		// zeek logs other record types by their name only
+		if name, ok := dnsTypeNames[recordType]; ok {
+			return "<" + name + ">", true
+		}
+		return "<unknown type=" + strconv.Itoa(int(recordType)) + ">", true
+	}
+}
+
+// readDNSName reads a possibly compressed domain name, returning the name and the offset after it
+func readDNSName(data []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	// limit the number of compression pointers to protect against loops
+	for jumps := 0; jumps < 32; {
+		if offset >= len(data) {
+			return "", 0, errMalformedDNS
+		}
+		length := int(data[offset])
+
+		switch {
+		case length == 0:
+			if next == -1 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			if offset+2 > len(data) {
+				return "", 0, errMalformedDNS
+			}
+			if next == -1 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(data[offset:offset+2]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(data) {
+				return "", 0, errMalformedDNS
+			}
+			labels = append(labels, string(data[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+	return "", 0, errMalformedDNS
+}
+
+// dnsClassName returns the name zeek uses for a dns class
+func dnsClassName(class uint16) string {
+	switch class {
+	case 1:
+		return "C_INTERNET"
+	case 3:
+		return "C_CHAOS"
+	case 4:
+		return "C_HESIOD"
+	case 254:
+		return "C_NONE"
+	case 255:
+		return "C_ANY"
+	}
+	return strconv.Itoa(int(class))
+}
+
+// dnsTypeName returns the name zeek uses for a dns record type
+func dnsTypeName(recordType uint16) string {
+	if name, ok := dnsTypeNames[recordType]; ok {
+		return name
+	}
+	return strconv.Itoa(int(recordType))
+}
+
+// dnsResponseCodeName returns the name zeek uses for a dns response code
+func dnsResponseCodeName(rcode uint8) string {
+	if name, ok := dnsResponseCodeNames[rcode]; ok {
+		return name
+	}
+	return strconv.Itoa(int(rcode))
+}
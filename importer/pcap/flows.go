@@ -0,0 +1,545 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"time"
+
+	"activecm/rita/importer/zeektypes"
+)
+
+// inactivity timeouts after which a flow is considered finished, matching zeek's defaults
+const (
+	tcpInactivityTimeout    = 5 * time.Minute
+	udpInactivityTimeout    = time.Minute
+	icmpInactivityTimeout   = time.Minute
+	closedInactivityTimeout = 5 * time.Second
+)
+
+// sweepInterval is how often, in capture time, flows are checked for inactivity
+const sweepInterval = 10 * time.Second
+
+// maxBufferedSegments limits the number of out of order segments buffered for a single tcp stream
+const maxBufferedSegments = 64
+
+// maxAppBuffer limits the amount of stream data buffered while waiting for a tls or dns message to complete
+const maxAppBuffer = 64 * 1024
+
+// dnsPorts are the udp and tcp ports whose traffic is parsed as dns (dns, mdns and llmnr)
+var dnsPorts = map[uint16]bool{53: true, 5353: true, 5355: true}
+
+// Handler receives the zeek records produced by a Processor
+type Handler interface {
+	Conn(zeektypes.Conn)
+	DNS(zeektypes.DNS)
+	HTTP(zeektypes.HTTP)
+	SSL(zeektypes.SSL)
+}
+
+// Processor reassembles packets into flows and produces the conn, dns, http and ssl records zeek would log for them
+type Processor struct {
+	handler   Handler
+	flows     map[flowKey]*flow
+	lastSweep time.Time
+}
+
+// flowKey identifies a flow regardless of the direction of a packet
+type flowKey struct {
+	addrA, addrB string
+	portA, portB uint16
+	proto        uint8
+}
+
+// endpoint tracks one side of a flow
+type endpoint struct {
+	packets      int64
+	ipBytes      int64
+	payloadBytes int64 // bytes of udp and icmp payloads, tcp payloads are counted by the stream
+	syn          bool
+	fin          bool
+	rst          bool
+	stream       tcpStream
+}
+
+// flow tracks a single connection
+type flow struct {
+	uid      string
+	start    time.Time
+	last     time.Time
+	origIP   net.IP
+	respIP   net.IP
+	origPort uint16
+	respPort uint16
+	proto    uint8
+	orig     endpoint
+	resp     endpoint
+	history  []byte
+	flipped  bool
+	service  string
+
+	// application layer parsers, set once the protocol of the flow has been detected
+	appChecked bool
+	dns        *dnsSession
+	tls        *tlsSession
+	http       *httpSession
+}
+
+// NewProcessor returns a processor that sends the records it produces to the handler
+func NewProcessor(handler Handler) *Processor {
+	return &Processor{handler: handler, flows: make(map[flowKey]*flow)}
+}
+
+// Process adds a packet to its flow. Packets must be processed in the order they were captured.
+func (p *Processor) Process(packet Packet) {
+	decoded, ok := decodePacket(packet.LinkType, packet.Data)
+	if !ok {
+		return
+	}
+	ts := packet.Timestamp
+
+	// periodically finish flows that have been inactive for longer than their timeout
+	if ts.Sub(p.lastSweep) >= sweepInterval {
+		p.sweep(ts)
+		p.lastSweep = ts
+	}
+
+	key := newFlowKey(&decoded)
+	f, ok := p.flows[key]
+
+	// a new connection attempt on a closed tcp flow starts a new flow
+	if ok && decoded.proto == protoTCP && decoded.tcpFlags&(tcpSYN|tcpACK) == tcpSYN && f.closed() {
+		p.finish(key, f)
+		ok = false
+	}
+	if !ok {
+		f = newFlow(&decoded, ts)
+		p.flows[key] = f
+	}
+
+	p.update(f, &decoded, ts)
+}
+
+// Flush finishes all remaining flows. It must be called once all packets have been processed.
+func (p *Processor) Flush() {
+	for key, f := range p.flows {
+		p.finish(key, f)
+	}
+}
+
+// sweep finishes all flows that have been inactive for longer than their timeout
+func (p *Processor) sweep(now time.Time) {
+	for key, f := range p.flows {
+		if now.Sub(f.last) > f.timeout() {
+			p.finish(key, f)
+		}
+	}
+}
+
+// finish removes a flow from the flow table and sends its records to the handler
+func (p *Processor) finish(key flowKey, f *flow) {
+	delete(p.flows, key)
+
+	f.orig.stream.flush()
+	f.resp.stream.flush()
+
+	if f.dns != nil {
+		// log queries that were never answered
+		for _, query := range f.dns.pending {
+			p.handler.DNS(f.dnsRecord(query.ts, query.msg, nil, 0))
+		}
+	}
+	if f.http != nil {
+		// log requests that were never answered
+		for _, request := range f.http.pending {
+			p.handler.HTTP(f.httpRecord(request, nil))
+		}
+	}
+	if f.tls != nil && f.tls.client != nil {
+		p.handler.SSL(f.sslRecord())
+	}
+
+	p.handler.Conn(f.conn())
+}
+
+// newFlowKey returns the key of the flow a packet belongs to, ordering the endpoints so that both directions
+// of a flow have the same key
+func newFlowKey(packet *decodedPacket) flowKey {
+	a, b := string(packet.src.To16()), string(packet.dst.To16())
+	portA, portB := packet.srcPort, packet.dstPort
+	if packet.proto != protoTCP && packet.proto != protoUDP {
+		// icmp and other protocols are tracked by address pair
+		portA, portB = 0, 0
+	}
+	if a > b || (a == b && portA > portB) {
+		a, b, portA, portB = b, a, portB, portA
+	}
+	return flowKey{addrA: a, addrB: b, portA: portA, portB: portB, proto: packet.proto}
+}
+
+// newFlow creates a flow from its first packet
+func newFlow(packet *decodedPacket, ts time.Time) *flow {
+	f := &flow{
+		start:    ts,
+		last:     ts,
+		origIP:   packet.src,
+		respIP:   packet.dst,
+		origPort: packet.srcPort,
+		respPort: packet.dstPort,
+		proto:    packet.proto,
+	}
+
+	switch packet.proto {
+	case protoTCP:
+		// the responder was seen first if the first packet is a syn-ack, or if there was no handshake
+		// and the packet was sent from a well known port to an ephemeral one
+		synAck := packet.tcpFlags&(tcpSYN|tcpACK) == tcpSYN|tcpACK
+		noSyn := packet.tcpFlags&tcpSYN == 0
+		f.flipped = synAck || (noSyn && isServerPort(packet.srcPort, packet.dstPort))
+	case protoUDP:
+		f.flipped = isServerPort(packet.srcPort, packet.dstPort)
+	case protoICMP, protoICMPv6:
+		// zeek logs the icmp type and code in place of the ports
+		f.origPort, f.respPort = uint16(packet.icmpType), uint16(packet.icmpCode)
+	}
+	if f.flipped {
+		f.origIP, f.respIP = f.respIP, f.origIP
+		f.origPort, f.respPort = f.respPort, f.origPort
+	}
+
+	f.uid = flowUID(f)
+	return f
+}
+
+// isServerPort returns whether a packet was likely sent by a server, based on its ports
+func isServerPort(srcPort uint16, dstPort uint16) bool {
+	return srcPort < 1024 && dstPort >= 1024
+}
+
+// flowUID returns a deterministic zeek style uid for a flow, so that importing the same capture again
+// produces the same uids
+func flowUID(f *flow) string {
+	h := fnv.New64a()
+	h.Write(f.origIP.To16())
+	h.Write(f.respIP.To16())
+	var buf [13]byte
+	binary.BigEndian.PutUint16(buf[0:2], f.origPort)
+	binary.BigEndian.PutUint16(buf[2:4], f.respPort)
+	buf[4] = f.proto
+	binary.BigEndian.PutUint64(buf[5:13], uint64(f.start.UnixNano()))
+	h.Write(buf[:])
+	return "C" + strconv.FormatUint(h.Sum64(), 36)
+}
+
+// update adds a packet to a flow
+func (p *Processor) update(f *flow, packet *decodedPacket, ts time.Time) {
+	if ts.After(f.last) {
+		f.last = ts
+	}
+
+	fromOrig := packet.src.Equal(f.origIP) && (f.proto != protoTCP && f.proto != protoUDP || packet.srcPort == f.origPort)
+	if f.origIP.Equal(f.respIP) && f.origPort == f.respPort {
+		fromOrig = true
+	}
+	ep := &f.resp
+	if fromOrig {
+		ep = &f.orig
+	}
+
+	ep.packets++
+	ep.ipBytes += int64(packet.ipLength)
+
+	switch f.proto {
+	case protoTCP:
+		p.updateTCP(f, ep, fromOrig, packet, ts)
+	case protoUDP:
+		ep.payloadBytes += int64(packet.payloadLength)
+		if packet.payloadLength > 0 {
+			f.addHistory(fromOrig, 'D')
+		}
+		if dnsPorts[f.respPort] || dnsPorts[f.origPort] {
+			f.handleDNS(p, packet.payload, ts)
+		}
+	default:
+		ep.payloadBytes += int64(packet.payloadLength)
+	}
+}
+
+// updateTCP tracks the state of a tcp flow and passes its reassembled payload to the application layer parsers
+func (p *Processor) updateTCP(f *flow, ep *endpoint, fromOrig bool, packet *decodedPacket, ts time.Time) {
+	flags := packet.tcpFlags
+	switch {
+	case flags&tcpSYN != 0 && flags&tcpACK != 0:
+		ep.syn = true
+		f.addHistory(fromOrig, 'H')
+	case flags&tcpSYN != 0:
+		ep.syn = true
+		f.addHistory(fromOrig, 'S')
+	case flags&tcpACK != 0 && packet.payloadLength == 0 && flags&(tcpFIN|tcpRST) == 0:
+		f.addHistory(fromOrig, 'A')
+	}
+	if packet.payloadLength > 0 {
+		f.addHistory(fromOrig, 'D')
+	}
+	if flags&tcpFIN != 0 {
+		ep.fin = true
+		f.addHistory(fromOrig, 'F')
+	}
+	if flags&tcpRST != 0 {
+		ep.rst = true
+		f.addHistory(fromOrig, 'R')
+	}
+
+	data, gap := ep.stream.add(packet.seq, flags, packet.payload, packet.payloadLength)
+	if gap {
+		// the application layer can't be parsed past missing data
+		f.stopApp(fromOrig)
+	}
+	if len(data) > 0 {
+		f.handleStream(p, fromOrig, data, ts)
+	}
+}
+
+// addHistory records the first occurrence of an event in the zeek history of a flow, using uppercase
+// letters for the originator and lowercase letters for the responder
+func (f *flow) addHistory(fromOrig bool, event byte) {
+	if !fromOrig {
+		event += 'a' - 'A'
+	}
+	for _, seen := range f.history {
+		if seen == event {
+			return
+		}
+	}
+	f.history = append(f.history, event)
+}
+
+// closed returns whether a tcp flow has been torn down
+func (f *flow) closed() bool {
+	return f.orig.rst || f.resp.rst || (f.orig.fin && f.resp.fin)
+}
+
+// timeout returns the inactivity timeout of a flow
+func (f *flow) timeout() time.Duration {
+	switch f.proto {
+	case protoTCP:
+		if f.closed() {
+			return closedInactivityTimeout
+		}
+		return tcpInactivityTimeout
+	case protoUDP:
+		return udpInactivityTimeout
+	}
+	return icmpInactivityTimeout
+}
+
+// connState returns the zeek conn_state of a flow
+func (f *flow) connState() string {
+	if f.proto != protoTCP {
+		// other protocols have no connection state, so mimic zeek's behavior of marking them as attempted or seen
+		if f.resp.packets == 0 {
+			return "S0"
+		}
+		return "SF"
+	}
+
+	o, r := f.orig, f.resp
+	switch {
+	// no handshake seen
+	case !o.syn && !r.syn:
+		return "OTH"
+	// connection attempt, no syn-ack
+	case o.syn && !r.syn:
+		switch {
+		case r.rst:
+			return "REJ"
+		case o.rst:
+			return "RSTOS0"
+		case o.fin:
+			return "SH"
+		}
+		return "S0"
+	// syn-ack seen without the originator's syn
+	case !o.syn && r.syn:
+		switch {
+		case r.rst:
+			return "RSTRH"
+		case r.fin:
+			return "SHR"
+		}
+		return "OTH"
+	}
+
+	// connection established
+	switch {
+	case o.rst:
+		return "RSTO"
+	case r.rst:
+		return "RSTR"
+	case o.fin && r.fin:
+		return "SF"
+	case o.fin:
+		return "S2"
+	case r.fin:
+		return "S3"
+	}
+	return "S1"
+}
+
+// conn returns the conn record of a flow
+func (f *flow) conn() zeektypes.Conn {
+	history := string(f.history)
+	if f.flipped {
+		history = "^" + history
+	}
+
+	return zeektypes.Conn{
+		TimeStamp:       zeektypes.Timestamp(f.start.Unix()),
+		UID:             f.uid,
+		Source:          f.origIP.String(),
+		SourcePort:      int(f.origPort),
+		Destination:     f.respIP.String(),
+		DestinationPort: int(f.respPort),
+		Proto:           protoName(f.proto),
+		Service:         f.service,
+		Duration:        f.last.Sub(f.start).Seconds(),
+		OrigBytes:       f.orig.payloadBytes + f.orig.stream.bytes,
+		RespBytes:       f.resp.payloadBytes + f.resp.stream.bytes,
+		ConnState:       f.connState(),
+		MissedBytes:     f.orig.stream.missed + f.resp.stream.missed,
+		History:         history,
+		OrigPackets:     f.orig.packets,
+		OrigIPBytes:     f.orig.ipBytes,
+		RespPackets:     f.resp.packets,
+		RespIPBytes:     f.resp.ipBytes,
+	}
+}
+
+// protoName returns the name zeek uses for a transport protocol
+func protoName(proto uint8) string {
+	switch proto {
+	case protoTCP:
+		return "tcp"
+	case protoUDP:
+		return "udp"
+	case protoICMP, protoICMPv6:
+		return "icmp"
+	}
+	return "unknown_transport"
+}
+
+// tcpStream reassembles one direction of a tcp flow
+type tcpStream struct {
+	started bool
+	next    uint32 // sequence number of the next expected byte
+	bytes   int64  // payload bytes in sequence space, including missed bytes
+	missed  int64  // bytes that were never captured
+	pending []segment
+}
+
+// segment is an out of order tcp segment
+type segment struct {
+	seq     uint32
+	payload []byte
+	length  int
+}
+
+// add adds a segment to the stream, returning any data that is now in order. The returned gap flag is set
+// if data was skipped, either because it was missing from the capture or truncated by the snap length.
+func (s *tcpStream) add(seq uint32, flags uint8, payload []byte, length int) ([]byte, bool) {
+	if flags&tcpSYN != 0 {
+		if !s.started {
+			s.started, s.next = true, seq+1
+		}
+		return nil, false
+	}
+	if length == 0 {
+		return nil, false
+	}
+	if !s.started {
+		// the handshake wasn't captured, so start the stream at the first segment
+		s.started, s.next = true, seq
+	}
+
+	if int32(seq-s.next) > 0 {
+		// the segment is ahead of the stream, buffer it until the missing data arrives
+		s.pending = append(s.pending, segment{seq: seq, payload: append([]byte(nil), payload...), length: length})
+		if len(s.pending) > maxBufferedSegments {
+			// the missing data is unlikely to arrive anymore
+			data, _ := s.skipGap()
+			return data, true
+		}
+		return nil, false
+	}
+
+	data, gap := s.deliver(seq, payload, length)
+	more, moreGap := s.drain()
+	return append(data, more...), gap || moreGap
+}
+
+// deliver adds an in order or overlapping segment to the stream, trimming any retransmitted data
+func (s *tcpStream) deliver(seq uint32, payload []byte, length int) ([]byte, bool) {
+	overlap := int(s.next - seq)
+	if overlap >= length {
+		// retransmission of data that was already delivered
+		return nil, false
+	}
+	length -= overlap
+	if overlap < len(payload) {
+		payload = payload[overlap:]
+	} else {
+		payload = nil
+	}
+
+	s.next += uint32(length)
+	s.bytes += int64(length)
+
+	// the capture was truncated, so the rest of the segment is missing
+	return payload, len(payload) < length
+}
+
+// drain delivers any buffered segments that are now in order
+func (s *tcpStream) drain() ([]byte, bool) {
+	var data []byte
+	gap := false
+	for delivered := true; delivered; {
+		delivered = false
+		for i, seg := range s.pending {
+			if int32(seg.seq-s.next) <= 0 {
+				s.pending = append(s.pending[:i], s.pending[i+1:]...)
+				segData, segGap := s.deliver(seg.seq, seg.payload, seg.length)
+				data = append(data, segData...)
+				gap = gap || segGap
+				delivered = true
+				break
+			}
+		}
+	}
+	return data, gap
+}
+
+// skipGap gives up on missing data, continuing the stream at the earliest buffered segment
+func (s *tcpStream) skipGap() ([]byte, bool) {
+	if len(s.pending) == 0 {
+		return nil, false
+	}
+	earliest := s.pending[0].seq
+	for _, seg := range s.pending[1:] {
+		if int32(seg.seq-earliest) < 0 {
+			earliest = seg.seq
+		}
+	}
+	missing := int64(earliest - s.next)
+	s.missed += missing
+	s.bytes += missing
+	s.next = earliest
+	return s.drain()
+}
+
+// flush accounts for the data that is still buffered when the flow finishes
+func (s *tcpStream) flush() {
+	for len(s.pending) > 0 {
+		s.skipGap()
+	}
+}
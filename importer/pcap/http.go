@@ -0,0 +1,221 @@
+package pcap
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHTTPHeaderSize limits the size of the headers of a single http message
+const maxHTTPHeaderSize = 16 * 1024
+
+var httpMethods = []string{"GET", "POST", "HEAD", "PUT", "DELETE", "OPTIONS", "CONNECT", "PATCH", "TRACE", "PROPFIND"}
+
+// httpRequest holds the fields of an http request needed for the http log
+type httpRequest struct {
+	ts        time.Time
+	depth     int64 // position of the request within its connection
+	method    string
+	uri       string
+	version   string
+	host      string
+	userAgent string
+	referrer  string
+	origin    string
+	bodyLen   int64
+}
+
+// httpResponse holds the fields of an http response needed for the http log
+type httpResponse struct {
+	statusCode int64
+	statusMsg  string
+	bodyLen    int64
+	mimeType   string
+}
+
+// httpStream parses the http messages of one direction of a tcp stream
+type httpStream struct {
+	buf      []byte
+	skip     int64 // body bytes of the current message that still have to be skipped
+	stopped  bool  // set once the stream can no longer be parsed (ie, chunked or unknown body length)
+	response bool
+	// methods of the requests that haven't been answered yet, since responses to HEAD requests don't have a body
+	methods []string
+}
+
+// isHTTPRequest returns whether a stream starts with an http request line
+func isHTTPRequest(data []byte) bool {
+	for _, method := range httpMethods {
+		if len(data) > len(method) && string(data[:len(method)]) == method && data[len(method)] == ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// feed adds reassembled stream data and returns the headers of all messages that were completed by it
+func (s *httpStream) feed(data []byte) [][]string {
+	if s.stopped {
+		return nil
+	}
+
+	// skip the body of the previous message
+	if s.skip > 0 {
+		n := min(s.skip, int64(len(data)))
+		s.skip -= n
+		data = data[n:]
+	}
+	s.buf = append(s.buf, data...)
+
+	var messages [][]string
+	for len(s.buf) > 0 && s.skip == 0 {
+		end := bytes.Index(s.buf, []byte("\r\n\r\n"))
+		if end == -1 {
+			if len(s.buf) > maxHTTPHeaderSize {
+				s.stop()
+			}
+			break
+		}
+
+		lines := strings.Split(string(s.buf[:end]), "\r\n")
+		s.buf = s.buf[end+4:]
+		messages = append(messages, lines)
+
+		// determine how much of the body has to be skipped before the next message
+		bodyLen, ok := httpBodyLength(lines, s.response)
+		// informational responses don't answer the request
+		if code, _ := parseStatusLine(lines[0]); s.response && code >= 200 && len(s.methods) > 0 {
+			if s.methods[0] == "HEAD" {
+				bodyLen, ok = 0, true
+			}
+			s.methods = s.methods[1:]
+		}
+		if !ok {
+			s.stop()
+			break
+		}
+		n := min(bodyLen, int64(len(s.buf)))
+		s.buf = s.buf[n:]
+		s.skip = bodyLen - n
+	}
+
+	return messages
+}
+
+func (s *httpStream) stop() {
+	s.stopped = true
+	s.buf = nil
+}
+
+// httpBodyLength returns the length of the body of a message, if it can be determined from its headers
+func httpBodyLength(lines []string, response bool) (int64, bool) {
+	if response {
+		// responses without a body
+		if code, _ := parseStatusLine(lines[0]); code < 200 || code == 204 || code == 304 {
+			return 0, true
+		}
+	}
+
+	length, hasLength := int64(0), false
+	for _, line := range lines[1:] {
+		name, value, _ := strings.Cut(line, ":")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length":
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			length, hasLength = n, true
+		case "transfer-encoding":
+			if strings.Contains(strings.ToLower(value), "chunked") {
+				return 0, false
+			}
+		}
+	}
+
+	// requests without a content length don't have a body, responses are read until the connection closes
+	if !hasLength && response {
+		return 0, false
+	}
+	return length, true
+}
+
+// parseRequest parses the request line and headers of an http request
+func parseRequest(lines []string, ts time.Time) (httpRequest, bool) {
+	parts := strings.SplitN(lines[0], " ", 3)
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/") {
+		return httpRequest{}, false
+	}
+
+	request := httpRequest{
+		ts:      ts,
+		method:  parts[0],
+		uri:     parts[1],
+		version: strings.TrimPrefix(parts[2], "HTTP/"),
+	}
+
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "host":
+			request.host = value
+		case "user-agent":
+			request.userAgent = value
+		case "referer":
+			request.referrer = value
+		case "origin":
+			request.origin = value
+		case "content-length":
+			request.bodyLen, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+
+	return request, true
+}
+
+// parseResponse parses the status line and headers of an http response
+func parseResponse(lines []string) (httpResponse, bool) {
+	code, msg := parseStatusLine(lines[0])
+	if code == 0 {
+		return httpResponse{}, false
+	}
+
+	response := httpResponse{statusCode: code, statusMsg: msg}
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length":
+			response.bodyLen, _ = strconv.ParseInt(value, 10, 64)
+		case "content-type":
+			mimeType, _, _ := strings.Cut(value, ";")
+			response.mimeType = strings.TrimSpace(mimeType)
+		}
+	}
+
+	return response, true
+}
+
+// parseStatusLine returns the status code and message of an http status line
+func parseStatusLine(line string) (int64, string) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "HTTP/") {
+		return 0, ""
+	}
+	code, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ""
+	}
+	if len(parts) == 3 {
+		return code, parts[2]
+	}
+	return code, ""
+}
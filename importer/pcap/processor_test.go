@@ -0,0 +1,223 @@
+package pcap
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"activecm/rita/importer/zeektypes"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordCollector is a Handler that keeps all records it receives
+type recordCollector struct {
+	conns []zeektypes.Conn
+	dns   []zeektypes.DNS
+	http  []zeektypes.HTTP
+	ssl   []zeektypes.SSL
+}
+
+func (c *recordCollector) Conn(conn zeektypes.Conn) { c.conns = append(c.conns, conn) }
+func (c *recordCollector) DNS(dns zeektypes.DNS)    { c.dns = append(c.dns, dns) }
+func (c *recordCollector) HTTP(http zeektypes.HTTP) { c.http = append(c.http, http) }
+func (c *recordCollector) SSL(ssl zeektypes.SSL)    { c.ssl = append(c.ssl, ssl) }
+
+// processCapture runs all packets of a capture file through a processor
+func processCapture(t *testing.T, path string) *recordCollector {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	capture, err := NewReader(reader)
+	require.NoError(t, err)
+
+	collector := &recordCollector{}
+	processor := NewProcessor(collector)
+	for {
+		packet, err := capture.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		processor.Process(packet)
+	}
+	processor.Flush()
+
+	return collector
+}
+
+func TestProcessor(t *testing.T) {
+	start := time.Date(2024, 4, 19, 16, 0, 0, 0, time.UTC).Unix()
+
+	// the same packets, written as a little endian microsecond pcap, a big endian nanosecond pcapng
+	// and a gzipped nanosecond pcap
+	for _, path := range []string{"../../test_data/pcap/sample.pcap", "../../test_data/pcap/sample.pcapng", "../../test_data/pcap/sample_ns.pcap.gz"} {
+		t.Run(path, func(t *testing.T) {
+			records := processCapture(t, path)
+
+			// index the conn records by originator port, since flows are flushed in no particular order
+			require.Len(t, records.conns, 5, "capture should contain 5 flows")
+			conns := make(map[int]zeektypes.Conn)
+			for _, conn := range records.conns {
+				require.NotEmpty(t, conn.UID, "conn should have a uid")
+				conns[conn.SourcePort] = conn
+			}
+
+			// dns query and its answer
+			require.Len(t, records.dns, 1)
+			require.Equal(t, conns[51000].UID, records.dns[0].UID, "dns record should be linked to its conn")
+			require.InDelta(t, 0.025, records.dns[0].RTT, 0.000001)
+			records.dns[0].UID, records.dns[0].RTT = "", 0
+			require.Equal(t, zeektypes.DNS{
+				TimeStamp: zeektypes.Timestamp(start + 1), Source: "10.0.0.5", SourcePort: 51000, Destination: "8.8.8.8",
+				DestinationPort: 53, Proto: "udp", TransID: 0x1234, Query: "example.com", QClass: 1, QClassName: "C_INTERNET",
+				QType: 1, QTypeName: "A", RCode: 0, RCodeName: "NOERROR", RD: true, RA: true,
+				Answers: []string{"www.example.com", "93.184.216.34"}, TTLs: []float64{60, 300},
+			}, records.dns[0])
+
+			// http request, which was split into out of order segments
+			require.Len(t, records.http, 1)
+			require.Equal(t, conns[51001].UID, records.http[0].UID, "http record should be linked to its conn")
+			records.http[0].UID = ""
+			require.Equal(t, zeektypes.HTTP{
+				TimeStamp: zeektypes.Timestamp(start + 2), Source: "10.0.0.5", SourcePort: 51001, Destination: "93.184.216.34",
+				DestinationPort: 80, TransDepth: 1, Method: "GET", Host: "example.com", URI: "/index.html", Version: "1.1",
+				UserAgent: "rita-test/1.0", RespLen: 5, StatusCode: 200, StatusMsg: "OK", RespMimeTypes: []string{"text/html"},
+			}, records.http[0])
+
+			// tls handshake, whose ClientHello was split across two segments
+			require.Len(t, records.ssl, 1)
+			require.Equal(t, conns[51002].UID, records.ssl[0].UID, "ssl record should be linked to its conn")
+			records.ssl[0].UID = ""
+			require.Equal(t, zeektypes.SSL{
+				TimeStamp: zeektypes.Timestamp(start + 5), Source: "10.0.0.5", SourcePort: 51002, Destination: "93.184.216.34",
+				DestinationPort: 443, Version: "TLSv13", ServerName: "example.com", Established: true,
+				JA3: "13479e2787be3a74d48b11b86bcdee2e", JA3S: "f4febc55ea12b31ae17cfb7e614afda8",
+			}, records.ssl[0])
+
+			expectedConns := []zeektypes.Conn{
+				{
+					TimeStamp: zeektypes.Timestamp(start + 1), Source: "10.0.0.5", SourcePort: 51000, Destination: "8.8.8.8",
+					DestinationPort: 53, Proto: "udp", Service: "dns", Duration: 0.025, OrigBytes: 29, RespBytes: 74,
+					ConnState: "SF", History: "Dd", OrigPackets: 1, OrigIPBytes: 57, RespPackets: 1, RespIPBytes: 102,
+				},
+				// the retransmitted response is only counted once in the payload bytes
+				{
+					TimeStamp: zeektypes.Timestamp(start + 2), Source: "10.0.0.5", SourcePort: 51001, Destination: "93.184.216.34",
+					DestinationPort: 80, Proto: "tcp", Service: "http", Duration: 0.09, OrigBytes: 87, RespBytes: 83,
+					ConnState: "SF", History: "ShADadFf", OrigPackets: 6, OrigIPBytes: 6*40 + 87, RespPackets: 5, RespIPBytes: 5*40 + 2*83,
+				},
+				{
+					TimeStamp: zeektypes.Timestamp(start + 5), Source: "10.0.0.5", SourcePort: 51002, Destination: "93.184.216.34",
+					DestinationPort: 443, Proto: "tcp", Service: "ssl", Duration: 1.5, OrigBytes: 115, RespBytes: 63,
+					ConnState: "RSTO", History: "ShADdR", OrigPackets: 5, OrigIPBytes: 5*40 + 115, RespPackets: 2, RespIPBytes: 2*40 + 63,
+				},
+				// icmp type and code are logged in place of the ports
+				{
+					TimeStamp: zeektypes.Timestamp(start + 3610), Source: "10.0.0.5", SourcePort: 8, Destination: "10.0.0.1",
+					DestinationPort: 0, Proto: "icmp", Duration: 0.001, OrigBytes: 4, RespBytes: 4,
+					ConnState: "SF", OrigPackets: 1, OrigIPBytes: 32, RespPackets: 1, RespIPBytes: 32,
+				},
+				{
+					TimeStamp: zeektypes.Timestamp(start + 3630), Source: "10.0.0.5", SourcePort: 40000, Destination: "10.0.0.1",
+					DestinationPort: 9999, Proto: "udp", OrigBytes: 5, ConnState: "S0", History: "D", OrigPackets: 1, OrigIPBytes: 33,
+				},
+			}
+
+			for _, expected := range expectedConns {
+				conn, ok := conns[expected.SourcePort]
+				require.True(t, ok, "conn should exist for originator port %d", expected.SourcePort)
+				require.InDelta(t, expected.Duration, conn.Duration, 0.000001)
+				conn.UID, conn.Duration, expected.Duration = "", 0, 0
+				require.Equal(t, expected, conn)
+			}
+		})
+	}
+}
+
+func TestProcessorFlowDirection(t *testing.T) {
+	// build raw ipv4 packets directly, since only the flow tracking is under test
+	tcpPacket := func(src, dst [4]byte, srcPort, dstPort uint16, flags uint8) Packet {
+		data := make([]byte, 40)
+		data[0], data[3], data[9] = 0x45, 40, protoTCP
+		copy(data[12:16], src[:])
+		copy(data[16:20], dst[:])
+		data[20], data[21], data[22], data[23] = byte(srcPort>>8), byte(srcPort), byte(dstPort>>8), byte(dstPort)
+		data[32], data[33] = 5<<4, flags
+		return Packet{Timestamp: time.Unix(1713542400, 0), LinkType: linkTypeRaw, Data: data}
+	}
+	client, server := [4]byte{10, 0, 0, 5}, [4]byte{10, 0, 0, 9}
+
+	tests := []struct {
+		name           string
+		packets        []Packet
+		expectedSource string
+		expectedState  string
+		expectedFlows  int
+	}{
+		{
+			name:           "Syn-Ack Seen First",
+			packets:        []Packet{tcpPacket(server, client, 22, 50000, tcpSYN|tcpACK), tcpPacket(server, client, 22, 50000, tcpRST)},
+			expectedSource: "10.0.0.5",
+			expectedState:  "RSTRH",
+			expectedFlows:  1,
+		},
+		{
+			name:           "Rejected Connection",
+			packets:        []Packet{tcpPacket(client, server, 50000, 22, tcpSYN), tcpPacket(server, client, 22, 50000, tcpRST|tcpACK)},
+			expectedSource: "10.0.0.5",
+			expectedState:  "REJ",
+			expectedFlows:  1,
+		},
+		{
+			name:           "Midstream From Server Port",
+			packets:        []Packet{tcpPacket(server, client, 22, 50000, tcpACK)},
+			expectedSource: "10.0.0.5",
+			expectedState:  "OTH",
+			expectedFlows:  1,
+		},
+		{
+			name: "Reused Ports After Reset",
+			packets: []Packet{
+				tcpPacket(client, server, 50000, 22, tcpSYN), tcpPacket(server, client, 22, 50000, tcpRST|tcpACK),
+				tcpPacket(client, server, 50000, 22, tcpSYN),
+			},
+			expectedSource: "10.0.0.5",
+			expectedFlows:  2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collector := &recordCollector{}
+			processor := NewProcessor(collector)
+			for _, packet := range test.packets {
+				processor.Process(packet)
+			}
+			processor.Flush()
+
+			require.Len(t, collector.conns, test.expectedFlows)
+			for _, conn := range collector.conns {
+				require.Equal(t, test.expectedSource, conn.Source, "originator should be the client")
+			}
+			if test.expectedState != "" {
+				require.Equal(t, test.expectedState, collector.conns[0].ConnState)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var ErrUnknownCaptureFormat = errors.New("unknown packet capture format, expected pcap or pcapng")
+var errMalformedBlock = errors.New("malformed pcapng block")
+
+// magic numbers of the supported capture formats
+const (
+	pcapMagicMicroseconds = 0xa1b2c3d4
+	pcapMagicNanoseconds  = 0xa1b23c4d
+	pcapngSectionHeader   = 0x0a0d0d0a
+	pcapngByteOrderMagic  = 0x1a2b3c4d
+)
+
+// pcapng block types
+const (
+	pcapngInterfaceDescription = 0x00000001
+	pcapngSimplePacket         = 0x00000003
+	pcapngEnhancedPacket       = 0x00000006
+)
+
+// maxPacketSize limits the size of a single packet to protect against corrupted captures
+const maxPacketSize = 256 * 1024
+
+// Packet is a single packet read from a capture file
+type Packet struct {
+	Timestamp time.Time
+	LinkType  uint32
+	Data      []byte
+}
+
+// pcapngInterface holds the fields of a pcapng interface description block needed to read its packets
+type pcapngInterface struct {
+	linkType uint32
+	tsUnit   time.Duration // duration of a single timestamp tick
+	tsPerSec uint64        // timestamp ticks per second, used when the resolution isn't a whole number of nanoseconds
+}
+
+// Reader reads packets from a pcap or pcapng capture file
+type Reader struct {
+	reader     *bufio.Reader
+	isPCAPNG   bool
+	byteOrder  binary.ByteOrder
+	linkType   uint32
+	nanos      bool
+	interfaces []pcapngInterface
+}
+
+// NewReader detects the format of a capture file and returns a reader for its packets
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{reader: bufio.NewReaderSize(r, 1024*1024)}
+
+	magicBytes, err := reader.reader.Peek(4)
+	if err != nil {
+		return nil, ErrUnknownCaptureFormat
+	}
+
+	switch {
+	case binary.BigEndian.Uint32(magicBytes) == pcapngSectionHeader:
+		reader.isPCAPNG = true
+		// the section header is read like any other block, setting the byte order of the section
+		return reader, nil
+	case binary.LittleEndian.Uint32(magicBytes) == pcapMagicMicroseconds:
+		reader.byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(magicBytes) == pcapMagicMicroseconds:
+		reader.byteOrder = binary.BigEndian
+	case binary.LittleEndian.Uint32(magicBytes) == pcapMagicNanoseconds:
+		reader.byteOrder, reader.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(magicBytes) == pcapMagicNanoseconds:
+		reader.byteOrder, reader.nanos = binary.BigEndian, true
+	default:
+		return nil, ErrUnknownCaptureFormat
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader.reader, header); err != nil {
+		return nil, ErrUnknownCaptureFormat
+	}
+	// the upper bits of the link type field are used for the fcs length, which is ignored
+	reader.linkType = reader.byteOrder.Uint32(header[20:24]) & 0x0fffffff
+
+	return reader, nil
+}
+
+// Next returns the next packet in the capture, or io.EOF once all packets have been read
+func (r *Reader) Next() (Packet, error) {
+	if r.isPCAPNG {
+		return r.nextPCAPNG()
+	}
+	return r.nextPCAP()
+}
+
+// nextPCAP reads the next packet record of a pcap file
+func (r *Reader) nextPCAP() (Packet, error) {
+	header := make([]byte, 16)
+	n, err := io.ReadFull(r.reader, header)
+	if n == 0 && errors.Is(err, io.EOF) {
+		return Packet{}, io.EOF
+	}
+	if err != nil {
+		return Packet{}, io.ErrUnexpectedEOF
+	}
+
+	seconds := int64(r.byteOrder.Uint32(header[0:4]))
+	fraction := int64(r.byteOrder.Uint32(header[4:8]))
+	capturedLength := r.byteOrder.Uint32(header[8:12])
+
+	if capturedLength > maxPacketSize {
+		return Packet{}, fmt.Errorf("packet length %d exceeds maximum packet size", capturedLength)
+	}
+
+	data := make([]byte, capturedLength)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return Packet{}, io.ErrUnexpectedEOF
+	}
+
+	if !r.nanos {
+		fraction *= int64(time.Microsecond)
+	}
+
+	return Packet{Timestamp: time.Unix(seconds, fraction).UTC(), LinkType: r.linkType, Data: data}, nil
+}
+
+// nextPCAPNG reads blocks of a pcapng file until a packet block is found
+func (r *Reader) nextPCAPNG() (Packet, error) {
+	for {
+		blockType, body, err := r.readBlock()
+		if err != nil {
+			return Packet{}, err
+		}
+
+		switch blockType {
+		case pcapngSectionHeader:
+			// a new section may change the byte order and resets the interfaces
+			r.interfaces = nil
+		case pcapngInterfaceDescription:
+			if len(body) < 8 {
+				return Packet{}, errMalformedBlock
+			}
+			iface := pcapngInterface{linkType: uint32(r.byteOrder.Uint16(body[0:2])), tsUnit: time.Microsecond}
+			r.parseInterfaceOptions(&iface, body[8:])
+			r.interfaces = append(r.interfaces, iface)
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				return Packet{}, errMalformedBlock
+			}
+			interfaceID := r.byteOrder.Uint32(body[0:4])
+			if int(interfaceID) >= len(r.interfaces) {
+				return Packet{}, errMalformedBlock
+			}
+			iface := r.interfaces[interfaceID]
+
+			ticks := uint64(r.byteOrder.Uint32(body[4:8]))<<32 | uint64(r.byteOrder.Uint32(body[8:12]))
+			capturedLength := r.byteOrder.Uint32(body[12:16])
+			if int(capturedLength) > len(body)-20 {
+				return Packet{}, errMalformedBlock
+			}
+
+			return Packet{
+				Timestamp: iface.timestamp(ticks),
+				LinkType:  iface.linkType,
+				Data:      body[20 : 20+capturedLength],
+			}, nil
+		case pcapngSimplePacket:
+			// simple packet blocks don't have a timestamp, so they can't be assigned to a flow
+			continue
+		}
+		// all other block types (name resolution, statistics, etc) are skipped
+	}
+}
+
+// readBlock reads a single pcapng block, returning its type and body
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, 8)
+	n, err := io.ReadFull(r.reader, header)
+	if n == 0 && errors.Is(err, io.EOF) {
+		return 0, nil, io.EOF
+	}
+	if err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+
+	// the block type of the section header is the same in either byte order, and the byte order
+	// magic that follows it determines the byte order of the rest of the section
+	blockType := binary.BigEndian.Uint32(header[0:4])
+	if blockType == pcapngSectionHeader {
+		magic, err := r.reader.Peek(4)
+		if err != nil {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == pcapngByteOrderMagic:
+			r.byteOrder = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == pcapngByteOrderMagic:
+			r.byteOrder = binary.BigEndian
+		default:
+			return 0, nil, ErrUnknownCaptureFormat
+		}
+	} else if r.byteOrder == nil {
+		return 0, nil, ErrUnknownCaptureFormat
+	} else {
+		blockType = r.byteOrder.Uint32(header[0:4])
+	}
+
+	totalLength := r.byteOrder.Uint32(header[4:8])
+	if totalLength < 12 || totalLength%4 != 0 || totalLength > maxPacketSize {
+		return 0, nil, errMalformedBlock
+	}
+
+	// the body is followed by a copy of the total length
+	body := make([]byte, totalLength-8)
+	if _, err := io.ReadFull(r.reader, body); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+
+	return blockType, body[:len(body)-4], nil
+}
+
+// parseInterfaceOptions sets the timestamp resolution of an interface from the if_tsresol option
+func (r *Reader) parseInterfaceOptions(iface *pcapngInterface, options []byte) {
+	for len(options) >= 4 {
+		code := r.byteOrder.Uint16(options[0:2])
+		length := int(r.byteOrder.Uint16(options[2:4]))
+		options = options[4:]
+
+		// end of options
+		if code == 0 || length > len(options) {
+			return
+		}
+
+		// if_tsresol: the most significant bit indicates a power of 2 instead of a power of 10
+		if code == 9 && length == 1 {
+			exponent := uint64(options[0] & 0x7f)
+			if options[0]&0x80 != 0 {
+				iface.tsUnit, iface.tsPerSec = 0, uint64(1)<<exponent
+			} else if exponent <= 9 {
+				iface.tsUnit = time.Duration(math.Pow10(9 - int(exponent)))
+			} else {
+				iface.tsUnit, iface.tsPerSec = 0, uint64(math.Pow10(int(exponent)))
+			}
+		}
+
+		// options are padded to 32 bits
+		padded := (length + 3) &^ 3
+		if padded > len(options) {
+			return
+		}
+		options = options[padded:]
+	}
+}
+
+// timestamp converts a number of timestamp ticks into a time using the resolution of the interface
+func (iface pcapngInterface) timestamp(ticks uint64) time.Time {
+	if iface.tsUnit > 0 {
+		return time.Unix(0, 0).Add(time.Duration(ticks) * iface.tsUnit).UTC()
+	}
+	seconds := ticks / iface.tsPerSec
+	remainder := ticks % iface.tsPerSec
+	return time.Unix(int64(seconds), int64(remainder*uint64(time.Second)/iface.tsPerSec)).UTC()
+}
@@ -0,0 +1,250 @@
+package pcap
+
+import (
+	"crypto/md5" //nolint:gosec // ja3 fingerprints are defined as md5 hashes
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const (
+	tlsRecordHandshake   = 22
+	tlsClientHello       = 1
+	tlsServerHello       = 2
+	tlsExtServerName     = 0
+	tlsExtSupportedGroup = 10
+	tlsExtPointFormats   = 11
+	tlsExtSupportedVers  = 43
+)
+
+// handshakeStatus is the result of reading a tls handshake message from a partially reassembled stream
+type handshakeStatus int
+
+const (
+	handshakeIncomplete handshakeStatus = iota
+	handshakeComplete
+	handshakeInvalid
+)
+
+// clientHello holds the fields of a tls ClientHello needed for the ssl log
+type clientHello struct {
+	serverName string
+	ja3        string
+	ja3Hash    string
+}
+
+// serverHello holds the fields of a tls ServerHello needed for the ssl log
+type serverHello struct {
+	version  string
+	ja3s     string
+	ja3sHash string
+}
+
+// isTLSHandshake returns whether a stream starts with a tls handshake record
+func isTLSHandshake(data []byte) bool {
+	return len(data) >= 3 && data[0] == tlsRecordHandshake && data[1] == 3 && data[2] <= 4
+}
+
+// readHandshakeMessage reads the first handshake message from a stream of tls records. The message can be split
+// across multiple records, so the record payloads are joined until the message is complete.
+func readHandshakeMessage(data []byte) (uint8, []byte, handshakeStatus) {
+	var message []byte
+	for {
+		if len(data) < 5 {
+			return 0, nil, handshakeIncomplete
+		}
+		if !isTLSHandshake(data) {
+			return 0, nil, handshakeInvalid
+		}
+		length := int(binary.BigEndian.Uint16(data[3:5]))
+		if len(data) < 5+length {
+			return 0, nil, handshakeIncomplete
+		}
+		message = append(message, data[5:5+length]...)
+		data = data[5+length:]
+
+		if len(message) >= 4 {
+			messageLength := int(message[1])<<16 | int(message[2])<<8 | int(message[3])
+			if len(message) >= 4+messageLength {
+				return message[0], message[4 : 4+messageLength], handshakeComplete
+			}
+		}
+	}
+}
+
+// parseClientHello parses a ClientHello handshake message, computing its ja3 fingerprint
+func parseClientHello(body []byte) (clientHello, bool) {
+	var hello clientHello
+	r := byteReader{data: body}
+
+	version := r.uint16()
+	r.skip(32) // random
+	r.skip(int(r.uint8()))
+	cipherSuites := r.bytes(int(r.uint16()))
+	r.skip(int(r.uint8())) // compression methods
+	if r.err {
+		return hello, false
+	}
+
+	var ciphers []string
+	for i := 0; i+1 < len(cipherSuites); i += 2 {
+		if cipher := binary.BigEndian.Uint16(cipherSuites[i : i+2]); !isGREASE(cipher) {
+			ciphers = append(ciphers, strconv.Itoa(int(cipher)))
+		}
+	}
+
+	var extensions, groups, pointFormats []string
+	if r.remaining() >= 2 {
+		ext := byteReader{data: r.bytes(int(r.uint16()))}
+		for ext.remaining() >= 4 && !ext.err {
+			extType := ext.uint16()
+			extData := byteReader{data: ext.bytes(int(ext.uint16()))}
+			if isGREASE(extType) {
+				continue
+			}
+			extensions = append(extensions, strconv.Itoa(int(extType)))
+
+			switch extType {
+			case tlsExtServerName:
+				hello.serverName = parseServerName(extData)
+			case tlsExtSupportedGroup:
+				list := byteReader{data: extData.bytes(int(extData.uint16()))}
+				for list.remaining() >= 2 {
+					if group := list.uint16(); !isGREASE(group) {
+						groups = append(groups, strconv.Itoa(int(group)))
+					}
+				}
+			case tlsExtPointFormats:
+				for _, format := range extData.bytes(int(extData.uint8())) {
+					pointFormats = append(pointFormats, strconv.Itoa(int(format)))
+				}
+			}
+		}
+	}
+
+	hello.ja3 = strings.Join([]string{
+		strconv.Itoa(int(version)),
+		strings.Join(ciphers, "-"),
+		strings.Join(extensions, "-"),
+		strings.Join(groups, "-"),
+		strings.Join(pointFormats, "-"),
+	}, ",")
+	hello.ja3Hash = md5Hex(hello.ja3)
+
+	return hello, true
+}
+
+// parseServerHello parses a ServerHello handshake message, computing its ja3s fingerprint
+func parseServerHello(body []byte) (serverHello, bool) {
+	var hello serverHello
+	r := byteReader{data: body}
+
+	version := r.uint16()
+	r.skip(32) // random
+	r.skip(int(r.uint8()))
+	cipher := r.uint16()
+	r.skip(1) // compression method
+	if r.err {
+		return hello, false
+	}
+
+	negotiated := version
+	var extensions []string
+	if r.remaining() >= 2 {
+		ext := byteReader{data: r.bytes(int(r.uint16()))}
+		for ext.remaining() >= 4 && !ext.err {
+			extType := ext.uint16()
+			extData := byteReader{data: ext.bytes(int(ext.uint16()))}
+			extensions = append(extensions, strconv.Itoa(int(extType)))
+
+			// tls 1.3 negotiates its version using an extension
+			if extType == tlsExtSupportedVers && extData.remaining() == 2 {
+				negotiated = extData.uint16()
+			}
+		}
+	}
+
+	hello.version = tlsVersionName(negotiated)
+	hello.ja3s = strings.Join([]string{strconv.Itoa(int(version)), strconv.Itoa(int(cipher)), strings.Join(extensions, "-")}, ",")
+	hello.ja3sHash = md5Hex(hello.ja3s)
+
+	return hello, true
+}
+
+// parseServerName returns the first host name of a server name indication extension
+func parseServerName(ext byteReader) string {
+	list := byteReader{data: ext.bytes(int(ext.uint16()))}
+	for list.remaining() >= 3 && !list.err {
+		nameType := list.uint8()
+		name := list.bytes(int(list.uint16()))
+		if nameType == 0 && !list.err {
+			return string(name)
+		}
+	}
+	return ""
+}
+
+// isGREASE returns whether a value is one of the reserved GREASE values (RFC 8701), which are excluded from ja3
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+// tlsVersionName returns the name zeek uses for a tls version
+func tlsVersionName(version uint16) string {
+	switch version {
+	case 0x0300:
+		return "SSLv3"
+	case 0x0301:
+		return "TLSv10"
+	case 0x0302:
+		return "TLSv11"
+	case 0x0303:
+		return "TLSv12"
+	case 0x0304:
+		return "TLSv13"
+	}
+	return "unknown-" + strconv.Itoa(int(version))
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec // ja3 fingerprints are defined as md5 hashes
+	return hex.EncodeToString(sum[:])
+}
+
+// byteReader reads big endian values from a byte slice, recording an error instead of panicking if the data is too short
+type byteReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *byteReader) remaining() int { return len(r.data) }
+
+func (r *byteReader) bytes(n int) []byte {
+	if n > len(r.data) {
+		r.err = true
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *byteReader) skip(n int) { r.bytes(n) }
+
+func (r *byteReader) uint8() uint8 {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *byteReader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
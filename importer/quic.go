@@ -0,0 +1,211 @@
+package importer
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer/zeektypes"
+	"activecm/rita/logger"
+	"activecm/rita/progressbar"
+	"activecm/rita/util"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+type QUICEntry struct {
+	ImportTime        time.Time        `ch:"import_time"`
+	ZeekUID           util.FixedString `ch:"zeek_uid"`
+	Hash              util.FixedString `ch:"hash"`
+	Timestamp         time.Time        `ch:"ts"`
+	Src               net.IP           `ch:"src"`
+	Dst               net.IP           `ch:"dst"`
+	SrcNUID           uuid.UUID        `ch:"src_nuid"`
+	DstNUID           uuid.UUID        `ch:"dst_nuid"`
+	SrcPort           uint16           `ch:"src_port"`
+	DstPort           uint16           `ch:"dst_port"`
+	Duration          float64          `ch:"duration"`
+	SrcLocal          bool             `ch:"src_local"`
+	DstLocal          bool             `ch:"dst_local"`
+	SrcBytes          int64            `ch:"src_bytes"`
+	DstBytes          int64            `ch:"dst_bytes"`
+	SrcIPBytes        int64            `ch:"src_ip_bytes"`
+	DstIPBytes        int64            `ch:"dst_ip_bytes"`
+	SrcPackets        int64            `ch:"src_packets"`
+	DstPackets        int64            `ch:"dst_packets"`
+	Proto             string           `ch:"proto"`
+	Service           string           `ch:"service"`
+	ConnState         string           `ch:"conn_state"`
+	Version           string           `ch:"version"`
+	ClientInitialDCID string           `ch:"client_initial_dcid"`
+	ClientSCID        string           `ch:"client_scid"`
+	ServerSCID        string           `ch:"server_scid"`
+	ServerName        string           `ch:"server_name"`
+	ClientProtocol    string           `ch:"client_protocol"`
+	History           string           `ch:"history"`
+}
+
+// parseQUIC listens on a channel of raw quic log records, formats them and sends them to be linked with conn records and written to the database
+func parseQUIC(quic <-chan zeektypes.QUIC, output chan<- database.Data, importTime time.Time, numQUIC *uint64) {
+	// loop over raw quic channel
+	for q := range quic {
+
+		// parse raw record as a quic entry
+		entry, err := formatQUICRecord(&q, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numQUIC, 1)
+	}
+}
+
+// formatQUICRecord takes a raw quic record and formats it into the structure needed by the database
+func formatQUICRecord(parseQUIC *zeektypes.QUIC, importTime time.Time) (*QUICEntry, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// get source destination pair
+	src := parseQUIC.Source
+	dst := parseQUIC.Destination
+
+	// parse source and destination
+	srcIP := net.ParseIP(src)
+	dstIP := net.ParseIP(dst)
+
+	// verify that both addresses were parsed successfully
+	if (srcIP == nil) || (dstIP == nil) {
+		return nil, errors.New(errParseSrcDst)
+	}
+
+	// get sni
+	sni := parseQUIC.ServerName
+	if sni == "" {
+		return nil, fmt.Errorf("could not parse QUIC connection %s -> %s: %w", src, dst, errServerNameEmpty)
+	}
+
+	ignore := cfg.Filter.FilterDomain(sni) || cfg.Filter.FilterConnPair(srcIP, dstIP) || cfg.Filter.FilterSNIPair(srcIP)
+	if ignore {
+		return nil, nil
+	}
+
+	srcNUID := util.ParseNetworkID(srcIP, parseQUIC.AgentUUID)
+	dstNUID := util.ParseNetworkID(dstIP, parseQUIC.AgentUUID)
+
+	zeekUID, err := util.NewFixedStringHash(parseQUIC.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	// quic connections are tracked by the same hash as ssl connections to the same server name so that
+	// connections to an fqdn over QUIC and over TLS are analyzed together
+	hash, err := util.NewFixedStringHash(srcIP.To16().String(), srcNUID.String(), sni)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &QUICEntry{
+		ImportTime:        importTime,
+		ZeekUID:           zeekUID,
+		Hash:              hash,
+		Timestamp:         time.Unix(int64(parseQUIC.TimeStamp), 0),
+		Src:               srcIP,
+		Dst:               dstIP,
+		SrcNUID:           srcNUID,
+		DstNUID:           dstNUID,
+		SrcPort:           uint16(parseQUIC.SourcePort),
+		DstPort:           uint16(parseQUIC.DestinationPort),
+		SrcLocal:          cfg.Filter.CheckIfInternal(srcIP),
+		DstLocal:          cfg.Filter.CheckIfInternal(dstIP),
+		Version:           parseQUIC.Version,
+		ClientInitialDCID: parseQUIC.ClientInitialDCID,
+		ClientSCID:        parseQUIC.ClientSCID,
+		ServerSCID:        parseQUIC.ServerSCID,
+		ServerName:        sni,
+		ClientProtocol:    parseQUIC.ClientProtocol,
+		History:           parseQUIC.History,
+	}
+
+	return entry, nil
+}
+
+// writeLinkedQUIC links the quic records with their conn records by zeek uid and writes them to the quic table
+func (importer *Importer) writeLinkedQUIC(ctx context.Context, progress *tea.Program, barID int, quicWriter *database.BulkWriter) error {
+	logger := logger.GetLogger()
+
+	var totalQUIC uint64
+	err := importer.Database.Conn.QueryRow(importer.Database.GetContext(), `
+		SELECT count() FROM quic_tmp
+	`).Scan(&totalQUIC)
+	if err != nil {
+		return err
+	}
+
+	// zeek hands the TLS handshake inside of a QUIC connection to the ssl analyzer as well, so connections
+	// that already have an ssl record are skipped to avoid counting them twice
+	rows, err := importer.Database.Conn.Query(importer.Database.GetContext(), `
+	SELECT
+		q.zeek_uid as zeek_uid, q.hash as hash, c.ts AS ts, q.src as src, q.src_nuid as src_nuid, q.dst as dst, q.dst_nuid as dst_nuid,
+		q.src_port as src_port, q.dst_port as dst_port, q.src_local as src_local, q.dst_local as dst_local,
+		q.version as version, q.client_initial_dcid as client_initial_dcid, q.client_scid as client_scid, q.server_scid as server_scid,
+		q.server_name as server_name, q.client_protocol as client_protocol, q.history as history,
+		c.proto as proto, c.service as service,
+		c.src_ip_bytes as src_ip_bytes,
+		c.dst_ip_bytes as dst_ip_bytes,
+		c.src_bytes as src_bytes,
+		c.dst_bytes as dst_bytes,
+		c.duration as duration,
+		c.conn_state as conn_state,
+		c.src_packets as src_packets,
+		c.dst_packets as dst_packets
+	FROM quic_tmp q
+	INNER JOIN conn_tmp c USING zeek_uid
+	WHERE q.zeek_uid NOT IN (SELECT zeek_uid FROM ssl_tmp)
+	`)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for rows.Next() {
+		select {
+		// abort this function if the context was cancelled
+		case <-ctx.Done():
+			logger.Warn().Msg("cancelling QUIC connection linking")
+			rows.Close()
+			return ctx.Err()
+		default:
+			var entry QUICEntry
+
+			if err := rows.ScanStruct(&entry); err != nil {
+				rows.Close()
+				return err
+			}
+			i++
+			if i%1000 == 0 {
+				progress.Send(progressbar.ProgressMsg{ID: barID, Percent: float64(float64(i) / float64(totalQUIC))})
+			}
+			entry.ImportTime = importer.Database.ImportStartedAt
+
+			quicWriter.WriteChannel <- &entry
+		}
+	}
+	rows.Close()
+	progress.Send(progressbar.ProgressMsg{ID: barID, Percent: 1})
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"activecm/rita/database"
+	"activecm/rita/importer/zeektypes"
+	"activecm/rita/util"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	errRecordTypeMissingPrefix     = errors.New("record type must have a prefix")
+	errRecordTypeInvalidTable      = errors.New("record type table name must only contain letters, numbers and underscores")
+	errRecordTypeMissingDDL        = errors.New("record type must have a statement to create its table")
+	errRecordTypeMissingFormat     = errors.New("record type must have a format function")
+	errRecordTypeInvalidStruct     = errors.New("record type must be a struct with a zeektypes.Timestamp TimeStamp field")
+	errRecordTypePrefixConflict    = errors.New("record type prefix conflicts with the prefix of another log type")
+	errRecordTypeInvalidFieldTags  = errors.New("record type fields must have both a zeek and a zeektype tag")
+	errRecordTypeAlreadyRegistered = errors.New("record type is already registered")
+)
+
+// tableNameRegex matches the names that the tables of registered record types can be created with
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// builtinPrefixes holds the prefixes of the log types that are built into the importer
+var builtinPrefixes = []string{
+	ConnPrefix, OpenConnPrefix, DNSPrefix, HTTPPrefix, OpenHTTPPrefix, SSLPrefix, OpenSSLPrefix, X509Prefix, SSHPrefix, QUICPrefix,
+	DHCPPrefix, SMBFilesPrefix, SMBMappingPrefix, DCERPCPrefix, NTLMPrefix, KerberosPrefix, EVEPrefix, ECSPrefix, FlowPrefix, PCAPPrefix,
+}
+
+// RecordType describes a zeek log type that isn't built into RITA, such as the output of a site-specific zeek script.
+// Records of the log are parsed into Z the same way as the types in the zeektypes package, using the zeek and zeektype
+// tags of its fields for TSV logs and the json tags for JSON logs. Z must have a TimeStamp field of type zeektypes.Timestamp,
+// and *Z must have a SetLogPath method that records the path of the log that the record was parsed from.
+type RecordType[Z any] struct {
+	// Prefix is the name that log files of this type start with and the path field in their TSV header (ie, "mysite_beacons")
+	Prefix string
+	// Table is the name of the table in the dataset that the records are written to
+	Table string
+	// CreateTable is the statement that creates the table, which refers to the dataset as {database:Identifier}
+	CreateTable string
+	// TTL is the expression that rolling datasets delete the rows of the table by (ie, "ts + INTERVAL 2 WEEKS"). It defaults
+	// to database.DefaultSensorTableTTL, which requires the table to have an import_time column.
+	TTL string
+	// Format formats a parsed record into the row that is written to the table, using the ch tags of the row's fields.
+	// Records are left out of the import if Format returns an error or a nil row.
+	Format func(record *Z, importTime time.Time) (database.Data, error)
+}
+
+// recordType is the part of a RecordType that the importer needs without knowing its record struct
+type recordType interface {
+	prefix() string
+	table() string
+	newPipeline() recordPipeline
+}
+
+// recordPipeline parses the logs of a registered record type and formats their records for the database
+type recordPipeline interface {
+	// digest parses the log at path, sending its records to be formatted
+	digest(afs afero.Fs, path string, errc chan<- error, rejectedLines chan<- RejectedLine, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString)
+	// parse formats the records of the parsed logs and sends them to be written to the database until close is called
+	parse(output chan<- database.Data, importTime time.Time)
+	// close stops parse once the records of all logs have been formatted
+	close()
+	// count returns the number of records that were formatted
+	count() uint64
+}
+
+var (
+	recordTypesMu sync.RWMutex
+	// recordTypes holds the record types registered with RegisterRecordType, by prefix
+	recordTypes = make(map[string]recordType)
+)
+
+// RegisterRecordType registers a log type so that its logs are imported into its table alongside the built-in log types.
+// Record types must be registered before importing, usually from an init function. Since log files are matched to their
+// type by the start of their name, the prefix can't start with or be the start of the prefix of any other log type.
+func RegisterRecordType[Z any, P zeekRecord[Z]](r RecordType[Z]) error {
+	switch {
+	case r.Prefix == "":
+		return errRecordTypeMissingPrefix
+	case !tableNameRegex.MatchString(r.Table):
+		return fmt.Errorf("%w: %q", errRecordTypeInvalidTable, r.Table)
+	case strings.TrimSpace(r.CreateTable) == "":
+		return errRecordTypeMissingDDL
+	case r.Format == nil:
+		return errRecordTypeMissingFormat
+	}
+
+	if err := validateRecordStruct(reflect.TypeFor[Z]()); err != nil {
+		return fmt.Errorf("%w: %s", err, r.Prefix)
+	}
+
+	recordTypesMu.Lock()
+	defer recordTypesMu.Unlock()
+
+	if _, ok := recordTypes[r.Prefix]; ok {
+		return fmt.Errorf("%w: %s", errRecordTypeAlreadyRegistered, r.Prefix)
+	}
+
+	for _, other := range slices.Concat(builtinPrefixes, registeredPrefixes()) {
+		if strings.HasPrefix(r.Prefix, other) || strings.HasPrefix(other, r.Prefix) {
+			return fmt.Errorf("%w: %s, %s", errRecordTypePrefixConflict, r.Prefix, other)
+		}
+	}
+
+	if err := database.RegisterSensorTable(r.Table, r.CreateTable, r.TTL); err != nil {
+		return err
+	}
+
+	recordTypes[r.Prefix] = registeredRecordType[Z, P]{r}
+	return nil
+}
+
+// RegisteredRecordTypePrefix returns the prefix of the registered record type that the log at path belongs to, if any
+func RegisteredRecordTypePrefix(path string) (string, bool) {
+	recordTypesMu.RLock()
+	defer recordTypesMu.RUnlock()
+
+	for prefix := range recordTypes {
+		if strings.HasPrefix(filepath.Base(path), prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// registeredRecordTypes returns the registered record types sorted by prefix
+func registeredRecordTypes() []recordType {
+	recordTypesMu.RLock()
+	defer recordTypesMu.RUnlock()
+
+	types := make([]recordType, 0, len(recordTypes))
+	for _, prefix := range registeredPrefixes() {
+		types = append(types, recordTypes[prefix])
+	}
+	return types
+}
+
+// registeredPrefixes returns the sorted prefixes of the registered record types, recordTypesMu must be held by the caller
+func registeredPrefixes() []string {
+	prefixes := make([]string, 0, len(recordTypes))
+	for prefix := range recordTypes {
+		prefixes = append(prefixes, prefix)
+	}
+	slices.Sort(prefixes)
+	return prefixes
+}
+
+// validateRecordStruct checks that records can be parsed into the struct type the same way as the built-in zeek types
+func validateRecordStruct(structType reflect.Type) error {
+	if structType.Kind() != reflect.Struct {
+		return errRecordTypeInvalidStruct
+	}
+
+	timestamp, ok := structType.FieldByName("TimeStamp")
+	if !ok || timestamp.Type != reflect.TypeFor[zeektypes.Timestamp]() {
+		return errRecordTypeInvalidStruct
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if (field.Tag.Get("zeek") == "") != (field.Tag.Get("zeektype") == "") {
+			return errRecordTypeInvalidFieldTags
+		}
+	}
+
+	return nil
+}
+
+func (r RecordType[Z]) prefix() string { return r.Prefix }
+
+func (r RecordType[Z]) table() string { return r.Table }
+
+// registeredRecordType is the recordType of a registered RecordType, which keeps the pointer type that its records are
+// parsed through
+type registeredRecordType[Z any, P zeekRecord[Z]] struct {
+	RecordType[Z]
+}
+
+func (r registeredRecordType[Z, P]) newPipeline() recordPipeline {
+	return &recordTypePipeline[Z, P]{recordType: r.RecordType, entries: make(chan Z, 1000)}
+}
+
+// recordTypePipeline is the recordPipeline of a RecordType
+type recordTypePipeline[Z any, P zeekRecord[Z]] struct {
+	recordType RecordType[Z]
+	entries    chan Z
+	numRecords uint64
+}
+
+func (p *recordTypePipeline[Z, P]) digest(afs afero.Fs, path string, errc chan<- error, rejectedLines chan<- RejectedLine, metaDBChan chan<- MetaDBFile, database string, importID util.FixedString) {
+	parseFile[Z, P](afs, path, p.entries, errc, rejectedLines, metaDBChan, database, importID)
+}
+
+func (p *recordTypePipeline[Z, P]) parse(output chan<- database.Data, importTime time.Time) {
+	for entry := range p.entries {
+		row, err := p.recordType.Format(&entry, importTime)
+		if err != nil || row == nil {
+			continue
+		}
+
+		output <- row
+		atomic.AddUint64(&p.numRecords, 1)
+	}
+}
+
+func (p *recordTypePipeline[Z, P]) close() { close(p.entries) }
+
+func (p *recordTypePipeline[Z, P]) count() uint64 { return atomic.LoadUint64(&p.numRecords) }
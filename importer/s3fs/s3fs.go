@@ -0,0 +1,338 @@
+// Package s3fs implements a read-only afero filesystem over a bucket of S3 compatible object storage, so that logs can
+// be walked and imported from object storage through the same code paths as logs on disk
+package s3fs
+
+import (
+	"activecm/rita/config"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/spf13/afero"
+)
+
+var ErrBucketDoesNotExist = errors.New("bucket does not exist")
+var ErrMissingBucketName = errors.New("S3 URL must include a bucket name, ie, s3://bucket/prefix")
+
+// URLScheme is the scheme of the URLs used to import logs from S3, ie, s3://bucket/prefix
+const URLScheme = "s3://"
+
+// errCodeNoSuchKey is the error code returned by S3 when an object doesn't exist
+const errCodeNoSuchKey = "NoSuchKey"
+
+// Fs is a read-only filesystem over the objects of a bucket, where each prefix ending in a slash is a directory.
+// The path of an object is its key with a leading slash, ie, the object 2024-04-19/conn.log.gz is at /2024-04-19/conn.log.gz.
+type Fs struct {
+	ctx    context.Context
+	client *minio.Client
+	bucket string
+	mu     sync.Mutex
+	// etags holds the ETag of each object that was listed or stat'd, so that objects are imported as they were
+	// when they were walked
+	etags map[string]string
+}
+
+// File is an object or a directory of objects in a bucket
+type File struct {
+	fs   *Fs
+	name string
+	info os.FileInfo
+	// object is the contents of the object, which is nil for directories
+	object *minio.Object
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// IsURL returns whether the path is an S3 URL
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, URLScheme)
+}
+
+// ParseURL splits an S3 URL into its bucket and the prefix of the objects to import
+func ParseURL(url string) (string, string, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(url, URLScheme), "/")
+	if bucket == "" {
+		return "", "", ErrMissingBucketName
+	}
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// New connects to the object storage server in the config and returns a filesystem over the bucket
+func New(ctx context.Context, cfg config.S3, bucket string) (*Fs, error) {
+	// empty credentials are used to access public buckets anonymously
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to S3 endpoint %s: %w", cfg.Endpoint, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBucketDoesNotExist, bucket)
+	}
+
+	return &Fs{
+		ctx:    ctx,
+		client: client,
+		bucket: bucket,
+		etags:  make(map[string]string),
+	}, nil
+}
+
+func (fs *Fs) Name() string { return "s3fs" }
+
+func (fs *Fs) Open(name string) (afero.File, error) {
+	info, err := fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{fs: fs, name: name, info: info}
+	if info.IsDir() {
+		return file, nil
+	}
+
+	// fail instead of reading a different version of the object if it was replaced after it was walked
+	key := objectKey(name)
+	var opts minio.GetObjectOptions
+	if etag, ok := fs.etag(key); ok {
+		if err := opts.SetMatchETag(etag); err != nil {
+			return nil, err
+		}
+	}
+
+	file.object, err = fs.client.GetObject(fs.ctx, fs.bucket, key, opts)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return file, nil
+}
+
+func (fs *Fs) OpenFile(name string, flag int, _ os.FileMode) (afero.File, error) {
+	if flag != os.O_RDONLY {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EPERM}
+	}
+	return fs.Open(name)
+}
+
+func (fs *Fs) Stat(name string) (os.FileInfo, error) {
+	key := objectKey(name)
+	if key == "" {
+		return &fileInfo{name: "/", isDir: true}, nil
+	}
+
+	object, err := fs.client.StatObject(fs.ctx, fs.bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		fs.setETag(key, object.ETag)
+		return newFileInfo(object), nil
+	}
+	if minio.ToErrorResponse(err).Code != errCodeNoSuchKey {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	// directories aren't objects, so a key is a directory if there are objects under it
+	ctx, cancel := context.WithCancel(fs.ctx)
+	defer cancel()
+	for object := range fs.client.ListObjects(ctx, fs.bucket, minio.ListObjectsOptions{Prefix: key + "/", MaxKeys: 1}) {
+		if object.Err != nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: object.Err}
+		}
+		return &fileInfo{name: path.Base(key), isDir: true}, nil
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// FileID identifies an object by its URL and its ETag, so that an object that was imported is skipped by later imports
+// unless it is uploaded again with different contents
+func (fs *Fs) FileID(name string) (string, error) {
+	key := objectKey(name)
+
+	etag, ok := fs.etag(key)
+	if !ok {
+		object, err := fs.client.StatObject(fs.ctx, fs.bucket, key, minio.StatObjectOptions{})
+		if err != nil {
+			return "", &os.PathError{Op: "stat", Path: name, Err: err}
+		}
+		etag = object.ETag
+		fs.setETag(key, etag)
+	}
+
+	return fmt.Sprintf("%s%s/%s?etag=%s", URLScheme, fs.bucket, key, etag), nil
+}
+
+func (fs *Fs) Create(string) (afero.File, error) { return nil, syscall.EPERM }
+
+func (fs *Fs) Mkdir(string, os.FileMode) error { return syscall.EPERM }
+
+func (fs *Fs) MkdirAll(string, os.FileMode) error { return syscall.EPERM }
+
+func (fs *Fs) Remove(string) error { return syscall.EPERM }
+
+func (fs *Fs) RemoveAll(string) error { return syscall.EPERM }
+
+func (fs *Fs) Rename(string, string) error { return syscall.EPERM }
+
+func (fs *Fs) Chmod(string, os.FileMode) error { return syscall.EPERM }
+
+func (fs *Fs) Chown(string, int, int) error { return syscall.EPERM }
+
+func (fs *Fs) Chtimes(string, time.Time, time.Time) error { return syscall.EPERM }
+
+func (fs *Fs) etag(key string) (string, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	etag, ok := fs.etags[key]
+	return etag, ok
+}
+
+func (fs *Fs) setETag(key string, etag string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.etags[key] = etag
+}
+
+// objectKey returns the key of the object at the path
+func objectKey(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func newFileInfo(object minio.ObjectInfo) *fileInfo {
+	return &fileInfo{
+		name:    path.Base(object.Key),
+		size:    object.Size,
+		modTime: object.LastModified,
+	}
+}
+
+func (f *File) Close() error {
+	if f.object == nil {
+		return nil
+	}
+	return f.object.Close()
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	if f.object == nil {
+		return 0, syscall.EISDIR
+	}
+	return f.object.Read(p)
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.object == nil {
+		return 0, syscall.EISDIR
+	}
+	return f.object.ReadAt(p, off)
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.object == nil {
+		return 0, syscall.EISDIR
+	}
+	return f.object.Seek(offset, whence)
+}
+
+func (f *File) Write([]byte) (int, error) { return 0, syscall.EPERM }
+
+func (f *File) WriteAt([]byte, int64) (int, error) { return 0, syscall.EPERM }
+
+func (f *File) WriteString(string) (int, error) { return 0, syscall.EPERM }
+
+func (f *File) Truncate(int64) error { return syscall.EPERM }
+
+func (f *File) Sync() error { return nil }
+
+func (f *File) Name() string { return f.name }
+
+func (f *File) Stat() (os.FileInfo, error) { return f.info, nil }
+
+// Readdir lists the objects and directories directly under the directory
+func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	if f.object != nil {
+		return nil, syscall.ENOTDIR
+	}
+
+	prefix := objectKey(f.name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	ctx, cancel := context.WithCancel(f.fs.ctx)
+	defer cancel()
+
+	var infos []os.FileInfo
+	for object := range f.fs.client.ListObjects(ctx, f.fs.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, &os.PathError{Op: "readdir", Path: f.name, Err: object.Err}
+		}
+
+		name := strings.TrimPrefix(object.Key, prefix)
+		switch {
+		// some tools create empty objects to mark directories
+		case name == "":
+			continue
+		// prefixes are listed with a trailing slash
+		case strings.HasSuffix(name, "/"):
+			infos = append(infos, &fileInfo{name: strings.TrimSuffix(name, "/"), isDir: true})
+		default:
+			f.fs.setETag(object.Key, object.ETag)
+			infos = append(infos, newFileInfo(object))
+		}
+
+		if count > 0 && len(infos) >= count {
+			break
+		}
+	}
+
+	return infos, nil
+}
+
+func (f *File) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, nil
+}
+
+func (info *fileInfo) Name() string { return info.name }
+
+func (info *fileInfo) Size() int64 { return info.size }
+
+func (info *fileInfo) Mode() os.FileMode {
+	if info.isDir {
+		return os.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (info *fileInfo) ModTime() time.Time { return info.modTime }
+
+func (info *fileInfo) IsDir() bool { return info.isDir }
+
+func (info *fileInfo) Sys() any { return nil }
@@ -0,0 +1,41 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		expectedBucket string
+		expectedPrefix string
+		expectedErr    error
+	}{
+		{name: "Bucket and Prefix", url: "s3://zeek/sensor1/2024-04-19", expectedBucket: "zeek", expectedPrefix: "sensor1/2024-04-19"},
+		{name: "Trailing Slash", url: "s3://zeek/sensor1/", expectedBucket: "zeek", expectedPrefix: "sensor1"},
+		{name: "Bucket Only", url: "s3://zeek", expectedBucket: "zeek", expectedPrefix: ""},
+		{name: "Bucket Only, Trailing Slash", url: "s3://zeek/", expectedBucket: "zeek", expectedPrefix: ""},
+		{name: "Missing Bucket", url: "s3:///sensor1", expectedErr: ErrMissingBucketName},
+		{name: "Empty URL", url: "s3://", expectedErr: ErrMissingBucketName},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.True(t, IsURL(test.url), "url should be recognized as an S3 URL")
+
+			bucket, prefix, err := ParseURL(test.url)
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr, "error should match expected value")
+				return
+			}
+			require.NoError(t, err, "parsing url should not produce an error")
+			require.Equal(t, test.expectedBucket, bucket, "bucket should match expected value")
+			require.Equal(t, test.expectedPrefix, prefix, "prefix should match expected value")
+		})
+	}
+
+	require.False(t, IsURL("/opt/zeek/logs"), "local path should not be recognized as an S3 URL")
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/importer/zeektypes"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// LogSample summarizes the records read from the start of a log file without importing them
+type LogSample struct {
+	Path string
+	// Records is the number of records that were sampled
+	Records int
+	// Filtered is the number of sampled records that would be left out of the import, by the filter rule that applies to them
+	Filtered map[string]int
+}
+
+// SampleLogFile reads up to limit records from the log at path, validating its header against the log type given by prefix
+// and counting which of the records would be filtered out by each rule in the filter config. Only the connection pair and domain
+// filters of log types whose records are filtered that way are estimated, the rest of the log types are only validated.
+func SampleLogFile(afs afero.Fs, path string, prefix string, filter *config.Filter, limit int) (LogSample, error) {
+	sample := LogSample{Path: path, Filtered: make(map[string]int)}
+
+	// logs that were bucketed into hours by record timestamp only have the records of that hour sampled, see HourPath
+	filePath, hour, hourErr := splitHourPath(path)
+	byHour := hourErr == nil
+	if !byHour {
+		filePath = path
+	}
+
+	scanner, closeFile, err := newLogScanner(afs, path)
+	if err != nil {
+		return sample, err
+	}
+	defer closeFile()
+
+	// only the separator, path and field order of the header are needed to sample the records
+	var header ZeekHeader[zeektypes.Conn]
+	header.fieldMapping = fieldMappingFor(filePath)
+	domainField := sampleDomainField(prefix)
+	tsIndex, srcIndex, dstIndex, domainIndex := -1, -1, -1, -1
+
+	for scanner.Scan() && sample.Records < limit {
+		line := scanner.Bytes()
+		if len(line) < 1 {
+			continue
+		}
+
+		var record struct {
+			TimeStamp   zeektypes.Timestamp `json:"ts"`
+			Source      string              `json:"id.orig_h"`
+			Destination string              `json:"id.resp_h"`
+			Query       string              `json:"query"`
+			Host        string              `json:"host"`
+			ServerName  string              `json:"server_name"`
+		}
+		var domain string
+
+		switch {
+		case line[0] == '#':
+			if header.isJSON {
+				continue
+			}
+			if _, err := header.parseHeader(scanner.Text()); err != nil {
+				return sample, err
+			}
+			continue
+
+		case line[0] == '{' && !header.isTSV:
+			if err := header.fieldMapping.unmarshalJSON(line, &record); err != nil {
+				continue
+			}
+			header.isJSON = true
+			switch domainField {
+			case "query":
+				domain = record.Query
+			case "host":
+				domain = record.Host
+			case "server_name":
+				domain = record.ServerName
+			}
+
+		default:
+			if header.isJSON {
+				continue
+			}
+
+			// check that the header was parsed before the first record and matches the log type
+			if !header.isTSV {
+				if header.separator == "" || len(header.fieldOrder) == 0 {
+					return sample, errUnknownFileType
+				}
+				header.fsPath = filePath
+				if err := header.validatePathPrefix(); err != nil {
+					return sample, err
+				}
+				header.isTSV = true
+				tsIndex = slices.Index(header.fieldOrder, "ts")
+				srcIndex = slices.Index(header.fieldOrder, "id.orig_h")
+				dstIndex = slices.Index(header.fieldOrder, "id.resp_h")
+				domainIndex = slices.Index(header.fieldOrder, domainField)
+			}
+
+			fields := strings.Split(scanner.Text(), header.separator)
+			if tsIndex >= 0 && tsIndex < len(fields) {
+				seconds, err := strconv.ParseFloat(fields[tsIndex], 64)
+				if err == nil {
+					record.TimeStamp = zeektypes.Timestamp(seconds)
+				}
+			}
+			if srcIndex >= 0 && srcIndex < len(fields) {
+				record.Source = fields[srcIndex]
+			}
+			if dstIndex >= 0 && dstIndex < len(fields) {
+				record.Destination = fields[dstIndex]
+			}
+			if domainIndex >= 0 && domainIndex < len(fields) && fields[domainIndex] != header.unsetField {
+				domain = fields[domainIndex]
+			}
+		}
+
+		if byHour && !timestampInHour(record.TimeStamp, hour) {
+			continue
+		}
+
+		sample.Records++
+		if rule := sampleFilterRule(filter, prefix, net.ParseIP(record.Source), net.ParseIP(record.Destination), domain); rule != "" {
+			sample.Filtered[rule]++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return sample, err
+	}
+
+	return sample, nil
+}
+
+// sampleDomainField returns the field that holds the domain that records of the log type are filtered by
+func sampleDomainField(prefix string) string {
+	switch prefix {
+	case DNSPrefix:
+		return "query"
+	case HTTPPrefix, OpenHTTPPrefix:
+		return "host"
+	case SSLPrefix, OpenSSLPrefix, QUICPrefix:
+		return "server_name"
+	}
+	return ""
+}
+
+// sampleFilterRule returns the filter rule that would leave a sampled record out of the import, or an empty string if
+// the record would be imported
+func sampleFilterRule(filter *config.Filter, prefix string, srcIP net.IP, dstIP net.IP, domain string) string {
+	if domain != "" && filter.FilterDomain(domain) {
+		return config.FilterRuleNeverIncludedDomains
+	}
+
+	if srcIP == nil || dstIP == nil {
+		return ""
+	}
+
+	switch prefix {
+	case DNSPrefix:
+		return filter.DNSPairFilterRule(srcIP, dstIP)
+	case ConnPrefix, OpenConnPrefix, HTTPPrefix, OpenHTTPPrefix, SSLPrefix, OpenSSLPrefix, SSHPrefix, QUICPrefix:
+		return filter.ConnPairFilterRule(srcIP, dstIP)
+	}
+	return ""
+}
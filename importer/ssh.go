@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"activecm/rita/config"
+	"activecm/rita/database"
+	"activecm/rita/importer/zeektypes"
+	"activecm/rita/logger"
+	"activecm/rita/progressbar"
+	"activecm/rita/util"
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+type SSHEntry struct {
+	ImportTime           time.Time        `ch:"import_time"`
+	ZeekUID              util.FixedString `ch:"zeek_uid"`
+	Hash                 util.FixedString `ch:"hash"`
+	Timestamp            time.Time        `ch:"ts"`
+	Src                  net.IP           `ch:"src"`
+	Dst                  net.IP           `ch:"dst"`
+	SrcNUID              uuid.UUID        `ch:"src_nuid"`
+	DstNUID              uuid.UUID        `ch:"dst_nuid"`
+	SrcPort              uint16           `ch:"src_port"`
+	DstPort              uint16           `ch:"dst_port"`
+	Duration             float64          `ch:"duration"`
+	SrcLocal             bool             `ch:"src_local"`
+	DstLocal             bool             `ch:"dst_local"`
+	SrcBytes             int64            `ch:"src_bytes"`
+	DstBytes             int64            `ch:"dst_bytes"`
+	SrcIPBytes           int64            `ch:"src_ip_bytes"`
+	DstIPBytes           int64            `ch:"dst_ip_bytes"`
+	SrcPackets           int64            `ch:"src_packets"`
+	DstPackets           int64            `ch:"dst_packets"`
+	Proto                string           `ch:"proto"`
+	Service              string           `ch:"service"`
+	ConnState            string           `ch:"conn_state"`
+	Version              uint8            `ch:"version"`
+	AuthSuccess          bool             `ch:"auth_success"`
+	AuthAttempts         uint32           `ch:"auth_attempts"`
+	Client               string           `ch:"client"`
+	Server               string           `ch:"server"`
+	CipherAlgorithm      string           `ch:"cipher_alg"`
+	MACAlgorithm         string           `ch:"mac_alg"`
+	CompressionAlgorithm string           `ch:"compression_alg"`
+	KexAlgorithm         string           `ch:"kex_alg"`
+	HostKeyAlgorithm     string           `ch:"host_key_alg"`
+	HostKey              string           `ch:"host_key"`
+	HASSH                string           `ch:"hassh"`
+	HASSHServer          string           `ch:"hassh_server"`
+}
+
+// parseSSH listens on a channel of raw ssh log records, formats them and sends them to be linked with conn records and written to the database
+func parseSSH(ssh <-chan zeektypes.SSH, output chan<- database.Data, importTime time.Time, numSSH *uint64) {
+	// loop over raw ssh channel
+	for s := range ssh {
+
+		// parse raw record as an ssh entry
+		entry, err := formatSSHRecord(&s, importTime)
+		if err != nil {
+			continue
+		}
+
+		// entry was subject to filtering
+		if entry == nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numSSH, 1)
+	}
+}
+
+// formatSSHRecord takes a raw ssh record and formats it into the structure needed by the database
+func formatSSHRecord(parseSSH *zeektypes.SSH, importTime time.Time) (*SSHEntry, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// parse source and destination
+	srcIP := net.ParseIP(parseSSH.Source)
+	dstIP := net.ParseIP(parseSSH.Destination)
+
+	// verify that both addresses were parsed successfully
+	if (srcIP == nil) || (dstIP == nil) {
+		return nil, errors.New(errParseSrcDst)
+	}
+
+	if cfg.Filter.FilterConnPair(srcIP, dstIP) {
+		return nil, nil
+	}
+
+	srcNUID := util.ParseNetworkID(srcIP, parseSSH.AgentUUID)
+	dstNUID := util.ParseNetworkID(dstIP, parseSSH.AgentUUID)
+
+	zeekUID, err := util.NewFixedStringHash(parseSSH.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ssh connections are tracked by the same hash as the unique connection between the two hosts so that
+	// the modifiers can be matched up with the connection's threat indicators
+	hash, err := util.NewFixedStringHash(srcIP.To16().String(), srcNUID.String(), dstIP.To16().String(), dstNUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &SSHEntry{
+		ImportTime:           importTime,
+		ZeekUID:              zeekUID,
+		Hash:                 hash,
+		Timestamp:            time.Unix(int64(parseSSH.TimeStamp), 0),
+		Src:                  srcIP,
+		Dst:                  dstIP,
+		SrcNUID:              srcNUID,
+		DstNUID:              dstNUID,
+		SrcPort:              uint16(parseSSH.SourcePort),
+		DstPort:              uint16(parseSSH.DestinationPort),
+		SrcLocal:             cfg.Filter.CheckIfInternal(srcIP),
+		DstLocal:             cfg.Filter.CheckIfInternal(dstIP),
+		Version:              uint8(parseSSH.Version),
+		AuthSuccess:          parseSSH.AuthSuccess,
+		AuthAttempts:         uint32(parseSSH.AuthAttempts),
+		Client:               parseSSH.Client,
+		Server:               parseSSH.Server,
+		CipherAlgorithm:      parseSSH.CipherAlgorithm,
+		MACAlgorithm:         parseSSH.MACAlgorithm,
+		CompressionAlgorithm: parseSSH.CompressionAlgorithm,
+		KexAlgorithm:         parseSSH.KexAlgorithm,
+		HostKeyAlgorithm:     parseSSH.HostKeyAlgorithm,
+		HostKey:              parseSSH.HostKey,
+		HASSH:                parseSSH.HASSH,
+		HASSHServer:          parseSSH.HASSHServer,
+	}
+
+	return entry, nil
+}
+
+// writeLinkedSSH links the ssh records with their conn records by zeek uid and writes them to the ssh table
+func (importer *Importer) writeLinkedSSH(ctx context.Context, progress *tea.Program, barID int, sshWriter *database.BulkWriter) error {
+	logger := logger.GetLogger()
+
+	var totalSSH uint64
+	err := importer.Database.Conn.QueryRow(importer.Database.GetContext(), `
+		SELECT count() FROM ssh_tmp
+	`).Scan(&totalSSH)
+	if err != nil {
+		return err
+	}
+
+	rows, err := importer.Database.Conn.Query(importer.Database.GetContext(), `
+	SELECT
+		s.zeek_uid as zeek_uid, s.hash as hash, c.ts AS ts, s.src as src, s.src_nuid as src_nuid, s.dst as dst, s.dst_nuid as dst_nuid,
+		s.src_port as src_port, s.dst_port as dst_port, s.src_local as src_local, s.dst_local as dst_local,
+		s.version as version, s.auth_success as auth_success, s.auth_attempts as auth_attempts, s.client as client, s.server as server,
+		s.cipher_alg as cipher_alg, s.mac_alg as mac_alg, s.compression_alg as compression_alg, s.kex_alg as kex_alg,
+		s.host_key_alg as host_key_alg, s.host_key as host_key, s.hassh as hassh, s.hassh_server as hassh_server,
+		c.proto as proto, c.service as service,
+		c.src_ip_bytes as src_ip_bytes,
+		c.dst_ip_bytes as dst_ip_bytes,
+		c.src_bytes as src_bytes,
+		c.dst_bytes as dst_bytes,
+		c.duration as duration,
+		c.conn_state as conn_state,
+		c.src_packets as src_packets,
+		c.dst_packets as dst_packets
+	FROM ssh_tmp s
+	INNER JOIN conn_tmp c USING zeek_uid
+	`)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for rows.Next() {
+		select {
+		// abort this function if the context was cancelled
+		case <-ctx.Done():
+			logger.Warn().Msg("cancelling SSH connection linking")
+			rows.Close()
+			return ctx.Err()
+		default:
+			var entry SSHEntry
+
+			if err := rows.ScanStruct(&entry); err != nil {
+				rows.Close()
+				return err
+			}
+			i++
+			if i%1000 == 0 {
+				progress.Send(progressbar.ProgressMsg{ID: barID, Percent: float64(float64(i) / float64(totalSSH))})
+			}
+			entry.ImportTime = importer.Database.ImportStartedAt
+
+			sshWriter.WriteChannel <- &entry
+		}
+	}
+	rows.Close()
+	progress.Send(progressbar.ProgressMsg{ID: barID, Percent: 1})
+
+	return nil
+}
@@ -54,6 +54,8 @@ type SSLEntry struct {
 	Established      bool             `ch:"established"`
 	ServerCertFUIDs  []string         `ch:"server_cert_fuids"`
 	ClientCertFUIDs  []string         `ch:"client_cert_fuids"`
+	ServerCertFPs    []string         `ch:"server_cert_fps"`
+	ClientCertFPs    []string         `ch:"client_cert_fps"`
 	ServerSubject    string           `ch:"server_subject"`
 	ServerIssuer     string           `ch:"server_issuer"`
 	ClientSubject    string           `ch:"client_subject"`
@@ -172,6 +174,8 @@ func formatSSLRecord(parseSSL *zeektypes.SSL, importTime time.Time) (*SSLEntry,
 		Established:      parseSSL.Established,
 		ServerCertFUIDs:  parseSSL.CertChainFuids,
 		ClientCertFUIDs:  parseSSL.ClientCertChainFuids,
+		ServerCertFPs:    parseSSL.CertChainFps,
+		ClientCertFPs:    parseSSL.ClientCertChainFps,
 		ServerSubject:    parseSSL.Subject,
 		ServerIssuer:     parseSSL.Issuer,
 		ClientSubject:    parseSSL.ClientSubject,
@@ -212,7 +216,7 @@ func (importer *Importer) writeLinkedSSL(ctx context.Context, progress *tea.Prog
 		s.zeek_uid as zeek_uid, c.ts AS ts, s.src as src, s.src_nuid as src_nuid, s.dst as dst, s.dst_nuid as dst_nuid,
 		s.src_port as src_port, s.dst_port as dst_port, s.src_local as src_local, s.dst_local as dst_local, server_name as server_name,
 		s.version as version, s.cipher as cipher, s.curve as curve, s.resumed as resumed, s.next_protocol as next_protocol, s.established as established, 
-		s.server_cert_fuids as server_cert_fuids, client_cert_fuids, server_subject, server_issuer, client_subject, client_issuer, validation_status,
+		s.server_cert_fuids as server_cert_fuids, client_cert_fuids, server_cert_fps, client_cert_fps, server_subject, server_issuer, client_subject, client_issuer, validation_status,
 		ja3, ja3s,
 		-- set proto and service regardless of whether it was linked already or not
 		-- since multi-requests can use different dst ports and still have the same UID, so
@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"activecm/rita/database"
+	"activecm/rita/importer/zeektypes"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var errMissingFUID = errors.New("blank or missing id and fingerprint fields in x509 log entry, skipping entry")
+
+// maxDateTime is the latest timestamp that can be stored in a ClickHouse DateTime column
+var maxDateTime = time.Unix(4294967295, 0)
+
+type X509Entry struct {
+	ImportTime              time.Time `ch:"import_time"`
+	Timestamp               time.Time `ch:"ts"`
+	FUID                    string    `ch:"fuid"`
+	Fingerprint             string    `ch:"fingerprint"`
+	Version                 uint8     `ch:"version"`
+	Serial                  string    `ch:"serial"`
+	Subject                 string    `ch:"subject"`
+	Issuer                  string    `ch:"issuer"`
+	NotValidBefore          time.Time `ch:"not_valid_before"`
+	NotValidAfter           time.Time `ch:"not_valid_after"`
+	KeyAlgorithm            string    `ch:"key_alg"`
+	SignatureAlgorithm      string    `ch:"sig_alg"`
+	KeyType                 string    `ch:"key_type"`
+	KeyLength               uint32    `ch:"key_length"`
+	Exponent                string    `ch:"exponent"`
+	Curve                   string    `ch:"curve"`
+	SANDNS                  []string  `ch:"san_dns"`
+	SANURI                  []string  `ch:"san_uri"`
+	SANEmail                []string  `ch:"san_email"`
+	SANIP                   []string  `ch:"san_ip"`
+	BasicConstraintsCA      bool      `ch:"basic_constraints_ca"`
+	BasicConstraintsPathLen uint32    `ch:"basic_constraints_path_len"`
+	HostCert                bool      `ch:"host_cert"`
+	ClientCert              bool      `ch:"client_cert"`
+}
+
+// parseX509 listens on a channel of raw x509 log records, formats them and sends them to be written to the database
+func parseX509(x509 <-chan zeektypes.X509, output chan<- database.Data, importTime time.Time, numX509 *uint64) {
+	// loop over raw x509 channel
+	for x := range x509 {
+
+		// parse raw record as an x509 entry
+		entry, err := formatX509Record(&x, importTime)
+		if err != nil {
+			continue
+		}
+
+		output <- entry
+		// increment record counter
+		atomic.AddUint64(numX509, 1)
+	}
+}
+
+// formatX509Record takes a raw x509 record and formats it into the structure needed by the database
+func formatX509Record(parseX509 *zeektypes.X509, importTime time.Time) (*X509Entry, error) {
+	// certificates are referenced by their file id in the ssl log, but newer versions of zeek
+	// only log the fingerprint, so at least one of them must be set to be able to link this certificate
+	if parseX509.FUID == "" && parseX509.Fingerprint == "" {
+		return nil, errMissingFUID
+	}
+
+	entry := &X509Entry{
+		ImportTime:              importTime,
+		Timestamp:               time.Unix(int64(parseX509.TimeStamp), 0),
+		FUID:                    parseX509.FUID,
+		Fingerprint:             parseX509.Fingerprint,
+		Version:                 uint8(parseX509.Version),
+		Serial:                  parseX509.Serial,
+		Subject:                 parseX509.Subject,
+		Issuer:                  parseX509.Issuer,
+		NotValidBefore:          clampCertificateTime(parseX509.NotValidBefore),
+		NotValidAfter:           clampCertificateTime(parseX509.NotValidAfter),
+		KeyAlgorithm:            parseX509.KeyAlgorithm,
+		SignatureAlgorithm:      parseX509.SignatureAlgorithm,
+		KeyType:                 parseX509.KeyType,
+		KeyLength:               uint32(parseX509.KeyLength),
+		Exponent:                parseX509.Exponent,
+		Curve:                   parseX509.Curve,
+		SANDNS:                  parseX509.SANDNS,
+		SANURI:                  parseX509.SANURI,
+		SANEmail:                parseX509.SANEmail,
+		SANIP:                   parseX509.SANIP,
+		BasicConstraintsCA:      parseX509.BasicConstraintsCA,
+		BasicConstraintsPathLen: uint32(parseX509.BasicConstraintsPathLen),
+		HostCert:                parseX509.HostCert,
+		ClientCert:              parseX509.ClientCert,
+	}
+
+	return entry, nil
+}
+
+// clampCertificateTime converts a certificate validity timestamp into a time that fits in a DateTime column,
+// since certificates (especially self-signed ones) are commonly issued with validity dates far in the future
+func clampCertificateTime(ts zeektypes.Timestamp) time.Time {
+	if ts < 0 {
+		return time.Unix(0, 0)
+	}
+	if int64(ts) > maxDateTime.Unix() {
+		return maxDateTime
+	}
+	return time.Unix(int64(ts), 0)
+}
@@ -0,0 +1,37 @@
+package zeektypes
+
+// EntryTypeDCERPC should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekDCERPC](fs, zeekFile) to read from the file.
+const EntryTypeDCERPC = "dce_rpc"
+
+// DCERPC provides a data structure for entries in the zeek dce_rpc log
+type DCERPC struct {
+	// TimeStamp of this request
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UID is the Unique Id for this connection (generated by zeek)
+	UID string `zeek:"uid" zeektype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `zeek:"id.orig_p" zeektype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `zeek:"id.resp_h" zeektype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `zeek:"id.resp_p" zeektype:"port" json:"id.resp_p"`
+	// RTT is the round trip time from the request to the response
+	RTT float64 `zeek:"rtt" zeektype:"interval" json:"rtt"`
+	// NamedPipe is the remote pipe name
+	NamedPipe string `zeek:"named_pipe" zeektype:"string" json:"named_pipe"`
+	// Endpoint is the name of the remote interface (ie, svcctl, atsvc)
+	Endpoint string `zeek:"endpoint" zeektype:"string" json:"endpoint"`
+	// Operation is the operation seen in the call (ie, CreateServiceW)
+	Operation string `zeek:"operation" zeektype:"string" json:"operation"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (d *DCERPC) SetLogPath(path string) { d.LogPath = path }
@@ -0,0 +1,51 @@
+package zeektypes
+
+// EntryTypeDHCP should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekDHCP](fs, zeekFile) to read from the file.
+const EntryTypeDHCP = "dhcp"
+
+// DHCP provides a data structure for entries in the zeek dhcp log
+type DHCP struct {
+	// TimeStamp of the first message in the DHCP transaction
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UIDs are the unique ids of the connections that carried the DHCP messages of this transaction
+	UIDs []string `zeek:"uids" zeektype:"set[string]" json:"uids"`
+	// ClientAddr is the IP address of the client, which is only set if the client already had an address
+	ClientAddr string `zeek:"client_addr" zeektype:"addr" json:"client_addr"`
+	// ServerAddr is the IP address of the server that handed out the lease
+	ServerAddr string `zeek:"server_addr" zeektype:"addr" json:"server_addr"`
+	// ClientPort is the port the client sent its messages from
+	ClientPort int `zeek:"client_port" zeektype:"port" json:"client_port"`
+	// ServerPort is the port the server sent its messages from
+	ServerPort int `zeek:"server_port" zeektype:"port" json:"server_port"`
+	// MAC is the client's hardware address
+	MAC string `zeek:"mac" zeektype:"string" json:"mac"`
+	// HostName is the name given by the client in the Hostname option
+	HostName string `zeek:"host_name" zeektype:"string" json:"host_name"`
+	// ClientFQDN is the FQDN given by the client in the Client FQDN option
+	ClientFQDN string `zeek:"client_fqdn" zeektype:"string" json:"client_fqdn"`
+	// Domain is the domain given by the server
+	Domain string `zeek:"domain" zeektype:"string" json:"domain"`
+	// RequestedAddr is the IP address requested by the client
+	RequestedAddr string `zeek:"requested_addr" zeektype:"addr" json:"requested_addr"`
+	// AssignedAddr is the IP address assigned to the client by the server
+	AssignedAddr string `zeek:"assigned_addr" zeektype:"addr" json:"assigned_addr"`
+	// LeaseTime is the amount of time that the server gave the client the lease for
+	LeaseTime float64 `zeek:"lease_time" zeektype:"interval" json:"lease_time"`
+	// ClientMessage is the message given by the client when declining or releasing an address
+	ClientMessage string `zeek:"client_message" zeektype:"string" json:"client_message"`
+	// ServerMessage is the message given by the server when rejecting a request
+	ServerMessage string `zeek:"server_message" zeektype:"string" json:"server_message"`
+	// MsgTypes are the types of the DHCP messages seen in this transaction (ie, DISCOVER, OFFER, REQUEST, ACK)
+	MsgTypes []string `zeek:"msg_types" zeektype:"vector[string]" json:"msg_types"`
+	// Duration is the time between the first and last message of this transaction
+	Duration float64 `zeek:"duration" zeektype:"interval" json:"duration"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (d *DHCP) SetLogPath(path string) { d.LogPath = path }
@@ -0,0 +1,352 @@
+package zeektypes
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// EntryTypeECS should be matched against the filename prefix of Elastic Common Schema NDJSON exports
+const EntryTypeECS = "ecs"
+
+// ecsJSON decodes numbers as json numbers, so that large integers aren't rounded when dotted field names are expanded
+var ecsJSON = jsoniter.Config{EscapeHTML: true, SortMapKeys: true, ValidateJsonRawMessage: true, UseNumber: true}.Froze()
+
+// ECSTimestamp is an ECS @timestamp, stored as fractional unix seconds like EVE timestamps
+type ECSTimestamp float64
+
+// ECSKeyword is an ECS keyword field, which may have been indexed from either a JSON string or number
+type ECSKeyword string
+
+// ECS provides a data structure for the fields of a Zeek record that was shipped to Elasticsearch using the
+// Elastic Common Schema (ie, by the Filebeat or Elastic Agent Zeek integrations). Fields may be exported either
+// as nested objects or with dotted names (ie, "source.ip"), both of which are decoded into the same structure.
+type ECS struct {
+	// TimeStamp of this record
+	TimeStamp ECSTimestamp `json:"@timestamp"`
+	// Event describes the log the record came from
+	Event ECSEvent `json:"event"`
+	// Source is the originator of the connection
+	Source ECSEndpoint `json:"source"`
+	// Destination is the responder of the connection
+	Destination ECSEndpoint `json:"destination"`
+	// Network describes the protocols of the connection
+	Network ECSNetwork `json:"network"`
+	// DNS is set for dns records
+	DNS *ECSDNS `json:"dns"`
+	// URL is set for http records
+	URL *ECSURL `json:"url"`
+	// HTTP is set for http records
+	HTTP *ECSHTTP `json:"http"`
+	// UserAgent is set for http records
+	UserAgent *ECSUserAgent `json:"user_agent"`
+	// TLS is set for ssl records
+	TLS *ECSTLS `json:"tls"`
+	// Zeek contains the fields of the original zeek record that have no ECS equivalent
+	Zeek ECSZeek `json:"zeek"`
+	// Observer is the sensor that produced the record
+	Observer ECSHost `json:"observer"`
+	// Host is the host that shipped the record
+	Host ECSHost `json:"host"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (e *ECS) SetLogPath(path string) { e.LogPath = path }
+
+// ECSEvent contains the event fields of an ECS record
+type ECSEvent struct {
+	// Dataset is the name of the log the record came from (ie, zeek.connection, zeek.dns)
+	Dataset string `json:"dataset"`
+	// Module is the name of the integration that shipped the record (ie, zeek)
+	Module string `json:"module"`
+	// ID is the unique id of the event
+	ID ECSKeyword `json:"id"`
+	// Duration is the duration of the event in nanoseconds
+	Duration float64 `json:"duration"`
+}
+
+// ECSEndpoint contains the fields of the source or destination of an ECS record
+type ECSEndpoint struct {
+	// IP is the address of the endpoint
+	IP string `json:"ip"`
+	// Port is the port of the endpoint
+	Port int `json:"port"`
+	// Bytes is the number of bytes sent by the endpoint
+	Bytes int64 `json:"bytes"`
+	// Packets is the number of packets sent by the endpoint
+	Packets int64 `json:"packets"`
+}
+
+// ECSNetwork contains the network fields of an ECS record
+type ECSNetwork struct {
+	// Transport is the transport protocol of the connection (ie, tcp, udp, icmp)
+	Transport string `json:"transport"`
+	// Protocol is the application layer protocol of the connection (ie, dns, http, ssl)
+	Protocol string `json:"protocol"`
+}
+
+// ECSDNS contains the dns fields of an ECS record
+type ECSDNS struct {
+	// ID is the dns transaction id
+	ID ECSKeyword `json:"id"`
+	// Question is the question of the message
+	Question ECSDNSQuestion `json:"question"`
+	// ResponseCode is the name of the response code (ie, NOERROR, NXDOMAIN)
+	ResponseCode string `json:"response_code"`
+	// HeaderFlags are the names of the header flags that were set (ie, AA, RD)
+	HeaderFlags []string `json:"header_flags"`
+	// Answers contains the answers of the message
+	Answers []ECSDNSAnswer `json:"answers"`
+	// ResolvedIP contains the addresses found in the answers
+	ResolvedIP []string `json:"resolved_ip"`
+}
+
+// ECSDNSQuestion contains the question of a dns record
+type ECSDNSQuestion struct {
+	// Name is the name being queried
+	Name string `json:"name"`
+	// Type is the name of the type of record being queried
+	Type string `json:"type"`
+	// Class is the name of the class of record being queried
+	Class string `json:"class"`
+}
+
+// ECSDNSAnswer contains a single answer of a dns record
+type ECSDNSAnswer struct {
+	// Name is the name of the answer record
+	Name string `json:"name"`
+	// Type is the type of the answer record
+	Type string `json:"type"`
+	// TTL is the time to live of the answer record
+	TTL float64 `json:"ttl"`
+	// Data is the data of the answer record
+	Data string `json:"data"`
+}
+
+// ECSURL contains the url fields of an http record
+type ECSURL struct {
+	// Domain is the value of the host header
+	Domain string `json:"domain"`
+	// Original is the uri used in the request
+	Original string `json:"original"`
+}
+
+// ECSHTTP contains the http fields of an http record
+type ECSHTTP struct {
+	// Version is the http version of the request (ie, 1.1)
+	Version string `json:"version"`
+	// Request contains the fields of the request
+	Request ECSHTTPRequest `json:"request"`
+	// Response contains the fields of the response
+	Response ECSHTTPResponse `json:"response"`
+}
+
+// ECSHTTPRequest contains the request fields of an http record
+type ECSHTTPRequest struct {
+	// Method is the request method used
+	Method string `json:"method"`
+	// Referrer is the value of the referer header
+	Referrer string `json:"referrer"`
+	// Body contains the length of the request body
+	Body ECSHTTPBody `json:"body"`
+}
+
+// ECSHTTPResponse contains the response fields of an http record
+type ECSHTTPResponse struct {
+	// StatusCode is the status code of the response
+	StatusCode int64 `json:"status_code"`
+	// MimeType is the mime type of the response body
+	MimeType string `json:"mime_type"`
+	// Body contains the length of the response body
+	Body ECSHTTPBody `json:"body"`
+}
+
+// ECSHTTPBody contains the length of an http request or response body
+type ECSHTTPBody struct {
+	Bytes int64 `json:"bytes"`
+}
+
+// ECSUserAgent contains the user agent of an http record
+type ECSUserAgent struct {
+	Original string `json:"original"`
+}
+
+// ECSTLS contains the tls fields of an ssl record
+type ECSTLS struct {
+	// Version is the negotiated version of the session (ie, 1.2)
+	Version string `json:"version"`
+	// VersionProtocol is the protocol of the negotiated version (ie, tls)
+	VersionProtocol string `json:"version_protocol"`
+	// Cipher is the cipher that was chosen by the server
+	Cipher string `json:"cipher"`
+	// Established indicates that the handshake was completed
+	Established bool `json:"established"`
+	// Resumed indicates that the session was resumed
+	Resumed bool `json:"resumed"`
+	// Client contains the fields of the client
+	Client ECSTLSClient `json:"client"`
+	// Server contains the fields of the server
+	Server ECSTLSServer `json:"server"`
+}
+
+// ECSTLSClient contains the client fields of an ssl record
+type ECSTLSClient struct {
+	// ServerName is the server name indication sent by the client
+	ServerName string `json:"server_name"`
+	// JA3 is the client fingerprint
+	JA3 string `json:"ja3"`
+}
+
+// ECSTLSServer contains the server fields of an ssl record
+type ECSTLSServer struct {
+	// Subject is the subject of the server certificate
+	Subject string `json:"subject"`
+	// Issuer is the issuer of the server certificate
+	Issuer string `json:"issuer"`
+	// JA3S is the server fingerprint
+	JA3S string `json:"ja3s"`
+}
+
+// ECSZeek contains the zeek specific fields of an ECS record
+type ECSZeek struct {
+	// SessionID is the uid of the zeek record
+	SessionID string `json:"session_id"`
+	// Connection contains the conn fields that have no ECS equivalent
+	Connection ECSZeekConnection `json:"connection"`
+}
+
+// ECSZeekConnection contains the conn fields of an ECS record that have no ECS equivalent
+type ECSZeekConnection struct {
+	// State is the zeek conn_state of the connection
+	State string `json:"state"`
+	// History is the zeek history of the connection
+	History string `json:"history"`
+	// LocalOrig indicates that the connection originated locally
+	LocalOrig bool `json:"local_orig"`
+	// LocalResp indicates that the connection was responded to locally
+	LocalResp bool `json:"local_resp"`
+	// MissedBytes is the number of bytes missed in content gaps
+	MissedBytes int64 `json:"missed_bytes"`
+	// OrigIPBytes is the number of ip level bytes sent by the originator
+	OrigIPBytes int64 `json:"orig_ip_bytes"`
+	// RespIPBytes is the number of ip level bytes sent by the responder
+	RespIPBytes int64 `json:"resp_ip_bytes"`
+	// ICMP contains the icmp type and code of icmp connections
+	ICMP ECSZeekICMP `json:"icmp"`
+}
+
+// ECSZeekICMP contains the icmp type and code of an icmp connection
+type ECSZeekICMP struct {
+	Type int `json:"type"`
+	Code int `json:"code"`
+}
+
+// ECSHost contains the name of a host
+type ECSHost struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+}
+
+// UnmarshalJSON unmarshals ECS records, expanding any dotted field names into nested objects
+func (e *ECS) UnmarshalJSON(data []byte) error {
+	var fields map[string]interface{}
+	if err := ecsJSON.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	expanded, err := ecsJSON.Marshal(expandECSFields(fields))
+	if err != nil {
+		return err
+	}
+
+	// decode through an alias type so that this method isn't called again
+	type ecs ECS
+	return ecsJSON.Unmarshal(expanded, (*ecs)(e))
+}
+
+// expandECSFields converts the dotted field names of an object into nested objects (ie, {"source.ip": ...} into
+// {"source": {"ip": ...}}), merging them with any nested objects that were already present
+func expandECSFields(fields map[string]interface{}) map[string]interface{} {
+	expanded := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if object, ok := value.(map[string]interface{}); ok {
+			value = expandECSFields(object)
+		}
+
+		parts := strings.Split(name, ".")
+		parent := expanded
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := parent[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				parent[part] = child
+			}
+			parent = child
+		}
+
+		last := parts[len(parts)-1]
+		existing, isObject := parent[last].(map[string]interface{})
+		object, valueIsObject := value.(map[string]interface{})
+		if isObject && valueIsObject {
+			for k, v := range object {
+				existing[k] = v
+			}
+			continue
+		}
+		parent[last] = value
+	}
+	return expanded
+}
+
+// UnmarshalJSON unmarshals ECS timestamps
+func (ts *ECSTimestamp) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &str); err != nil {
+		// timestamps are sometimes exported as epoch milliseconds
+		var millis float64
+		if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &millis); err != nil {
+			return ErrInvalidZeekTimestamp
+		}
+		*ts = ECSTimestamp(millis / 1000)
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(str))
+	if err != nil {
+		return ErrInvalidZeekTimestamp
+	}
+
+	*ts = ECSTimestamp(float64(t.UnixNano()) / float64(time.Second))
+	return nil
+}
+
+// Unix returns the timestamp in whole unix seconds
+func (ts ECSTimestamp) Unix() Timestamp { return Timestamp(ts) }
+
+// UnmarshalJSON unmarshals ECS keywords from either strings or numbers
+func (k *ECSKeyword) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &str); err == nil {
+		*k = ECSKeyword(str)
+		return nil
+	}
+
+	var num jsoniter.Number
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	*k = ECSKeyword(num.String())
+	return nil
+}
+
+// Int returns the keyword as an integer, or 0 if it isn't one
+func (k ECSKeyword) Int() int64 {
+	n, err := strconv.ParseInt(string(k), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
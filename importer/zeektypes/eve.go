@@ -0,0 +1,215 @@
+package zeektypes
+
+import (
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// EntryTypeEVE should be matched against the filename prefix of Suricata EVE JSON logs
+const EntryTypeEVE = "eve"
+
+// EVETimeFmt is the timestamp format used by Suricata in EVE JSON logs
+const EVETimeFmt = "2006-01-02T15:04:05.999999-0700"
+
+// EVETimestamp is a Suricata EVE timestamp, stored as fractional unix seconds so that flow durations can be computed
+type EVETimestamp float64
+
+// EVE provides a data structure for the fields shared by every event in a Suricata EVE JSON log, along
+// with the event specific fields of the event types that map onto zeek logs
+type EVE struct {
+	// TimeStamp of this event
+	TimeStamp EVETimestamp `json:"timestamp"`
+	// FlowID is the id of the flow this event belongs to, shared by all events in a flow (similar to a zeek uid)
+	FlowID uint64 `json:"flow_id"`
+	// EventType is the type of this event (ie, flow, dns, http, tls, alert, etc)
+	EventType string `json:"event_type"`
+	// Source is the source address of the flow
+	Source string `json:"src_ip"`
+	// SourcePort is the source port of the flow
+	SourcePort int `json:"src_port"`
+	// Destination is the destination address of the flow
+	Destination string `json:"dest_ip"`
+	// DestinationPort is the destination port of the flow
+	DestinationPort int `json:"dest_port"`
+	// Proto is the transport protocol of the flow (ie, TCP, UDP, ICMP)
+	Proto string `json:"proto"`
+	// AppProto is the application layer protocol detected for the flow
+	AppProto string `json:"app_proto"`
+	// ICMPType is the icmp type of the flow, if it is an icmp flow
+	ICMPType int `json:"icmp_type"`
+	// ICMPCode is the icmp code of the flow, if it is an icmp flow
+	ICMPCode int `json:"icmp_code"`
+	// Host is the sensor name configured in suricata.yaml
+	Host string `json:"host"`
+	// Flow is set for flow events
+	Flow *EVEFlow `json:"flow"`
+	// TCP is set for flow events of tcp flows
+	TCP *EVETCP `json:"tcp"`
+	// DNS is set for dns events
+	DNS *EVEDNS `json:"dns"`
+	// HTTP is set for http events
+	HTTP *EVEHTTP `json:"http"`
+	// TLS is set for tls events
+	TLS *EVETLS `json:"tls"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (e *EVE) SetLogPath(path string) { e.LogPath = path }
+
+// EVEFlow contains the flow specific fields of a flow event
+type EVEFlow struct {
+	// PktsToServer is the number of packets sent by the client
+	PktsToServer int64 `json:"pkts_toserver"`
+	// PktsToClient is the number of packets sent by the server
+	PktsToClient int64 `json:"pkts_toclient"`
+	// BytesToServer is the number of bytes (including headers) sent by the client
+	BytesToServer int64 `json:"bytes_toserver"`
+	// BytesToClient is the number of bytes (including headers) sent by the server
+	BytesToClient int64 `json:"bytes_toclient"`
+	// Start is the timestamp of the first packet of the flow
+	Start EVETimestamp `json:"start"`
+	// End is the timestamp of the last packet of the flow
+	End EVETimestamp `json:"end"`
+	// State is the state of the flow when it was logged (ie, new, established, closed)
+	State string `json:"state"`
+	// Reason is the reason the flow was logged (ie, timeout, shutdown)
+	Reason string `json:"reason"`
+}
+
+// EVETCP contains the tcp specific fields of a flow event
+type EVETCP struct {
+	// TCPFlags is the hex representation of all tcp flags seen in the flow
+	TCPFlags string `json:"tcp_flags"`
+	// SYN indicates that a syn was seen
+	SYN bool `json:"syn"`
+	// FIN indicates that a fin was seen
+	FIN bool `json:"fin"`
+	// RST indicates that a rst was seen
+	RST bool `json:"rst"`
+	// ACK indicates that an ack was seen
+	ACK bool `json:"ack"`
+	// State is the state of the tcp session when the flow was logged
+	State string `json:"state"`
+}
+
+// EVEDNS contains the dns specific fields of a dns event, in both the version 2 and version 3 formats
+type EVEDNS struct {
+	// Version is the version of the dns logging format
+	Version int `json:"version"`
+	// Type is the type of dns event (query/answer for version 2, request/response for version 3)
+	Type string `json:"type"`
+	// ID is the dns transaction id
+	ID int64 `json:"id"`
+	// RRName is the name being queried (version 2)
+	RRName string `json:"rrname"`
+	// RRType is the type of record being queried (version 2)
+	RRType string `json:"rrtype"`
+	// RCode is the name of the response code
+	RCode string `json:"rcode"`
+	// Flags is the hex representation of the dns header flags
+	Flags string `json:"flags"`
+	// AA indicates that the response is authoritative
+	AA bool `json:"aa"`
+	// TC indicates that the response was truncated
+	TC bool `json:"tc"`
+	// RD indicates that recursion was desired
+	RD bool `json:"rd"`
+	// RA indicates that recursion was available
+	RA bool `json:"ra"`
+	// Queries contains the questions of the message (version 3)
+	Queries []EVEDNSQuery `json:"queries"`
+	// Answers contains the answers of the message when logged in the detailed format
+	Answers []EVEDNSAnswer `json:"answers"`
+	// Grouped contains the answer data grouped by record type when logged in the grouped format
+	Grouped map[string][]interface{} `json:"grouped"`
+}
+
+// EVEDNSQuery contains a single question of a dns event
+type EVEDNSQuery struct {
+	// RRName is the name being queried
+	RRName string `json:"rrname"`
+	// RRType is the type of record being queried
+	RRType string `json:"rrtype"`
+}
+
+// EVEDNSAnswer contains a single answer of a dns event
+type EVEDNSAnswer struct {
+	// RRName is the name of the answer record
+	RRName string `json:"rrname"`
+	// RRType is the type of the answer record
+	RRType string `json:"rrtype"`
+	// TTL is the time to live of the answer record
+	TTL int64 `json:"ttl"`
+	// RData is the data of the answer record
+	RData string `json:"rdata"`
+}
+
+// EVEHTTP contains the http specific fields of an http event
+type EVEHTTP struct {
+	// Hostname is the value of the host header
+	Hostname string `json:"hostname"`
+	// URL is the uri used in the request
+	URL string `json:"url"`
+	// UserAgent is the value of the user agent header
+	UserAgent string `json:"http_user_agent"`
+	// ContentType is the value of the content type header of the response
+	ContentType string `json:"http_content_type"`
+	// Referrer is the value of the referer header
+	Referrer string `json:"http_refer"`
+	// Method is the request method used
+	Method string `json:"http_method"`
+	// Protocol is the http version of the request (ie, HTTP/1.1)
+	Protocol string `json:"protocol"`
+	// Status is the status code of the response
+	Status int64 `json:"status"`
+	// Length is the length of the response body
+	Length int64 `json:"length"`
+}
+
+// EVETLS contains the tls specific fields of a tls event
+type EVETLS struct {
+	// Subject is the subject of the server certificate
+	Subject string `json:"subject"`
+	// Issuer is the issuer of the server certificate
+	Issuer string `json:"issuerdn"`
+	// Serial is the serial number of the server certificate
+	Serial string `json:"serial"`
+	// Fingerprint is the sha1 fingerprint of the server certificate
+	Fingerprint string `json:"fingerprint"`
+	// SNI is the server name indication sent by the client
+	SNI string `json:"sni"`
+	// Version is the negotiated version of the session (ie, TLS 1.2)
+	Version string `json:"version"`
+	// JA3 is the client fingerprint, only set if ja3 is enabled
+	JA3 *EVEJA3 `json:"ja3"`
+	// JA3S is the server fingerprint, only set if ja3 is enabled
+	JA3S *EVEJA3 `json:"ja3s"`
+}
+
+// EVEJA3 contains a ja3/ja3s hash and the string it was computed from
+type EVEJA3 struct {
+	Hash   string `json:"hash"`
+	String string `json:"string"`
+}
+
+// UnmarshalJSON unmarshals EVE timestamps
+func (ts *EVETimestamp) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	t, err := time.Parse(EVETimeFmt, strings.TrimSpace(str))
+	if err != nil {
+		return ErrInvalidZeekTimestamp
+	}
+
+	*ts = EVETimestamp(float64(t.UnixNano()) / float64(time.Second))
+	return nil
+}
+
+// Unix returns the timestamp in whole unix seconds
+func (ts EVETimestamp) Unix() Timestamp { return Timestamp(ts) }
@@ -0,0 +1,49 @@
+package zeektypes
+
+// EntryTypeKerberos should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekKerberos](fs, zeekFile) to read from the file.
+const EntryTypeKerberos = "kerberos"
+
+// Kerberos provides a data structure for entries in the zeek kerberos log
+type Kerberos struct {
+	// TimeStamp of this request
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UID is the Unique Id for this connection (generated by zeek)
+	UID string `zeek:"uid" zeektype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `zeek:"id.orig_p" zeektype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `zeek:"id.resp_h" zeektype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `zeek:"id.resp_p" zeektype:"port" json:"id.resp_p"`
+	// RequestType is the type of request (AS for ticket granting tickets, TGS for service tickets)
+	RequestType string `zeek:"request_type" zeektype:"string" json:"request_type"`
+	// Client is the client principal
+	Client string `zeek:"client" zeektype:"string" json:"client"`
+	// Service is the service principal that a ticket was requested for
+	Service string `zeek:"service" zeektype:"string" json:"service"`
+	// Success indicates if the request was successful
+	Success bool `zeek:"success" zeektype:"bool" json:"success"`
+	// ErrorMsg is the error message returned by the KDC if the request failed
+	ErrorMsg string `zeek:"error_msg" zeektype:"string" json:"error_msg"`
+	// From is the timestamp that the ticket is valid from
+	From Timestamp `zeek:"from" zeektype:"time" json:"from"`
+	// Till is the timestamp that the ticket is valid until
+	Till Timestamp `zeek:"till" zeektype:"time" json:"till"`
+	// Cipher is the encryption type of the ticket
+	Cipher string `zeek:"cipher" zeektype:"string" json:"cipher"`
+	// Forwardable indicates if the ticket can be forwarded
+	Forwardable bool `zeek:"forwardable" zeektype:"bool" json:"forwardable"`
+	// Renewable indicates if the ticket can be renewed
+	Renewable bool `zeek:"renewable" zeektype:"bool" json:"renewable"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (k *Kerberos) SetLogPath(path string) { k.LogPath = path }
@@ -0,0 +1,43 @@
+package zeektypes
+
+// EntryTypeQUIC should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekQUIC](fs, zeekFile) to read from the file.
+const EntryTypeQUIC = "quic"
+
+// QUIC provides a data structure for entries in the zeek quic log
+type QUIC struct {
+	// TimeStamp of the first QUIC packet of this connection
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UID is the Unique Id for this connection (generated by zeek)
+	UID string `zeek:"uid" zeektype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `zeek:"id.orig_p" zeektype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `zeek:"id.resp_h" zeektype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `zeek:"id.resp_p" zeektype:"port" json:"id.resp_p"`
+	// Version is the QUIC version of the connection (ie, 1, draft-29 or the hex value of an unknown version)
+	Version string `zeek:"version" zeektype:"string" json:"version"`
+	// ClientInitialDCID is the first destination connection ID chosen by the client
+	ClientInitialDCID string `zeek:"client_initial_dcid" zeektype:"string" json:"client_initial_dcid"`
+	// ClientSCID is the source connection ID chosen by the client
+	ClientSCID string `zeek:"client_scid" zeektype:"string" json:"client_scid"`
+	// ServerSCID is the source connection ID chosen by the server
+	ServerSCID string `zeek:"server_scid" zeektype:"string" json:"server_scid"`
+	// ServerName is the value of the Server Name Indicator (SNI) in the client's TLS ClientHello
+	ServerName string `zeek:"server_name" zeektype:"string" json:"server_name"`
+	// ClientProtocol is the first protocol offered by the client in the ALPN extension (ie, h3)
+	ClientProtocol string `zeek:"client_protocol" zeektype:"string" json:"client_protocol"`
+	// History is the sequence of QUIC packet types seen in this connection
+	History string `zeek:"history" zeektype:"string" json:"history"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (q *QUIC) SetLogPath(path string) { q.LogPath = path }
@@ -0,0 +1,41 @@
+package zeektypes
+
+// EntryTypeSMBFiles should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekSMBFiles](fs, zeekFile) to read from the file.
+const EntryTypeSMBFiles = "smb_files"
+
+// SMBFiles provides a data structure for entries in the zeek smb_files log
+type SMBFiles struct {
+	// TimeStamp of this file action
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UID is the Unique Id for this connection (generated by zeek)
+	UID string `zeek:"uid" zeektype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `zeek:"id.orig_p" zeektype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `zeek:"id.resp_h" zeektype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `zeek:"id.resp_p" zeektype:"port" json:"id.resp_p"`
+	// FUID is the unique id of the file
+	FUID string `zeek:"fuid" zeektype:"string" json:"fuid"`
+	// Action is the action taken on the file (ie, SMB::FILE_OPEN, SMB::FILE_WRITE)
+	Action string `zeek:"action" zeektype:"enum" json:"action"`
+	// Path is the share path that the file was accessed through
+	Path string `zeek:"path" zeektype:"string" json:"path"`
+	// Name is the filename
+	Name string `zeek:"name" zeektype:"string" json:"name"`
+	// Size is the total size of the file
+	Size int64 `zeek:"size" zeektype:"count" json:"size"`
+	// PrevName is the previous name of the file if it was renamed
+	PrevName string `zeek:"prev_name" zeektype:"string" json:"prev_name"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (s *SMBFiles) SetLogPath(path string) { s.LogPath = path }
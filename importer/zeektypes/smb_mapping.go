@@ -0,0 +1,37 @@
+package zeektypes
+
+// EntryTypeSMBMapping should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekSMBMapping](fs, zeekFile) to read from the file.
+const EntryTypeSMBMapping = "smb_mapping"
+
+// SMBMapping provides a data structure for entries in the zeek smb_mapping log
+type SMBMapping struct {
+	// TimeStamp of this tree connect
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UID is the Unique Id for this connection (generated by zeek)
+	UID string `zeek:"uid" zeektype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `zeek:"id.orig_p" zeektype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `zeek:"id.resp_h" zeektype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `zeek:"id.resp_p" zeektype:"port" json:"id.resp_p"`
+	// Path is the name of the tree that was mapped (ie, \\HOST\ADMIN$)
+	Path string `zeek:"path" zeektype:"string" json:"path"`
+	// Service is the type of resource that was mapped
+	Service string `zeek:"service" zeektype:"string" json:"service"`
+	// NativeFileSystem is the file system of the tree
+	NativeFileSystem string `zeek:"native_file_system" zeektype:"string" json:"native_file_system"`
+	// ShareType is the type of share (DISK, PIPE, PRINT)
+	ShareType string `zeek:"share_type" zeektype:"string" json:"share_type"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (s *SMBMapping) SetLogPath(path string) { s.LogPath = path }
@@ -0,0 +1,58 @@
+package zeektypes
+
+// EntryTypeSSH should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekSSH](fs, zeekFile) to read from the file.
+const EntryTypeSSH = "ssh"
+
+// SSH provides a data structure for entries in the zeek ssh log
+type SSH struct {
+	// TimeStamp of this connection
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// UID is the Unique Id for this connection (generated by zeek)
+	UID string `zeek:"uid" zeektype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `zeek:"id.orig_h" zeektype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `zeek:"id.orig_p" zeektype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `zeek:"id.resp_h" zeektype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `zeek:"id.resp_p" zeektype:"port" json:"id.resp_p"`
+	// Version is the SSH major version (1 or 2)
+	Version int64 `zeek:"version" zeektype:"count" json:"version"`
+	// AuthSuccess indicates if the client was successfully authenticated. Zeek infers this from the size of the
+	// packets exchanged after the key exchange, so it may be unset if the result couldn't be determined.
+	AuthSuccess bool `zeek:"auth_success" zeektype:"bool" json:"auth_success"`
+	// AuthAttempts is the number of authentication attempts that were seen
+	AuthAttempts int64 `zeek:"auth_attempts" zeektype:"count" json:"auth_attempts"`
+	// Direction is the direction of the connection (INBOUND or OUTBOUND) if the local networks were configured in zeek
+	Direction string `zeek:"direction" zeektype:"enum" json:"direction"`
+	// Client is the version string of the client
+	Client string `zeek:"client" zeektype:"string" json:"client"`
+	// Server is the version string of the server
+	Server string `zeek:"server" zeektype:"string" json:"server"`
+	// CipherAlgorithm is the encryption algorithm in use
+	CipherAlgorithm string `zeek:"cipher_alg" zeektype:"string" json:"cipher_alg"`
+	// MACAlgorithm is the signing (MAC) algorithm in use
+	MACAlgorithm string `zeek:"mac_alg" zeektype:"string" json:"mac_alg"`
+	// CompressionAlgorithm is the compression algorithm in use
+	CompressionAlgorithm string `zeek:"compression_alg" zeektype:"string" json:"compression_alg"`
+	// KexAlgorithm is the key exchange algorithm in use
+	KexAlgorithm string `zeek:"kex_alg" zeektype:"string" json:"kex_alg"`
+	// HostKeyAlgorithm is the server host key's algorithm
+	HostKeyAlgorithm string `zeek:"host_key_alg" zeektype:"string" json:"host_key_alg"`
+	// HostKey is the server's key fingerprint
+	HostKey string `zeek:"host_key" zeektype:"string" json:"host_key"`
+	// HASSH is the fingerprint of the client's offered algorithms. Only set if the hassh package is loaded in zeek.
+	HASSH string `zeek:"hassh" zeektype:"string" json:"hassh"`
+	// HASSHServer is the fingerprint of the server's offered algorithms. Only set if the hassh package is loaded in zeek.
+	HASSHServer string `zeek:"hasshServer" zeektype:"string" json:"hasshServer"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (s *SSH) SetLogPath(path string) { s.LogPath = path }
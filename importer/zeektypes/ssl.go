@@ -72,6 +72,11 @@ type SSL struct {
 	CertChainFuids []string `zeek:"cert_chain_fuids" zeektype:"vector[string]" json:"cert_chain_fuids"`
 	// ClientCertChainFuids
 	ClientCertChainFuids []string `zeek:"client_cert_chain_fuids" zeektype:"vector[string]" json:"client_cert_chain_fuids"`
+	// CertChainFps : Fingerprints of the certificates offered by the server, which replace
+	// cert_chain_fuids in zeek 5 and later
+	CertChainFps []string `zeek:"cert_chain_fps" zeektype:"vector[string]" json:"cert_chain_fps"`
+	// ClientCertChainFps : Fingerprints of the certificates offered by the client
+	ClientCertChainFps []string `zeek:"client_cert_chain_fps" zeektype:"vector[string]" json:"client_cert_chain_fps"`
 	// Subject
 	Subject string `zeek:"subject" zeektype:"string" json:"subject"`
 	// Issuer
@@ -0,0 +1,63 @@
+package zeektypes
+
+// EntryTypeX509 should be matched against zeekFile.EntryType()
+// before using OpenZeekReader[ZeekX509](fs, zeekFile) to read from the file.
+const EntryTypeX509 = "x509"
+
+// X509 provides a data structure for entries in the zeek x509 log
+type X509 struct {
+	// TimeStamp of when the certificate was seen
+	TimeStamp Timestamp `zeek:"ts" zeektype:"time" json:"ts"`
+	// FUID is the file id of this certificate, referenced by the cert_chain_fuids field in the ssl log
+	FUID string `zeek:"id" zeektype:"string" json:"id"`
+	// Fingerprint is the hash of the certificate (replaces the file id in newer versions of zeek)
+	Fingerprint string `zeek:"fingerprint" zeektype:"string" json:"fingerprint"`
+	// Version is the version number of the certificate
+	Version int64 `zeek:"certificate.version" zeektype:"count" json:"certificate.version"`
+	// Serial is the serial number of the certificate
+	Serial string `zeek:"certificate.serial" zeektype:"string" json:"certificate.serial"`
+	// Subject is the subject of the certificate
+	Subject string `zeek:"certificate.subject" zeektype:"string" json:"certificate.subject"`
+	// Issuer is the issuer of the certificate
+	Issuer string `zeek:"certificate.issuer" zeektype:"string" json:"certificate.issuer"`
+	// NotValidBefore is the timestamp before which the certificate is not valid
+	NotValidBefore Timestamp `zeek:"certificate.not_valid_before" zeektype:"time" json:"certificate.not_valid_before"`
+	// NotValidAfter is the timestamp after which the certificate is not valid
+	NotValidAfter Timestamp `zeek:"certificate.not_valid_after" zeektype:"time" json:"certificate.not_valid_after"`
+	// KeyAlgorithm is the name of the key algorithm
+	KeyAlgorithm string `zeek:"certificate.key_alg" zeektype:"string" json:"certificate.key_alg"`
+	// SignatureAlgorithm is the name of the signature algorithm
+	SignatureAlgorithm string `zeek:"certificate.sig_alg" zeektype:"string" json:"certificate.sig_alg"`
+	// KeyType is the key type, if key is parseable by openssl (rsa, dsa or ec)
+	KeyType string `zeek:"certificate.key_type" zeektype:"string" json:"certificate.key_type"`
+	// KeyLength is the key length in bits
+	KeyLength int64 `zeek:"certificate.key_length" zeektype:"count" json:"certificate.key_length"`
+	// Exponent is the exponent, if RSA-certificate
+	Exponent string `zeek:"certificate.exponent" zeektype:"string" json:"certificate.exponent"`
+	// Curve is the curve, if EC-certificate
+	Curve string `zeek:"certificate.curve" zeektype:"string" json:"certificate.curve"`
+	// SANDNS contains the DNS entries of the subject alternative name extension
+	SANDNS []string `zeek:"san.dns" zeektype:"vector[string]" json:"san.dns"`
+	// SANURI contains the URI entries of the subject alternative name extension
+	SANURI []string `zeek:"san.uri" zeektype:"vector[string]" json:"san.uri"`
+	// SANEmail contains the email entries of the subject alternative name extension
+	SANEmail []string `zeek:"san.email" zeektype:"vector[string]" json:"san.email"`
+	// SANIP contains the IP entries of the subject alternative name extension
+	SANIP []string `zeek:"san.ip" zeektype:"vector[addr]" json:"san.ip"`
+	// BasicConstraintsCA indicates if the certificate is a CA certificate
+	BasicConstraintsCA bool `zeek:"basic_constraints.ca" zeektype:"bool" json:"basic_constraints.ca"`
+	// BasicConstraintsPathLen is the maximum path length of the certificate chain
+	BasicConstraintsPathLen int64 `zeek:"basic_constraints.path_len" zeektype:"count" json:"basic_constraints.path_len"`
+	// HostCert indicates if this certificate was sent by the server
+	HostCert bool `zeek:"host_cert" zeektype:"bool" json:"host_cert"`
+	// ClientCert indicates if this certificate was sent by the client
+	ClientCert bool `zeek:"client_cert" zeektype:"bool" json:"client_cert"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `zeek:"agent_hostname" zeektype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `zeek:"agent_uuid" zeektype:"string" json:"agent_uuid"`
+	// Path of log file containing this record
+	LogPath string
+}
+
+func (x *X509) SetLogPath(path string) { x.LogPath = path }
@@ -461,8 +461,8 @@ func (d *TTLTestSuite) TestTableTTLs() {
 func optimizeTables(t *testing.T, db *database.DB) {
 	t.Helper()
 
-	sensorTables := []string{"conn", "uconn", "http", "ssl", "usni", "dns", "udns", "pdns_raw", "pdns", "mime_type_uris",
-		"threat_mixtape", "port_info", "http_proto", "tls_proto", "rare_signatures", "big_ol_histogram", "exploded_dns"}
+	sensorTables := []string{"conn", "uconn", "http", "ssl", "usni", "dns", "udns", "nxdomain", "pdns_raw", "pdns", "mime_type_uris",
+		"threat_mixtape", "port_info", "http_proto", "tls_proto", "rare_signatures", "big_ol_histogram", "exploded_dns", "dns_lexical"}
 
 	for _, table := range sensorTables {
 		// require.NoError(t, d.changeTime("+26 hours"), "changing time should not produce an error")
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	driver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
@@ -20,6 +21,9 @@ import (
 const RARE_SIGNATURE_MODIFIER_NAME = "rare_signature"
 const MIME_TYPE_MISMATCH_MODIFIER_NAME = "mime_type_mismatch"
 const C2_OVER_DNS_DIRECT_CONNECTIONS_MODIFIER_NAME = "c2_over_dns_direct_conns"
+const CERTIFICATE_ANOMALY_MODIFIER_NAME = "cert_anomaly"
+const RARE_SSH_VERSION_MODIFIER_NAME = "rare_ssh_version"
+const FAST_FLUX_MODIFIER_NAME = "fast_flux"
 
 // we must batch if we want all of the modifiers pre-scored in one row
 // we don't need to if we don't need them all in the same row
@@ -84,6 +88,21 @@ func (modifier *Modifier) Modify() error {
 		return err
 	})
 
+	modifierErrGroup.Go(func() error {
+		err := modifier.detectCertificateAnomalies(ctx)
+		return err
+	})
+
+	modifierErrGroup.Go(func() error {
+		err := modifier.detectRareSSHVersion(ctx)
+		return err
+	})
+
+	modifierErrGroup.Go(func() error {
+		err := modifier.detectFastFlux(ctx)
+		return err
+	})
+
 	// wait for all modifier threads to finish
 	if err := modifierErrGroup.Wait(); err != nil {
 		logger.Fatal().Err(err).Msg("could not perform modifier detection")
@@ -224,6 +243,200 @@ func (modifier *Modifier) detectMIMETypeMismatch(ctx context.Context) error {
 	return nil
 }
 
+func (modifier *Modifier) detectCertificateAnomalies(ctx context.Context) error {
+	logger := logger.GetLogger()
+	logger.Debug().Msg("Starting detection of certificate anomalies...")
+	chCtx := modifier.Database.QueryParameters(clickhouse.Parameters{
+		"min_ts":           fmt.Sprintf("%d", modifier.minTS.UTC().Unix()),
+		"import_id":        modifier.ImportID.Hex(),
+		"short_lived_days": fmt.Sprintf("%d", modifier.Config.Modifiers.ShortLivedCertificateDays),
+		"new_cert_days":    fmt.Sprintf("%d", modifier.Config.Modifiers.NewCertificateDays),
+	})
+
+	rows, err := modifier.Database.Conn.Query(chCtx, `--sql
+		WITH server_certs AS (
+			-- get the most recent leaf certificate presented by the server for each SNI connection pair, which newer
+			-- versions of zeek reference by its fingerprint and older versions reference by its file id
+			SELECT hash, argMax(if(length(server_cert_fps) > 0, server_cert_fps[1], server_cert_fuids[1]), ts) as cert_id, max(ts) as last_used
+			FROM ssl
+			WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND (length(server_cert_fps) > 0 OR length(server_cert_fuids) > 0)
+			GROUP BY hash
+		), certs AS (
+			-- certificates can be looked up by either their file id or their fingerprint, depending on which one the ssl log uses
+			SELECT fuid as cert_id, subject, issuer, not_valid_before, not_valid_after FROM x509 WHERE fuid != ''
+			UNION ALL
+			SELECT fingerprint as cert_id, subject, issuer, not_valid_before, not_valid_after FROM x509 WHERE fingerprint != ''
+		), cert_anomalies AS (
+			SELECT c.hash as hash, x.subject as cert_subject, x.issuer as cert_issuer, 
+				x.not_valid_before as cert_not_valid_before, x.not_valid_after as cert_not_valid_after,
+				arrayFilter(a -> a != '', [
+					if(x.subject = x.issuer, 'self-signed', ''),
+					if(x.not_valid_after < c.last_used, 'expired', ''),
+					if(dateDiff('day', x.not_valid_before, x.not_valid_after) < {short_lived_days:Int32}, 'short-lived', ''),
+					if(dateDiff('day', x.not_valid_before, c.last_used) < {new_cert_days:Int32}, 'newly issued', '')
+				]) as anomalies
+			FROM server_certs c
+			ANY INNER JOIN certs x ON c.cert_id = x.cert_id
+		)
+		SELECT hash, src, src_nuid, dst, dst_nuid, fqdn, last_seen, arrayStringConcat(a.anomalies, ', ') as modifier_value,
+			cert_subject, cert_issuer, cert_not_valid_before, cert_not_valid_after
+		FROM threat_mixtape t
+		INNER JOIN cert_anomalies a USING hash
+		-- only score the certificates of SNI connection pairs that are beaconing
+		WHERE t.import_id = unhex({import_id:String}) AND t.beacon_type = 'sni' AND t.beacon_score > 0 AND length(a.anomalies) > 0
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	return modifier.scoopModifiers(ctx, CERTIFICATE_ANOMALY_MODIFIER_NAME, modifier.Config.Modifiers.CertificateAnomalyScoreIncrease, rows)
+}
+
+func (modifier *Modifier) detectRareSSHVersion(ctx context.Context) error {
+	logger := logger.GetLogger()
+	logger.Debug().Msg("Starting detection of rare SSH versions...")
+	chCtx := modifier.Database.QueryParameters(clickhouse.Parameters{
+		"min_ts":               fmt.Sprintf("%d", modifier.minTS.UTC().Unix()),
+		"import_id":            modifier.ImportID.Hex(),
+		"prevalence_threshold": fmt.Sprint(modifier.Config.Modifiers.RareSSHVersionPrevalenceThreshold),
+	})
+
+	rows, err := modifier.Database.Conn.Query(chCtx, `--sql
+		WITH ssh_hosts AS (
+			-- get the number of hosts on the network that run SSH clients and servers
+			SELECT uniqExactIf((src, src_nuid), client != '') as clients, uniqExactIf((dst, dst_nuid), server != '') as servers
+			FROM ssh
+			WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64}))
+		), client_versions AS (
+			-- client versions are rare if only a small share of the hosts that run SSH clients use them
+			SELECT client, uniqExact(src, src_nuid) as times_used
+			FROM ssh
+			WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND client != ''
+			GROUP BY client
+			HAVING times_used / (SELECT clients FROM ssh_hosts) <= {prevalence_threshold:Float32}
+		), server_versions AS (
+			-- server versions are rare if only a small share of the hosts that run SSH servers run them
+			SELECT server, uniqExact(dst, dst_nuid) as times_used
+			FROM ssh
+			WHERE ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64})) AND server != ''
+			GROUP BY server
+			HAVING times_used / (SELECT servers FROM ssh_hosts) <= {prevalence_threshold:Float32}
+		), rare_versions AS (
+			SELECT s.hash as hash, groupUniqArrayIf(s.client, c.client != '') as rare_clients, groupUniqArrayIf(s.server, v.server != '') as rare_servers
+			FROM ssh s
+			LEFT JOIN client_versions c ON s.client = c.client
+			LEFT JOIN server_versions v ON s.server = v.server
+			WHERE s.ts >= toStartOfHour(fromUnixTimestamp({min_ts:Int64}))
+			GROUP BY hash
+			HAVING length(rare_clients) > 0 OR length(rare_servers) > 0
+		)
+		SELECT hash, src, src_nuid, dst, dst_nuid, fqdn, last_seen,
+			arrayStringConcat(arrayConcat(arrayMap(x -> concat('client: ', x), r.rare_clients), arrayMap(x -> concat('server: ', x), r.rare_servers)), ', ') as modifier_value
+		FROM threat_mixtape t
+		INNER JOIN rare_versions r USING hash
+		WHERE t.import_id = unhex({import_id:String})
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	return modifier.scoopModifiers(ctx, RARE_SSH_VERSION_MODIFIER_NAME, modifier.Config.Modifiers.RareSSHVersionScoreIncrease, rows)
+}
+
+func (modifier *Modifier) detectFastFlux(ctx context.Context) error {
+	logger := logger.GetLogger()
+	logger.Debug().Msg("Starting detection of fast flux domains...")
+	chCtx := modifier.Database.QueryParameters(clickhouse.Parameters{
+		"min_ts":                fmt.Sprintf("%d", modifier.minTS.UTC().Unix()),
+		"import_id":             modifier.ImportID.Hex(),
+		"resolved_ip_threshold": fmt.Sprintf("%d", modifier.Config.Modifiers.FastFluxResolvedIPThreshold),
+		"network_threshold":     fmt.Sprintf("%d", modifier.Config.Modifiers.FastFluxNetworkThreshold),
+		"new_ips_per_hour":      fmt.Sprint(modifier.Config.Modifiers.FastFluxNewIPsPerHour),
+		"max_ttl":               fmt.Sprintf("%d", modifier.Config.Modifiers.FastFluxMaxTTL),
+	})
+
+	// networks are counted by /16 (/32 for IPv6) since there is no ASN data to count them by
+	rows, err := modifier.Database.Conn.Query(chCtx, `--sql
+		WITH resolutions AS (
+			-- get when each fqdn was first resolved to each ip
+			SELECT fqdn, resolved_ip, minMerge(first_seen) as first_seen
+			FROM pdns
+			WHERE day >= toStartOfDay(fromUnixTimestamp({min_ts:Int64}))
+			GROUP BY fqdn, resolved_ip
+		), ttls AS (
+			-- get the median ttl that each fqdn was resolved with, so that a single answer with a low ttl doesn't count
+			SELECT query as fqdn, assumeNotNull(quantileExact(0.5)(ttl)) as median_ttl
+			FROM pdns_raw
+			WHERE ts >= toStartOfDay(fromUnixTimestamp({min_ts:Int64})) AND ttl IS NOT NULL
+			GROUP BY fqdn
+		), fast_flux AS (
+			-- fast flux domains keep resolving to new ips spread across many networks with low ttls
+			SELECT fqdn, count() as resolved_ip_count,
+				uniqExact(if(startsWith(toString(resolved_ip), '::ffff:'), IPv6CIDRToRange(resolved_ip, 112).1, IPv6CIDRToRange(resolved_ip, 32).1)) as resolved_ip_networks,
+				-- new ips per hour over the time span that the ips were first seen in, which is counted as at least an hour
+				toFloat32(count() / greatest(dateDiff('second', min(first_seen), max(first_seen)) / 3600, 1)) as resolved_ip_rate,
+				any(m.median_ttl) as resolved_ip_ttl,
+				arraySlice(arrayMap(r -> r.2, arrayReverseSort(r -> r.1, groupArray((first_seen, resolved_ip)))), 1, 50) as resolved_ips
+			FROM resolutions
+			INNER JOIN ttls m USING fqdn
+			GROUP BY fqdn
+			HAVING resolved_ip_count >= {resolved_ip_threshold:UInt64} AND resolved_ip_networks >= {network_threshold:UInt64}
+				AND resolved_ip_rate >= {new_ips_per_hour:Float32} AND resolved_ip_ttl <= {max_ttl:UInt32}
+		)
+		SELECT hash, src, src_nuid, dst, dst_nuid, fqdn, last_seen,
+			concat(toString(f.resolved_ip_count), ' IPs in ', toString(f.resolved_ip_networks), ' networks, ',
+				toString(round(f.resolved_ip_rate, 1)), ' new IPs per hour, median TTL ', toString(f.resolved_ip_ttl), 's') as modifier_value,
+			resolved_ip_count, resolved_ip_networks, resolved_ip_rate, resolved_ip_ttl, resolved_ips
+		FROM threat_mixtape t
+		INNER JOIN fast_flux f USING fqdn
+		WHERE t.import_id = unhex({import_id:String})
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	return modifier.scoopModifiers(ctx, FAST_FLUX_MODIFIER_NAME, modifier.Config.Modifiers.FastFluxScoreIncrease, rows)
+}
+
+// scoopModifiers writes each connection pair returned by a modifier query to the mixtape with the modifier's name and score
+func (modifier *Modifier) scoopModifiers(ctx context.Context, name string, score float32, rows driver.Rows) error {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	for rows.Next() {
+		select {
+		// abort this function if the context was cancelled
+		case <-ctx.Done():
+			logger.Warn().Str("modifier", name).Msg("cancelling modifier query")
+			return ctx.Err()
+		default:
+			var res analysis.ThreatMixtape
+			if err := rows.ScanStruct(&res); err != nil {
+				return fmt.Errorf("could not read entry for %s modifier detection: %w", name, err)
+			}
+
+			// set analyzed at time to the time the import was started
+			res.AnalyzedAt = modifier.Database.ImportStartedAt.Truncate(time.Microsecond)
+
+			// set the first seen timestamp to the beginning of the Unix epoch because ClickHouse is being
+			// finicky with these fields not being directly set
+			res.FirstSeenHistorical = time.Unix(0, 0)
+
+			res.ImportID = modifier.ImportID
+			res.ModifierName = name
+			res.ModifierScore = score
+
+			// send the modifier to the writer
+			modifier.writer.WriteChannel <- &res
+		}
+	}
+
+	return rows.Err()
+}
+
 // RESULTS
 
 // SELECT max(last_seen) as most_recent, hash, src, dst, fqdn, beacon_score, long_conn_score, strobe_score, sum(modifier_score) as modifier_delta
@@ -60,6 +60,15 @@ func FormatToCSV(items []list.Item, relativeTimestamp time.Time) (string, error)
 		"Connection Count",
 		"Total Bytes",
 		"Port:Proto:Service",
+		"Source Hostname",
+		"Source MAC",
+		"Lateral Movement Score",
+		"DNS Tunneling Score",
+		"DGA Score",
+		"Port Scan Score",
+		"Exfiltration Score",
+		"SSH Brute Force Score",
+		"SSH Login After Failures Score",
 	}
 
 	// loop over the results and format into rows and columns
@@ -76,6 +85,8 @@ func FormatToCSV(items []list.Item, relativeTimestamp time.Time) (string, error)
 			fmt.Sprint(item.Subdomains), fmt.Sprint(item.C2OverDNSScore), strconv.FormatBool(item.ThreatIntelScore > 0),
 			fmt.Sprint(item.Prevalence), item.GetFirstSeen(relativeTimestamp), strconv.FormatBool(item.MissingHostCount > 0),
 			fmt.Sprint(item.Count), fmt.Sprint(item.TotalBytes), fmt.Sprintf("\"%s\"", strings.Join(item.PortProtoService, ",")),
+			item.SrcHostName, item.SrcMAC, fmt.Sprint(item.LateralMovementScore), fmt.Sprint(item.DNSTunnelingScore), fmt.Sprint(item.DGAScore), fmt.Sprint(item.PortScanScore), fmt.Sprint(item.ExfiltrationScore),
+			fmt.Sprint(item.SSHBruteForceScore), fmt.Sprint(item.SSHLoginFailuresScore),
 		}
 		// create comma-delimited string from each field in this row
 		formattedRow := strings.Join(fields, ",")
@@ -10,7 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const expectedCSVHeader = "Severity,Source IP,Destination IP,FQDN,Beacon Score,Strobe,Total Duration,Long Connection Score,Subdomains,C2 Over DNS Score,Threat Intel,Prevalence,First Seen,Missing Host Header,Connection Count,Total Bytes,Port:Proto:Service\n"
+const expectedCSVHeader = "Severity,Source IP,Destination IP,FQDN,Beacon Score,Strobe,Total Duration,Long Connection Score,Subdomains,C2 Over DNS Score,Threat Intel,Prevalence,First Seen,Missing Host Header,Connection Count,Total Bytes,Port:Proto:Service,Source Hostname,Source MAC,Lateral Movement Score,DNS Tunneling Score,DGA Score,Port Scan Score,Exfiltration Score,SSH Brute Force Score,SSH Login After Failures Score\n"
 
 func (s *ViewerTestSuite) TestGetCSVOutput() {
 	// minTimestamp, maxTimestamp, _, useCurrentTime, err := s.db.GetBeaconMinMaxTimestamps()
@@ -39,7 +39,7 @@ func (s *ViewerTestSuite) TestGetCSVOutput() {
 			expectedCSV: expectedCSVHeader +
 				// "Critical,10.55.100.111,::,cdn.content.prod.cms.msn.com,1,false,2686.96,0,0.00,0,0.00,false,0.36,6 years ago,false,48,114487,\"80:tcp:http\"\n", //+
 				// "Critical,::,::,r-1x.com,0.00,false,0,0.00,0,0.00,false,0.00,23 hours ago,false,0,0,\"\"\n",
-				`Critical,192.168.88.2,165.227.88.15,,0,true,15176.8545,0.41078964,0,0,false,0.06666667,23 hours ago,false,108858,43451342,"53:tcp:,53:udp:dns"`,
+				`Critical,192.168.88.2,165.227.88.15,,0,true,15176.8545,0.41078964,0,0,false,0.06666667,23 hours ago,false,108858,43451342,"53:tcp:,53:udp:dns",,,0,0,0,0,0,0,0`,
 			expectedError: false,
 		},
 	}
@@ -96,11 +96,13 @@ func (s *ViewerTestSuite) TestFormatToCSV() {
 					TotalBytesFormatted:      "23.21 MiB",
 					MissingHostHeaderScore:   0.1,
 					MissingHostCount:         0,
+					SrcHostName:              "desktop-7h2kq",
+					SrcMAC:                   "00:1a:2b:3c:4d:5e",
 				}),
 			},
 			relativeTimestamp: time.Now(),
 			expectedCSV: expectedCSVHeader +
-				"High,10.55.100.111,88.221.81.192,example.com,0.75,false,10800,0.8,3,0.45,true,0.35,3 days ago,false,2574,24335500,\"80:tcp:http,443:tcp:https\"",
+				"High,10.55.100.111,88.221.81.192,example.com,0.75,false,10800,0.8,3,0.45,true,0.35,3 days ago,false,2574,24335500,\"80:tcp:http,443:tcp:https\",desktop-7h2kq,00:1a:2b:3c:4d:5e,0,0,0,0,0,0,0",
 			expectedError: false,
 		},
 		{
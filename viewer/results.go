@@ -36,13 +36,58 @@ type MixtapeResult struct {
 	PortProtoService         []string  `ch:"port_proto_service"`
 	C2OverDNSScore           float32   `ch:"c2_over_dns_score"`
 	C2OverDNSDirectConnScore float32   `ch:"c2_over_dns_direct_conn_score"`
+	DNSTunnelingScore        float32   `ch:"dns_tunneling_score"`
+	LabelEntropy             float32   `ch:"label_entropy"`
+	AvgLabelLength           float32   `ch:"avg_label_length"`
+	EncodedLabelRatio        float32   `ch:"encoded_label_ratio"`
+	TXTNullQueryRatio        float32   `ch:"txt_null_query_ratio"`
 	ThreatIntelScore         float32   `ch:"threat_intel_score"`
 	ThreatIntelDataSizeScore float32   `ch:"threat_intel_data_size_score"`
 	TotalBytes               uint64    `ch:"total_bytes"`
 	TotalBytesFormatted      string    `ch:"total_bytes_formatted"`
+	BytesSent                int64     `ch:"bytes_sent"`
+	BytesSentFormatted       string    `ch:"bytes_sent_formatted"`
+	BytesReceived            int64     `ch:"bytes_received"`
+	BytesReceivedFormatted   string    `ch:"bytes_received_formatted"`
+	OffHoursBytesSent        int64     `ch:"off_hours_bytes_sent"`
+	ExfiltrationScore        float32   `ch:"exfiltration_score"`
 	MissingHostHeaderScore   float32   `ch:"missing_host_header_score"`
 	MissingHostCount         uint64    `ch:"missing_host_count"`
 	ProxyIPs                 []net.IP  `ch:"proxy_ips"`
+	CertSubject              string    `ch:"cert_subject"`
+	CertIssuer               string    `ch:"cert_issuer"`
+	CertNotValidBefore       time.Time `ch:"cert_not_valid_before"`
+	CertNotValidAfter        time.Time `ch:"cert_not_valid_after"`
+	CertAnomalies            string    `ch:"cert_anomalies"`
+	CertAnomalyScore         float32   `ch:"cert_anomaly_score"`
+	FastFlux                 string    `ch:"fast_flux"`
+	FastFluxScore            float32   `ch:"fast_flux_score"`
+	ResolvedIPCount          uint64    `ch:"resolved_ip_count"`
+	ResolvedIPNetworks       uint64    `ch:"resolved_ip_networks"`
+	ResolvedIPRate           float32   `ch:"resolved_ip_rate"`
+	ResolvedIPTTL            uint32    `ch:"resolved_ip_ttl"`
+	ResolvedIPs              []net.IP  `ch:"resolved_ips"`
+	RareSSHVersions          string    `ch:"rare_ssh_versions"`
+	RareSSHVersionScore      float32   `ch:"rare_ssh_version_score"`
+	DGAScore                 float32   `ch:"dga_score"`
+	NXDomainCount            uint64    `ch:"nxdomain_count"`
+	DGADomains               uint64    `ch:"dga_domains"`
+	DGASampleDomains         []string  `ch:"dga_sample_domains"`
+	PortScanScore            float32   `ch:"port_scan_score"`
+	ScanType                 string    `ch:"scan_type"`
+	ScannedHosts             uint64    `ch:"scanned_hosts"`
+	ScannedPorts             uint64    `ch:"scanned_ports"`
+	SSHBruteForceScore       float32   `ch:"ssh_brute_force_score"`
+	SSHAuthAttempts          uint64    `ch:"ssh_auth_attempts"`
+	SSHLoginFailuresScore    float32   `ch:"ssh_login_after_failures_score"`
+	SSHFailedLogins          uint64    `ch:"ssh_failed_logins"`
+	LateralMovementScore     float32   `ch:"lateral_movement_score"`
+	LateralHosts             uint64    `ch:"lateral_hosts"`
+	AdminShareHosts          uint64    `ch:"admin_share_hosts"`
+	ServiceControlHosts      uint64    `ch:"service_control_hosts"`
+	NewServiceTickets        uint64    `ch:"new_service_tickets"`
+	SrcHostName              string    `ch:"src_host_name"`
+	SrcMAC                   string    `ch:"src_mac"`
 
 	TotalModifierScore float32 `ch:"total_modifier_score"`
 }
@@ -60,6 +105,18 @@ func (i Item) GetDst() string {
 	if i.Dst.String() == "::" && len(i.FQDN) > 0 {
 		return i.FQDN
 	}
+	// lateral movement is scored per source, so show how many internal hosts it reached instead
+	if i.Dst.String() == "::" && i.LateralMovementScore > 0 {
+		return fmt.Sprintf("%d internal hosts", i.LateralHosts)
+	}
+	// dga is also scored per source, so show how many generated domains it got NXDOMAIN answers for
+	if i.Dst.String() == "::" && i.DGAScore > 0 {
+		return fmt.Sprintf("%d generated domains", i.DGADomains)
+	}
+	// horizontal port scans are scored per source and port, so show how many hosts were probed on the port
+	if i.Dst.String() == "::" && i.PortScanScore > 0 && len(i.PortProtoService) > 0 {
+		return fmt.Sprintf("%d hosts on %s", i.ScannedHosts, strings.TrimSuffix(i.PortProtoService[0], ":"))
+	}
 	return i.Dst.String()
 }
 
@@ -191,12 +248,23 @@ func BuildResultsQuery(filter Filter, currentPage, pageSize int, minTimestamp ti
 		proxy_ips,
 		total_bytes,
 		total_bytes_formatted,
+		bytes_sent,
+		bytes_sent_formatted,
+		bytes_received,
+		bytes_received_formatted,
+		off_hours_bytes_sent,
+		exfiltration_score,
 		subdomains,
 		-- arrayDistinct(flatten(port_proto_service)) as port_proto_service,
 		port_proto_service,
 		beacon_score as beacon_score,
 		beacon_threat_score,
 		c2_over_dns_score,
+		dns_tunneling_score,
+		label_entropy,
+		avg_label_length,
+		encoded_label_ratio,
+		txt_null_query_ratio,
 		strobe_score,
 		total_duration,
 		long_conn_score,
@@ -209,23 +277,69 @@ func BuildResultsQuery(filter Filter, currentPage, pageSize int, minTimestamp ti
 		missing_host_count,
 		missing_host_header_score,
 		c2_over_dns_direct_conn_score,
+		cert_subject,
+		cert_issuer,
+		cert_not_valid_before,
+		cert_not_valid_after,
+		cert_anomalies,
+		cert_anomaly_score,
+		fast_flux,
+		fast_flux_score,
+		resolved_ip_count,
+		resolved_ip_networks,
+		resolved_ip_rate,
+		resolved_ip_ttl,
+		resolved_ips,
+		rare_ssh_versions,
+		rare_ssh_version_score,
+		dga_score,
+		nxdomain_count,
+		dga_domains,
+		dga_sample_domains,
+		port_scan_score,
+		scan_type,
+		scanned_hosts,
+		scanned_ports,
+		ssh_brute_force_score,
+		ssh_auth_attempts,
+		ssh_login_after_failures_score,
+		ssh_failed_logins,
+		lateral_movement_score,
+		lateral_hosts,
+		admin_share_hosts,
+		service_control_hosts,
+		new_service_tickets,
+		-- only use the dhcp lease for the source if it hadn't expired by the time the connection was last seen
+		if(d.lease_time = 0 OR d.lease_end >= r.last_seen, d.host_name, '') as src_host_name,
+		if(d.lease_time = 0 OR d.lease_end >= r.last_seen, d.mac, '') as src_mac,
 		total_modifier_score,
 		toFloat32(base_score + total_modifier_score + prevalence_score + first_seen_score + missing_host_header_score + threat_intel_data_size_score + c2_over_dns_direct_conn_score) as final_score
 		-- base_score
 		-- total_modifier_score
 	
 		FROM (
-		SELECT hash, src, dst, fqdn,
+		SELECT hash, src, src_nuid, dst, fqdn, last_seen,
 			groupUniqArrayArray(proxy_ips) as proxy_ips,
 			max(proxy_count) as proxy_count,
 			max(count) as count,
 			sum(total_bytes) as total_bytes,
 			formatReadableSize(total_bytes) as total_bytes_formatted,
+			max(bytes_sent) as bytes_sent,
+			formatReadableSize(bytes_sent) as bytes_sent_formatted,
+			max(bytes_received) as bytes_received,
+			formatReadableSize(bytes_received) as bytes_received_formatted,
+			max(off_hours_bytes_sent) as off_hours_bytes_sent,
+			toFloat32(sum(exfiltration_score)) as exfiltration_score,
 			sum(subdomain_count) as subdomains,
 			flatten(groupArray(port_proto_service)) as port_proto_service,
 			toFloat32(sum(beacon_score)) as beacon_score,
 			toFloat32(sum(beacon_threat_score)) as beacon_threat_score,
 			toFloat32(sum(c2_over_dns_score)) as c2_over_dns_score,
+			toFloat32(sum(dns_tunneling_score)) as dns_tunneling_score,
+			max(label_entropy) as label_entropy,
+			max(avg_label_length) as avg_label_length,
+			greatest(max(hex_label_ratio), max(base32_label_ratio), max(base64_label_ratio)) as encoded_label_ratio,
+			max(txt_null_query_ratio) as txt_null_query_ratio,
 			toFloat32(sum(strobe_score)) as strobe_score,
 			toFloat32(sum(total_duration)) as total_duration,
 			toFloat32(sum(long_conn_score)) as  long_conn_score,
@@ -234,12 +348,44 @@ func BuildResultsQuery(filter Filter, currentPage, pageSize int, minTimestamp ti
 			max(first_seen_historical) as first_seen_historical,
 			toFloat32(sum(first_seen_score)) as first_seen_score,
 			toFloat32(sum(threat_intel_score)) as threat_intel_score,
+			toFloat32(sum(dga_score)) as dga_score,
+			sum(nxdomain_count) as nxdomain_count,
+			sum(dga_domains) as dga_domains,
+			flatten(groupArray(dga_sample_domains)) as dga_sample_domains,
+			toFloat32(sum(port_scan_score)) as port_scan_score,
+			max(scan_type) as scan_type,
+			sum(scanned_hosts) as scanned_hosts,
+			sum(scanned_ports) as scanned_ports,
+			toFloat32(sum(ssh_brute_force_score)) as ssh_brute_force_score,
+			sum(ssh_auth_attempts) as ssh_auth_attempts,
+			toFloat32(sum(ssh_login_after_failures_score)) as ssh_login_after_failures_score,
+			sum(ssh_failed_logins) as ssh_failed_logins,
+			toFloat32(sum(lateral_movement_score)) as lateral_movement_score,
+			sum(lateral_hosts) as lateral_hosts,
+			sum(admin_share_hosts) as admin_share_hosts,
+			sum(service_control_hosts) as service_control_hosts,
+			sum(new_service_tickets) as new_service_tickets,
 			toFloat32(sum(threat_intel_data_size_score)) as threat_intel_data_size_score,
 			sum(missing_host_count) as missing_host_count,
 			toFloat32(sum(missing_host_header_score)) as missing_host_header_score,
 			toFloat32(sum(c2_over_dns_direct_conn_score)) as c2_over_dns_direct_conn_score,
+			max(cert_subject) as cert_subject,
+			max(cert_issuer) as cert_issuer,
+			max(cert_not_valid_before) as cert_not_valid_before,
+			max(cert_not_valid_after) as cert_not_valid_after,
+			anyIf(modifier_value, modifier_name = 'cert_anomaly') as cert_anomalies,
+			toFloat32(sumIf(modifier_score, modifier_name = 'cert_anomaly')) as cert_anomaly_score,
+			anyIf(modifier_value, modifier_name = 'fast_flux') as fast_flux,
+			toFloat32(sumIf(modifier_score, modifier_name = 'fast_flux')) as fast_flux_score,
+			maxIf(resolved_ip_count, modifier_name = 'fast_flux') as resolved_ip_count,
+			maxIf(resolved_ip_networks, modifier_name = 'fast_flux') as resolved_ip_networks,
+			maxIf(resolved_ip_rate, modifier_name = 'fast_flux') as resolved_ip_rate,
+			maxIf(resolved_ip_ttl, modifier_name = 'fast_flux') as resolved_ip_ttl,
+			anyIf(resolved_ips, modifier_name = 'fast_flux') as resolved_ips,
+			anyIf(modifier_value, modifier_name = 'rare_ssh_version') as rare_ssh_versions,
+			toFloat32(sumIf(modifier_score, modifier_name = 'rare_ssh_version')) as rare_ssh_version_score,
 			toFloat32(sum(modifier_score)) as total_modifier_score,
-			greatest(beacon_threat_score, long_conn_score, strobe_score, c2_over_dns_score, threat_intel_score) as base_score
+			greatest(beacon_threat_score, long_conn_score, strobe_score, exfiltration_score, c2_over_dns_score, dns_tunneling_score, dga_score, port_scan_score, ssh_brute_force_score, ssh_login_after_failures_score, threat_intel_score, lateral_movement_score) as base_score
 		FROM threat_mixtape t
 		INNER JOIN (SELECT hash, argMax(import_id, last_seen) as import_id, max(last_seen) as max_last_seen FROM threat_mixtape GROUP BY hash) x
 		ON t.hash = x.hash and t.last_seen = x.max_last_seen and t.import_id = x.import_id
@@ -278,7 +424,7 @@ func BuildResultsQuery(filter Filter, currentPage, pageSize int, minTimestamp ti
 
 	// set group by
 	query += `--sql
-		GROUP BY hash, src, dst, fqdn
+		GROUP BY hash, src, src_nuid, dst, fqdn, last_seen
  	`
 
 	// set having conditions for numerical filters
@@ -313,9 +459,11 @@ func BuildResultsQuery(filter Filter, currentPage, pageSize int, minTimestamp ti
 		query += "HAVING " + strings.Join(havingConditions, " AND ")
 	}
 
-	// add parentheses to close subquery
+	// add parentheses to close subquery and look up the dhcp lease that the source held at the time it was last seen
 	query += `--sql
-	)`
+	) r
+	ASOF LEFT JOIN dhcp d ON r.src = d.ip AND r.src_nuid = d.nuid AND r.last_seen >= d.lease_start
+	`
 
 	// add where conditions to the outer part of the query if any were specified
 	outerWhereConditions := []string{}
@@ -124,6 +124,12 @@ func (m *sidebarModel) getSidebarContents() string {
 	bytesHeader := bytesHeaderStyle.Render("Total Bytes")
 	bytes := lipgloss.JoinVertical(lipgloss.Top, bytesHeader, m.Data.TotalBytesFormatted)
 
+	// get bytes sent and received by the source
+	sentReceived := ""
+	if m.Data.BytesSent > 0 || m.Data.BytesReceived > 0 {
+		sentReceived = lipgloss.JoinVertical(lipgloss.Top, bytesHeaderStyle.Render("Bytes Sent / Received"), fmt.Sprintf("%s / %s", m.Data.BytesSentFormatted, m.Data.BytesReceivedFormatted))
+	}
+
 	// get port:proto:service
 	portProtoService := m.Data.GetPortProtoService()
 	// DEBUG SIDEFEED SCROLLING WITH LONG PORT:PROTO:SERVICE
@@ -148,8 +154,135 @@ func (m *sidebarModel) getSidebarContents() string {
 
 	}
 
+	// get certificate details
+	certInfo := ""
+	if m.Data.CertSubject != "" {
+		certInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 Certificate 」"))
+		certHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+		certValueStyle := lipgloss.NewStyle().Width(m.Viewport.Width)
+
+		subject := lipgloss.JoinVertical(lipgloss.Top, certHeaderStyle.Render("Subject"), certValueStyle.Render(m.Data.CertSubject))
+		issuer := lipgloss.JoinVertical(lipgloss.Top, certHeaderStyle.Render("Issuer"), certValueStyle.Render(m.Data.CertIssuer))
+		validity := lipgloss.JoinVertical(lipgloss.Top, certHeaderStyle.Render("Validity"), fmt.Sprintf("%s - %s", m.Data.CertNotValidBefore.UTC().Format(time.DateOnly), m.Data.CertNotValidAfter.UTC().Format(time.DateOnly)))
+		certInfo = lipgloss.JoinVertical(lipgloss.Top, certInfoLabel, subject, issuer, validity)
+	}
+
+	// get lateral movement details
+	lateralInfo := ""
+	if m.Data.LateralMovementScore > 0 {
+		lateralInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 Lateral Movement 」"))
+		lateralHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		adminShares := lipgloss.JoinVertical(lipgloss.Top, lateralHeaderStyle.Render("New Admin Share Hosts"), fmt.Sprintf("%d", m.Data.AdminShareHosts))
+		serviceControl := lipgloss.JoinVertical(lipgloss.Top, lateralHeaderStyle.Render("New Service Control Hosts"), fmt.Sprintf("%d", m.Data.ServiceControlHosts))
+		serviceTickets := lipgloss.JoinVertical(lipgloss.Top, lateralHeaderStyle.Render("New Kerberos Service Tickets"), fmt.Sprintf("%d", m.Data.NewServiceTickets))
+		lateralInfo = lipgloss.JoinVertical(lipgloss.Top, lateralInfoLabel, renderIndicator(m.Data.LateralMovementScore, fmt.Sprintf("%1.2f%%", m.Data.LateralMovementScore*100)), adminShares, serviceControl, serviceTickets)
+	}
+
+	// get dns tunneling details
+	tunnelingInfo := ""
+	if m.Data.DNSTunnelingScore > 0 {
+		tunnelingInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 DNS Tunneling 」"))
+		tunnelingHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		entropy := lipgloss.JoinVertical(lipgloss.Top, tunnelingHeaderStyle.Render("Subdomain Entropy"), fmt.Sprintf("%1.2f bits", m.Data.LabelEntropy))
+		labelLength := lipgloss.JoinVertical(lipgloss.Top, tunnelingHeaderStyle.Render("Average Label Length"), fmt.Sprintf("%1.1f", m.Data.AvgLabelLength))
+		encoded := lipgloss.JoinVertical(lipgloss.Top, tunnelingHeaderStyle.Render("Encoded Labels"), fmt.Sprintf("%1.2f%%", m.Data.EncodedLabelRatio*100))
+		queryTypes := lipgloss.JoinVertical(lipgloss.Top, tunnelingHeaderStyle.Render("TXT/NULL Queries"), fmt.Sprintf("%1.2f%%", m.Data.TXTNullQueryRatio*100))
+		tunnelingInfo = lipgloss.JoinVertical(lipgloss.Top, tunnelingInfoLabel, renderIndicator(m.Data.DNSTunnelingScore, fmt.Sprintf("%1.2f%%", m.Data.DNSTunnelingScore*100)), entropy, labelLength, encoded, queryTypes)
+	}
+
+	// get the resolved ip history of fast flux domains
+	resolvedIPInfo := ""
+	if len(m.Data.ResolvedIPs) > 0 {
+		resolvedIPInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 Resolved IPs 」"))
+		resolvedIPHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		resolved := lipgloss.JoinVertical(lipgloss.Top, resolvedIPHeaderStyle.Render("Unique IPs"), fmt.Sprintf("%d in %d networks", m.Data.ResolvedIPCount, m.Data.ResolvedIPNetworks))
+		rate := lipgloss.JoinVertical(lipgloss.Top, resolvedIPHeaderStyle.Render("New IPs Per Hour"), fmt.Sprintf("%1.1f", m.Data.ResolvedIPRate))
+		ttl := lipgloss.JoinVertical(lipgloss.Top, resolvedIPHeaderStyle.Render("Median TTL"), fmt.Sprintf("%ds", m.Data.ResolvedIPTTL))
+
+		ips := make([]string, 0, len(m.Data.ResolvedIPs)+1)
+		for _, ip := range m.Data.ResolvedIPs {
+			ips = append(ips, ip.String())
+		}
+		if remaining := int(m.Data.ResolvedIPCount) - len(m.Data.ResolvedIPs); remaining > 0 {
+			ips = append(ips, fmt.Sprintf("... %d more", remaining))
+		}
+		history := lipgloss.JoinVertical(lipgloss.Top, resolvedIPHeaderStyle.Render("Most Recently Resolved"), strings.Join(ips, "\n"))
+		resolvedIPInfo = lipgloss.JoinVertical(lipgloss.Top, resolvedIPInfoLabel, resolved, rate, ttl, history)
+	}
+
+	// get dga details
+	dgaInfo := ""
+	if m.Data.DGAScore > 0 {
+		dgaInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 DGA 」"))
+		dgaHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		nxdomains := lipgloss.JoinVertical(lipgloss.Top, dgaHeaderStyle.Render("NXDOMAIN Answers"), fmt.Sprintf("%d", m.Data.NXDomainCount))
+		generated := lipgloss.JoinVertical(lipgloss.Top, dgaHeaderStyle.Render("Generated Domains"), fmt.Sprintf("%d", m.Data.DGADomains))
+		samples := lipgloss.JoinVertical(lipgloss.Top, dgaHeaderStyle.Render("Sample Domains"), strings.Join(m.Data.DGASampleDomains, "\n"))
+		dgaInfo = lipgloss.JoinVertical(lipgloss.Top, dgaInfoLabel, renderIndicator(m.Data.DGAScore, fmt.Sprintf("%1.2f%%", m.Data.DGAScore*100)), nxdomains, generated, samples)
+	}
+
+	// get exfiltration details
+	exfiltrationInfo := ""
+	if m.Data.ExfiltrationScore > 0 {
+		exfiltrationInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 Exfiltration 」"))
+		exfiltrationHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		uploadRatio := "nothing received"
+		if m.Data.BytesReceived > 0 {
+			uploadRatio = fmt.Sprintf("%.1f:1", float64(m.Data.BytesSent)/float64(m.Data.BytesReceived))
+		}
+		ratio := lipgloss.JoinVertical(lipgloss.Top, exfiltrationHeaderStyle.Render("Sent / Received Ratio"), uploadRatio)
+		offHours := lipgloss.JoinVertical(lipgloss.Top, exfiltrationHeaderStyle.Render("Sent During Off Hours"), fmt.Sprintf("%1.2f%%", float64(m.Data.OffHoursBytesSent)/float64(m.Data.BytesSent)*100))
+		exfiltrationInfo = lipgloss.JoinVertical(lipgloss.Top, exfiltrationInfoLabel, renderIndicator(m.Data.ExfiltrationScore, fmt.Sprintf("%1.2f%%", m.Data.ExfiltrationScore*100)), ratio, offHours)
+	}
+
+	// get port scan details
+	portScanInfo := ""
+	if m.Data.PortScanScore > 0 {
+		portScanInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 Port Scan 」"))
+		portScanHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		scanType := lipgloss.JoinVertical(lipgloss.Top, portScanHeaderStyle.Render("Scan Type"), m.Data.ScanType)
+		scannedHosts := lipgloss.JoinVertical(lipgloss.Top, portScanHeaderStyle.Render("Hosts Probed"), fmt.Sprintf("%d", m.Data.ScannedHosts))
+		scannedPorts := lipgloss.JoinVertical(lipgloss.Top, portScanHeaderStyle.Render("Ports Probed"), fmt.Sprintf("%d", m.Data.ScannedPorts))
+		portScanInfo = lipgloss.JoinVertical(lipgloss.Top, portScanInfoLabel, renderIndicator(m.Data.PortScanScore, fmt.Sprintf("%1.2f%%", m.Data.PortScanScore*100)), scanType, scannedHosts, scannedPorts)
+	}
+
+	// get ssh brute force and login after failures details
+	sshInfo := ""
+	if m.Data.SSHBruteForceScore > 0 || m.Data.SSHLoginFailuresScore > 0 {
+		sshInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 SSH 」"))
+		sshHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+
+		sshInfo = sshInfoLabel
+		if m.Data.SSHBruteForceScore > 0 {
+			attempts := lipgloss.JoinVertical(lipgloss.Top, sshHeaderStyle.Render("Brute Force"), renderIndicator(m.Data.SSHBruteForceScore, fmt.Sprintf("%d auth attempts in one hour", m.Data.SSHAuthAttempts)))
+			sshInfo = lipgloss.JoinVertical(lipgloss.Top, sshInfo, attempts)
+		}
+		if m.Data.SSHLoginFailuresScore > 0 {
+			failures := lipgloss.JoinVertical(lipgloss.Top, sshHeaderStyle.Render("Login After Failures"), renderIndicator(m.Data.SSHLoginFailuresScore, fmt.Sprintf("logged in after %d failed attempts", m.Data.SSHFailedLogins)))
+			sshInfo = lipgloss.JoinVertical(lipgloss.Top, sshInfo, failures)
+		}
+	}
+
+	// get the source's hostname and MAC address from its dhcp lease
+	srcHostInfo := ""
+	if m.Data.SrcHostName != "" || m.Data.SrcMAC != "" {
+		srcHostInfoLabel := lipgloss.NewStyle().MarginTop(1).Render(sectionStyle.Render("「 Source Host 」"))
+		srcHostHeaderStyle := lipgloss.NewStyle().Background(overlay2).Foreground(base).Bold(true).Padding(0, 2)
+		srcHostValueStyle := lipgloss.NewStyle().Width(m.Viewport.Width)
+
+		hostName := lipgloss.JoinVertical(lipgloss.Top, srcHostHeaderStyle.Render("Hostname"), srcHostValueStyle.Render(m.Data.SrcHostName))
+		mac := lipgloss.JoinVertical(lipgloss.Top, srcHostHeaderStyle.Render("MAC"), srcHostValueStyle.Render(m.Data.SrcMAC))
+		srcHostInfo = lipgloss.JoinVertical(lipgloss.Top, srcHostInfoLabel, hostName, mac)
+	}
+
 	// join contents
-	return lipgloss.JoinVertical(lipgloss.Top, heading, modifierLabel, modifiers, connInfoLabel, connCount, bytes, ports)
+	return lipgloss.JoinVertical(lipgloss.Top, heading, modifierLabel, modifiers, connInfoLabel, connCount, bytes, sentReceived, ports, certInfo, exfiltrationInfo, lateralInfo, tunnelingInfo, dgaInfo, portScanInfo, sshInfo, resolvedIPInfo, srcHostInfo)
 }
 
 func (m *sidebarModel) renderModifiers() string {
@@ -229,6 +362,18 @@ func (m *sidebarModel) getModifiers() []modifier {
 		modifiers = append(modifiers, modifier{label: "Missing Host Header", value: fmt.Sprintf("Was missing host %dx", m.Data.MissingHostCount), delta: m.Data.MissingHostHeaderScore})
 	}
 
+	if m.Data.CertAnomalies != "" {
+		modifiers = append(modifiers, modifier{label: "Certificate", value: m.Data.CertAnomalies, delta: m.Data.CertAnomalyScore})
+	}
+
+	if m.Data.FastFlux != "" {
+		modifiers = append(modifiers, modifier{label: "Fast Flux", value: m.Data.FastFlux, delta: m.Data.FastFluxScore})
+	}
+
+	if m.Data.RareSSHVersions != "" {
+		modifiers = append(modifiers, modifier{label: "Rare SSH Version", value: m.Data.RareSSHVersions, delta: m.Data.RareSSHVersionScore})
+	}
+
 	if m.Data.ThreatIntelDataSizeScore != 0 {
 		var label string
 		if m.Data.ThreatIntelDataSizeScore > 0 {